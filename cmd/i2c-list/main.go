@@ -28,8 +28,40 @@ func printPin(fn string, p pin.Pin) {
 	}
 }
 
+// scan returns the addresses of the devices acknowledging a one byte read.
+//
+// The reserved 7-bit addresses are skipped. When tenBit is true, the 10-bit
+// addresses are also scanned.
+func scan(bus i2c.Bus, tenBit bool) []uint16 {
+	var out []uint16
+	var b [1]byte
+	for addr := uint16(0x08); addr < 0x78; addr++ {
+		if bus.Tx(addr, nil, b[:]) == nil {
+			out = append(out, addr)
+		}
+	}
+	if tenBit {
+		for addr := uint16(0); addr < 0x400; addr++ {
+			if bus.Tx(addr|i2c.TenBit, nil, b[:]) == nil {
+				out = append(out, addr|i2c.TenBit)
+			}
+		}
+	}
+	return out
+}
+
+func printAddr(addr uint16) {
+	if addr&i2c.TenBit != 0 {
+		fmt.Printf("    0x%03X (10-bit)\n", addr&^i2c.TenBit)
+	} else {
+		fmt.Printf("    0x%02X\n", addr)
+	}
+}
+
 func mainImpl() error {
 	verbose := flag.Bool("v", false, "verbose mode")
+	doScan := flag.Bool("scan", false, "scan the buses for devices")
+	tenBit := flag.Bool("10", false, "also scan 10-bit addresses; implies -scan")
 	flag.Parse()
 	if !*verbose {
 		log.SetOutput(ioutil.Discard)
@@ -63,6 +95,12 @@ func mainImpl() error {
 			printPin("SCL", p.SCL())
 			printPin("SDA", p.SDA())
 		}
+		if *doScan || *tenBit {
+			fmt.Print("  Devices:\n")
+			for _, addr := range scan(bus, *tenBit) {
+				printAddr(addr)
+			}
+		}
 		if err := bus.Close(); err != nil {
 			return err
 		}
@@ -3,6 +3,9 @@
 // that can be found in the LICENSE file.
 
 // onewire-list lists all onewire buses and devices.
+//
+// The devices are identified by their family code and the sensors with a
+// registered driver are read.
 package main
 
 import (
@@ -15,7 +18,9 @@ import (
 
 	"periph.io/x/periph/conn/onewire"
 	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/physic"
 	"periph.io/x/periph/conn/pin/pinreg"
+	_ "periph.io/x/periph/devices/ds18b20"
 )
 
 func mainImpl() error {
@@ -57,18 +62,47 @@ func mainImpl() error {
 				fmt.Printf("  Q: %-10s\n", p)
 			}
 		}
-		addresses, err := bus.Search(false)
+		devices, err := onewire.Discover(bus)
+		for _, d := range devices {
+			printDevice(&d)
+		}
 		if err != nil {
 			fmt.Println("  Search error:", err)
-			continue
-		}
-		for _, address := range addresses {
-			fmt.Printf("  Device address: %#016X\n", address)
 		}
 	}
 	return nil
 }
 
+// printDevice prints the address and the family of the device, and a reading
+// for sensors.
+func printDevice(d *onewire.Device) {
+	fmt.Printf("  Device address: %#016X %-8s", uint64(d.Addr), d.Addr.Family())
+	if !d.Addr.CheckCRC() {
+		fmt.Print(" (invalid CRC)")
+	}
+	if d.Err != nil {
+		fmt.Printf(" Open error: %v\n", d.Err)
+		return
+	}
+	if s, ok := d.Dev.(physic.SenseEnv); ok {
+		var e physic.Env
+		if err := s.Sense(&e); err != nil {
+			fmt.Printf(" Sense error: %v\n", err)
+			return
+		}
+		if e.Temperature != 0 {
+			fmt.Printf(" %8s", e.Temperature)
+		}
+		if e.Pressure != 0 {
+			fmt.Printf(" %10s", e.Pressure)
+		}
+		if e.Humidity != 0 {
+			fmt.Printf(" %9s", e.Humidity)
+		}
+	}
+	fmt.Print("\n")
+}
+
 func main() {
 	if err := mainImpl(); err != nil {
 		fmt.Fprintf(os.Stderr, "onewire-list: %s.\n", err)
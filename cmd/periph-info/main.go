@@ -14,6 +14,7 @@ import (
 	"os"
 
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn/bustrace"
 )
 
 func printDrivers(drivers []periph.DriverFailure) {
@@ -32,8 +33,26 @@ func printDrivers(drivers []periph.DriverFailure) {
 	}
 }
 
+// printStats prints the bus statistics saved by bustrace.Tracer.WriteStatsFile().
+func printStats(path string) error {
+	stats, err := bustrace.ReadStatsFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Bus statistics:\n")
+	if len(stats) == 0 {
+		fmt.Print("  <none>\n")
+		return nil
+	}
+	for i := range stats {
+		fmt.Printf("- %s\n", &stats[i])
+	}
+	return nil
+}
+
 func mainImpl() error {
 	verbose := flag.Bool("v", false, "verbose mode")
+	stats := flag.String("stats", "", "print the bus statistics saved in this file by bustrace and exit")
 	flag.Parse()
 	if !*verbose {
 		log.SetOutput(ioutil.Discard)
@@ -42,6 +61,9 @@ func mainImpl() error {
 	if flag.NArg() != 0 {
 		return errors.New("unexpected argument, try -help")
 	}
+	if *stats != "" {
+		return printStats(*stats)
+	}
 
 	state, err := hostInit()
 	if err != nil {
@@ -0,0 +1,294 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package bustrace traces the transactions done on I²C, SPI and 1-wire buses
+// and the operations done on GPIO pins.
+//
+// A Tracer wraps buses and pins. It sends a structured Event for every
+// transaction to its Sink and maintains counters and latency histograms per
+// bus and device address.
+//
+// Tracing is opt-in: once a Tracer is passed to Enable(), the buses and pins
+// returned by i2creg.Open(), spireg.Open(), onewirereg.Open() and
+// gpioreg.ByName() are wrapped with it.
+//
+// The statistics can be saved with Tracer.WriteStatsFile() and printed with
+// periph-info -stats, or queried live from periph-web -trace.
+package bustrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Enable makes the registries wrap the buses and pins they return with t.
+//
+// It should be called before the buses and pins are opened. Pass nil to
+// disable tracing; buses and pins already opened continue to be traced.
+func Enable(t *Tracer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = t
+}
+
+// Enabled returns the Tracer passed to Enable(), or nil if tracing is
+// disabled.
+func Enabled() *Tracer {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Event is a traced bus transaction or pin operation.
+type Event struct {
+	// Start is when the operation started.
+	Start time.Time
+	// Duration is how long the operation took.
+	Duration time.Duration
+	// Kind is the kind of bus: "i2c", "spi", "onewire" or "gpio".
+	Kind string
+	// Bus is the name of the bus or of the pin.
+	Bus string
+	// Addr is the device address on I²C buses; it is 0 otherwise.
+	Addr uint16
+	// Op is the operation, e.g. "tx", "search", "in", "out", "read".
+	Op string
+	// W and R are the number of bytes written and read.
+	W int
+	R int
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+func (e *Event) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s", e.Kind, e.Bus)
+	if e.Kind == "i2c" {
+		fmt.Fprintf(&b, " 0x%02X", e.Addr)
+	}
+	fmt.Fprintf(&b, " %s", e.Op)
+	if e.W != 0 {
+		fmt.Fprintf(&b, " w=%d", e.W)
+	}
+	if e.R != 0 {
+		fmt.Fprintf(&b, " r=%d", e.R)
+	}
+	fmt.Fprintf(&b, " %s", e.Duration)
+	if e.Err != nil {
+		fmt.Fprintf(&b, " err=%v", e.Err)
+	}
+	return b.String()
+}
+
+// Sink receives the traced events.
+//
+// Record is called synchronously after each operation, potentially
+// concurrently, so it must be fast and safe for concurrent use. e must not be
+// retained after Record returns.
+type Sink interface {
+	Record(e *Event)
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(e *Event)
+
+// Record implements Sink.
+func (s SinkFunc) Record(e *Event) {
+	s(e)
+}
+
+// LatencyBuckets are the upper bounds of the buckets of a Histogram. The last
+// bucket of a Histogram counts the operations slower than the last bound.
+var LatencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Histogram is a latency histogram.
+type Histogram struct {
+	Counts [len(LatencyBuckets) + 1]uint64
+	Sum    time.Duration
+	Max    time.Duration
+}
+
+// Mean returns the average latency.
+func (h *Histogram) Mean() time.Duration {
+	n := uint64(0)
+	for _, c := range h.Counts {
+		n += c
+	}
+	if n == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(n)
+}
+
+func (h *Histogram) add(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+// Stats are the counters of a device address on a bus, or of a pin.
+type Stats struct {
+	Kind    string
+	Bus     string
+	Addr    uint16
+	Count   uint64 // Number of operations
+	Errors  uint64 // Number of operations that returned an error
+	W       uint64 // Number of bytes written
+	R       uint64 // Number of bytes read
+	Latency Histogram
+}
+
+func (s *Stats) String() string {
+	n := s.Kind + " " + s.Bus
+	if s.Kind == "i2c" {
+		n += fmt.Sprintf(" 0x%02X", s.Addr)
+	}
+	return fmt.Sprintf("%s: %d ops, %d errors, w=%dB r=%dB, mean %s, max %s", n, s.Count, s.Errors, s.W, s.R, s.Latency.Mean(), s.Latency.Max)
+}
+
+// Tracer traces the buses and pins it wraps.
+//
+// The zero value is valid and only maintains the statistics.
+type Tracer struct {
+	// Sink receives every event. It can be nil.
+	Sink Sink
+
+	mu    sync.Mutex
+	stats map[statsKey]*Stats
+}
+
+// Stats returns a snapshot of the statistics, sorted by kind, bus and
+// address.
+func (t *Tracer) Stats() []Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Stats, 0, len(t.stats))
+	for _, s := range t.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Bus != out[j].Bus {
+			return out[i].Bus < out[j].Bus
+		}
+		return out[i].Addr < out[j].Addr
+	})
+	return out
+}
+
+// Reset clears the statistics.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = nil
+}
+
+// WriteStats writes a snapshot of the statistics as JSON.
+func (t *Tracer) WriteStats(w io.Writer) error {
+	b, err := json.MarshalIndent(t.Stats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteStatsFile writes a snapshot of the statistics to the file path.
+//
+// The file can be printed with periph-info -stats.
+func (t *Tracer) WriteStatsFile(path string) error {
+	b, err := json.MarshalIndent(t.Stats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// ReadStats reads statistics written by Tracer.WriteStats().
+func ReadStats(r io.Reader) ([]Stats, error) {
+	var s []Stats
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("bustrace: invalid statistics: %v", err)
+	}
+	return s, nil
+}
+
+// ReadStatsFile reads statistics from the file path.
+func ReadStatsFile(path string) ([]Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadStats(f)
+}
+
+//
+
+var (
+	mu      sync.Mutex
+	current *Tracer
+)
+
+type statsKey struct {
+	kind string
+	bus  string
+	addr uint16
+}
+
+// record updates the statistics and sends e to the sink.
+func (t *Tracer) record(e *Event) {
+	k := statsKey{e.Kind, e.Bus, e.Addr}
+	t.mu.Lock()
+	if t.stats == nil {
+		t.stats = map[statsKey]*Stats{}
+	}
+	s := t.stats[k]
+	if s == nil {
+		s = &Stats{Kind: e.Kind, Bus: e.Bus, Addr: e.Addr}
+		t.stats[k] = s
+	}
+	s.Count++
+	if e.Err != nil {
+		s.Errors++
+	}
+	s.W += uint64(e.W)
+	s.R += uint64(e.R)
+	s.Latency.add(e.Duration)
+	t.mu.Unlock()
+	if t.Sink != nil {
+		t.Sink.Record(e)
+	}
+}
+
+// trace runs the operation f and records it.
+func (t *Tracer) trace(kind, bus string, addr uint16, op string, w, r int, f func() error) error {
+	e := Event{Start: time.Now(), Kind: kind, Bus: bus, Addr: addr, Op: op, W: w, R: r}
+	e.Err = f()
+	e.Duration = time.Since(e.Start)
+	t.record(&e)
+	return e.Err
+}
@@ -0,0 +1,507 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/i2c/smbus"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewiretest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func TestEnable(t *testing.T) {
+	defer Enable(nil)
+	if Enabled() != nil {
+		t.Fatal("tracing is disabled by default")
+	}
+	tr := &Tracer{}
+	Enable(tr)
+	if Enabled() != tr {
+		t.Fatal("expected tr")
+	}
+}
+
+func TestEvent_String(t *testing.T) {
+	data := []struct {
+		e        Event
+		expected string
+	}{
+		{Event{Duration: time.Microsecond, Kind: "i2c", Bus: "I2C1", Addr: 0x76, Op: "tx", W: 1, R: 2}, "i2c I2C1 0x76 tx w=1 r=2 1µs"},
+		{Event{Duration: time.Millisecond, Kind: "spi", Bus: "SPI0.0", Op: "txpackets", W: 4, Err: errors.New("oops")}, "spi SPI0.0 txpackets w=4 1ms err=oops"},
+		{Event{Kind: "gpio", Bus: "GPIO2", Op: "read"}, "gpio GPIO2 read 0s"},
+	}
+	for i, line := range data {
+		if s := line.e.String(); s != line.expected {
+			t.Fatalf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := Histogram{}
+	if m := h.Mean(); m != 0 {
+		t.Fatal(m)
+	}
+	for _, d := range []time.Duration{time.Microsecond, 10 * time.Microsecond, 50 * time.Microsecond, 2 * time.Second} {
+		h.add(d)
+	}
+	if h.Counts != [...]uint64{2, 1, 0, 0, 0, 0, 1} {
+		t.Fatal(h.Counts)
+	}
+	if h.Max != 2*time.Second {
+		t.Fatal(h.Max)
+	}
+	if m := h.Mean(); m != (2*time.Second+61*time.Microsecond)/4 {
+		t.Fatal(m)
+	}
+}
+
+func TestTracer_Stats(t *testing.T) {
+	r := &Ring{}
+	tr := &Tracer{Sink: r}
+	tr.record(&Event{Kind: "spi", Bus: "SPI0.0", Op: "tx", W: 2, R: 2})
+	tr.record(&Event{Kind: "i2c", Bus: "I2C1", Addr: 0x76, Op: "tx", W: 1, R: 8})
+	tr.record(&Event{Kind: "i2c", Bus: "I2C1", Addr: 0x50, Op: "tx", W: 3, Err: errors.New("nack")})
+	tr.record(&Event{Kind: "i2c", Bus: "I2C1", Addr: 0x76, Op: "tx", W: 1, R: 8})
+	s := tr.Stats()
+	if len(s) != 3 {
+		t.Fatal(s)
+	}
+	if s[0].Addr != 0x50 || s[0].Errors != 1 || s[1].Addr != 0x76 || s[1].Count != 2 || s[1].W != 2 || s[1].R != 16 || s[2].Kind != "spi" {
+		t.Fatal(s)
+	}
+	if str := s[1].String(); str != "i2c I2C1 0x76: 2 ops, 0 errors, w=2B r=16B, mean 0s, max 0s" {
+		t.Fatal(str)
+	}
+	if e := r.Events(); len(e) != 4 {
+		t.Fatal(e)
+	}
+	tr.Reset()
+	if s := tr.Stats(); len(s) != 0 {
+		t.Fatal(s)
+	}
+}
+
+func TestTracer_WriteStats(t *testing.T) {
+	tr := &Tracer{}
+	tr.record(&Event{Duration: time.Millisecond, Kind: "i2c", Bus: "I2C1", Addr: 0x76, Op: "tx", W: 1, R: 8})
+	buf := bytes.Buffer{}
+	if err := tr.WriteStats(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadStats(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 1 || s[0] != tr.Stats()[0] {
+		t.Fatal(s)
+	}
+	if _, err := ReadStats(strings.NewReader("{")); err == nil {
+		t.Fatal("invalid JSON")
+	}
+
+	d, err := ioutil.TempDir("", "bustrace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	p := filepath.Join(d, "stats.json")
+	if err := tr.WriteStatsFile(p); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := ReadStatsFile(p); err != nil || len(s) != 1 || s[0] != tr.Stats()[0] {
+		t.Fatal(s, err)
+	}
+	if _, err := ReadStatsFile(filepath.Join(d, "missing")); err == nil {
+		t.Fatal("missing file")
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := &Ring{Size: 3}
+	for i := 0; i < 5; i++ {
+		r.Record(&Event{W: i})
+	}
+	e := r.Events()
+	if len(e) != 3 || e[0].W != 2 || e[1].W != 3 || e[2].W != 4 {
+		t.Fatal(e)
+	}
+	r.Reset()
+	if e := r.Events(); len(e) != 0 {
+		t.Fatal(e)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var lines []string
+	l := &Logger{Logf: func(format string, v ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, v...))
+	}}
+	l.Record(&Event{Kind: "gpio", Bus: "GPIO2", Op: "out"})
+	if len(lines) != 1 || lines[0] != "gpio GPIO2 out 0s" {
+		t.Fatal(lines)
+	}
+}
+
+func TestI2C(t *testing.T) {
+	s := &i2ctest.Sim{
+		Devices: map[uint16]*i2ctest.SimDevice{0x76: {Regs: make([]byte, 256)}},
+		SCLPin:  &gpiotest.Pin{N: "SCL"},
+		SDAPin:  &gpiotest.Pin{N: "SDA"},
+	}
+	tr := &Tracer{}
+	b := tr.I2C(s)
+	if str := b.String(); str != "sim" {
+		t.Fatal(str)
+	}
+	r := make([]byte, 2)
+	if err := b.Tx(0x76, []byte{0}, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := i2c.TxContext(context.Background(), b, 0x76, []byte{0}, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx(0x10, []byte{0}, nil); err == nil {
+		t.Fatal("no device at 0x10")
+	}
+	if err := b.SetSpeed(physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	if p := b.(i2c.Pins).SCL(); p != s.SCLPin {
+		t.Fatal(p)
+	}
+	if p := b.(i2c.Pins).SDA(); p != s.SDAPin {
+		t.Fatal(p)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(smbus.Native); ok {
+		t.Fatal("i2ctest.Sim doesn't implement smbus.Native")
+	}
+	st := tr.Stats()
+	if len(st) != 2 || st[0].Addr != 0x10 || st[0].Errors != 1 || st[1].Count != 2 || st[1].W != 2 || st[1].R != 4 {
+		t.Fatal(st)
+	}
+}
+
+func TestI2C_native(t *testing.T) {
+	r := &Ring{}
+	tr := &Tracer{Sink: r}
+	b := tr.I2C(&nativeBus{})
+	d := smbus.Dev{Bus: b, Addr: 0x0B}
+	if v, err := d.ReadByteData(0x10); v != 0x42 || err != nil {
+		t.Fatal(v, err)
+	}
+	if e := r.Events(); len(e) != 1 || e[0].Op != "smbus" || e[0].Addr != 0x0B || e[0].W != 1 || e[0].R != 1 {
+		t.Fatal(e)
+	}
+}
+
+func TestI2C_native_size(t *testing.T) {
+	// The sizes are the ones of the I²C transaction smbus.Dev would emulate.
+	data := []struct {
+		p    smbus.Protocol
+		read bool
+		n    byte
+		pec  bool
+		w, r uint64
+	}{
+		{smbus.Quick, false, 0, true, 0, 0},
+		{smbus.Byte, true, 0, false, 0, 1},
+		{smbus.Byte, false, 0, true, 2, 0},
+		{smbus.ByteData, false, 0, false, 2, 0},
+		{smbus.WordData, true, 0, true, 1, 3},
+		{smbus.ProcCall, false, 0, false, 3, 2},
+		{smbus.BlockData, true, 0, false, 1, 1 + smbus.MaxBlock},
+		{smbus.BlockData, false, 4, false, 6, 0},
+		{smbus.BlockProcCall, false, 4, false, 6, 1 + smbus.MaxBlock},
+		{smbus.I2CBlockData, true, 4, false, 1, 4},
+		{smbus.I2CBlockData, false, 4, true, 6, 0},
+	}
+	tr := &Tracer{}
+	b := tr.I2C(&nativeBus{}).(smbus.Native)
+	var buf [smbus.DataSize]byte
+	for i, line := range data {
+		buf[0] = line.n
+		if err := b.SMBusTx(0x0B, line.p, line.read, 0x10, buf[:], line.pec); err != nil {
+			t.Fatal(err)
+		}
+		st := tr.Stats()
+		if len(st) != 1 || st[0].W != line.w || st[0].R != line.r {
+			t.Fatal(i, st)
+		}
+		tr.Reset()
+	}
+}
+
+func TestSPI(t *testing.T) {
+	tr := &Tracer{}
+	p := tr.SPI(&spitest.Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{1, 2}, R: []byte{3, 4}},
+				{W: []byte{5}, R: []byte{6}},
+				{W: []byte{7}},
+			},
+			D: conn.Full,
+		},
+	})
+	if err := p.LimitSpeed(physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if m := c.(conn.Limits).MaxTxSize(); m != 0 {
+		t.Fatal(m)
+	}
+	r := make([]byte, 2)
+	if err := c.Tx([]byte{1, 2}, r); err != nil {
+		t.Fatal(err)
+	}
+	pkts := []spi.Packet{{W: []byte{5}, R: make([]byte, 1)}, {W: []byte{7}}}
+	if err := spi.TxPacketsContext(context.Background(), c, pkts); err != nil {
+		t.Fatal(err)
+	}
+	if p := c.(spi.Pins).CS(); p != nil {
+		t.Fatal(p)
+	}
+	if p := p.(spi.Pins).CLK(); p != nil {
+		t.Fatal(p)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	st := tr.Stats()
+	if len(st) != 1 || st[0].Kind != "spi" || st[0].Count != 2 || st[0].W != 4 || st[0].R != 3 {
+		t.Fatal(st)
+	}
+}
+
+func TestSPI_Connect_err(t *testing.T) {
+	tr := &Tracer{}
+	p := tr.SPI(&spitest.Record{})
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0, 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0, 8); err == nil {
+		t.Fatal("Connect() can only be called once")
+	}
+}
+
+func TestOneWire(t *testing.T) {
+	tr := &Tracer{}
+	b := tr.OneWire(&searchBus{
+		Playback: onewiretest.Playback{
+			Ops: []onewiretest.IO{{W: []byte{0xCC, 0x44}, Pull: onewire.StrongPullup}, {W: []byte{0x33}, R: make([]byte, 8)}},
+		},
+		devices: []onewire.Address{0x7a00000131825228},
+	})
+	if err := b.Tx([]byte{0xCC, 0x44}, nil, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if err := onewire.TxContext(context.Background(), b, []byte{0x33}, make([]byte, 8), onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	a, err := b.Search(false)
+	if err != nil || len(a) != 1 {
+		t.Fatal(a, err)
+	}
+	if p := b.(onewire.Pins).Q(); p != nil {
+		t.Fatal(p)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	st := tr.Stats()
+	if len(st) != 1 || st[0].Count != 3 || st[0].W != 3 || st[0].R != 8 {
+		t.Fatal(st)
+	}
+}
+
+func TestOneWire_searcher(t *testing.T) {
+	a := onewiretest.MakeAddress(0x28, 1)
+	tr := &Tracer{}
+	b := tr.OneWire(&onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {}}})
+	s, ok := b.(onewire.BusSearcher)
+	if !ok {
+		t.Fatal("expected onewire.BusSearcher")
+	}
+	addrs, err := onewire.Search(s, false)
+	if err != nil || len(addrs) != 1 || addrs[0] != a {
+		t.Fatal(addrs, err)
+	}
+}
+
+func TestPin(t *testing.T) {
+	r := &Ring{}
+	tr := &Tracer{Sink: r}
+	g := &gpiotest.Pin{N: "GPIO2", Num: 2, EdgesChan: make(chan gpio.Level, 1)}
+	p := tr.Pin(g)
+	if n := p.Name(); n != "GPIO2" {
+		t.Fatal(n)
+	}
+	if real := p.(gpio.RealPin).Real(); real != g {
+		t.Fatal(real)
+	}
+	if err := p.In(gpio.PullDown, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	g.EdgesChan <- gpio.High
+	if !p.WaitForEdge(-1) {
+		t.Fatal("expected edge")
+	}
+	if err := p.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatal(l)
+	}
+	if err := p.PWM(gpio.DutyHalf, physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	g.EdgesChan <- gpio.Low
+	if e, ok := p.(gpio.PinEdgeEvents).WaitForEdgeEvent(-1); !ok || e.Level != gpio.Low {
+		t.Fatal(e, ok)
+	}
+	g.EdgesChan <- gpio.High
+	if !p.(gpio.PinInContext).WaitForEdgeContext(context.Background()) {
+		t.Fatal("expected edge")
+	}
+	if _, ok := p.(gpio.Grouper); ok {
+		t.Fatal("gpiotest.Pin doesn't implement gpio.Grouper")
+	}
+	var ops []string
+	for _, e := range r.Events() {
+		ops = append(ops, e.Op)
+	}
+	if s := strings.Join(ops, ","); s != "in,wait,out,read,pwm,wait,wait" {
+		t.Fatal(s)
+	}
+	if st := tr.Stats(); len(st) != 1 || st[0].Bus != "GPIO2" || st[0].Count != 7 {
+		t.Fatal(st)
+	}
+}
+
+func TestPin_optional(t *testing.T) {
+	tr := &Tracer{}
+	p := tr.Pin(gpio.INVALID)
+	if _, ok := p.(gpio.PinEdgeEvents); ok {
+		t.Fatal("gpio.INVALID doesn't implement gpio.PinEdgeEvents")
+	}
+	if _, ok := p.(gpio.PinInContext); ok {
+		t.Fatal("gpio.INVALID doesn't implement gpio.PinInContext")
+	}
+	// The optional interfaces of the real pin are found through an alias.
+	a := &groupPin{Pin: gpiotest.Pin{N: "GPIO3"}}
+	b := &groupPin{Pin: gpiotest.Pin{N: "GPIO4"}}
+	p = tr.Pin(&alias{a})
+	if _, ok := p.(gpio.PinEdgeEvents); !ok {
+		t.Fatal("expected gpio.PinEdgeEvents")
+	}
+	gr, ok := p.(gpio.Grouper)
+	if !ok {
+		t.Fatal("expected gpio.Grouper")
+	}
+	// The traced pins are resolved to the real pins.
+	g := gr.Group([]gpio.PinIO{p, tr.Pin(b)})
+	if g == nil {
+		t.Fatal("expected a native group")
+	}
+	if pins := g.Pins(); len(pins) != 2 || pins[0] != a || pins[1] != b {
+		t.Fatal(pins)
+	}
+	g, err := gpio.NewGroup(p, tr.Pin(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*group); !ok {
+		t.Fatal("expected a native group")
+	}
+}
+
+//
+
+// searchBus returns devices on Search() without doing the search cycle on the
+// playback.
+type searchBus struct {
+	onewiretest.Playback
+	devices []onewire.Address
+}
+
+func (s *searchBus) Search(alarmOnly bool) ([]onewire.Address, error) {
+	return s.devices, nil
+}
+
+// nativeBus implements smbus.Native; every byte read is 0x42.
+type nativeBus struct {
+	i2ctest.Playback
+}
+
+func (n *nativeBus) SMBusSupported(p smbus.Protocol, read, pec bool) bool {
+	return true
+}
+
+func (n *nativeBus) SMBusTx(addr uint16, p smbus.Protocol, read bool, cmd byte, data []byte, pec bool) error {
+	data[0] = 0x42
+	return nil
+}
+
+// groupPin implements gpio.Grouper.
+type groupPin struct {
+	gpiotest.Pin
+}
+
+func (g *groupPin) Group(pins []gpio.PinIO) gpio.Group {
+	for _, p := range pins {
+		if _, ok := p.(*groupPin); !ok {
+			return nil
+		}
+	}
+	return &group{pins}
+}
+
+type group struct {
+	pins []gpio.PinIO
+}
+
+func (g *group) String() string                   { return gpio.GroupString(g.pins) }
+func (g *group) Halt() error                      { return nil }
+func (g *group) Pins() []gpio.PinIO               { return g.pins }
+func (g *group) Out(bits, mask uint64) error      { return nil }
+func (g *group) Read(mask uint64) (uint64, error) { return 0, nil }
+
+// alias implements gpio.RealPin.
+type alias struct {
+	gpio.PinIO
+}
+
+func (a *alias) Real() gpio.PinIO {
+	return a.PinIO
+}
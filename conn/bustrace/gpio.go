@@ -0,0 +1,190 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"context"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+)
+
+// Pin returns p wrapped so that its operations are traced.
+//
+// In(), Read(), WaitForEdge(), Out() and PWM() are traced. The returned pin
+// implements gpio.RealPin to give access to the optional interfaces of p.
+//
+// It also implements gpio.PinEdgeEvents, gpio.PinInContext and gpio.Grouper
+// when the real pin behind p does. WaitForEdgeEvent() and
+// WaitForEdgeContext() are traced.
+func (t *Tracer) Pin(p gpio.PinIO) gpio.PinIO {
+	w := &pin{PinIO: p, t: t}
+	// Aliases hide the optional interfaces of the real pin.
+	r := w.Real()
+	e, isE := r.(gpio.PinEdgeEvents)
+	c, isC := r.(gpio.PinInContext)
+	g, isG := r.(gpio.Grouper)
+	switch {
+	case isE && isC && isG:
+		return &struct {
+			*pin
+			pinEvents
+			pinContext
+			pinGrouper
+		}{w, pinEvents{w, e}, pinContext{w, c}, pinGrouper{g}}
+	case isE && isC:
+		return &struct {
+			*pin
+			pinEvents
+			pinContext
+		}{w, pinEvents{w, e}, pinContext{w, c}}
+	case isE && isG:
+		return &struct {
+			*pin
+			pinEvents
+			pinGrouper
+		}{w, pinEvents{w, e}, pinGrouper{g}}
+	case isC && isG:
+		return &struct {
+			*pin
+			pinContext
+			pinGrouper
+		}{w, pinContext{w, c}, pinGrouper{g}}
+	case isE:
+		return &struct {
+			*pin
+			pinEvents
+		}{w, pinEvents{w, e}}
+	case isC:
+		return &struct {
+			*pin
+			pinContext
+		}{w, pinContext{w, c}}
+	case isG:
+		return &struct {
+			*pin
+			pinGrouper
+		}{w, pinGrouper{g}}
+	default:
+		return w
+	}
+}
+
+//
+
+type pin struct {
+	gpio.PinIO
+	t *Tracer
+}
+
+func (p *pin) Real() gpio.PinIO {
+	if r, ok := p.PinIO.(gpio.RealPin); ok {
+		return r.Real()
+	}
+	return p.PinIO
+}
+
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	return p.t.trace("gpio", p.PinIO.Name(), 0, "in", 0, 0, func() error {
+		return p.PinIO.In(pull, edge)
+	})
+}
+
+func (p *pin) Read() gpio.Level {
+	var l gpio.Level
+	_ = p.t.trace("gpio", p.PinIO.Name(), 0, "read", 0, 0, func() error {
+		l = p.PinIO.Read()
+		return nil
+	})
+	return l
+}
+
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	var b bool
+	_ = p.t.trace("gpio", p.PinIO.Name(), 0, "wait", 0, 0, func() error {
+		b = p.PinIO.WaitForEdge(timeout)
+		return nil
+	})
+	return b
+}
+
+func (p *pin) Out(l gpio.Level) error {
+	return p.t.trace("gpio", p.PinIO.Name(), 0, "out", 0, 0, func() error {
+		return p.PinIO.Out(l)
+	})
+}
+
+func (p *pin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	return p.t.trace("gpio", p.PinIO.Name(), 0, "pwm", 0, 0, func() error {
+		return p.PinIO.PWM(duty, f)
+	})
+}
+
+// pinEvents implements gpio.PinEdgeEvents for a pin whose real pin does.
+type pinEvents struct {
+	p *pin
+	e gpio.PinEdgeEvents
+}
+
+func (e pinEvents) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	var ev gpio.EdgeEvent
+	var b bool
+	_ = e.p.t.trace("gpio", e.p.PinIO.Name(), 0, "wait", 0, 0, func() error {
+		ev, b = e.e.WaitForEdgeEvent(timeout)
+		return nil
+	})
+	return ev, b
+}
+
+// pinContext implements gpio.PinInContext for a pin whose real pin does.
+type pinContext struct {
+	p *pin
+	c gpio.PinInContext
+}
+
+func (c pinContext) WaitForEdgeContext(ctx context.Context) bool {
+	var b bool
+	_ = c.p.t.trace("gpio", c.p.PinIO.Name(), 0, "wait", 0, 0, func() error {
+		b = c.c.WaitForEdgeContext(ctx)
+		return nil
+	})
+	return b
+}
+
+// pinGrouper implements gpio.Grouper for a pin whose real pin does.
+//
+// The pins are resolved to their real pin first, since the driver only
+// recognizes its own pins.
+type pinGrouper struct {
+	g gpio.Grouper
+}
+
+func (g pinGrouper) Group(pins []gpio.PinIO) gpio.Group {
+	resolved := make([]gpio.PinIO, len(pins))
+	for i, p := range pins {
+		for {
+			r, ok := p.(gpio.RealPin)
+			if !ok {
+				break
+			}
+			p = r.Real()
+		}
+		resolved[i] = p
+	}
+	return g.g.Group(resolved)
+}
+
+var _ gpio.PinIO = &pin{}
+var _ gpio.RealPin = &pin{}
+var _ gpio.PinEdgeEvents = &struct {
+	*pin
+	pinEvents
+}{}
+var _ gpio.PinInContext = &struct {
+	*pin
+	pinContext
+}{}
+var _ gpio.Grouper = &pinGrouper{}
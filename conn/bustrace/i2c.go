@@ -0,0 +1,149 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/smbus"
+	"periph.io/x/periph/conn/physic"
+)
+
+// I2C returns b wrapped so that its transactions are traced.
+//
+// The returned bus implements i2c.BusContext and i2c.Pins. It also implements
+// smbus.Native when b does; SMBus transactions are traced as "smbus", with
+// the sizes of the I²C transaction smbus.Dev would emulate.
+func (t *Tracer) I2C(b i2c.BusCloser) i2c.BusCloser {
+	i := &i2cBus{t: t, b: b}
+	if n, ok := b.(smbus.Native); ok {
+		return &i2cNative{i2cBus: i, n: n}
+	}
+	return i
+}
+
+//
+
+type i2cBus struct {
+	t *Tracer
+	b i2c.BusCloser
+}
+
+func (i *i2cBus) String() string {
+	return i.b.String()
+}
+
+func (i *i2cBus) Close() error {
+	return i.b.Close()
+}
+
+func (i *i2cBus) Tx(addr uint16, w, r []byte) error {
+	return i.t.trace("i2c", i.b.String(), addr, "tx", len(w), len(r), func() error {
+		return i.b.Tx(addr, w, r)
+	})
+}
+
+func (i *i2cBus) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	return i.t.trace("i2c", i.b.String(), addr, "tx", len(w), len(r), func() error {
+		return i2c.TxContext(ctx, i.b, addr, w, r)
+	})
+}
+
+func (i *i2cBus) SetSpeed(f physic.Frequency) error {
+	return i.b.SetSpeed(f)
+}
+
+func (i *i2cBus) SCL() gpio.PinIO {
+	if p, ok := i.b.(i2c.Pins); ok {
+		return p.SCL()
+	}
+	return gpio.INVALID
+}
+
+func (i *i2cBus) SDA() gpio.PinIO {
+	if p, ok := i.b.(i2c.Pins); ok {
+		return p.SDA()
+	}
+	return gpio.INVALID
+}
+
+// i2cNative is an i2cBus whose bus implements smbus.Native.
+type i2cNative struct {
+	*i2cBus
+	n smbus.Native
+}
+
+func (i *i2cNative) SMBusSupported(p smbus.Protocol, read, pec bool) bool {
+	return i.n.SMBusSupported(p, read, pec)
+}
+
+func (i *i2cNative) SMBusTx(addr uint16, p smbus.Protocol, read bool, cmd byte, data []byte, pec bool) error {
+	w, r := smbusSize(p, read, data, pec)
+	return i.t.trace("i2c", i.b.String(), addr, "smbus", w, r, func() error {
+		return i.n.SMBusTx(addr, p, read, cmd, data, pec)
+	})
+}
+
+// smbusSize returns the number of bytes written and read by a SMBus
+// transaction, sized the same way as when smbus.Dev emulates it with Tx().
+func smbusSize(p smbus.Protocol, read bool, data []byte, pec bool) (int, int) {
+	n := 0
+	if len(data) != 0 {
+		n = int(data[0])
+	}
+	w, r := 0, 0
+	switch p {
+	case smbus.Byte:
+		if read {
+			r = 1
+		} else {
+			w = 1
+		}
+	case smbus.ByteData, smbus.WordData:
+		l := 1
+		if p == smbus.WordData {
+			l = 2
+		}
+		w = 1
+		if read {
+			r = l
+		} else {
+			w += l
+		}
+	case smbus.ProcCall:
+		w, r = 3, 2
+	case smbus.BlockData:
+		w = 1
+		if read {
+			// The count is not known in advance, the maximum is read.
+			r = 1 + smbus.MaxBlock
+		} else {
+			w += 1 + n
+		}
+	case smbus.BlockProcCall:
+		w, r = 2+n, 1+smbus.MaxBlock
+	case smbus.I2CBlockData:
+		w = 1
+		if read {
+			r = n
+		} else {
+			w += n
+		}
+	}
+	if pec && (w != 0 || r != 0) {
+		if r == 0 {
+			w++
+		} else {
+			r++
+		}
+	}
+	return w, r
+}
+
+var _ i2c.BusContext = &i2cBus{}
+var _ i2c.Pins = &i2cBus{}
+var _ smbus.Native = &i2cNative{}
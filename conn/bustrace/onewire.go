@@ -0,0 +1,83 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// OneWire returns b wrapped so that its transactions are traced.
+//
+// The returned bus implements onewire.BusContext and onewire.Pins. It also
+// implements onewire.BusSearcher when b does; Search() is traced but not the
+// individual SearchTriplet() calls.
+func (t *Tracer) OneWire(b onewire.BusCloser) onewire.BusCloser {
+	o := &oneWireBus{t: t, b: b}
+	if s, ok := b.(onewire.BusSearcher); ok {
+		return &oneWireSearcher{oneWireBus: o, s: s}
+	}
+	return o
+}
+
+//
+
+type oneWireBus struct {
+	t *Tracer
+	b onewire.BusCloser
+}
+
+func (o *oneWireBus) String() string {
+	return o.b.String()
+}
+
+func (o *oneWireBus) Close() error {
+	return o.b.Close()
+}
+
+func (o *oneWireBus) Tx(w, r []byte, power onewire.Pullup) error {
+	return o.t.trace("onewire", o.b.String(), 0, "tx", len(w), len(r), func() error {
+		return o.b.Tx(w, r, power)
+	})
+}
+
+func (o *oneWireBus) TxContext(ctx context.Context, w, r []byte, power onewire.Pullup) error {
+	return o.t.trace("onewire", o.b.String(), 0, "tx", len(w), len(r), func() error {
+		return onewire.TxContext(ctx, o.b, w, r, power)
+	})
+}
+
+func (o *oneWireBus) Search(alarmOnly bool) ([]onewire.Address, error) {
+	var a []onewire.Address
+	err := o.t.trace("onewire", o.b.String(), 0, "search", 0, 0, func() error {
+		var err error
+		a, err = o.b.Search(alarmOnly)
+		return err
+	})
+	return a, err
+}
+
+func (o *oneWireBus) Q() gpio.PinIO {
+	if p, ok := o.b.(onewire.Pins); ok {
+		return p.Q()
+	}
+	return gpio.INVALID
+}
+
+// oneWireSearcher is a oneWireBus whose bus implements onewire.BusSearcher.
+type oneWireSearcher struct {
+	*oneWireBus
+	s onewire.BusSearcher
+}
+
+func (o *oneWireSearcher) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	return o.s.SearchTriplet(direction)
+}
+
+var _ onewire.BusContext = &oneWireBus{}
+var _ onewire.Pins = &oneWireBus{}
+var _ onewire.BusSearcher = &oneWireSearcher{}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"log"
+	"sync"
+)
+
+// Ring is a Sink that keeps the last Size events in memory.
+type Ring struct {
+	// Size is the number of events kept. It defaults to 1024.
+	Size int
+
+	mu     sync.Mutex
+	events []Event
+	next   int
+}
+
+// Record implements Sink.
+func (r *Ring) Record(e *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := r.Size
+	if size <= 0 {
+		size = 1024
+	}
+	if len(r.events) < size {
+		r.events = append(r.events, *e)
+		return
+	}
+	r.events[r.next] = *e
+	r.next = (r.next + 1) % len(r.events)
+}
+
+// Events returns a copy of the recorded events, oldest first.
+func (r *Ring) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Event, 0, len(r.events))
+	out = append(out, r.events[r.next:]...)
+	return append(out, r.events[:r.next]...)
+}
+
+// Reset discards the recorded events.
+func (r *Ring) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+	r.next = 0
+}
+
+// Logger is a Sink that logs every event.
+type Logger struct {
+	// Logf defaults to log.Printf.
+	Logf func(format string, v ...interface{})
+}
+
+// Record implements Sink.
+func (l *Logger) Record(e *Event) {
+	logf := l.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	logf("%s", e)
+}
+
+var _ Sink = &Ring{}
+var _ Sink = &Logger{}
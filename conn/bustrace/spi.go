@@ -0,0 +1,160 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bustrace
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// SPI returns p wrapped so that the transactions of its connection are
+// traced.
+//
+// The returned port implements spi.Pins. The connection returned by Connect()
+// implements spi.ConnContext, conn.Limits and spi.Pins.
+func (t *Tracer) SPI(p spi.PortCloser) spi.PortCloser {
+	return &spiPort{t: t, p: p}
+}
+
+//
+
+type spiPort struct {
+	t *Tracer
+	p spi.PortCloser
+}
+
+func (s *spiPort) String() string {
+	return s.p.String()
+}
+
+func (s *spiPort) Close() error {
+	return s.p.Close()
+}
+
+func (s *spiPort) LimitSpeed(f physic.Frequency) error {
+	return s.p.LimitSpeed(f)
+}
+
+func (s *spiPort) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	c, err := s.p.Connect(f, mode, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &spiConn{t: s.t, c: c, name: s.p.String()}, nil
+}
+
+func (s *spiPort) CLK() gpio.PinOut {
+	return spiPins(s.p).CLK()
+}
+
+func (s *spiPort) MOSI() gpio.PinOut {
+	return spiPins(s.p).MOSI()
+}
+
+func (s *spiPort) MISO() gpio.PinIn {
+	return spiPins(s.p).MISO()
+}
+
+func (s *spiPort) CS() gpio.PinOut {
+	return spiPins(s.p).CS()
+}
+
+type spiConn struct {
+	t    *Tracer
+	c    spi.Conn
+	name string
+}
+
+func (s *spiConn) String() string {
+	return s.c.String()
+}
+
+func (s *spiConn) Duplex() conn.Duplex {
+	return s.c.Duplex()
+}
+
+func (s *spiConn) MaxTxSize() int {
+	if l, ok := s.c.(conn.Limits); ok {
+		return l.MaxTxSize()
+	}
+	return 0
+}
+
+func (s *spiConn) Tx(w, r []byte) error {
+	return s.t.trace("spi", s.name, 0, "tx", len(w), len(r), func() error {
+		return s.c.Tx(w, r)
+	})
+}
+
+func (s *spiConn) TxContext(ctx context.Context, w, r []byte) error {
+	return s.t.trace("spi", s.name, 0, "tx", len(w), len(r), func() error {
+		return conn.TxContext(ctx, s.c, w, r)
+	})
+}
+
+func (s *spiConn) TxPackets(p []spi.Packet) error {
+	w, r := packetsLen(p)
+	return s.t.trace("spi", s.name, 0, "txpackets", w, r, func() error {
+		return s.c.TxPackets(p)
+	})
+}
+
+func (s *spiConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	w, r := packetsLen(p)
+	return s.t.trace("spi", s.name, 0, "txpackets", w, r, func() error {
+		return spi.TxPacketsContext(ctx, s.c, p)
+	})
+}
+
+func (s *spiConn) CLK() gpio.PinOut {
+	return spiPins(s.c).CLK()
+}
+
+func (s *spiConn) MOSI() gpio.PinOut {
+	return spiPins(s.c).MOSI()
+}
+
+func (s *spiConn) MISO() gpio.PinIn {
+	return spiPins(s.c).MISO()
+}
+
+func (s *spiConn) CS() gpio.PinOut {
+	return spiPins(s.c).CS()
+}
+
+func packetsLen(p []spi.Packet) (int, int) {
+	w, r := 0, 0
+	for i := range p {
+		w += len(p[i].W)
+		r += len(p[i].R)
+	}
+	return w, r
+}
+
+// spiPins returns the spi.Pins implementation of i, or one returning
+// gpio.INVALID.
+func spiPins(i interface{}) spi.Pins {
+	if p, ok := i.(spi.Pins); ok {
+		return p
+	}
+	return noPins{}
+}
+
+type noPins struct{}
+
+func (noPins) CLK() gpio.PinOut  { return gpio.INVALID }
+func (noPins) MOSI() gpio.PinOut { return gpio.INVALID }
+func (noPins) MISO() gpio.PinIn  { return gpio.INVALID }
+func (noPins) CS() gpio.PinOut   { return gpio.INVALID }
+
+var _ spi.PortCloser = &spiPort{}
+var _ spi.Pins = &spiPort{}
+var _ spi.ConnContext = &spiConn{}
+var _ conn.Limits = &spiConn{}
+var _ spi.Pins = &spiConn{}
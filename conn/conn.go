@@ -4,7 +4,11 @@
 
 package conn
 
-import "strconv"
+import (
+	"context"
+	"errors"
+	"strconv"
+)
 
 // Resource is a basic resource (like a gpio pin) or a device.
 type Resource interface {
@@ -101,3 +105,50 @@ type Limits interface {
 	// Returns 0 if undefined.
 	MaxTxSize() int
 }
+
+// ConnContext is optionally implemented by a Conn that supports aborting a
+// transaction via a context.Context.
+//
+// Use TxContext() to transparently support implementations that do not
+// implement this interface.
+type ConnContext interface {
+	Conn
+	// TxContext does a single transaction like Tx().
+	//
+	// It returns ctx.Err() if ctx is done before the transaction is started or
+	// while it is waiting for exclusive access to the connection. A transaction
+	// that is already in progress on the wire may not be interruptible.
+	TxContext(ctx context.Context, w, r []byte) error
+}
+
+// TxContext does a single transaction on c like Tx() but aborts when ctx is
+// done.
+//
+// It uses c.TxContext() if c implements ConnContext. Otherwise ctx is checked
+// before starting the transaction and c.Tx() is called; in this case, an
+// in-progress transaction cannot be aborted.
+func TxContext(ctx context.Context, c Conn, w, r []byte) error {
+	if cc, ok := c.(ConnContext); ok {
+		return cc.TxContext(ctx, w, r)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Tx(w, r)
+}
+
+// Errors returned by the drivers. They are usually wrapped with a driver
+// specific message via fmt.Errorf("...: %w", err), so use errors.Is() to test
+// for them.
+var (
+	// ErrTimeout is returned when a transaction didn't complete in time.
+	ErrTimeout = errors.New("conn: timeout")
+	// ErrBusy is returned when the bus is in use by another master or process.
+	ErrBusy = errors.New("conn: bus busy")
+	// ErrUnsupported is returned when an operation, a mode or a parameter is
+	// not supported by the driver or the hardware.
+	ErrUnsupported = errors.New("conn: not supported")
+	// ErrTooLarge is returned when a transaction is larger than what the
+	// driver supports. See Limits.
+	ErrTooLarge = errors.New("conn: transaction too large")
+)
@@ -5,6 +5,7 @@
 package conn
 
 import (
+	"context"
 	"testing"
 )
 
@@ -13,3 +14,60 @@ func TestDuplex(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestTxContext(t *testing.T) {
+	c := &fakeConn{}
+	if err := TxContext(context.Background(), c, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 1 {
+		t.Fatal(c.count)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TxContext(ctx, c, []byte{1}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if c.count != 1 {
+		t.Fatal(c.count)
+	}
+}
+
+func TestTxContext_Native(t *testing.T) {
+	c := &fakeConnContext{}
+	if err := TxContext(context.Background(), c, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 0 || c.countCtx != 1 {
+		t.Fatal(c.count, c.countCtx)
+	}
+}
+
+//
+
+type fakeConn struct {
+	count int
+}
+
+func (f *fakeConn) String() string {
+	return "fake"
+}
+
+func (f *fakeConn) Tx(w, r []byte) error {
+	f.count++
+	return nil
+}
+
+func (f *fakeConn) Duplex() Duplex {
+	return Half
+}
+
+type fakeConnContext struct {
+	fakeConn
+	countCtx int
+}
+
+func (f *fakeConnContext) TxContext(ctx context.Context, w, r []byte) error {
+	f.countCtx++
+	return nil
+}
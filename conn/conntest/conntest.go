@@ -7,20 +7,27 @@ package conntest
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"periph.io/x/periph/conn"
 )
 
-// IsErr returns true if the error is from a conntest failure.
+// IsErr returns true if the error is from a conntest failure, even if it is
+// wrapped.
 func IsErr(err error) bool {
-	_, ok := err.(testErr)
-	return ok
+	var t testErr
+	return errors.As(err, &t)
 }
 
 // Errorf returns a new error that returns true with IsErr().
+//
+// Like fmt.Errorf(), the %w verb can be used to wrap an error, e.g.
+// conn.ErrUnsupported, so it can be tested with errors.Is().
 func Errorf(format string, a ...interface{}) error {
 	return testErr{fmt.Errorf(format, a...)}
 }
@@ -38,12 +45,20 @@ func (r *RecordRaw) String() string {
 // Tx implements conn.Conn.
 func (r *RecordRaw) Tx(w, read []byte) error {
 	if len(read) != 0 {
-		return Errorf("conntest: not implemented")
+		return Errorf("conntest: read: %w", conn.ErrUnsupported)
 	}
 	_, err := r.W.Write(w)
 	return err
 }
 
+// TxContext implements conn.ConnContext.
+func (r *RecordRaw) TxContext(ctx context.Context, w, read []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.Tx(w, read)
+}
+
 // Duplex implements conn.Conn.
 func (r *RecordRaw) Duplex() conn.Duplex {
 	return conn.Half
@@ -53,15 +68,23 @@ func (r *RecordRaw) Duplex() conn.Duplex {
 type IO struct {
 	W []byte
 	R []byte
+	// T is the time elapsed since the first transaction was recorded. It is
+	// informational and ignored by Playback.
+	T time.Duration
+	// Err is the error returned by the transaction. Playback returns it.
+	Err error
 }
 
 // Record implements conn.Conn that records everything written to it.
 //
 // This can then be used to feed to Playback to do "replay" based unit tests.
+// Use Save() to serialize the recorded transactions.
 type Record struct {
 	sync.Mutex
 	Conn conn.Conn // Conn can be nil if only writes are being recorded.
 	Ops  []IO
+
+	clock Stopwatch
 }
 
 func (r *Record) String() string {
@@ -70,6 +93,16 @@ func (r *Record) String() string {
 
 // Tx implements conn.Conn.
 func (r *Record) Tx(w, read []byte) error {
+	return r.TxContext(context.Background(), w, read)
+}
+
+// TxContext implements conn.ConnContext.
+//
+// ctx is forwarded to Conn, if any.
+func (r *Record) TxContext(ctx context.Context, w, read []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	io := IO{}
 	if len(w) != 0 {
 		io.W = make([]byte, len(w))
@@ -77,21 +110,20 @@ func (r *Record) Tx(w, read []byte) error {
 	}
 	r.Lock()
 	defer r.Unlock()
+	io.T = r.clock.Elapsed()
 	if r.Conn == nil {
 		if len(read) != 0 {
-			return Errorf("conntest: read unsupported when no bus is connected")
+			return Errorf("conntest: read when no bus is connected: %w", conn.ErrUnsupported)
 		}
 	} else {
-		if err := r.Conn.Tx(w, read); err != nil {
-			return err
-		}
+		io.Err = conn.TxContext(ctx, r.Conn, w, read)
 	}
 	if len(read) != 0 {
 		io.R = make([]byte, len(read))
 		copy(io.R, read)
 	}
 	r.Ops = append(r.Ops, io)
-	return nil
+	return io.Err
 }
 
 // Duplex implements conn.Conn.
@@ -109,12 +141,20 @@ func (r *Record) Duplex() conn.Duplex {
 //
 // Set DontPanic to true to return an error instead of panicking, which is the
 // default.
+//
+// Use Load() or LoadGolden() to play back a serialized trace.
 type Playback struct {
 	sync.Mutex
 	Ops       []IO
 	D         conn.Duplex
 	Count     int
 	DontPanic bool
+
+	// Golden is the path of the trace file the Ops were loaded from.
+	Golden string
+	// Update, when set, records the transactions instead of playing back Ops.
+	// Close() then writes them to Golden. See LoadGolden().
+	Update *Record
 }
 
 func (p *Playback) String() string {
@@ -122,9 +162,14 @@ func (p *Playback) String() string {
 }
 
 // Close verifies that all the expected Ops have been consumed.
+//
+// When Update is set, it writes the recorded transactions to Golden instead.
 func (p *Playback) Close() error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return WriteTraceFile(p.Golden, p.Update.trace())
+	}
 	if len(p.Ops) != p.Count {
 		return errorf(p.DontPanic, "conntest: expected playback to be empty: I/O count %d; expected %d", p.Count, len(p.Ops))
 	}
@@ -135,6 +180,9 @@ func (p *Playback) Close() error {
 func (p *Playback) Tx(w, r []byte) error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return p.Update.Tx(w, r)
+	}
 	if len(p.Ops) <= p.Count {
 		return errorf(p.DontPanic, "conntest: unexpected Tx() (count #%d) expecting []conntest.IO{W:%#v, R:%#v}", p.Count, w, r)
 	}
@@ -146,7 +194,15 @@ func (p *Playback) Tx(w, r []byte) error {
 	}
 	copy(r, p.Ops[p.Count].R)
 	p.Count++
-	return nil
+	return p.Ops[p.Count-1].Err
+}
+
+// TxContext implements conn.ConnContext.
+func (p *Playback) TxContext(ctx context.Context, w, r []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Tx(w, r)
 }
 
 // Duplex implements conn.Conn.
@@ -174,11 +230,37 @@ func (d *Discard) Tx(w, r []byte) error {
 	return nil
 }
 
+// TxContext implements conn.ConnContext.
+func (d *Discard) TxContext(ctx context.Context, w, r []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.Tx(w, r)
+}
+
 // Duplex implements conn.Conn.
 func (d *Discard) Duplex() conn.Duplex {
 	return d.D
 }
 
+// Stopwatch timestamps IOs relative to the first one.
+//
+// It is meant to be used by Record implementations. The zero value is ready to
+// use. It is not safe for concurrent use.
+type Stopwatch struct {
+	start time.Time
+}
+
+// Elapsed returns the time elapsed since the first call to Elapsed, which
+// returns 0.
+func (s *Stopwatch) Elapsed() time.Duration {
+	if s.start.IsZero() {
+		s.start = time.Now()
+		return 0
+	}
+	return time.Since(s.start)
+}
+
 //
 
 // errorf is the internal implementation that optionally panic.
@@ -196,6 +278,14 @@ type testErr struct {
 	error
 }
 
+func (t testErr) Unwrap() error {
+	return errors.Unwrap(t.error)
+}
+
 var _ conn.Conn = &RecordRaw{}
 var _ conn.Conn = &Record{}
 var _ conn.Conn = &Playback{}
+var _ conn.ConnContext = &RecordRaw{}
+var _ conn.ConnContext = &Record{}
+var _ conn.ConnContext = &Playback{}
+var _ conn.ConnContext = &Discard{}
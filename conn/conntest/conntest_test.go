@@ -6,7 +6,10 @@ package conntest
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn"
 )
@@ -20,8 +23,8 @@ func TestRecordRaw(t *testing.T) {
 	if s := r.String(); s != "recordraw" {
 		t.Fatal(s)
 	}
-	if r.Tx(nil, []byte{0}) == nil {
-		t.Fatal("cannot accept read buffer")
+	if err := r.Tx(nil, []byte{0}); !IsErr(err) || !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal("cannot accept read buffer", err)
 	}
 	if err := r.Tx([]byte{'a'}, nil); err != nil {
 		t.Fatal(err)
@@ -36,8 +39,8 @@ func TestRecord_empty(t *testing.T) {
 	if s := r.String(); s != "record" {
 		t.Fatal(s)
 	}
-	if r.Tx(nil, []byte{'a'}) == nil {
-		t.Fatal("Bus is nil")
+	if err := r.Tx(nil, []byte{'a'}); !IsErr(err) || !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal("Bus is nil", err)
 	}
 	if d := r.Duplex(); d != conn.DuplexUnknown {
 		t.Fatal(d)
@@ -218,3 +221,36 @@ func TestDiscard(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStopwatch(t *testing.T) {
+	var s Stopwatch
+	if d := s.Elapsed(); d != 0 {
+		t.Fatal(d)
+	}
+	time.Sleep(time.Millisecond)
+	if d := s.Elapsed(); d < time.Millisecond {
+		t.Fatal(d)
+	}
+}
+
+func TestTxContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := &Playback{Ops: []IO{{W: []byte{10}, R: []byte{12}}}}
+	r := &Record{Conn: p}
+	v := [1]byte{}
+	for _, c := range []conn.ConnContext{&RecordRaw{W: &bytes.Buffer{}}, r, p, &Discard{}} {
+		if err := c.TxContext(ctx, []byte{10}, nil); err != context.Canceled {
+			t.Fatal(c, err)
+		}
+	}
+	if err := r.TxContext(context.Background(), []byte{10}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+	if v[0] != 12 || len(r.Ops) != 1 || p.Count != 1 {
+		t.Fatal(v, r.Ops, p.Count)
+	}
+	if err := (&Discard{}).TxContext(context.Background(), nil, v[:]); err != nil || v[0] != 0 {
+		t.Fatal(err, v)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+
+	"periph.io/x/periph/conn"
+)
+
+// Fault is a bus fault that can be injected by an Injector.
+type Fault int
+
+// Faults that can be injected.
+//
+// NACK and Timeout abort the transaction before it is sent to the underlying
+// bus and return a *FaultError. The other faults need a read buffer; the
+// transaction is done then the data read is corrupted.
+const (
+	NoFault   Fault = iota
+	NACK            // The device didn't acknowledge the transaction.
+	Timeout         // The transaction timed out.
+	ShortRead       // Only the first half of the read buffer was received; returns a *FaultError.
+	BitFlip         // A random bit of the read buffer is inverted; no error is returned.
+	CRC             // The last byte of the read buffer, usually a CRC, is inverted; no error is returned.
+)
+
+const faultName = "NoFaultNACKTimeoutShortReadBitFlipCRC"
+
+var faultIndex = [...]uint8{0, 7, 11, 18, 27, 34, 37}
+
+func (f Fault) String() string {
+	if f < 0 || int(f) >= len(faultIndex)-1 {
+		return fmt.Sprintf("Fault(%d)", f)
+	}
+	return faultName[faultIndex[f]:faultIndex[f+1]]
+}
+
+// FaultError is the error returned for an injected fault.
+type FaultError struct {
+	Fault Fault
+	Index int // Index of the transaction, starting at 0.
+}
+
+func (f *FaultError) Error() string {
+	return fmt.Sprintf("conntest: injected %s in transaction #%d", f.Fault, f.Index)
+}
+
+// Timeout returns true if the injected fault is a Timeout.
+func (f *FaultError) Timeout() bool {
+	return f.Fault == Timeout
+}
+
+// Is returns true for conn.ErrTimeout if the injected fault is a Timeout.
+//
+// The bus specific fakes convert a NACK to the error of their bus, e.g.
+// i2ctest.Faulty returns an *i2c.NACKError.
+func (f *FaultError) Is(target error) bool {
+	return f.Fault == Timeout && target == conn.ErrTimeout
+}
+
+// Injected is a fault injected by an Injector.
+type Injected struct {
+	Index int    // Index of the transaction, starting at 0.
+	Fault Fault  // Fault injected.
+	Bus   string // Name of the bus the transaction was done on.
+	Bit   int    // Bit inverted for BitFlip, number of bytes read for ShortRead.
+}
+
+func (i *Injected) String() string {
+	switch i.Fault {
+	case ShortRead:
+		return fmt.Sprintf("%s in transaction #%d on %s; read %d bytes", i.Fault, i.Index, i.Bus, i.Bit)
+	case BitFlip:
+		return fmt.Sprintf("%s in transaction #%d on %s; bit %d", i.Fault, i.Index, i.Bus, i.Bit)
+	default:
+		return fmt.Sprintf("%s in transaction #%d on %s", i.Fault, i.Index, i.Bus)
+	}
+}
+
+// Injector decides which fault to inject in each transaction.
+//
+// The faults listed in Schedule are injected first. Then, with a chance of
+// Probability, a fault is picked randomly from Faults. The random generator is
+// seeded with Seed so a failing run can be reproduced.
+//
+// An Injector can be shared by multiple Faulty wrappers, in which case the
+// transactions are counted across all of them.
+//
+// A nil *Injector injects no fault.
+type Injector struct {
+	sync.Mutex
+	// Probability is the chance, between 0 and 1, that a fault is injected in a
+	// transaction that is not in Schedule.
+	Probability float64
+	// Faults lists the faults to pick from when Probability triggers. All the
+	// faults are used when empty.
+	Faults []Fault
+	// Seed is the seed of the random generator.
+	Seed int64
+	// Schedule maps the index of a transaction, starting at 0, to the fault to
+	// inject in it.
+	Schedule map[int]Fault
+	// Logf is called for each injected fault. It defaults to log.Printf.
+	Logf func(format string, a ...interface{})
+	// Log lists the injected faults.
+	Log []Injected
+
+	count int
+	rnd   *rand.Rand
+}
+
+// Inject runs tx, a transaction on the bus name with read buffer r, and
+// injects the next fault in it.
+//
+// It returns the error to return to the caller.
+func (i *Injector) Inject(name string, r []byte, tx func() error) error {
+	if i == nil {
+		return tx()
+	}
+	f, index, bit := i.next(name, len(r))
+	switch f {
+	case NoFault:
+		return tx()
+	case NACK, Timeout:
+		return &FaultError{Fault: f, Index: index}
+	}
+	if err := tx(); err != nil {
+		return err
+	}
+	switch f {
+	case ShortRead:
+		for j := bit; j < len(r); j++ {
+			r[j] = 0
+		}
+		return &FaultError{Fault: f, Index: index}
+	case BitFlip:
+		r[bit/8] ^= 1 << uint(bit%8)
+	case CRC:
+		r[len(r)-1] ^= 0xFF
+	}
+	return nil
+}
+
+// Count returns the number of transactions seen so far.
+func (i *Injector) Count() int {
+	i.Lock()
+	defer i.Unlock()
+	return i.count
+}
+
+//
+
+var allFaults = []Fault{NACK, Timeout, ShortRead, BitFlip, CRC}
+
+// next decides the fault to inject in the next transaction and logs it.
+func (i *Injector) next(name string, n int) (Fault, int, int) {
+	i.Lock()
+	defer i.Unlock()
+	index := i.count
+	i.count++
+	f, ok := i.Schedule[index]
+	if !ok && i.Probability > 0 && i.rand().Float64() < i.Probability {
+		faults := i.Faults
+		if len(faults) == 0 {
+			faults = allFaults
+		}
+		f = faults[i.rand().Intn(len(faults))]
+	}
+	if f >= ShortRead && n == 0 {
+		// There is nothing to corrupt.
+		f = NoFault
+	}
+	if f == NoFault {
+		return f, index, 0
+	}
+	bit := 0
+	switch f {
+	case ShortRead:
+		bit = n / 2
+	case BitFlip:
+		bit = i.rand().Intn(8 * n)
+	}
+	in := Injected{Index: index, Fault: f, Bus: name, Bit: bit}
+	i.Log = append(i.Log, in)
+	logf := i.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	logf("conntest: injected %s", &in)
+	return f, index, bit
+}
+
+func (i *Injector) rand() *rand.Rand {
+	if i.rnd == nil {
+		i.rnd = rand.New(rand.NewSource(i.Seed))
+	}
+	return i.rnd
+}
+
+// Faulty implements conn.Conn and injects faults in the transactions done on
+// Conn.
+//
+// It can wrap a Record or a Playback, or be wrapped by a Record to record the
+// faults seen by a driver.
+type Faulty struct {
+	Conn     conn.Conn
+	Injector *Injector
+}
+
+func (f *Faulty) String() string {
+	return "faulty"
+}
+
+// Tx implements conn.Conn.
+func (f *Faulty) Tx(w, r []byte) error {
+	return f.TxContext(context.Background(), w, r)
+}
+
+// TxContext implements conn.ConnContext.
+func (f *Faulty) TxContext(ctx context.Context, w, r []byte) error {
+	return f.Injector.Inject(f.Conn.String(), r, func() error {
+		return conn.TxContext(ctx, f.Conn, w, r)
+	})
+}
+
+// Duplex implements conn.Conn.
+func (f *Faulty) Duplex() conn.Duplex {
+	return f.Conn.Duplex()
+}
+
+var _ conn.Conn = &Faulty{}
+var _ conn.ConnContext = &Faulty{}
@@ -0,0 +1,178 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn"
+)
+
+func TestFault_String(t *testing.T) {
+	data := []struct {
+		f        Fault
+		expected string
+	}{
+		{NoFault, "NoFault"},
+		{NACK, "NACK"},
+		{Timeout, "Timeout"},
+		{ShortRead, "ShortRead"},
+		{BitFlip, "BitFlip"},
+		{CRC, "CRC"},
+		{Fault(10), "Fault(10)"},
+	}
+	for i, line := range data {
+		if s := line.f.String(); s != line.expected {
+			t.Errorf("#%d: %q != %q", i, s, line.expected)
+		}
+	}
+}
+
+func TestFaulty_Schedule(t *testing.T) {
+	var logs []string
+	inj := &Injector{
+		Schedule: map[int]Fault{0: NACK, 1: Timeout, 2: ShortRead, 3: CRC, 5: BitFlip, 6: CRC},
+		Logf: func(format string, a ...interface{}) {
+			logs = append(logs, format)
+		},
+	}
+	p := &Playback{
+		Ops: []IO{
+			{W: []byte{1}, R: []byte{0xAA, 0xBB, 0xCC, 0xDD}},
+			{W: []byte{2}, R: []byte{0x12, 0x34}},
+			{W: []byte{3}, R: []byte{0x56}},
+			{W: []byte{4}, R: []byte{0xFF}},
+			{W: []byte{5}},
+		},
+		D: conn.Half,
+	}
+	f := &Faulty{Conn: p, Injector: inj}
+	if s := f.String(); s != "faulty" {
+		t.Fatal(s)
+	}
+	if d := f.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	r := make([]byte, 4)
+	// NACK and Timeout are not forwarded.
+	err := f.Tx([]byte{1}, r)
+	if fe, ok := err.(*FaultError); !ok || fe.Fault != NACK || fe.Index != 0 || fe.Timeout() || errors.Is(err, conn.ErrTimeout) {
+		t.Fatal(err)
+	}
+	err = f.Tx([]byte{1}, r)
+	if fe, ok := err.(*FaultError); !ok || fe.Fault != Timeout || !fe.Timeout() || !errors.Is(err, conn.ErrTimeout) {
+		t.Fatal(err)
+	}
+	if err.Error() != "conntest: injected Timeout in transaction #1" {
+		t.Fatal(err)
+	}
+	if p.Count != 0 {
+		t.Fatal(p.Count)
+	}
+	err = f.Tx([]byte{1}, r)
+	if fe, ok := err.(*FaultError); !ok || fe.Fault != ShortRead {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0xAA, 0xBB, 0, 0}) {
+		t.Fatal(r)
+	}
+	r = r[:2]
+	if err := f.Tx([]byte{2}, r); err != nil || !bytes.Equal(r, []byte{0x12, 0xCB}) {
+		t.Fatal(r, err)
+	}
+	r = r[:1]
+	if err := f.Tx([]byte{3}, r); err != nil || r[0] != 0x56 {
+		t.Fatal(r, err)
+	}
+	if err := f.Tx([]byte{4}, r); err != nil || r[0] != 0xFF^(1<<uint(inj.Log[4].Bit)) {
+		t.Fatal(r, err)
+	}
+	// Faults corrupting data are not injected without a read buffer.
+	if err := f.Tx([]byte{5}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c := inj.Count(); c != 7 {
+		t.Fatal(c)
+	}
+	if len(inj.Log) != 5 || len(logs) != 5 {
+		t.Fatal(inj.Log, logs)
+	}
+	expected := []string{
+		"NACK in transaction #0 on playback",
+		"Timeout in transaction #1 on playback",
+		"ShortRead in transaction #2 on playback; read 2 bytes",
+		"CRC in transaction #3 on playback",
+	}
+	for i, s := range expected {
+		if x := inj.Log[i].String(); x != s {
+			t.Errorf("#%d: %q != %q", i, x, s)
+		}
+	}
+	if inj.Log[4].Fault != BitFlip || inj.Log[4].Bit > 7 {
+		t.Fatal(inj.Log[4])
+	}
+}
+
+func TestFaulty_Probability(t *testing.T) {
+	run := func(seed int64) []Injected {
+		inj := &Injector{Probability: 0.5, Seed: seed, Faults: []Fault{NACK, BitFlip}, Logf: func(string, ...interface{}) {}}
+		f := &Faulty{Conn: &Discard{D: conn.Full}, Injector: inj}
+		r := make([]byte, 2)
+		for i := 0; i < 100; i++ {
+			if err := f.Tx(nil, r); err != nil {
+				if fe, ok := err.(*FaultError); !ok || fe.Fault != NACK {
+					t.Fatal(err)
+				}
+			}
+		}
+		return inj.Log
+	}
+	a := run(1)
+	if len(a) < 25 || len(a) > 75 {
+		t.Fatalf("unexpected number of faults: %d", len(a))
+	}
+	b := run(1)
+	if len(a) != len(b) {
+		t.Fatal("seed is not deterministic")
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("#%d: %v != %v", i, a[i], b[i])
+		}
+		if a[i].Fault != NACK && a[i].Fault != BitFlip {
+			t.Fatal(a[i])
+		}
+	}
+}
+
+func TestFaulty_Record(t *testing.T) {
+	// Record the faults seen by a driver.
+	inj := &Injector{Schedule: map[int]Fault{1: NACK}, Logf: func(string, ...interface{}) {}}
+	r := &Record{Conn: &Faulty{Conn: &Discard{D: conn.Full}, Injector: inj}}
+	for i := 0; i < 3; i++ {
+		err := r.Tx([]byte{byte(i)}, nil)
+		if (i == 1) != (err != nil) {
+			t.Fatal(i, err)
+		}
+	}
+	if len(r.Ops) != 3 || r.Ops[1].Err == nil {
+		t.Fatal(r.Ops)
+	}
+	if fe, ok := r.Ops[1].Err.(*FaultError); !ok || fe.Fault != NACK {
+		t.Fatal(r.Ops[1].Err)
+	}
+}
+
+func TestInjector_nil(t *testing.T) {
+	f := &Faulty{Conn: &Discard{D: conn.Full}}
+	if err := f.Tx(nil, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"periph.io/x/periph/conn"
+)
+
+// UpdateGolden specifies that the LoadGolden() functions of the *test
+// packages return a Playback that forwards the transactions to a real bus
+// and rewrites the golden file on Close(), instead of playing the file back.
+//
+// It is true when the environment variable PERIPH_UPDATE_GOLDEN is set. Tests
+// can also set it, for example from a command line flag.
+var UpdateGolden = os.Getenv("PERIPH_UPDATE_GOLDEN") != ""
+
+// TraceVersion is the version of the trace format written by WriteTrace().
+const TraceVersion = 1
+
+// Trace is the serializable form of a recorded I/O flow.
+//
+// It is shared by all the *test packages. Use the Save() and Load() functions
+// of each package to convert between a Record, a Trace and a Playback.
+type Trace struct {
+	// Version is the version of the format; it is TraceVersion.
+	Version int `json:"version"`
+	// Kind is the kind of bus that was recorded, e.g. "conn", "i2c", "spi" or
+	// "onewire".
+	Kind string `json:"kind"`
+	// Bus is the name of the bus that was recorded.
+	Bus string `json:"bus,omitempty"`
+	// Meta is free form metadata about the bus, like its speed.
+	Meta map[string]string `json:"meta,omitempty"`
+	Ops  []TraceOp         `json:"ops"`
+}
+
+// TraceOp is a single transaction in a Trace.
+//
+// W and R are encoded as hexadecimal strings.
+type TraceOp struct {
+	// T is the time elapsed since the first transaction.
+	T time.Duration
+	// Addr is the device address, if applicable.
+	Addr uint16
+	W    []byte
+	R    []byte
+	// Pull is set if the transaction ended with a strong pull up on 1-wire.
+	Pull bool
+	// Err is the error returned by the transaction, if any.
+	Err string
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *TraceOp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&traceOp{
+		T:    int64(t.T),
+		Addr: t.Addr,
+		W:    hex.EncodeToString(t.W),
+		R:    hex.EncodeToString(t.R),
+		Pull: t.Pull,
+		Err:  t.Err,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TraceOp) UnmarshalJSON(b []byte) error {
+	var o traceOp
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	w, err := decodeHex(o.W)
+	if err != nil {
+		return err
+	}
+	r, err := decodeHex(o.R)
+	if err != nil {
+		return err
+	}
+	*t = TraceOp{T: time.Duration(o.T), Addr: o.Addr, W: w, R: r, Pull: o.Pull, Err: o.Err}
+	return nil
+}
+
+// Error returns Err as an error, or nil if Err is empty.
+func (t *TraceOp) Error() error {
+	if t.Err == "" {
+		return nil
+	}
+	return errors.New(t.Err)
+}
+
+// WriteTrace writes t as JSON.
+//
+// The output is stable and has one transaction per line, so it diffs well
+// when stored as a golden file.
+func WriteTrace(w io.Writer, t *Trace) error {
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	fmt.Fprintf(&b, "  \"version\": %d,\n", t.Version)
+	writeField(&b, "kind", t.Kind)
+	if t.Bus != "" {
+		writeField(&b, "bus", t.Bus)
+	}
+	if len(t.Meta) != 0 {
+		writeField(&b, "meta", t.Meta)
+	}
+	b.WriteString("  \"ops\": [")
+	for i := range t.Ops {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n    ")
+		o, err := json.Marshal(&t.Ops[i])
+		if err != nil {
+			return err
+		}
+		b.Write(o)
+	}
+	if len(t.Ops) != 0 {
+		b.WriteString("\n  ")
+	}
+	b.WriteString("]\n}\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ReadTrace reads a trace written by WriteTrace().
+func ReadTrace(r io.Reader) (*Trace, error) {
+	t := &Trace{}
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	if err := d.Decode(t); err != nil {
+		return nil, fmt.Errorf("conntest: invalid trace: %v", err)
+	}
+	if t.Version != TraceVersion {
+		return nil, fmt.Errorf("conntest: unsupported trace version %d", t.Version)
+	}
+	return t, nil
+}
+
+// WriteTraceFile writes t to the file path.
+func WriteTraceFile(path string, t *Trace) error {
+	var b bytes.Buffer
+	if err := WriteTrace(&b, t); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
+
+// ReadTraceFile reads a trace from the file path.
+func ReadTraceFile(path string) (*Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTrace(f)
+}
+
+// Save writes the transactions recorded by r as a trace.
+func Save(w io.Writer, r *Record) error {
+	return WriteTrace(w, r.trace())
+}
+
+// Load reads a trace written by Save() and returns it as a Playback.
+func Load(r io.Reader) (*Playback, error) {
+	t, err := ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	return playback(t)
+}
+
+// LoadGolden returns a Playback for the golden file path.
+//
+// When UpdateGolden is set, the file is not read; the returned Playback
+// forwards the transactions to c and rewrites the file on Close() instead.
+func LoadGolden(path string, c conn.Conn) (*Playback, error) {
+	if UpdateGolden {
+		if c == nil {
+			return nil, errors.New("conntest: a connection is required to update " + path)
+		}
+		return &Playback{Update: &Record{Conn: c}, Golden: path, D: c.Duplex()}, nil
+	}
+	t, err := ReadTraceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := playback(t)
+	if err != nil {
+		return nil, err
+	}
+	p.Golden = path
+	return p, nil
+}
+
+// ToTrace converts recorded IOs to their serializable form.
+func ToTrace(ops []IO) []TraceOp {
+	out := make([]TraceOp, len(ops))
+	for i, o := range ops {
+		out[i] = TraceOp{T: o.T, W: o.W, R: o.R}
+		if o.Err != nil {
+			out[i].Err = o.Err.Error()
+		}
+	}
+	return out
+}
+
+// FromTrace converts serialized transactions back to IOs.
+func FromTrace(ops []TraceOp) []IO {
+	out := make([]IO, len(ops))
+	for i := range ops {
+		out[i] = IO{W: ops[i].W, R: ops[i].R, T: ops[i].T, Err: ops[i].Error()}
+	}
+	return out
+}
+
+//
+
+// traceOp is the JSON encoding of TraceOp.
+type traceOp struct {
+	T    int64  `json:"t"`
+	Addr uint16 `json:"addr,omitempty"`
+	W    string `json:"w,omitempty"`
+	R    string `json:"r,omitempty"`
+	Pull bool   `json:"pull,omitempty"`
+	Err  string `json:"err,omitempty"`
+}
+
+func (r *Record) trace() *Trace {
+	r.Lock()
+	defer r.Unlock()
+	t := &Trace{Version: TraceVersion, Kind: "conn", Ops: ToTrace(r.Ops)}
+	if r.Conn != nil {
+		t.Bus = r.Conn.String()
+		t.Meta = map[string]string{"duplex": r.Conn.Duplex().String()}
+	}
+	return t
+}
+
+func playback(t *Trace) (*Playback, error) {
+	if t.Kind != "conn" {
+		return nil, fmt.Errorf("conntest: expected a conn trace, got %q", t.Kind)
+	}
+	p := &Playback{Ops: FromTrace(t.Ops)}
+	switch t.Meta["duplex"] {
+	case conn.Half.String():
+		p.D = conn.Half
+	case conn.Full.String():
+		p.D = conn.Full
+	}
+	return p, nil
+}
+
+func writeField(b *bytes.Buffer, name string, v interface{}) {
+	j, _ := json.Marshal(v)
+	fmt.Fprintf(b, "  %q: %s,\n", name, j)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
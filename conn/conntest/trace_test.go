@@ -0,0 +1,149 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package conntest
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+)
+
+func TestSave_Load(t *testing.T) {
+	p := &Playback{
+		Ops: []IO{
+			{W: []byte{0x10}, R: []byte{0xAB, 0xCD}},
+			{W: []byte{0x20}, Err: errors.New("oops")},
+		},
+		D: conn.Half,
+	}
+	r := Record{Conn: p}
+	b := make([]byte, 2)
+	if err := r.Tx([]byte{0x10}, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Tx([]byte{0x20}, nil); err == nil || err.Error() != "oops" {
+		t.Fatal(err)
+	}
+	if len(r.Ops) != 2 || r.Ops[1].Err == nil || r.Ops[1].T < r.Ops[0].T {
+		t.Fatal(r.Ops)
+	}
+	// Make the output deterministic.
+	r.Ops[0].T = 0
+	r.Ops[1].T = 1500 * time.Microsecond
+
+	buf := bytes.Buffer{}
+	if err := Save(&buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "version": 1,
+  "kind": "conn",
+  "bus": "playback",
+  "meta": {"duplex":"Half"},
+  "ops": [
+    {"t":0,"w":"10","r":"abcd"},
+    {"t":1500000,"w":"20","err":"oops"}
+  ]
+}
+`
+	if s := buf.String(); s != expected {
+		t.Fatalf("unexpected trace:\n%s", s)
+	}
+
+	l, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := l.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	if err := l.Tx([]byte{0x10}, b); err != nil || !bytes.Equal(b, []byte{0xAB, 0xCD}) {
+		t.Fatal(b, err)
+	}
+	if err := l.Tx([]byte{0x20}, nil); err == nil || err.Error() != "oops" {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadTrace_errors(t *testing.T) {
+	data := []string{
+		``,
+		`{"version": 2, "kind": "conn", "ops": []}`,
+		`{"version": 1, "kind": "conn", "unknown": 1, "ops": []}`,
+		`{"version": 1, "kind": "conn", "ops": [{"t": 0, "w": "zz"}]}`,
+		`{"version": 1, "kind": "conn", "ops": [{"t": 0, "r": "zz"}]}`,
+		`{"version": 1, "kind": "conn", "ops": [{"t": "x"}]}`,
+	}
+	for i, s := range data {
+		if _, err := ReadTrace(strings.NewReader(s)); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+	}
+	if _, err := Load(strings.NewReader(`{"version": 1, "kind": "i2c", "ops": []}`)); err == nil {
+		t.Fatal("wrong kind")
+	}
+	if _, err := ReadTraceFile(filepath.Join(os.TempDir(), "periph_conntest_missing.json")); err == nil {
+		t.Fatal("missing file")
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_conntest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+	defer func(u bool) {
+		UpdateGolden = u
+	}(UpdateGolden)
+
+	UpdateGolden = true
+	if _, err := LoadGolden(path, nil); err == nil {
+		t.Fatal("connection is required")
+	}
+	p, err := LoadGolden(path, &Discard{D: conn.Full})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	b := []byte{1}
+	if err := p.Tx([]byte{2}, b); err != nil || b[0] != 0 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	UpdateGolden = false
+	if p, err = LoadGolden(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.Golden != path || len(p.Ops) != 1 || p.Duplex() != conn.Full {
+		t.Fatal(p.Golden, p.Ops, p.Duplex())
+	}
+	b[0] = 1
+	if err := p.Tx([]byte{2}, b); err != nil || b[0] != 0 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGolden(filepath.Join(dir, "missing.json"), nil); err == nil {
+		t.Fatal("missing file")
+	}
+}
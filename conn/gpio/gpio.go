@@ -9,6 +9,7 @@
 package gpio
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
@@ -250,8 +251,104 @@ type RealPin interface {
 	Real() PinIO // Real returns the real pin behind an Alias
 }
 
+// EdgeEvent is an edge detected on an input pin.
+type EdgeEvent struct {
+	// Level is the level the pin transitioned to.
+	Level Level
+	// Time is the monotonic timestamp of the edge.
+	//
+	// The epoch is implementation specific so only the difference between two
+	// timestamps returned by the same pin is meaningful. On linux, drivers use
+	// the CLOCK_MONOTONIC clock when possible.
+	Time time.Duration
+}
+
+// PinEdgeEvents is optionally implemented by a PinIn that can report when
+// each edge happened and which level the pin transitioned to.
+//
+// This is useful to decode signals where the timing matters, like infrared
+// remotes, ultrasonic rangers or frequency counters.
+type PinEdgeEvents interface {
+	PinIn
+	// WaitForEdgeEvent waits for the next edge or immediately returns the
+	// oldest queued edge.
+	//
+	// Only waits for the kind of edge as specified in a previous In() call.
+	//
+	// Edges are queued as they are detected so that a burst of edges is not
+	// lost when the caller can't keep up. The queue depth is implementation
+	// specific; when the queue overflows, edges are lost. Calling In() flushes
+	// the queue.
+	//
+	// Returns false if the timeout occurred or In() was called while waiting,
+	// causing the function to exit.
+	//
+	// Specify -1 to effectively disable timeout.
+	WaitForEdgeEvent(timeout time.Duration) (EdgeEvent, bool)
+}
+
+// PinInContext is optionally implemented by a PinIn that supports aborting
+// WaitForEdge() via a context.Context.
+//
+// Use WaitForEdgeContext() to transparently support implementations that do
+// not implement this interface.
+type PinInContext interface {
+	PinIn
+	// WaitForEdgeContext waits for the next edge like WaitForEdge() until ctx
+	// is done.
+	//
+	// The deadline of ctx, if any, is used as the timeout.
+	//
+	// Returns false if ctx is done or In() was called while waiting, causing
+	// the function to exit.
+	WaitForEdgeContext(ctx context.Context) bool
+}
+
+// WaitForEdgeContext waits for the next edge on p until ctx is done.
+//
+// It uses p.WaitForEdgeContext() if p implements PinInContext. Otherwise it
+// emulates it by calling p.WaitForEdge() with a short timeout in a loop, so
+// cancellation is detected with a latency of up to 100ms. In this case, the
+// function exits when p.WaitForEdge() returns false before its timeout, for
+// example because no edge detection is enabled or In() was called while
+// waiting.
+//
+// p is not unwrapped via RealPin, since wrappers like debouncers change the
+// edges reported by the pin; the aliases returned by gpioreg implement
+// PinInContext themselves.
+func WaitForEdgeContext(ctx context.Context, p PinIn) bool {
+	if pc, ok := p.(PinInContext); ok {
+		return pc.WaitForEdgeContext(ctx)
+	}
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		timeout := contextPollPeriod
+		if d, ok := ctx.Deadline(); ok {
+			if r := time.Until(d); r < timeout {
+				if r <= 0 {
+					return false
+				}
+				timeout = r
+			}
+		}
+		start := time.Now()
+		if p.WaitForEdge(timeout) {
+			return true
+		}
+		if time.Since(start) < timeout {
+			return false
+		}
+	}
+}
+
 //
 
+// contextPollPeriod is the maximum latency to detect a canceled context when
+// emulating WaitForEdgeContext().
+const contextPollPeriod = 100 * time.Millisecond
+
 // errInvalidPin is returned when trying to use INVALID.
 var errInvalidPin = errors.New("gpio: invalid pin")
 
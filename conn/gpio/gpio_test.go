@@ -5,6 +5,7 @@
 package gpio
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -155,3 +156,94 @@ func TestInvalid(t *testing.T) {
 		t.Fatal("can't set func")
 	}
 }
+
+func TestWaitForEdgeContext(t *testing.T) {
+	p := &edgePin{edges: 1}
+	if !WaitForEdgeContext(context.Background(), p) {
+		t.Fatal("expected edge")
+	}
+	if p.calls != 2 {
+		t.Fatal(p.calls)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if WaitForEdgeContext(ctx, p) {
+		t.Fatal("unexpected edge")
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	if WaitForEdgeContext(ctx, &edgePin{edges: -1}) {
+		t.Fatal("unexpected edge")
+	}
+}
+
+func TestWaitForEdgeContext_NoEdge(t *testing.T) {
+	// WaitForEdge() returns false right away, e.g. when no edge detection is
+	// enabled.
+	p := &edgePin{edges: -1, early: true}
+	if WaitForEdgeContext(context.Background(), p) {
+		t.Fatal("unexpected edge")
+	}
+	if p.calls != 1 {
+		t.Fatal(p.calls)
+	}
+}
+
+func TestWaitForEdgeContext_Native(t *testing.T) {
+	p := &edgePinContext{}
+	if !WaitForEdgeContext(context.Background(), p) {
+		t.Fatal("expected edge")
+	}
+	if p.calls != 1 {
+		t.Fatal(p.calls)
+	}
+	// Wrappers implementing RealPin are not bypassed.
+	w := &edgePin{}
+	if !WaitForEdgeContext(context.Background(), &aliasPin{PinIO: w, real: p}) {
+		t.Fatal("expected edge")
+	}
+	if p.calls != 1 || w.calls != 1 {
+		t.Fatal(p.calls, w.calls)
+	}
+}
+
+//
+
+// edgePin times out edges times before returning an edge.
+type edgePin struct {
+	invalidPin
+	edges int
+	early bool // Return false without waiting for the timeout
+	calls int
+}
+
+func (e *edgePin) WaitForEdge(timeout time.Duration) bool {
+	e.calls++
+	if e.edges != 0 {
+		e.edges--
+		if !e.early {
+			time.Sleep(timeout)
+		}
+		return false
+	}
+	return true
+}
+
+type edgePinContext struct {
+	invalidPin
+	calls int
+}
+
+func (e *edgePinContext) WaitForEdgeContext(ctx context.Context) bool {
+	e.calls++
+	return true
+}
+
+type aliasPin struct {
+	PinIO
+	real PinIO
+}
+
+func (a *aliasPin) Real() PinIO {
+	return a.real
+}
@@ -6,10 +6,12 @@
 package gpioreg
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"sync"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/gpio"
 )
 
@@ -20,21 +22,17 @@ import (
 // position "P1_3", it's function name "I2C1_SDA".
 //
 // Returns nil if the gpio pin is not present.
+//
+// The pin is wrapped with the bustrace.Tracer passed to bustrace.Enable(), if
+// any. The same wrapper is returned for each lookup of the same name.
 func ByName(name string) gpio.PinIO {
-	mu.Lock()
-	defer mu.Unlock()
-	if p, ok := byName[name]; ok {
-		return p
-	}
-	if dest, ok := byAlias[name]; ok {
-		if p := getByNameDeep(dest); p != nil {
-			// Wraps the destination in an alias, so the name makes sense to the user.
-			// The main drawback is that casting into other gpio interfaces like
-			// gpio.PinPWM requires going through gpio.RealPin first.
-			return &pinAlias{p, name}
+	p := lookup(name)
+	if p != nil {
+		if t := bustrace.Enabled(); t != nil {
+			return traced(t, name, p)
 		}
 	}
-	return nil
+	return p
 }
 
 // All returns all the GPIO pins available on this host.
@@ -129,6 +127,7 @@ func RegisterAlias(alias string, dest string) error {
 func Unregister(name string) error {
 	mu.Lock()
 	defer mu.Unlock()
+	delete(byTrace, name)
 	if _, ok := byName[name]; ok {
 		delete(byName, name)
 		return nil
@@ -142,12 +141,56 @@ func Unregister(name string) error {
 
 //
 
+func lookup(name string) gpio.PinIO {
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := byName[name]; ok {
+		return p
+	}
+	if dest, ok := byAlias[name]; ok {
+		if p := getByNameDeep(dest); p != nil {
+			// Wraps the destination in an alias, so the name makes sense to the user.
+			// The main drawback is that casting into other gpio interfaces like
+			// gpio.PinPWM requires going through gpio.RealPin first.
+			return &pinAlias{p, name}
+		}
+	}
+	return nil
+}
+
+// traced returns p, found by name, wrapped with t.
+//
+// The wrapper is cached per name. It is reused as long as the tracer and the
+// real pin behind name didn't change.
+func traced(t *bustrace.Tracer, name string, p gpio.PinIO) gpio.PinIO {
+	real := p
+	if a, ok := p.(*pinAlias); ok {
+		real = a.PinIO
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := byTrace[name]; ok && c.t == t && c.real == real {
+		return c.p
+	}
+	w := t.Pin(p)
+	byTrace[name] = tracedPin{t, real, w}
+	return w
+}
+
 var (
 	mu      sync.Mutex
 	byName  = map[string]gpio.PinIO{}
 	byAlias = map[string]string{}
+	byTrace = map[string]tracedPin{}
 )
 
+// tracedPin is a pin wrapped by a bustrace.Tracer.
+type tracedPin struct {
+	t    *bustrace.Tracer
+	real gpio.PinIO
+	p    gpio.PinIO
+}
+
 // pinAlias implements an alias for a PinIO.
 //
 // pinAlias implements the RealPin interface, which allows querying for the
@@ -173,6 +216,13 @@ func (a *pinAlias) Real() gpio.PinIO {
 	return a.PinIO
 }
 
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// It uses the real pin's WaitForEdgeContext() if available.
+func (a *pinAlias) WaitForEdgeContext(ctx context.Context) bool {
+	return gpio.WaitForEdgeContext(ctx, a.PinIO)
+}
+
 // getByNameDeep recursively resolves the aliases to get the pin.
 func getByNameDeep(name string) gpio.PinIO {
 	if p, ok := byName[name]; ok {
@@ -211,3 +261,5 @@ func search(n int, f func(int) bool) int {
 	}
 	return lo
 }
+
+var _ gpio.PinInContext = &pinAlias{}
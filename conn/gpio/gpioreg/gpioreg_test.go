@@ -5,9 +5,12 @@
 package gpioreg
 
 import (
+	"context"
 	"testing"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
 )
 
 func TestRegister(t *testing.T) {
@@ -55,6 +58,55 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestByName_trace(t *testing.T) {
+	defer reset()
+	defer bustrace.Enable(nil)
+	p := &basicPin{PinIO: gpio.INVALID, name: "a", num: 0}
+	if err := Register(p); err != nil {
+		t.Fatal(err)
+	}
+	bustrace.Enable(&bustrace.Tracer{})
+	if ByName("b") != nil {
+		t.Fatal("unknown pin")
+	}
+	a := ByName("a")
+	if a == p {
+		t.Fatal("expected the pin to be traced")
+	}
+	if r := a.(gpio.RealPin).Real(); r != p {
+		t.Fatal(r)
+	}
+	if b := ByName("a"); b != a {
+		t.Fatal("expected the same wrapper")
+	}
+	if err := RegisterAlias("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	b := ByName("b")
+	if b == a || b != ByName("b") {
+		t.Fatal("expected one wrapper per name")
+	}
+	if r := b.(gpio.RealPin).Real(); r != p {
+		t.Fatal(r)
+	}
+	// The wrapper is replaced when the pin changes.
+	if err := Unregister("a"); err != nil {
+		t.Fatal(err)
+	}
+	p2 := &basicPin{PinIO: gpio.INVALID, name: "a", num: 1}
+	if err := Register(p2); err != nil {
+		t.Fatal(err)
+	}
+	if r := ByName("a").(gpio.RealPin).Real(); r != p2 {
+		t.Fatal(r)
+	}
+	// And when the tracer changes.
+	bustrace.Enable(&bustrace.Tracer{})
+	if c := ByName("b"); c == b {
+		t.Fatal("expected a new wrapper")
+	}
+}
+
 func TestRegister_fail(t *testing.T) {
 	defer reset()
 	if err := Register(&basicPin{PinIO: gpio.INVALID}); err == nil {
@@ -153,6 +205,26 @@ func TestRegisterAlias_chain(t *testing.T) {
 	}
 }
 
+func TestRegisterAlias_waitForEdgeContext(t *testing.T) {
+	defer reset()
+	p := &gpiotest.Pin{N: "a", Num: 0, EdgesChan: make(chan gpio.Level, 1)}
+	if err := Register(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterAlias("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	p.EdgesChan <- gpio.High
+	if !gpio.WaitForEdgeContext(context.Background(), ByName("b")) {
+		t.Fatal("expected edge")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if gpio.WaitForEdgeContext(ctx, ByName("b")) {
+		t.Fatal("context is canceled")
+	}
+}
+
 func TestRegisterAlias_fail(t *testing.T) {
 	defer reset()
 	if err := RegisterAlias("", "Dest"); err == nil {
@@ -238,4 +310,5 @@ func reset() {
 	defer mu.Unlock()
 	byName = map[string]gpio.PinIO{}
 	byAlias = map[string]string{}
+	byTrace = map[string]tracedPin{}
 }
@@ -6,6 +6,7 @@
 package gpiotest
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -33,6 +34,11 @@ type Pin struct {
 	EdgesChan chan gpio.Level  // Use it to fake edges
 	D         gpio.Duty        // PWM duty
 	F         physic.Frequency // PWM period
+
+	// EventsChan is used to fake timestamped edges. It can be used instead of
+	// or along EdgesChan. Use a buffered channel to simulate the queue of
+	// gpio.PinEdgeEvents.
+	EventsChan chan gpio.EdgeEvent
 }
 
 // String implements conn.Resource.
@@ -87,13 +93,14 @@ func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
 	} else if pull == gpio.PullUp {
 		p.L = gpio.High
 	}
-	if edge != gpio.NoEdge && p.EdgesChan == nil {
+	if edge != gpio.NoEdge && p.EdgesChan == nil && p.EventsChan == nil {
 		return errors.New("gpiotest: please set p.EdgesChan first")
 	}
 	// Flush any buffered edges.
 	for {
 		select {
 		case <-p.EdgesChan:
+		case <-p.EventsChan:
 		default:
 			return nil
 		}
@@ -109,17 +116,40 @@ func (p *Pin) Read() gpio.Level {
 
 // WaitForEdge implements gpio.PinIn.
 func (p *Pin) WaitForEdge(timeout time.Duration) bool {
-	if timeout == -1 {
-		_ = p.Out(<-p.EdgesChan)
-		return true
+	_, ok := p.WaitForEdgeEvent(timeout)
+	return ok
+}
+
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// Edges received from EdgesChan are timestamped when they are received.
+func (p *Pin) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	var t <-chan time.Time
+	if timeout != -1 {
+		t = time.After(timeout)
 	}
+	return p.waitForEdgeEvent(nil, t)
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	_, ok := p.waitForEdgeEvent(ctx.Done(), nil)
+	return ok
+}
+
+func (p *Pin) waitForEdgeEvent(done <-chan struct{}, t <-chan time.Time) (gpio.EdgeEvent, bool) {
+	var e gpio.EdgeEvent
 	select {
-	case <-time.After(timeout):
-		return false
-	case l := <-p.EdgesChan:
-		_ = p.Out(l)
-		return true
+	case <-done:
+		return e, false
+	case <-t:
+		return e, false
+	case e.Level = <-p.EdgesChan:
+		e.Time = time.Since(epoch)
+	case e = <-p.EventsChan:
 	}
+	_ = p.Out(e.Level)
+	return e, true
 }
 
 // Pull implements gpio.PinIn.
@@ -192,5 +222,10 @@ func (p *LogPinIO) PWM(duty gpio.Duty, f physic.Frequency) error {
 	return p.PinIO.PWM(duty, f)
 }
 
+// epoch is the reference for the timestamps of edges received from EdgesChan.
+var epoch = time.Now()
+
 var _ gpio.PinIO = &Pin{}
+var _ gpio.PinEdgeEvents = &Pin{}
+var _ gpio.PinInContext = &Pin{}
 var _ pin.PinFunc = &Pin{}
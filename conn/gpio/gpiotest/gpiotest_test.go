@@ -5,6 +5,7 @@
 package gpiotest
 
 import (
+	"context"
 	"flag"
 	"io/ioutil"
 	"log"
@@ -98,6 +99,61 @@ func TestPin_edge(t *testing.T) {
 	}
 }
 
+func TestPin_WaitForEdgeEvent(t *testing.T) {
+	p := &Pin{N: "GPIO1", Num: 1, EventsChan: make(chan gpio.EdgeEvent, 2)}
+	p.EventsChan <- gpio.EdgeEvent{Level: gpio.High, Time: time.Second}
+	p.EventsChan <- gpio.EdgeEvent{Level: gpio.Low, Time: 2 * time.Second}
+	if e, ok := p.WaitForEdgeEvent(-1); !ok || e.Level != gpio.High || e.Time != time.Second {
+		t.Fatal(e, ok)
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatalf("unexpected %s", l)
+	}
+	if e, ok := p.WaitForEdgeEvent(time.Minute); !ok || e.Level != gpio.Low || e.Time != 2*time.Second {
+		t.Fatal(e, ok)
+	}
+	if _, ok := p.WaitForEdgeEvent(time.Millisecond); ok {
+		t.Fatal("unexpected edge")
+	}
+
+	// Edges from EdgesChan are timestamped on reception.
+	p.EdgesChan = make(chan gpio.Level, 1)
+	p.EdgesChan <- gpio.High
+	if e, ok := p.WaitForEdgeEvent(-1); !ok || e.Level != gpio.High || e.Time <= 0 {
+		t.Fatal(e, ok)
+	}
+
+	// In() flushes the queue.
+	p.EventsChan <- gpio.EdgeEvent{Level: gpio.High}
+	if err := p.In(gpio.PullNoChange, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.WaitForEdgeEvent(0); ok {
+		t.Fatal("unexpected edge")
+	}
+}
+
+func TestPin_WaitForEdgeContext(t *testing.T) {
+	p := &Pin{N: "GPIO1", Num: 1, EdgesChan: make(chan gpio.Level, 1)}
+	p.EdgesChan <- gpio.High
+	if !p.WaitForEdgeContext(context.Background()) {
+		t.Fatal("expected edge")
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatalf("unexpected %s", l)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	if p.WaitForEdgeContext(ctx) {
+		t.Fatal("unexpected edge")
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if gpio.WaitForEdgeContext(ctx, p) {
+		t.Fatal("unexpected edge")
+	}
+}
+
 func TestPin_fail(t *testing.T) {
 	p := &Pin{N: "GPIO1", Num: 1, Fn: "I2C1_SDA"}
 	if err := p.In(gpio.Float, gpio.BothEdges); err == nil {
@@ -0,0 +1,139 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"errors"
+	"strings"
+
+	"periph.io/x/periph/conn"
+)
+
+// Group is a set of pins that are read or written as a single operation.
+//
+// Bit i of the bitmasks used by Out() and Read() maps to Pins()[i].
+//
+// Use NewGroup() to create a Group.
+type Group interface {
+	conn.Resource
+	// Pins returns the pins in the group.
+	Pins() []PinIO
+	// Out sets the pins selected by mask to the level of their corresponding
+	// bit in bits.
+	//
+	// Pins selected by mask that were not set as output are set as output
+	// first. Pins not selected by mask are not modified.
+	Out(bits, mask uint64) error
+	// Read returns the current level of the pins selected by mask as a bitmask.
+	//
+	// Bits not selected by mask are 0. It works for pins set as input or
+	// output.
+	Read(mask uint64) (uint64, error)
+}
+
+// Grouper is optionally implemented by a PinIO whose driver can access
+// multiple pins in a single operation, for example via a GPIO bank register.
+type Grouper interface {
+	// Group returns a native Group for pins.
+	//
+	// Returns nil if the pins cannot be grouped natively, for example because
+	// some of the pins are not managed by the same driver.
+	Group(pins []PinIO) Group
+}
+
+// NewGroup returns a Group to access pins as a single operation.
+//
+// Aliases are resolved to their real pin first. If the first pin implements
+// Grouper and accepts the pins, the native Group is returned. Otherwise the
+// returned Group accesses the pins sequentially.
+//
+// Up to 64 pins are supported.
+func NewGroup(pins ...PinIO) (Group, error) {
+	if len(pins) == 0 {
+		return nil, errors.New("gpio: group requires at least one pin")
+	}
+	if len(pins) > 64 {
+		return nil, errors.New("gpio: group supports up to 64 pins")
+	}
+	resolved := make([]PinIO, len(pins))
+	for i, p := range pins {
+		if p == nil {
+			return nil, errors.New("gpio: group pin cannot be nil")
+		}
+		for {
+			r, ok := p.(RealPin)
+			if !ok {
+				break
+			}
+			p = r.Real()
+		}
+		resolved[i] = p
+	}
+	if g, ok := resolved[0].(Grouper); ok {
+		if n := g.Group(resolved); n != nil {
+			return n, nil
+		}
+	}
+	return &pinGroup{pins: resolved}, nil
+}
+
+// GroupString returns a string representation of a Group made of pins.
+//
+// It is meant to be used by implementations of Group.
+func GroupString(pins []PinIO) string {
+	names := make([]string, len(pins))
+	for i, p := range pins {
+		names[i] = p.Name()
+	}
+	return "Group(" + strings.Join(names, ",") + ")"
+}
+
+//
+
+// pinGroup implements Group by accessing the pins sequentially.
+type pinGroup struct {
+	pins []PinIO
+}
+
+func (g *pinGroup) String() string {
+	return GroupString(g.pins)
+}
+
+// Halt implements conn.Resource.
+//
+// It is a no-op.
+func (g *pinGroup) Halt() error {
+	return nil
+}
+
+func (g *pinGroup) Pins() []PinIO {
+	return g.pins
+}
+
+func (g *pinGroup) Out(bits, mask uint64) error {
+	for i, p := range g.pins {
+		bit := uint64(1) << uint(i)
+		if mask&bit == 0 {
+			continue
+		}
+		if err := p.Out(Level(bits&bit != 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *pinGroup) Read(mask uint64) (uint64, error) {
+	var bits uint64
+	for i, p := range g.pins {
+		bit := uint64(1) << uint(i)
+		if mask&bit != 0 && p.Read() == High {
+			bits |= bit
+		}
+	}
+	return bits, nil
+}
+
+var _ Group = &pinGroup{}
@@ -0,0 +1,121 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package gpio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewGroup(t *testing.T) {
+	if _, err := NewGroup(); err == nil {
+		t.Fatal("no pin")
+	}
+	if _, err := NewGroup(nil); err == nil {
+		t.Fatal("nil pin")
+	}
+	if _, err := NewGroup(make([]PinIO, 65)...); err == nil {
+		t.Fatal("too many pins")
+	}
+	p0 := &groupPin{name: "P0"}
+	p1 := &groupPin{name: "P1"}
+	p2 := &groupPin{name: "P2", level: High}
+	g, err := NewGroup(p0, &aliasPin{PinIO: INVALID, real: p1}, p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := g.String(); s != "Group(P0,P1,P2)" {
+		t.Fatal(s)
+	}
+	if p := g.Pins(); len(p) != 3 || p[1] != p1 {
+		t.Fatal(p)
+	}
+	if err := g.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Out(0x3, 0x3); err != nil {
+		t.Fatal(err)
+	}
+	if p0.level != High || p1.level != High || p2.level != High {
+		t.Fatal(p0.level, p1.level, p2.level)
+	}
+	if p2.outs != 0 {
+		t.Fatal("pin outside mask was modified")
+	}
+	if err := g.Out(0x0, 0x2); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := g.Read(0x7); err != nil || v != 0x5 {
+		t.Fatal(v, err)
+	}
+	if v, err := g.Read(0x2); err != nil || v != 0 {
+		t.Fatal(v, err)
+	}
+	p1.err = errors.New("oops")
+	if err := g.Out(0x7, 0x7); err != p1.err {
+		t.Fatal(err)
+	}
+}
+
+func TestNewGroup_Native(t *testing.T) {
+	p0 := &grouperPin{groupPin: groupPin{name: "P0"}}
+	g, err := NewGroup(p0, &groupPin{name: "P1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*pinGroup); ok {
+		t.Fatal("expected native group")
+	}
+	p0.refuse = true
+	if g, err = NewGroup(p0, &groupPin{name: "P1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*pinGroup); !ok {
+		t.Fatal("expected fallback group")
+	}
+}
+
+//
+
+type groupPin struct {
+	invalidPin
+	name  string
+	level Level
+	outs  int
+	err   error
+}
+
+func (g *groupPin) Name() string {
+	return g.name
+}
+
+func (g *groupPin) Read() Level {
+	return g.level
+}
+
+func (g *groupPin) Out(l Level) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.outs++
+	g.level = l
+	return nil
+}
+
+type grouperPin struct {
+	groupPin
+	refuse bool
+}
+
+func (g *grouperPin) Group(pins []PinIO) Group {
+	if g.refuse {
+		return nil
+	}
+	return &nativeGroup{pinGroup{pins: pins}}
+}
+
+type nativeGroup struct {
+	pinGroup
+}
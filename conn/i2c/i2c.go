@@ -19,7 +19,9 @@
 package i2c
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 
@@ -41,6 +43,8 @@ type Bus interface {
 	//
 	// Write is done first, then read. One of 'w' or 'r' can be omitted for a
 	// unidirectional operation.
+	//
+	// addr is a 7-bit address or a 10-bit address; see TenBit.
 	Tx(addr uint16, w, r []byte) error
 	// SetSpeed changes the bus speed, if supported.
 	//
@@ -59,6 +63,36 @@ type BusCloser interface {
 	Bus
 }
 
+// BusContext is optionally implemented by a Bus that supports aborting a
+// transaction via a context.Context.
+//
+// Use TxContext() to transparently support implementations that do not
+// implement this interface.
+type BusContext interface {
+	Bus
+	// TxContext does a transaction at the specified device address like Tx().
+	//
+	// It returns ctx.Err() if ctx is done before the transaction is started or
+	// while it is waiting for exclusive access to the bus. A transaction that
+	// is already in progress on the wire may not be interruptible.
+	TxContext(ctx context.Context, addr uint16, w, r []byte) error
+}
+
+// TxContext does a transaction on b like Tx() but aborts when ctx is done.
+//
+// It uses b.TxContext() if b implements BusContext. Otherwise ctx is checked
+// before starting the transaction and b.Tx() is called; in this case, an
+// in-progress transaction cannot be aborted.
+func TxContext(ctx context.Context, b Bus, addr uint16, w, r []byte) error {
+	if bc, ok := b.(BusContext); ok {
+		return bc.TxContext(ctx, addr, w, r)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Tx(addr, w, r)
+}
+
 // Pins defines the pins that an I²C bus interconnect is using on the host.
 //
 // It is expected that a implementer of Bus also implement Pins but this is not
@@ -72,7 +106,7 @@ type Pins interface {
 
 // Dev is a device on a I²C bus.
 //
-// It implements conn.Conn.
+// It implements conn.Conn and conn.ConnContext.
 //
 // It saves from repeatedly specifying the device address.
 type Dev struct {
@@ -95,6 +129,13 @@ func (d *Dev) Tx(w, r []byte) error {
 	return d.Bus.Tx(d.Addr, w, r)
 }
 
+// TxContext does a transaction like Tx() but aborts when ctx is done.
+//
+// It's a wrapper for TxContext().
+func (d *Dev) TxContext(ctx context.Context, w, r []byte) error {
+	return TxContext(ctx, d.Bus, d.Addr, w, r)
+}
+
 // Write writes to the I²C bus without reading, implementing io.Writer.
 //
 // It's a wrapper for Tx()
@@ -110,6 +151,23 @@ func (d *Dev) Duplex() conn.Duplex {
 	return conn.Half
 }
 
+// TenBit marks a 10-bit address when OR'ed to it.
+//
+// 10-bit addresses in the range 0x000-0x07F overlap 7-bit addresses so they
+// must be marked with TenBit. Addresses in the range 0x080-0x3FF are 10-bit
+// addresses with or without TenBit.
+const TenBit uint16 = 0x8000
+
+// Is10Bit returns true if addr is a 10-bit address.
+func Is10Bit(addr uint16) bool {
+	return addr&TenBit != 0 || addr > 0x7F
+}
+
+// ValidAddr returns true if addr is a valid 7-bit or 10-bit address.
+func ValidAddr(addr uint16) bool {
+	return addr&^TenBit < 0x400
+}
+
 // Addr is an I²C slave address.
 type Addr uint16
 
@@ -130,6 +188,48 @@ func (a Addr) String() string {
 	return "0x" + strconv.FormatInt(int64(a), 16)
 }
 
+// Errors specific to I²C returned by the drivers, usually wrapped with a
+// driver specific message. Use errors.Is() to test for them.
+//
+// Timeouts, busy buses and unsupported operations are reported with the errors
+// defined in package conn.
+var (
+	// ErrNACK is matched by a *NACKError.
+	ErrNACK = errors.New("i2c: no acknowledge")
+	// ErrArbitrationLost is returned when another master took the bus during
+	// the transaction.
+	ErrArbitrationLost = errors.New("i2c: arbitration lost")
+)
+
+// NACKError is returned when the device at Addr didn't acknowledge its
+// address or a byte written to it.
+//
+// errors.Is(err, ErrNACK) returns true for a *NACKError.
+type NACKError struct {
+	Addr uint16
+	// Err is the driver specific error, if any. It is used as the message.
+	Err error
+}
+
+func (n *NACKError) Error() string {
+	if n.Err != nil {
+		return n.Err.Error()
+	}
+	return fmt.Sprintf("i2c: no acknowledge from device 0x%02X", n.Addr)
+}
+
+// Is returns true for ErrNACK.
+func (n *NACKError) Is(target error) bool {
+	return target == ErrNACK
+}
+
+// Unwrap returns Err.
+func (n *NACKError) Unwrap() error {
+	return n.Err
+}
+
+//
+
 var errI2CSetError = errors.New("invalid i2c address")
 
 var _ conn.Conn = &Dev{}
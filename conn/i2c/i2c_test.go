@@ -6,7 +6,9 @@ package i2c
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"periph.io/x/periph/conn"
@@ -75,6 +77,36 @@ func TestDevWriteErr(t *testing.T) {
 	}
 }
 
+func TestDevTxContext(t *testing.T) {
+	b := &fakeBus{r: []byte{1}}
+	d := Dev{b, 12}
+	r := make([]byte, 1)
+	if err := d.TxContext(context.Background(), []byte{3}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, []byte{3}) || r[0] != 1 || b.addr != 12 {
+		t.Fatal(b.w, r, b.addr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.TxContext(ctx, []byte{4}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, []byte{3}) {
+		t.Fatal(b.w)
+	}
+}
+
+func TestTxContext_Native(t *testing.T) {
+	b := &fakeBusContext{}
+	if err := TxContext(context.Background(), b, 12, []byte{3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.ctx != 1 || len(b.w) != 0 {
+		t.Fatal(b.ctx, b.w)
+	}
+}
+
 //
 
 type fakeBus struct {
@@ -105,6 +137,16 @@ func (f *fakeBus) SetSpeed(freq physic.Frequency) error {
 	return f.err
 }
 
+type fakeBusContext struct {
+	fakeBus
+	ctx int
+}
+
+func (f *fakeBusContext) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	f.ctx++
+	return nil
+}
+
 func TestAddr_Set(t *testing.T) {
 	tests := []struct {
 		str  string
@@ -145,3 +187,50 @@ func TestAddr_String(t *testing.T) {
 		}
 	}
 }
+
+func TestIs10Bit(t *testing.T) {
+	tests := []struct {
+		addr  uint16
+		ten   bool
+		valid bool
+	}{
+		{0x50, false, true},
+		{0x7F, false, true},
+		{0x80, true, true},
+		{0x3FF, true, true},
+		{0x400, true, false},
+		{0x50 | TenBit, true, true},
+		{0x3FF | TenBit, true, true},
+		{0x400 | TenBit, true, false},
+	}
+	for _, tt := range tests {
+		if got := Is10Bit(tt.addr); got != tt.ten {
+			t.Errorf("Is10Bit(0x%X) expected %t", tt.addr, tt.ten)
+		}
+		if got := ValidAddr(tt.addr); got != tt.valid {
+			t.Errorf("ValidAddr(0x%X) expected %t", tt.addr, tt.valid)
+		}
+	}
+}
+
+func TestNACKError(t *testing.T) {
+	var err error = &NACKError{Addr: 0x40}
+	if s := err.Error(); s != "i2c: no acknowledge from device 0x40" {
+		t.Fatal(s)
+	}
+	if !errors.Is(err, ErrNACK) || errors.Is(err, ErrArbitrationLost) {
+		t.Fatal("unexpected Is")
+	}
+	inner := errors.New("foo")
+	err = fmt.Errorf("wrapped: %w", &NACKError{Addr: 0x76, Err: inner})
+	if s := err.Error(); s != "wrapped: foo" {
+		t.Fatal(s)
+	}
+	var n *NACKError
+	if !errors.As(err, &n) || n.Addr != 0x76 {
+		t.Fatal("expected NACKError")
+	}
+	if !errors.Is(err, inner) || !errors.Is(err, ErrNACK) {
+		t.Fatal("unexpected Is")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/i2c"
 )
 
@@ -54,6 +55,9 @@ type Ref struct {
 //
 // When the I²C bus is provided by an off board plug and play bus like USB via
 // a FT232H USB device, there can be no associated number.
+//
+// The bus is wrapped with the bustrace.Tracer passed to bustrace.Enable(), if
+// any.
 func Open(name string) (i2c.BusCloser, error) {
 	var r *Ref
 	var err error
@@ -83,7 +87,14 @@ func Open(name string) (i2c.BusCloser, error) {
 	if r == nil {
 		return nil, errors.New("i2creg: can't open unknown bus: " + strconv.Quote(name))
 	}
-	return r.Open()
+	b, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	if t := bustrace.Enabled(); t != nil {
+		return t.I2C(b), nil
+	}
+	return b, nil
 }
 
 // All returns a copy of all the registered references to all know I²C buses
@@ -7,6 +7,7 @@ package i2creg
 import (
 	"testing"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/physic"
 )
@@ -33,6 +34,29 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpen_trace(t *testing.T) {
+	defer reset()
+	defer bustrace.Enable(nil)
+	tr := &bustrace.Tracer{}
+	bustrace.Enable(tr)
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*fakeBus); ok {
+		t.Fatal("expected the bus to be traced")
+	}
+	if err := b.Tx(0x76, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.Stats(); len(s) != 1 || s[0].Bus != "fake" || s[0].Addr != 0x76 || s[0].W != 1 {
+		t.Fatal(s)
+	}
+}
+
 func TestDefault_NoNumber(t *testing.T) {
 	defer reset()
 	if err := Register("a", nil, -1, fakeBuser); err != nil {
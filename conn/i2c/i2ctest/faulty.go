@@ -0,0 +1,72 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/physic"
+)
+
+// Faulty implements i2c.Bus and injects faults in the transactions done on
+// Bus.
+//
+// It can wrap a Record, a Playback or a Sim, or be wrapped by a Record. See
+// conntest.Injector for the faults that can be injected.
+type Faulty struct {
+	Bus      i2c.Bus
+	Injector *conntest.Injector
+}
+
+func (f *Faulty) String() string {
+	return "faulty"
+}
+
+// Tx implements i2c.Bus.
+func (f *Faulty) Tx(addr uint16, w, r []byte) error {
+	return f.TxContext(context.Background(), addr, w, r)
+}
+
+// TxContext implements i2c.BusContext.
+//
+// An injected NACK is returned as an *i2c.NACKError wrapping the
+// *conntest.FaultError.
+func (f *Faulty) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	err := f.Injector.Inject(f.Bus.String(), r, func() error {
+		return i2c.TxContext(ctx, f.Bus, addr, w, r)
+	})
+	if fe, ok := err.(*conntest.FaultError); ok && fe.Fault == conntest.NACK {
+		return &i2c.NACKError{Addr: addr, Err: fe}
+	}
+	return err
+}
+
+// SetSpeed implements i2c.Bus.
+func (f *Faulty) SetSpeed(freq physic.Frequency) error {
+	return f.Bus.SetSpeed(freq)
+}
+
+// SCL implements i2c.Pins.
+func (f *Faulty) SCL() gpio.PinIO {
+	if p, ok := f.Bus.(i2c.Pins); ok {
+		return p.SCL()
+	}
+	return gpio.INVALID
+}
+
+// SDA implements i2c.Pins.
+func (f *Faulty) SDA() gpio.PinIO {
+	if p, ok := f.Bus.(i2c.Pins); ok {
+		return p.SDA()
+	}
+	return gpio.INVALID
+}
+
+var _ i2c.Bus = &Faulty{}
+var _ i2c.BusContext = &Faulty{}
+var _ i2c.Pins = &Faulty{}
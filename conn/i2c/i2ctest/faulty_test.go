@@ -0,0 +1,54 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/physic"
+)
+
+func TestFaulty(t *testing.T) {
+	s := &Sim{Devices: map[uint16]*SimDevice{0x40: {Regs: []byte{0x12, 0x34}}}}
+	inj := &conntest.Injector{Schedule: map[int]conntest.Fault{0: conntest.NACK, 2: conntest.CRC}, Logf: t.Logf}
+	r := &Record{Bus: &Faulty{Bus: s, Injector: inj}}
+	if err := r.SetSpeed(100 * physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	if p := r.SCL(); p != nil {
+		t.Fatal(p)
+	}
+	b := make([]byte, 2)
+	err := r.Tx(0x40, []byte{0}, b)
+	var ne *i2c.NACKError
+	if !errors.As(err, &ne) || ne.Addr != 0x40 || !errors.Is(err, i2c.ErrNACK) {
+		t.Fatal(err)
+	}
+	var fe *conntest.FaultError
+	if !errors.As(err, &fe) || fe.Fault != conntest.NACK {
+		t.Fatal(err)
+	}
+	// A retry succeeds.
+	if err := r.Tx(0x40, []byte{0}, b); err != nil || !bytes.Equal(b, []byte{0x12, 0x34}) {
+		t.Fatal(b, err)
+	}
+	if err := r.Tx(0x40, []byte{0}, b); err != nil || !bytes.Equal(b, []byte{0x12, 0xCB}) {
+		t.Fatal(b, err)
+	}
+	// Errors from the wrapped bus are returned as-is.
+	if err := r.Tx(0x41, []byte{0}, nil); !conntest.IsErr(err) {
+		t.Fatal(err)
+	}
+	if len(r.Ops) != 4 || r.Ops[0].Err == nil || r.Ops[2].R[1] != 0xCB {
+		t.Fatal(r.Ops)
+	}
+	if len(inj.Log) != 2 {
+		t.Fatal(inj.Log)
+	}
+}
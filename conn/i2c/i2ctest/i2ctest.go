@@ -7,8 +7,11 @@ package i2ctest
 
 import (
 	"bytes"
+	"context"
 	"sync"
+	"time"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/conntest"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/i2c"
@@ -20,17 +23,26 @@ type IO struct {
 	Addr uint16
 	W    []byte
 	R    []byte
+	// T is the time elapsed since the first transaction was recorded. It is
+	// informational and ignored by Playback.
+	T time.Duration
+	// Err is the error returned by the transaction. Playback returns it.
+	Err error
 }
 
 // Record implements i2c.Bus that records everything written to it.
 //
 // This can then be used to feed to Playback to do "replay" based unit tests.
+// Use Save() to serialize the recorded transactions.
 //
 // Record doesn't implement i2c.BusCloser on purpose.
 type Record struct {
 	sync.Mutex
 	Bus i2c.Bus // Bus can be nil if only writes are being recorded.
 	Ops []IO
+
+	clock conntest.Stopwatch
+	speed physic.Frequency
 }
 
 func (r *Record) String() string {
@@ -39,6 +51,16 @@ func (r *Record) String() string {
 
 // Tx implements i2c.Bus
 func (r *Record) Tx(addr uint16, w, read []byte) error {
+	return r.TxContext(context.Background(), addr, w, read)
+}
+
+// TxContext implements i2c.BusContext.
+//
+// ctx is forwarded to Bus, if any.
+func (r *Record) TxContext(ctx context.Context, addr uint16, w, read []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	io := IO{Addr: addr}
 	if len(w) != 0 {
 		io.W = make([]byte, len(w))
@@ -46,25 +68,27 @@ func (r *Record) Tx(addr uint16, w, read []byte) error {
 	}
 	r.Lock()
 	defer r.Unlock()
+	io.T = r.clock.Elapsed()
 	if r.Bus == nil {
 		if len(read) != 0 {
-			return conntest.Errorf("i2ctest: read unsupported when no bus is connected")
+			return conntest.Errorf("i2ctest: read when no bus is connected: %w", conn.ErrUnsupported)
 		}
 	} else {
-		if err := r.Bus.Tx(addr, w, read); err != nil {
-			return err
-		}
+		io.Err = i2c.TxContext(ctx, r.Bus, addr, w, read)
 	}
 	if len(read) != 0 {
 		io.R = make([]byte, len(read))
 		copy(io.R, read)
 	}
 	r.Ops = append(r.Ops, io)
-	return nil
+	return io.Err
 }
 
 // SetSpeed implements i2c.Bus.
 func (r *Record) SetSpeed(f physic.Frequency) error {
+	r.Lock()
+	r.speed = f
+	r.Unlock()
 	if r.Bus != nil {
 		return r.Bus.SetSpeed(f)
 	}
@@ -94,6 +118,8 @@ func (r *Record) SDA() gpio.PinIO {
 //
 // Set DontPanic to true to return an error instead of panicking, which is the
 // default.
+//
+// Use Load() or LoadGolden() to play back a serialized trace.
 type Playback struct {
 	sync.Mutex
 	Ops       []IO
@@ -101,6 +127,12 @@ type Playback struct {
 	DontPanic bool
 	SDAPin    gpio.PinIO
 	SCLPin    gpio.PinIO
+
+	// Golden is the path of the trace file the Ops were loaded from.
+	Golden string
+	// Update, when set, records the transactions instead of playing back Ops.
+	// Close() then writes them to Golden. See LoadGolden().
+	Update *Record
 }
 
 func (p *Playback) String() string {
@@ -110,9 +142,14 @@ func (p *Playback) String() string {
 // Close implements i2c.BusCloser.
 //
 // Close() verifies that all the expected Ops have been consumed.
+//
+// When Update is set, it writes the recorded transactions to Golden instead.
 func (p *Playback) Close() error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return conntest.WriteTraceFile(p.Golden, p.Update.trace())
+	}
 	if len(p.Ops) != p.Count {
 		return errorf(p.DontPanic, "i2ctest: expected playback to be empty: I/O count %d; expected %d", p.Count, len(p.Ops))
 	}
@@ -123,10 +160,13 @@ func (p *Playback) Close() error {
 func (p *Playback) Tx(addr uint16, w, r []byte) error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return p.Update.Tx(addr, w, r)
+	}
 	if len(p.Ops) <= p.Count {
 		return errorf(p.DontPanic, "i2ctest: unexpected Tx() (count #%d) expecting i2ctest.IO{Addr:%d, W:%#v, R:%#v}", p.Count, addr, w, r)
 	}
-	if addr != p.Ops[p.Count].Addr {
+	if canonical(addr) != canonical(p.Ops[p.Count].Addr) {
 		return errorf(p.DontPanic, "i2ctest: unexpected addr (count #%d) %d != %d", p.Count, addr, p.Ops[p.Count].Addr)
 	}
 	if !bytes.Equal(p.Ops[p.Count].W, w) {
@@ -137,11 +177,22 @@ func (p *Playback) Tx(addr uint16, w, r []byte) error {
 	}
 	copy(r, p.Ops[p.Count].R)
 	p.Count++
-	return nil
+	return p.Ops[p.Count-1].Err
+}
+
+// TxContext implements i2c.BusContext.
+func (p *Playback) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Tx(addr, w, r)
 }
 
 // SetSpeed implements i2c.Bus.
 func (p *Playback) SetSpeed(f physic.Frequency) error {
+	if p.Update != nil {
+		return p.Update.SetSpeed(f)
+	}
 	return nil
 }
 
@@ -157,6 +208,15 @@ func (p *Playback) SDA() gpio.PinIO {
 
 //
 
+// canonical returns addr so that 10-bit addresses above 0x7F compare equal
+// with or without i2c.TenBit.
+func canonical(addr uint16) uint16 {
+	if addr&^i2c.TenBit > 0x7F {
+		return addr &^ i2c.TenBit
+	}
+	return addr
+}
+
 // errorf is the internal implementation that optionally panic.
 //
 // If dontPanic is false, it panics instead.
@@ -172,3 +232,5 @@ var _ i2c.Bus = &Record{}
 var _ i2c.Pins = &Record{}
 var _ i2c.Bus = &Playback{}
 var _ i2c.Pins = &Playback{}
+var _ i2c.BusContext = &Record{}
+var _ i2c.BusContext = &Playback{}
@@ -5,11 +5,13 @@
 package i2ctest
 
 import (
+	"context"
 	"testing"
 
 	"periph.io/x/periph/conn/conntest"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
 )
 
 func TestRecord_empty(t *testing.T) {
@@ -165,3 +167,45 @@ func TestRecord_Playback(t *testing.T) {
 		t.Fatal("Playback.Ops is empty")
 	}
 }
+
+func TestTxContext(t *testing.T) {
+	p := &Playback{Ops: []IO{{Addr: 23, W: []byte{10}, R: []byte{12}}}}
+	r := &Record{Bus: p}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.TxContext(ctx, 23, []byte{10}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if err := p.TxContext(ctx, 23, []byte{10}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	v := [1]byte{}
+	if err := i2c.TxContext(context.Background(), r, 23, []byte{10}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+	if v[0] != 12 || len(r.Ops) != 1 || p.Count != 1 {
+		t.Fatal(v, r.Ops, p.Count)
+	}
+}
+
+func TestPlayback_TenBit(t *testing.T) {
+	p := Playback{
+		Ops: []IO{
+			{Addr: 0x150, W: []byte{1}},
+			{Addr: 0x50 | i2c.TenBit, W: []byte{2}},
+		},
+		DontPanic: true,
+	}
+	if err := p.Tx(0x150|i2c.TenBit, []byte{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Tx(0x50, []byte{2}, nil); err == nil {
+		t.Fatal("7-bit and 10-bit addresses must not match")
+	}
+	if err := p.Tx(0x50|i2c.TenBit, []byte{2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
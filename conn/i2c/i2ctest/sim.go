@@ -0,0 +1,201 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"context"
+	"sync"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/physic"
+)
+
+// RegMode specifies how a register of a SimDevice can be accessed.
+//
+// The values can be OR'ed together.
+type RegMode uint8
+
+const (
+	// ReadWrite is the default mode.
+	ReadWrite RegMode = 0
+	// ReadOnly registers ignore writes.
+	ReadOnly RegMode = 1
+	// WriteOnly registers read as 0.
+	WriteOnly RegMode = 2
+	// ClearOnRead registers are reset to 0 after being read.
+	ClearOnRead RegMode = 4
+)
+
+// SimDevice is a simulated I²C device exposing a register file.
+//
+// A transaction starts by writing the register address, followed by the
+// values to write, then the values are read. The register address
+// auto-increments after each register is accessed, wrapping around at the end
+// of the register file. A read without a preceding write continues where the
+// last transaction stopped.
+//
+// The Sim lock is held while the device is accessed, including while the
+// callbacks are called.
+type SimDevice struct {
+	// AddrWidth is the number of bytes of the register address, encoded as big
+	// endian. The default is 1.
+	AddrWidth int
+	// RegWidth is the number of bytes of each register, encoded as big endian.
+	// The default is 1.
+	RegWidth int
+	// Regs is the register file, RegWidth bytes per register.
+	Regs []byte
+	// Modes optionally overrides the access mode of registers; registers not
+	// listed are ReadWrite.
+	Modes map[int]RegMode
+	// OnRead, if set, is called before register reg is read. It can update
+	// Regs, for example to simulate a measurement.
+	OnRead func(d *SimDevice, reg int)
+	// OnWrite, if set, is called after register reg was written. It can be
+	// used to simulate side effects like a reset or starting a measurement.
+	OnWrite func(d *SimDevice, reg int)
+
+	ptr int // current offset in Regs
+}
+
+// Sim implements i2c.Bus and simulates devices exposing register files.
+//
+// Unlike Playback, the exact sequence of transactions doesn't matter, which
+// permits testing drivers by their behavior.
+//
+// Transactions to an address without a device fail with an *i2c.NACKError,
+// like when the address is not acknowledged.
+//
+// The keys of Devices are 7-bit or 10-bit addresses; 10-bit addresses above
+// 0x7F match with or without i2c.TenBit.
+type Sim struct {
+	sync.Mutex
+	Devices map[uint16]*SimDevice
+	SDAPin  gpio.PinIO
+	SCLPin  gpio.PinIO
+}
+
+func (s *Sim) String() string {
+	return "sim"
+}
+
+// Close implements i2c.BusCloser.
+func (s *Sim) Close() error {
+	return nil
+}
+
+// Tx implements i2c.Bus.
+func (s *Sim) Tx(addr uint16, w, r []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	d := s.Devices[addr]
+	if d == nil && canonical(addr) != addr {
+		d = s.Devices[canonical(addr)]
+	} else if d == nil && i2c.Is10Bit(addr) {
+		d = s.Devices[addr|i2c.TenBit]
+	}
+	if d == nil {
+		return &i2c.NACKError{Addr: addr, Err: conntest.Errorf("i2ctest: no device at address 0x%02X; NACK", addr)}
+	}
+	return d.tx(w, r)
+}
+
+// TxContext implements i2c.BusContext.
+func (s *Sim) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Tx(addr, w, r)
+}
+
+// SetSpeed implements i2c.Bus.
+func (s *Sim) SetSpeed(f physic.Frequency) error {
+	return nil
+}
+
+// SCL implements i2c.Pins.
+func (s *Sim) SCL() gpio.PinIO {
+	return s.SCLPin
+}
+
+// SDA implements i2c.Pins.
+func (s *Sim) SDA() gpio.PinIO {
+	return s.SDAPin
+}
+
+//
+
+func (d *SimDevice) tx(w, r []byte) error {
+	aw, rw := d.widths()
+	if len(d.Regs) == 0 || len(d.Regs)%rw != 0 {
+		return conntest.Errorf("i2ctest: invalid register file size %d", len(d.Regs))
+	}
+	if d.ptr >= len(d.Regs) {
+		d.ptr = 0
+	}
+	if len(w) != 0 {
+		if len(w) < aw {
+			return conntest.Errorf("i2ctest: register address is too short")
+		}
+		reg := 0
+		for _, b := range w[:aw] {
+			reg = reg<<8 | int(b)
+		}
+		if reg*rw >= len(d.Regs) {
+			return conntest.Errorf("i2ctest: invalid register 0x%X", reg)
+		}
+		d.ptr = reg * rw
+		for _, b := range w[aw:] {
+			reg := d.ptr / rw
+			if d.Modes[reg]&ReadOnly == 0 {
+				d.Regs[d.ptr] = b
+			}
+			if d.ptr%rw == rw-1 && d.OnWrite != nil {
+				d.OnWrite(d, reg)
+			}
+			d.inc()
+		}
+	}
+	for i := range r {
+		reg := d.ptr / rw
+		if d.ptr%rw == 0 && d.OnRead != nil {
+			d.OnRead(d, reg)
+		}
+		m := d.Modes[reg]
+		if m&WriteOnly == 0 {
+			r[i] = d.Regs[d.ptr]
+		} else {
+			r[i] = 0
+		}
+		if m&ClearOnRead != 0 {
+			d.Regs[d.ptr] = 0
+		}
+		d.inc()
+	}
+	return nil
+}
+
+func (d *SimDevice) widths() (int, int) {
+	aw, rw := d.AddrWidth, d.RegWidth
+	if aw == 0 {
+		aw = 1
+	}
+	if rw == 0 {
+		rw = 1
+	}
+	return aw, rw
+}
+
+func (d *SimDevice) inc() {
+	if d.ptr++; d.ptr == len(d.Regs) {
+		d.ptr = 0
+	}
+}
+
+var _ i2c.BusCloser = &Sim{}
+var _ i2c.BusContext = &Sim{}
+var _ i2c.Pins = &Sim{}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+)
+
+func TestSim(t *testing.T) {
+	d := &SimDevice{Regs: []byte{0x60, 0, 0, 0}}
+	s := Sim{Devices: map[uint16]*SimDevice{0x76: d}, SDAPin: gpio.INVALID}
+	if s.String() != "sim" {
+		t.Fatal(s.String())
+	}
+	if s.SDA() != gpio.INVALID || s.SCL() != nil {
+		t.Fatal("unexpected pins")
+	}
+	if err := s.SetSpeed(0); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Tx(0x77, nil, nil)
+	var nack *i2c.NACKError
+	if !conntest.IsErr(err) || !errors.As(err, &nack) || nack.Addr != 0x77 {
+		t.Fatal("expected NACK", err)
+	}
+	m := mmr.Dev8{Conn: &i2c.Dev{Bus: &s, Addr: 0x76}, Order: binary.BigEndian}
+	if v, err := m.ReadUint8(0); err != nil || v != 0x60 {
+		t.Fatal(v, err)
+	}
+	if err := m.WriteUint16(2, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	// Auto-increment wraps around.
+	r := make([]byte, 3)
+	if err := s.Tx(0x76, []byte{3}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0x34, 0x60, 0}) {
+		t.Fatal(r)
+	}
+	// Read continues where the last transaction stopped.
+	if err := s.Tx(0x76, nil, r[:1]); err != nil || r[0] != 0x12 {
+		t.Fatal(r, err)
+	}
+	if err := s.Tx(0x76, []byte{4}, nil); !conntest.IsErr(err) {
+		t.Fatal("expected invalid register", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSim_Modes(t *testing.T) {
+	d := &SimDevice{
+		Regs:  []byte{1, 2, 3, 4},
+		Modes: map[int]RegMode{0: ReadOnly, 1: WriteOnly, 2: ReadOnly | ClearOnRead},
+	}
+	s := Sim{Devices: map[uint16]*SimDevice{0x10: d}}
+	if err := s.Tx(0x10, []byte{0, 0xA, 0xB, 0xC, 0xD}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d.Regs, []byte{1, 0xB, 3, 0xD}) {
+		t.Fatal(d.Regs)
+	}
+	r := make([]byte, 4)
+	if err := s.Tx(0x10, []byte{0}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{1, 0, 3, 0xD}) {
+		t.Fatal(r)
+	}
+	if err := s.Tx(0x10, []byte{2}, r[:1]); err != nil || r[0] != 0 {
+		t.Fatal("expected cleared on read", r, err)
+	}
+}
+
+func TestSim_Callbacks(t *testing.T) {
+	var writes []int
+	d := &SimDevice{
+		AddrWidth: 2,
+		RegWidth:  2,
+		Regs:      make([]byte, 8),
+		OnWrite: func(d *SimDevice, reg int) {
+			writes = append(writes, reg)
+			if reg == 1 && d.Regs[3] == 1 {
+				// Simulate a measurement being started.
+				d.Regs[4], d.Regs[5] = 0x12, 0x34
+			}
+		},
+		OnRead: func(d *SimDevice, reg int) {
+			if reg == 3 {
+				d.Regs[7]++
+			}
+		},
+	}
+	s := Sim{Devices: map[uint16]*SimDevice{0x10: d}}
+	if err := s.Tx(0x10, []byte{0}, nil); !conntest.IsErr(err) {
+		t.Fatal("expected short address", err)
+	}
+	if err := s.Tx(0x10, []byte{0, 1, 0, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != 1 || writes[0] != 1 {
+		t.Fatal(writes)
+	}
+	m := mmr.Dev16{Conn: &i2c.Dev{Bus: &s, Addr: 0x10}, Order: binary.BigEndian}
+	if v, err := m.ReadUint16(2); err != nil || v != 0x1234 {
+		t.Fatal(v, err)
+	}
+	for i := uint16(1); i < 3; i++ {
+		if v, err := m.ReadUint16(3); err != nil || v != i {
+			t.Fatal(v, err)
+		}
+	}
+	if err := s.TxContext(context.Background(), 0x10, []byte{0, 4}, nil); !conntest.IsErr(err) {
+		t.Fatal("expected invalid register", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.TxContext(ctx, 0x10, []byte{0, 0}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestSim_TenBit(t *testing.T) {
+	s := &Sim{
+		Devices: map[uint16]*SimDevice{
+			0x50:               {Regs: []byte{1}},
+			0x50 | i2c.TenBit:  {Regs: []byte{2}},
+			0x150:              {Regs: []byte{3}},
+			0x2A0 | i2c.TenBit: {Regs: []byte{4}},
+		},
+	}
+	data := []struct {
+		addr     uint16
+		expected byte
+	}{
+		{0x50, 1},
+		{0x50 | i2c.TenBit, 2},
+		{0x150, 3},
+		{0x150 | i2c.TenBit, 3},
+		{0x2A0, 4},
+		{0x2A0 | i2c.TenBit, 4},
+	}
+	for i, line := range data {
+		b := make([]byte, 1)
+		if err := s.Tx(line.addr, []byte{0}, b); err != nil || b[0] != line.expected {
+			t.Errorf("#%d: %v %v", i, b, err)
+		}
+	}
+	if err := s.Tx(0x51|i2c.TenBit, nil, nil); err == nil {
+		t.Fatal("expected NACK")
+	}
+}
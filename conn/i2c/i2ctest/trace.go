@@ -0,0 +1,87 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c"
+)
+
+// Save writes the transactions recorded by r as a trace.
+//
+// See conntest.Trace for the format.
+func Save(w io.Writer, r *Record) error {
+	return conntest.WriteTrace(w, r.trace())
+}
+
+// Load reads a trace written by Save() and returns it as a Playback.
+func Load(r io.Reader) (*Playback, error) {
+	t, err := conntest.ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	return playback(t)
+}
+
+// LoadGolden returns a Playback for the golden file path.
+//
+// When conntest.UpdateGolden is set, the file is not read; the returned
+// Playback forwards the transactions to b and rewrites the file on Close()
+// instead.
+func LoadGolden(path string, b i2c.Bus) (*Playback, error) {
+	if conntest.UpdateGolden {
+		if b == nil {
+			return nil, errors.New("i2ctest: a bus is required to update " + path)
+		}
+		return &Playback{Update: &Record{Bus: b}, Golden: path}, nil
+	}
+	t, err := conntest.ReadTraceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := playback(t)
+	if err != nil {
+		return nil, err
+	}
+	p.Golden = path
+	return p, nil
+}
+
+//
+
+func (r *Record) trace() *conntest.Trace {
+	r.Lock()
+	defer r.Unlock()
+	t := &conntest.Trace{Version: conntest.TraceVersion, Kind: "i2c", Ops: make([]conntest.TraceOp, len(r.Ops))}
+	for i, o := range r.Ops {
+		t.Ops[i] = conntest.TraceOp{T: o.T, Addr: o.Addr, W: o.W, R: o.R}
+		if o.Err != nil {
+			t.Ops[i].Err = o.Err.Error()
+		}
+	}
+	if r.Bus != nil {
+		t.Bus = r.Bus.String()
+	}
+	if r.speed != 0 {
+		t.Meta = map[string]string{"speed": r.speed.String()}
+	}
+	return t
+}
+
+func playback(t *conntest.Trace) (*Playback, error) {
+	if t.Kind != "i2c" {
+		return nil, fmt.Errorf("i2ctest: expected an i2c trace, got %q", t.Kind)
+	}
+	p := &Playback{Ops: make([]IO, len(t.Ops))}
+	for i := range t.Ops {
+		o := &t.Ops[i]
+		p.Ops[i] = IO{Addr: o.Addr, W: o.W, R: o.R, T: o.T, Err: o.Error()}
+	}
+	return p, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package i2ctest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/physic"
+)
+
+func TestSave_Load(t *testing.T) {
+	s := &Sim{Devices: map[uint16]*SimDevice{0x76: {Regs: []byte{0x60, 0x01}}}}
+	r := Record{Bus: s}
+	if err := r.SetSpeed(400 * physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 2)
+	if err := r.Tx(0x76, []byte{0}, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Tx(0x77, []byte{0}, nil); err == nil {
+		t.Fatal("expected NACK")
+	}
+	r.Ops[1].T = 0
+	buf := bytes.Buffer{}
+	if err := Save(&buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "version": 1,
+  "kind": "i2c",
+  "bus": "sim",
+  "meta": {"speed":"400kHz"},
+  "ops": [
+    {"t":0,"addr":118,"w":"00","r":"6001"},
+    {"t":0,"addr":119,"w":"00","err":"i2ctest: no device at address 0x77; NACK"}
+  ]
+}
+`
+	if s := buf.String(); s != expected {
+		t.Fatalf("unexpected trace:\n%s", s)
+	}
+	p, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[0], b[1] = 0, 0
+	if err := p.Tx(0x76, []byte{0}, b); err != nil || !bytes.Equal(b, []byte{0x60, 0x01}) {
+		t.Fatal(b, err)
+	}
+	if err := p.Tx(0x77, []byte{0}, nil); err == nil {
+		t.Fatal("expected recorded error")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(strings.NewReader(`{"version": 1, "kind": "spi", "ops": []}`)); err == nil {
+		t.Fatal("wrong kind")
+	}
+	if _, err := Load(strings.NewReader(``)); err == nil {
+		t.Fatal("empty")
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_i2ctest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+	defer func(u bool) {
+		conntest.UpdateGolden = u
+	}(conntest.UpdateGolden)
+
+	conntest.UpdateGolden = true
+	if _, err := LoadGolden(path, nil); err == nil {
+		t.Fatal("bus is required")
+	}
+	s := &Sim{Devices: map[uint16]*SimDevice{0x10: {Regs: []byte{0x42}}}}
+	p, err := LoadGolden(path, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetSpeed(physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	if err := p.Tx(0x10, []byte{0}, b); err != nil || b[0] != 0x42 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conntest.UpdateGolden = false
+	if p, err = LoadGolden(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	b[0] = 0
+	if err := p.Tx(0x10, []byte{0}, b); err != nil || b[0] != 0x42 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGolden(filepath.Join(dir, "missing.json"), nil); err == nil {
+		t.Fatal("missing file")
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package smbus implements the System Management Bus protocol on top of an
+// I²C bus.
+//
+// SMBus defines a set of well known transactions over I²C, optionally
+// protected with a Packet Error Code (PEC). It is used by chips like battery
+// gauges, PMBus regulators and thermal sensors.
+//
+// The transactions are run natively when the bus implements Native and
+// supports them, for example via the Linux kernel SMBus ioctl. Otherwise they
+// are emulated over i2c.Bus.Tx().
+//
+// See http://smbus.org/specs/ for the specification.
+package smbus
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"periph.io/x/periph/conn/i2c"
+)
+
+// AlertResponseAddress is the address a device asserting SMBALERT# responds
+// to with its own address.
+const AlertResponseAddress uint16 = 0x0C
+
+// MaxBlock is the maximum number of bytes in a block transaction.
+const MaxBlock = 32
+
+// DataSize is the size of the data buffer passed to Native.SMBusTx().
+const DataSize = MaxBlock + 2
+
+// Protocol is a SMBus transaction type.
+type Protocol uint8
+
+// Valid Protocol values.
+const (
+	Quick         Protocol = iota // Only the R/W bit is sent.
+	Byte                          // Send byte or receive byte.
+	ByteData                      // Write or read byte data.
+	WordData                      // Write or read word data.
+	ProcCall                      // Process call.
+	BlockData                     // Block write or block read.
+	BlockProcCall                 // Block write-block read process call.
+	I2CBlockData                  // I²C block write or read; not part of SMBus.
+)
+
+const protocolName = "QuickByteByteDataWordDataProcCallBlockDataBlockProcCallI2CBlockData"
+
+var protocolIndex = [...]uint8{0, 5, 9, 17, 25, 33, 42, 55, 67}
+
+func (p Protocol) String() string {
+	if int(p) >= len(protocolIndex)-1 {
+		return "Protocol(" + strconv.Itoa(int(p)) + ")"
+	}
+	return protocolName[protocolIndex[p]:protocolIndex[p+1]]
+}
+
+// Native is implemented by an i2c.Bus that can run SMBus transactions
+// natively.
+type Native interface {
+	i2c.Bus
+	// SMBusSupported returns true if the bus supports the transaction type p in
+	// the direction read. When pec is true, the bus must also support Packet
+	// Error Checking.
+	SMBusSupported(p Protocol, read, pec bool) bool
+	// SMBusTx runs a SMBus transaction.
+	//
+	// data is at least DataSize bytes long and has the same layout as the Linux
+	// kernel's union i2c_smbus_data:
+	//  - Byte, ByteData: data[0] is the byte. cmd is the byte sent in a Byte
+	//    write.
+	//  - WordData, ProcCall: data[0:2] is the word in little endian.
+	//  - BlockData, BlockProcCall, I2CBlockData: data[0] is the number of bytes
+	//    followed by the bytes. For an I2CBlockData read, data[0] is the number
+	//    of bytes to read.
+	//
+	// The response is written back to data. When pec is true, the PEC is
+	// generated and verified by the bus.
+	SMBusTx(addr uint16, p Protocol, read bool, cmd byte, data []byte, pec bool) error
+}
+
+// Dev is a device on a SMBus.
+type Dev struct {
+	Bus  i2c.Bus
+	Addr uint16
+	// PEC enables Packet Error Checking on all the transactions but Quick.
+	PEC bool
+}
+
+func (d *Dev) String() string {
+	s := "<nil>"
+	if d.Bus != nil {
+		s = d.Bus.String()
+	}
+	return s + "(" + strconv.Itoa(int(d.Addr)) + ")"
+}
+
+// Quick sends a quick command, where the R/W bit is the only data.
+//
+// It requires native support from the bus since it cannot be emulated over
+// i2c.Bus.Tx().
+func (d *Dev) Quick(read bool) error {
+	var data [DataSize]byte
+	return d.tx(Quick, read, 0, data[:])
+}
+
+// SendByte sends a single byte without a command.
+func (d *Dev) SendByte(b byte) error {
+	var data [DataSize]byte
+	return d.tx(Byte, false, b, data[:])
+}
+
+// ReceiveByte reads a single byte without a command.
+func (d *Dev) ReceiveByte() (byte, error) {
+	var data [DataSize]byte
+	err := d.tx(Byte, true, 0, data[:])
+	return data[0], err
+}
+
+// WriteByteData writes the byte b to the command cmd.
+func (d *Dev) WriteByteData(cmd, b byte) error {
+	var data [DataSize]byte
+	data[0] = b
+	return d.tx(ByteData, false, cmd, data[:])
+}
+
+// ReadByteData reads a byte from the command cmd.
+func (d *Dev) ReadByteData(cmd byte) (byte, error) {
+	var data [DataSize]byte
+	err := d.tx(ByteData, true, cmd, data[:])
+	return data[0], err
+}
+
+// WriteWordData writes the 16 bits word v to the command cmd.
+func (d *Dev) WriteWordData(cmd byte, v uint16) error {
+	var data [DataSize]byte
+	data[0] = byte(v)
+	data[1] = byte(v >> 8)
+	return d.tx(WordData, false, cmd, data[:])
+}
+
+// ReadWordData reads a 16 bits word from the command cmd.
+func (d *Dev) ReadWordData(cmd byte) (uint16, error) {
+	var data [DataSize]byte
+	err := d.tx(WordData, true, cmd, data[:])
+	return uint16(data[0]) | uint16(data[1])<<8, err
+}
+
+// ProcessCall writes the 16 bits word v to the command cmd and reads back a
+// 16 bits word.
+func (d *Dev) ProcessCall(cmd byte, v uint16) (uint16, error) {
+	var data [DataSize]byte
+	data[0] = byte(v)
+	data[1] = byte(v >> 8)
+	err := d.tx(ProcCall, false, cmd, data[:])
+	return uint16(data[0]) | uint16(data[1])<<8, err
+}
+
+// WriteBlockData writes up to MaxBlock bytes to the command cmd, prefixed
+// with the byte count.
+func (d *Dev) WriteBlockData(cmd byte, b []byte) error {
+	var data [DataSize]byte
+	if err := setBlock(data[:], b); err != nil {
+		return err
+	}
+	return d.tx(BlockData, false, cmd, data[:])
+}
+
+// ReadBlockData reads a block of up to MaxBlock bytes from the command cmd.
+func (d *Dev) ReadBlockData(cmd byte) ([]byte, error) {
+	var data [DataSize]byte
+	if err := d.tx(BlockData, true, cmd, data[:]); err != nil {
+		return nil, err
+	}
+	return getBlock(data[:])
+}
+
+// BlockProcessCall writes the block w to the command cmd and reads back a
+// block.
+func (d *Dev) BlockProcessCall(cmd byte, w []byte) ([]byte, error) {
+	var data [DataSize]byte
+	if err := setBlock(data[:], w); err != nil {
+		return nil, err
+	}
+	if err := d.tx(BlockProcCall, false, cmd, data[:]); err != nil {
+		return nil, err
+	}
+	return getBlock(data[:])
+}
+
+// WriteI2CBlockData writes up to MaxBlock bytes to the command cmd, without
+// the byte count.
+func (d *Dev) WriteI2CBlockData(cmd byte, b []byte) error {
+	var data [DataSize]byte
+	if err := setBlock(data[:], b); err != nil {
+		return err
+	}
+	return d.tx(I2CBlockData, false, cmd, data[:])
+}
+
+// ReadI2CBlockData reads len(b) bytes, up to MaxBlock, from the command cmd.
+func (d *Dev) ReadI2CBlockData(cmd byte, b []byte) error {
+	var data [DataSize]byte
+	if err := setBlock(data[:], b); err != nil {
+		return err
+	}
+	if err := d.tx(I2CBlockData, true, cmd, data[:]); err != nil {
+		return err
+	}
+	copy(b, data[1:])
+	return nil
+}
+
+// Alert queries the Alert Response Address and returns the address of the
+// device asserting SMBALERT#.
+//
+// When multiple devices assert SMBALERT#, the one with the lowest address wins
+// the arbitration; Alert must then be called again.
+func Alert(b i2c.Bus) (uint16, error) {
+	d := Dev{Bus: b, Addr: AlertResponseAddress}
+	v, err := d.ReceiveByte()
+	if err != nil {
+		return 0, err
+	}
+	return uint16(v >> 1), nil
+}
+
+// PEC updates the Packet Error Code crc with the bytes b.
+//
+// The PEC is a CRC-8 with the polynomial x⁸+x²+x+1 computed over all the
+// bytes of a transaction, including the address bytes. Start with a crc of 0.
+// A transaction including its PEC byte has a PEC of 0.
+func PEC(crc byte, b []byte) byte {
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+//
+
+func (d *Dev) tx(p Protocol, read bool, cmd byte, data []byte) error {
+	if d.Addr >= 0x80 {
+		return fmt.Errorf("smbus: invalid address 0x%X", d.Addr)
+	}
+	if n, ok := d.Bus.(Native); ok && n.SMBusSupported(p, read, d.PEC) {
+		return n.SMBusTx(d.Addr, p, read, cmd, data, d.PEC)
+	}
+	return d.emulate(p, read, cmd, data)
+}
+
+// emulate runs the transaction over i2c.Bus.Tx().
+func (d *Dev) emulate(p Protocol, read bool, cmd byte, data []byte) error {
+	var w, r []byte
+	switch p {
+	case Byte:
+		if read {
+			r = data[:1]
+		} else {
+			w = []byte{cmd}
+		}
+	case ByteData, WordData:
+		n := 1
+		if p == WordData {
+			n = 2
+		}
+		w = []byte{cmd}
+		if read {
+			r = data[:n]
+		} else {
+			w = append(w, data[:n]...)
+		}
+	case ProcCall:
+		w = []byte{cmd, data[0], data[1]}
+		r = data[:2]
+	case BlockData:
+		w = []byte{cmd}
+		if read {
+			// The count is not known in advance, read the maximum.
+			r = data[:1+MaxBlock]
+		} else {
+			w = append(w, data[:1+data[0]]...)
+		}
+	case BlockProcCall:
+		w = append([]byte{cmd}, data[:1+data[0]]...)
+		r = data[:1+MaxBlock]
+	case I2CBlockData:
+		w = []byte{cmd}
+		if read {
+			r = data[1 : 1+data[0]]
+		} else {
+			w = append(w, data[1:1+data[0]]...)
+		}
+	default:
+		return fmt.Errorf("smbus: %s requires native support from %s", p, d.Bus)
+	}
+	if !d.PEC {
+		return d.Bus.Tx(d.Addr, w, r)
+	}
+
+	addr := byte(d.Addr << 1)
+	crc := byte(0)
+	if len(w) != 0 {
+		crc = PEC(crc, []byte{addr})
+		crc = PEC(crc, w)
+	}
+	if len(r) == 0 {
+		return d.Bus.Tx(d.Addr, append(w, crc), nil)
+	}
+	buf := make([]byte, len(r)+1)
+	if err := d.Bus.Tx(d.Addr, w, buf); err != nil {
+		return err
+	}
+	n := len(r)
+	if p == BlockProcCall || (p == BlockData && read) {
+		if buf[0] > MaxBlock {
+			return fmt.Errorf("smbus: invalid block count %d", buf[0])
+		}
+		n = 1 + int(buf[0])
+	}
+	crc = PEC(crc, []byte{addr | 1})
+	if c := PEC(crc, buf[:n]); c != buf[n] {
+		return fmt.Errorf("smbus: PEC mismatch; got 0x%02X, expected 0x%02X", buf[n], c)
+	}
+	copy(r, buf[:n])
+	return nil
+}
+
+func setBlock(data, b []byte) error {
+	if len(b) > MaxBlock {
+		return errors.New("smbus: block is too large; maximum is " + strconv.Itoa(MaxBlock) + " bytes")
+	}
+	data[0] = byte(len(b))
+	copy(data[1:], b)
+	return nil
+}
+
+func getBlock(data []byte) ([]byte, error) {
+	n := int(data[0])
+	if n > MaxBlock {
+		return nil, fmt.Errorf("smbus: invalid block count %d", n)
+	}
+	out := make([]byte, n)
+	copy(out, data[1:1+n])
+	return out, nil
+}
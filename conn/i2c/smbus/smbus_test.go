@@ -0,0 +1,254 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package smbus
+
+import (
+	"bytes"
+	"testing"
+
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+	"periph.io/x/periph/conn/physic"
+)
+
+func TestPEC(t *testing.T) {
+	// CRC-8 check value.
+	if c := PEC(0, []byte("123456789")); c != 0xF4 {
+		t.Fatalf("0x%02X", c)
+	}
+	b := []byte{0x5A << 1, 0x07, 0x5A<<1 | 1, 0x12, 0x34}
+	c := PEC(0, b)
+	if v := PEC(0, append(b, c)); v != 0 {
+		t.Fatalf("0x%02X", v)
+	}
+	if v := PEC(PEC(0, b[:2]), b[2:]); v != c {
+		t.Fatalf("0x%02X", v)
+	}
+}
+
+func TestProtocol_String(t *testing.T) {
+	if s := I2CBlockData.String(); s != "I2CBlockData" {
+		t.Fatal(s)
+	}
+	if s := Protocol(10).String(); s != "Protocol(10)" {
+		t.Fatal(s)
+	}
+}
+
+func TestDev_emulated(t *testing.T) {
+	b := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: 0x5A, W: []byte{0x10}},
+			{Addr: 0x5A, R: []byte{0x42}},
+			{Addr: 0x5A, W: []byte{0x07, 0x42}},
+			{Addr: 0x5A, W: []byte{0x07}, R: []byte{0x43}},
+			{Addr: 0x5A, W: []byte{0x07, 0x34, 0x12}},
+			{Addr: 0x5A, W: []byte{0x07}, R: []byte{0x34, 0x12}},
+			{Addr: 0x5A, W: []byte{0x08, 0x01, 0x00}, R: []byte{0x02, 0x00}},
+			{Addr: 0x5A, W: []byte{0x09, 0x02, 0xAA, 0xBB}},
+			{Addr: 0x5A, W: []byte{0x09}, R: append([]byte{0x02, 0xAA, 0xBB}, make([]byte, 30)...)},
+			{Addr: 0x5A, W: []byte{0x0A, 0x01, 0xCC}, R: append([]byte{0x01, 0xDD}, make([]byte, 31)...)},
+			{Addr: 0x5A, W: []byte{0x0B, 0x01, 0x02}},
+			{Addr: 0x5A, W: []byte{0x0B}, R: []byte{0x03, 0x04}},
+			{Addr: AlertResponseAddress, R: []byte{0x5A << 1}},
+		},
+	}
+	d := Dev{Bus: &b, Addr: 0x5A}
+	if s := d.String(); s != "playback(90)" {
+		t.Fatal(s)
+	}
+	if err := d.Quick(false); err == nil {
+		t.Fatal("quick requires native support")
+	}
+	if err := d.SendByte(0x10); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReceiveByte(); err != nil || v != 0x42 {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteByteData(0x07, 0x42); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadByteData(0x07); err != nil || v != 0x43 {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteWordData(0x07, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadWordData(0x07); err != nil || v != 0x1234 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ProcessCall(0x08, 1); err != nil || v != 2 {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteBlockData(0x09, []byte{0xAA, 0xBB}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadBlockData(0x09); err != nil || !bytes.Equal(v, []byte{0xAA, 0xBB}) {
+		t.Fatal(v, err)
+	}
+	if v, err := d.BlockProcessCall(0x0A, []byte{0xCC}); err != nil || !bytes.Equal(v, []byte{0xDD}) {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteI2CBlockData(0x0B, []byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if err := d.ReadI2CBlockData(0x0B, buf); err != nil || !bytes.Equal(buf, []byte{3, 4}) {
+		t.Fatal(buf, err)
+	}
+	if a, err := Alert(&b); err != nil || a != 0x5A {
+		t.Fatal(a, err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDev_emulated_PEC(t *testing.T) {
+	const addr = 0x5A
+	w := byte(addr << 1)
+	r := w | 1
+	word := []byte{0x34, 0x12}
+	block := []byte{0x02, 0xAA, 0xBB}
+	blockRead := append(append(block, PEC(0, append([]byte{w, 0x09, r}, block...))), make([]byte, 30)...)
+	b := i2ctest.Playback{
+		Ops: []i2ctest.IO{
+			{Addr: addr, W: []byte{0x10, PEC(0, []byte{w, 0x10})}},
+			{Addr: addr, R: []byte{0x42, PEC(0, []byte{r, 0x42})}},
+			{Addr: addr, W: []byte{0x07, 0x34, 0x12, PEC(0, []byte{w, 0x07, 0x34, 0x12})}},
+			{Addr: addr, W: []byte{0x07}, R: append(word, PEC(0, []byte{w, 0x07, r, 0x34, 0x12}))},
+			{Addr: addr, W: []byte{0x09}, R: blockRead},
+			// Invalid PEC.
+			{Addr: addr, W: []byte{0x07}, R: []byte{0x34, 0x12, 0}},
+			// Invalid block count.
+			{Addr: addr, W: []byte{0x09}, R: append([]byte{33}, make([]byte, 33)...)},
+		},
+	}
+	d := Dev{Bus: &b, Addr: addr, PEC: true}
+	if err := d.SendByte(0x10); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReceiveByte(); err != nil || v != 0x42 {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteWordData(0x07, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadWordData(0x07); err != nil || v != 0x1234 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadBlockData(0x09); err != nil || !bytes.Equal(v, []byte{0xAA, 0xBB}) {
+		t.Fatal(v, err)
+	}
+	if _, err := d.ReadWordData(0x07); err == nil {
+		t.Fatal("expected PEC mismatch")
+	}
+	if _, err := d.ReadBlockData(0x09); err == nil {
+		t.Fatal("expected invalid block count")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDev_errors(t *testing.T) {
+	d := Dev{Bus: &i2ctest.Playback{DontPanic: true}, Addr: 0x80}
+	if err := d.SendByte(0); err == nil {
+		t.Fatal("invalid address")
+	}
+	d.Addr = 1
+	if err := d.WriteBlockData(0, make([]byte, 33)); err == nil {
+		t.Fatal("block too large")
+	}
+	if _, err := d.BlockProcessCall(0, make([]byte, 33)); err == nil {
+		t.Fatal("block too large")
+	}
+	if err := d.WriteI2CBlockData(0, make([]byte, 33)); err == nil {
+		t.Fatal("block too large")
+	}
+	if err := d.ReadI2CBlockData(0, make([]byte, 33)); err == nil {
+		t.Fatal("block too large")
+	}
+	if _, err := d.ReadBlockData(0); err == nil {
+		t.Fatal("bus error")
+	}
+	if _, err := Alert(d.Bus); err == nil {
+		t.Fatal("bus error")
+	}
+}
+
+func TestDev_native(t *testing.T) {
+	n := &native{supported: map[Protocol]bool{Quick: true, WordData: true, BlockData: true}}
+	d := Dev{Bus: n, Addr: 0x5A}
+	if err := d.Quick(true); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadWordData(0x07); err != nil || v != 0x0201 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadBlockData(0x07); err != nil || !bytes.Equal(v, []byte{0x02}) {
+		t.Fatal(v, err)
+	}
+	// Not supported natively, emulated instead.
+	if err := d.SendByte(1); err != nil {
+		t.Fatal(err)
+	}
+	// PEC is not supported natively.
+	d.PEC = true
+	if _, err := d.ReadWordData(0x07); err == nil {
+		t.Fatal("expected PEC mismatch")
+	}
+	expected := []Protocol{Quick, WordData, BlockData}
+	if len(n.ops) != len(expected) {
+		t.Fatal(n.ops)
+	}
+	for i := range expected {
+		if n.ops[i] != expected[i] {
+			t.Fatal(n.ops)
+		}
+	}
+	if n.tx != 2 {
+		t.Fatal(n.tx)
+	}
+}
+
+//
+
+type native struct {
+	supported map[Protocol]bool
+	ops       []Protocol
+	tx        int
+}
+
+func (n *native) String() string {
+	return "native"
+}
+
+func (n *native) Tx(addr uint16, w, r []byte) error {
+	n.tx++
+	for i := range r {
+		r[i] = 0
+	}
+	return nil
+}
+
+func (n *native) SetSpeed(f physic.Frequency) error {
+	return nil
+}
+
+func (n *native) SMBusSupported(p Protocol, read, pec bool) bool {
+	return !pec && n.supported[p]
+}
+
+func (n *native) SMBusTx(addr uint16, p Protocol, read bool, cmd byte, data []byte, pec bool) error {
+	n.ops = append(n.ops, p)
+	data[0] = 1
+	data[1] = 2
+	return nil
+}
+
+var _ Native = &native{}
+var _ i2c.Bus = &native{}
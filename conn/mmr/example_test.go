@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log"
+	"os"
 
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/i2c/i2creg"
@@ -92,3 +93,55 @@ func ExampleDev8_WriteStruct() {
 		log.Fatal(err)
 	}
 }
+
+func ExampleMap() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Open a connection, using I²C as an example:
+	b, err := i2creg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+	c := &i2c.Dev{Bus: b, Addr: 0x76}
+
+	// Describe a few registers of a bme280.
+	ctrlMeas := &mmr.Register{
+		Name: "ctrl_meas",
+		Addr: 0xF4,
+		Fields: []mmr.Field{
+			{Name: "osrs_t", Offset: 5, Width: 3},
+			{Name: "osrs_p", Offset: 2, Width: 3},
+			{Name: "mode", Offset: 0, Width: 2, Enum: map[uint64]string{0: "sleep", 1: "forced", 3: "normal"}},
+		},
+	}
+	status := &mmr.Register{
+		Name:     "status",
+		Addr:     0xF3,
+		Access:   mmr.ReadOnly,
+		Volatile: true,
+		Fields: []mmr.Field{
+			{Name: "measuring", Offset: 3, Width: 1},
+			{Name: "im_update", Offset: 0, Width: 1},
+		},
+	}
+	m := mmr.Map{
+		Dev:  &mmr.Dev8{Conn: c, Order: binary.BigEndian},
+		Regs: []*mmr.Register{status, ctrlMeas},
+	}
+
+	// Start a measurement; the other fields are left unchanged.
+	if err := m.Set(ctrlMeas, map[string]uint64{"mode": 1}); err != nil {
+		log.Fatal(err)
+	}
+	// Read both registers in one transaction and print them.
+	if err := m.ReadBlock(status, ctrlMeas); err != nil {
+		log.Fatal(err)
+	}
+	if err := m.Dump(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -8,6 +8,8 @@
 // The protocol is defined two supported commands:
 //  - Write Address, Read Value
 //  - Write Address, Write Value
+//
+// Map adds declarative register and bitfield definitions on top of it.
 package mmr
 
 import (
@@ -151,6 +153,32 @@ func (d *Dev8) WriteStruct(reg uint8, b interface{}) error {
 	return writeReg(d.Conn, d.Order, []byte{reg}, b)
 }
 
+// ReadRegs implements Accessor.
+//
+// reg must fit 8 bits.
+func (d *Dev8) ReadRegs(reg uint16, b []byte) error {
+	if reg > 0xFF {
+		return errRegAddr
+	}
+	if err := d.check(); err != nil {
+		return err
+	}
+	return d.Conn.Tx([]byte{byte(reg)}, b)
+}
+
+// WriteRegs implements Accessor.
+//
+// reg must fit 8 bits.
+func (d *Dev8) WriteRegs(reg uint16, b []byte) error {
+	if reg > 0xFF {
+		return errRegAddr
+	}
+	if err := d.check(); err != nil {
+		return err
+	}
+	return d.Conn.Tx(append([]byte{byte(reg)}, b...), nil)
+}
+
 func (d *Dev8) check() error {
 	if d.Conn.Duplex() != conn.Half {
 		return errors.New("reg: connection must be half-duplex")
@@ -306,6 +334,27 @@ func (d *Dev16) WriteStruct(reg uint16, b interface{}) error {
 	return writeReg(d.Conn, d.Order, r[:], b)
 }
 
+// ReadRegs implements Accessor.
+func (d *Dev16) ReadRegs(reg uint16, b []byte) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+	var r [2]byte
+	d.Order.PutUint16(r[:], reg)
+	return d.Conn.Tx(r[:], b)
+}
+
+// WriteRegs implements Accessor.
+func (d *Dev16) WriteRegs(reg uint16, b []byte) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+	w := make([]byte, 2+len(b))
+	d.Order.PutUint16(w, reg)
+	copy(w[2:], b)
+	return d.Conn.Tx(w, nil)
+}
+
 func (d *Dev16) check() error {
 	if d.Conn.Duplex() != conn.Half {
 		return errors.New("mmr: connection must be half-duplex")
@@ -316,39 +365,63 @@ func (d *Dev16) check() error {
 //
 
 func readReg(c conn.Conn, order binary.ByteOrder, reg []byte, b interface{}) error {
+	n, err := readSize(b)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, n)
+	if err := c.Tx(reg, buf); err != nil {
+		return err
+	}
+	return decodeReg(order, buf, b)
+}
+
+// writeReg writes an object `b` to register `reg`.
+//
+// Warning: reg is modified.
+func writeReg(c conn.Conn, order binary.ByteOrder, reg []byte, b interface{}) error {
+	buf, err := encodeReg(order, reg, b)
+	if err != nil {
+		return err
+	}
+	return c.Tx(buf, nil)
+}
+
+// readSize returns the number of bytes to read to decode `b`.
+func readSize(b interface{}) (int, error) {
 	if b == nil {
-		return errors.New("mmr: ReadRegStruct() requires a pointer or slice to an int or struct, got nil")
+		return 0, errors.New("mmr: ReadRegStruct() requires a pointer or slice to an int or struct, got nil")
 	}
 	v := reflect.ValueOf(b)
 	if !isAcceptableRead(v.Type()) {
-		return fmt.Errorf("mmr: ReadRegStruct() requires a slice or a pointer to a int or struct, got %s as %T", v.Kind(), b)
-	}
-	buf := make([]byte, int(getSize(v)))
-	if err := c.Tx(reg, buf); err != nil {
-		return err
+		return 0, fmt.Errorf("mmr: ReadRegStruct() requires a slice or a pointer to a int or struct, got %s as %T", v.Kind(), b)
 	}
+	return getSize(v), nil
+}
+
+func decodeReg(order binary.ByteOrder, buf []byte, b interface{}) error {
 	if err := binary.Read(bytes.NewReader(buf), order, b); err != nil {
 		return errors.New("mmr: decoding failed: " + err.Error())
 	}
 	return nil
 }
 
-// writeReg writes an object `b` to register `reg`.
+// encodeReg appends `b` encoded to `reg`.
 //
 // Warning: reg is modified.
-func writeReg(c conn.Conn, order binary.ByteOrder, reg []byte, b interface{}) error {
+func encodeReg(order binary.ByteOrder, reg []byte, b interface{}) ([]byte, error) {
 	if b == nil {
-		return errors.New("mmr: WriteRegStruct() requires a pointer or slice to an int or struct, got nil")
+		return nil, errors.New("mmr: WriteRegStruct() requires a pointer or slice to an int or struct, got nil")
 	}
 	t := reflect.TypeOf(b)
 	if !isAcceptableWrite(t) {
-		return fmt.Errorf("mmr: WriteRegStruct() requires a slice or a pointer to a int or struct, got %s as %T", t.Kind(), b)
+		return nil, fmt.Errorf("mmr: WriteRegStruct() requires a slice or a pointer to a int or struct, got %s as %T", t.Kind(), b)
 	}
 	buf := bytes.NewBuffer(reg)
 	if err := binary.Write(buf, order, b); err != nil {
-		return errors.New("mmr: encoding failed: " + err.Error())
+		return nil, errors.New("mmr: encoding failed: " + err.Error())
 	}
-	return c.Tx(buf.Bytes(), nil)
+	return buf.Bytes(), nil
 }
 
 // isAcceptableRead returns true if the struct can be safely serialized for
@@ -411,5 +484,9 @@ func isAcceptableInner(t reflect.Type) bool {
 	}
 }
 
+var errRegAddr = errors.New("mmr: register address doesn't fit 8 bits")
+
 var _ conn.Conn = &Dev8{}
 var _ conn.Conn = &Dev16{}
+var _ Accessor = &Dev8{}
+var _ Accessor = &Dev16{}
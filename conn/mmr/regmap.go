@@ -0,0 +1,453 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mmr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Accessor reads and writes the raw bytes of registers.
+//
+// Dev8 and Dev16 implement it over a half-duplex connection like I²C, and
+// SPIDev over SPI.
+type Accessor interface {
+	// ReadRegs reads len(b) bytes starting at register reg.
+	ReadRegs(reg uint16, b []byte) error
+	// WriteRegs writes b starting at register reg.
+	WriteRegs(reg uint16, b []byte) error
+}
+
+// Access is the access mode of a register or a field.
+type Access uint8
+
+// Valid Access values.
+const (
+	ReadWrite       Access = iota // The default.
+	ReadOnly                      // Writes are refused.
+	WriteOnly                     // Reads are refused; the last written value is cached.
+	WriteOneToClear               // Writing a 1 clears the bit; 0 is written unless set explicitly.
+)
+
+const accessName = "ReadWriteReadOnlyWriteOnlyWriteOneToClear"
+
+var accessIndex = [...]uint8{0, 9, 17, 26, 41}
+
+func (a Access) String() string {
+	if int(a) >= len(accessIndex)-1 {
+		return "Access(" + strconv.Itoa(int(a)) + ")"
+	}
+	return accessName[accessIndex[a]:accessIndex[a+1]]
+}
+
+// Field is a named bitfield in a register.
+type Field struct {
+	Name string
+	// Offset is the bit offset of the least significant bit of the field.
+	Offset uint
+	// Width is the number of bits of the field.
+	Width uint
+	// Access is the access mode of the field. A field in a ReadOnly or
+	// WriteOnly register inherits the register's mode.
+	Access Access
+	// Enum optionally names the values of the field. It is used by Format().
+	Enum map[uint64]string
+}
+
+// Get returns the value of the field in the register value v.
+func (f *Field) Get(v uint64) uint64 {
+	return (v >> f.Offset) & f.mask()
+}
+
+// Set returns the register value v with the field set to x.
+func (f *Field) Set(v, x uint64) uint64 {
+	m := f.mask()
+	return v&^(m<<f.Offset) | (x&m)<<f.Offset
+}
+
+// Format returns the value x of the field in human readable form.
+func (f *Field) Format(x uint64) string {
+	if s, ok := f.Enum[x]; ok {
+		return fmt.Sprintf("%d (%s)", x, s)
+	}
+	return strconv.FormatUint(x, 10)
+}
+
+func (f *Field) mask() uint64 {
+	if f.Width >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<f.Width - 1
+}
+
+// Register describes a register and its fields.
+type Register struct {
+	Name string
+	// Addr is the address of the register.
+	Addr uint16
+	// Size is the number of bytes of the register, between 1 and 8. The
+	// default is 1.
+	Size int
+	// Order is the byte order of the register. When nil, Map.Order is used.
+	Order binary.ByteOrder
+	// Access is the access mode of the register.
+	Access Access
+	// Volatile registers, like status registers, can change on their own. They
+	// are always read before being modified instead of using the cached value.
+	Volatile bool
+	// Fields lists the bitfields of the register.
+	Fields []Field
+}
+
+// Field returns the field named name, or nil.
+func (r *Register) Field(name string) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Name == name {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+func (r *Register) size() int {
+	if r.Size == 0 {
+		return 1
+	}
+	return r.Size
+}
+
+func (r *Register) check() error {
+	if s := r.size(); s < 1 || s > 8 {
+		return fmt.Errorf("mmr: register %s: invalid size %d", r.Name, r.Size)
+	}
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if f.Width == 0 || f.Offset+f.Width > uint(8*r.size()) {
+			return fmt.Errorf("mmr: register %s: field %s doesn't fit", r.Name, f.Name)
+		}
+	}
+	return nil
+}
+
+// fieldAccess returns the effective access mode of f.
+func (r *Register) fieldAccess(f *Field) Access {
+	if r.Access == ReadOnly || r.Access == WriteOnly {
+		return r.Access
+	}
+	return f.Access
+}
+
+// Map binds register descriptions to a device.
+//
+// It caches the last value read or written of each register, so fields can be
+// modified without reading the register first.
+//
+// It is safe for concurrent use.
+type Map struct {
+	Dev Accessor
+	// Order is the default byte order of the registers. The default is big
+	// endian.
+	Order binary.ByteOrder
+	// WordSize is the number of bytes at each address. The default is 1, for
+	// byte addressed registers. It is used by ReadBlock() to locate the
+	// registers in a block.
+	WordSize int
+	// Regs lists the registers of the device. It is used by Register() and
+	// Dump().
+	Regs []*Register
+
+	mu    sync.Mutex
+	cache map[*Register]uint64
+}
+
+// Register returns the register named name, or nil.
+func (m *Map) Register(name string) *Register {
+	for _, r := range m.Regs {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Read reads the register r from the device.
+func (m *Map) Read(r *Register) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.read(r)
+}
+
+// Write writes v to the register r.
+func (m *Map) Write(r *Register, v uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.write(r, v)
+}
+
+// Cached returns the last value read or written of register r, if any.
+func (m *Map) Cached(r *Register) (uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.cache[r]
+	return v, ok
+}
+
+// Invalidate drops the cached values, for example after the device was reset.
+func (m *Map) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = nil
+}
+
+// Get reads the register r from the device and returns the value of its field
+// name.
+func (m *Map) Get(r *Register, name string) (uint64, error) {
+	f := r.Field(name)
+	if f == nil {
+		return 0, fmt.Errorf("mmr: register %s has no field %s", r.Name, name)
+	}
+	if r.fieldAccess(f) == WriteOnly {
+		return 0, fmt.Errorf("mmr: field %s.%s is write-only", r.Name, name)
+	}
+	v, err := m.Read(r)
+	if err != nil {
+		return 0, err
+	}
+	return f.Get(v), nil
+}
+
+// Set modifies the fields of register r listed in values.
+//
+// The other fields keep their cached value. The register is read first when
+// it is volatile or not yet cached and readable. Fields with WriteOneToClear
+// access are written as 0 unless listed.
+func (m *Map) Set(r *Register, values map[string]uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := r.check(); err != nil {
+		return err
+	}
+	if r.Access == ReadOnly {
+		return fmt.Errorf("mmr: register %s is read-only", r.Name)
+	}
+	// Validate first to not do any I/O on invalid input.
+	for name, x := range values {
+		f := r.Field(name)
+		if f == nil {
+			return fmt.Errorf("mmr: register %s has no field %s", r.Name, name)
+		}
+		if r.fieldAccess(f) == ReadOnly {
+			return fmt.Errorf("mmr: field %s.%s is read-only", r.Name, name)
+		}
+		if x > f.mask() {
+			return fmt.Errorf("mmr: value %d doesn't fit field %s.%s", x, r.Name, name)
+		}
+	}
+	v, ok := m.cache[r]
+	if r.Access != WriteOnly && (!ok || r.Volatile) {
+		var err error
+		if v, err = m.read(r); err != nil {
+			return err
+		}
+	}
+	for i := range r.Fields {
+		f := &r.Fields[i]
+		if x, ok := values[f.Name]; ok {
+			v = f.Set(v, x)
+		} else if r.fieldAccess(f) == WriteOneToClear {
+			v = f.Set(v, 0)
+		}
+	}
+	return m.write(r, v)
+}
+
+// ReadBlock reads the registers regs in a single transaction.
+//
+// The block spans from the lowest to the highest register address, including
+// the registers in between that are not listed. The device must auto-increment
+// the register address.
+func (m *Map) ReadBlock(regs ...*Register) error {
+	if len(regs) == 0 {
+		return nil
+	}
+	ws := m.wordSize()
+	first, end := regs[0].Addr, 0
+	for _, r := range regs {
+		if err := r.check(); err != nil {
+			return err
+		}
+		if r.Access == WriteOnly {
+			return fmt.Errorf("mmr: register %s is write-only", r.Name)
+		}
+		if r.Addr < first {
+			first = r.Addr
+		}
+		if e := int(r.Addr)*ws + r.size(); e > end {
+			end = e
+		}
+	}
+	buf := make([]byte, end-int(first)*ws)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.Dev.ReadRegs(first, buf); err != nil {
+		return err
+	}
+	for _, r := range regs {
+		off := int(r.Addr-first) * ws
+		m.store(r, decode(m.order(r), buf[off:off+r.size()]))
+	}
+	return nil
+}
+
+// Dump writes the cached value of the registers in Regs, decoded as fields, in
+// human readable form.
+//
+// It does no I/O; use ReadBlock() or Read() first to refresh the cache.
+func (m *Map) Dump(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Regs {
+		v, ok := m.cache[r]
+		if !ok {
+			if _, err := fmt.Fprintf(w, "%s (0x%02X): unknown\n", r.Name, r.Addr); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s (0x%02X): 0x%0*X\n", r.Name, r.Addr, 2*r.size(), v); err != nil {
+			return err
+		}
+		// Sort the fields from the most significant bit like in datasheets.
+		fields := make([]*Field, len(r.Fields))
+		width := 0
+		for i := range r.Fields {
+			fields[i] = &r.Fields[i]
+			if l := len(r.Fields[i].Name); l > width {
+				width = l
+			}
+		}
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Offset > fields[j].Offset })
+		for _, f := range fields {
+			if _, err := fmt.Fprintf(w, "  %-*s %s\n", width, f.Name, f.Format(f.Get(v))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//
+
+func (m *Map) read(r *Register) (uint64, error) {
+	if err := r.check(); err != nil {
+		return 0, err
+	}
+	if r.Access == WriteOnly {
+		return 0, fmt.Errorf("mmr: register %s is write-only", r.Name)
+	}
+	buf := make([]byte, r.size())
+	if err := m.Dev.ReadRegs(r.Addr, buf); err != nil {
+		return 0, err
+	}
+	v := decode(m.order(r), buf)
+	m.store(r, v)
+	return v, nil
+}
+
+func (m *Map) write(r *Register, v uint64) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	if r.Access == ReadOnly {
+		return fmt.Errorf("mmr: register %s is read-only", r.Name)
+	}
+	if r.size() < 8 && v>>uint(8*r.size()) != 0 {
+		return fmt.Errorf("mmr: value 0x%X doesn't fit register %s", v, r.Name)
+	}
+	buf := make([]byte, r.size())
+	encode(m.order(r), buf, v)
+	if err := m.Dev.WriteRegs(r.Addr, buf); err != nil {
+		return err
+	}
+	m.store(r, v)
+	return nil
+}
+
+func (m *Map) store(r *Register, v uint64) {
+	if m.cache == nil {
+		m.cache = map[*Register]uint64{}
+	}
+	m.cache[r] = v
+}
+
+func (m *Map) order(r *Register) binary.ByteOrder {
+	if r.Order != nil {
+		return r.Order
+	}
+	if m.Order != nil {
+		return m.Order
+	}
+	return binary.BigEndian
+}
+
+func (m *Map) wordSize() int {
+	if m.WordSize <= 0 {
+		return 1
+	}
+	return m.WordSize
+}
+
+// decode decodes b with order. Sizes other than 1, 2, 4 and 8 bytes are only
+// supported in little endian or big endian.
+func decode(order binary.ByteOrder, b []byte) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	case 8:
+		return order.Uint64(b)
+	}
+	var v uint64
+	if order == binary.LittleEndian {
+		for i := len(b) - 1; i >= 0; i-- {
+			v = v<<8 | uint64(b[i])
+		}
+	} else {
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+	}
+	return v
+}
+
+func encode(order binary.ByteOrder, b []byte, v uint64) {
+	switch len(b) {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		order.PutUint16(b, uint16(v))
+	case 4:
+		order.PutUint32(b, uint32(v))
+	case 8:
+		order.PutUint64(b, v)
+	default:
+		if order == binary.LittleEndian {
+			for i := range b {
+				b[i] = byte(v >> uint(8*i))
+			}
+		} else {
+			for i := range b {
+				b[len(b)-1-i] = byte(v >> uint(8*i))
+			}
+		}
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mmr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+)
+
+func TestAccess_String(t *testing.T) {
+	if s := WriteOneToClear.String(); s != "WriteOneToClear" {
+		t.Fatal(s)
+	}
+	if s := Access(10).String(); s != "Access(10)" {
+		t.Fatal(s)
+	}
+}
+
+func TestField(t *testing.T) {
+	f := Field{Name: "mode", Offset: 4, Width: 3, Enum: map[uint64]string{2: "fast"}}
+	if v := f.Get(0xA5); v != 2 {
+		t.Fatal(v)
+	}
+	if v := f.Set(0xA5, 7); v != 0xF5 {
+		t.Fatalf("0x%X", v)
+	}
+	if v := f.Set(0xA5, 0xF); v != 0xF5 {
+		t.Fatalf("0x%X", v)
+	}
+	if s := f.Format(2); s != "2 (fast)" {
+		t.Fatal(s)
+	}
+	if s := f.Format(3); s != "3" {
+		t.Fatal(s)
+	}
+	f = Field{Width: 64}
+	if v := f.Get(^uint64(0)); v != ^uint64(0) {
+		t.Fatal(v)
+	}
+}
+
+func TestMap_I2C(t *testing.T) {
+	s := &i2ctest.Sim{
+		Devices: map[uint16]*i2ctest.SimDevice{
+			0x76: {Regs: []byte{0x08, 0x27, 0x12, 0x34, 0x56}},
+		},
+	}
+	status := &Register{
+		Name:     "status",
+		Addr:     0,
+		Access:   ReadOnly,
+		Volatile: true,
+		Fields:   []Field{{Name: "measuring", Offset: 3, Width: 1}},
+	}
+	ctrl := &Register{
+		Name: "ctrl",
+		Addr: 1,
+		Fields: []Field{
+			{Name: "osrs", Offset: 5, Width: 3},
+			{Name: "en", Offset: 2, Width: 1},
+			{Name: "mode", Offset: 0, Width: 2, Enum: map[uint64]string{0: "sleep", 3: "normal"}},
+		},
+	}
+	data := &Register{Name: "data", Addr: 2, Size: 3, Access: ReadOnly}
+	m := Map{
+		Dev:  &Dev8{Conn: &i2c.Dev{Bus: s, Addr: 0x76}, Order: binary.BigEndian},
+		Regs: []*Register{status, ctrl, data},
+	}
+	if r := m.Register("ctrl"); r != ctrl {
+		t.Fatal(r)
+	}
+	if r := m.Register("foo"); r != nil {
+		t.Fatal(r)
+	}
+	if v, err := m.Get(status, "measuring"); err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	if err := m.Set(ctrl, map[string]uint64{"mode": 0, "en": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Devices[0x76].Regs[1]; v != 0x24 {
+		t.Fatalf("0x%X", v)
+	}
+	// The cached value is used; a change behind the Map's back is overwritten.
+	s.Devices[0x76].Regs[1] = 0
+	if err := m.Set(ctrl, map[string]uint64{"mode": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Devices[0x76].Regs[1]; v != 0x27 {
+		t.Fatalf("0x%X", v)
+	}
+	if v, ok := m.Cached(ctrl); !ok || v != 0x27 {
+		t.Fatal(v, ok)
+	}
+	m.Invalidate()
+	if _, ok := m.Cached(ctrl); ok {
+		t.Fatal("expected no cached value")
+	}
+	s.Devices[0x76].Regs[0] = 0
+	if err := m.ReadBlock(data, status); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m.Cached(data); !ok || v != 0x123456 {
+		t.Fatalf("0x%X", v)
+	}
+	if v, ok := m.Cached(ctrl); ok {
+		t.Fatal(v)
+	}
+	if v, err := m.Read(ctrl); err != nil || v != 0x27 {
+		t.Fatal(v, err)
+	}
+	buf := bytes.Buffer{}
+	if err := m.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "status (0x00): 0x00\n" +
+		"  measuring 0\n" +
+		"ctrl (0x01): 0x27\n" +
+		"  osrs 1\n" +
+		"  en   1\n" +
+		"  mode 3 (normal)\n" +
+		"data (0x02): 0x123456\n"
+	if s := buf.String(); s != expected {
+		t.Fatal(s)
+	}
+	m.Invalidate()
+	buf.Reset()
+	if err := m.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.HasPrefix(s, "status (0x00): unknown\n") {
+		t.Fatal(s)
+	}
+}
+
+func TestMap_Access(t *testing.T) {
+	a := &accessor{regs: make([]byte, 16)}
+	ro := &Register{Name: "ro", Access: ReadOnly, Fields: []Field{{Name: "a", Width: 8}}}
+	wo := &Register{Name: "wo", Addr: 1, Access: WriteOnly, Fields: []Field{{Name: "a", Width: 4}, {Name: "b", Offset: 4, Width: 4}}}
+	irq := &Register{
+		Name: "irq",
+		Addr: 2,
+		Fields: []Field{
+			{Name: "en", Offset: 0, Width: 1},
+			{Name: "flag", Offset: 7, Width: 1, Access: WriteOneToClear},
+			{Name: "id", Offset: 4, Width: 2, Access: ReadOnly},
+		},
+	}
+	m := Map{Dev: a}
+	if err := m.Write(ro, 1); err == nil {
+		t.Fatal("read-only")
+	}
+	if err := m.Set(ro, map[string]uint64{"a": 1}); err == nil {
+		t.Fatal("read-only")
+	}
+	if _, err := m.Read(wo); err == nil {
+		t.Fatal("write-only")
+	}
+	if _, err := m.Get(wo, "a"); err == nil {
+		t.Fatal("write-only")
+	}
+	if err := m.ReadBlock(ro, wo); err == nil {
+		t.Fatal("write-only")
+	}
+	// Write-only registers are modified from the cache, starting at 0.
+	if err := m.Set(wo, map[string]uint64{"a": 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(wo, map[string]uint64{"b": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if a.regs[1] != 0x35 || a.reads != 0 {
+		t.Fatal(a.regs, a.reads)
+	}
+	// The pending flag is not cleared by accident.
+	a.regs[2] = 0x90
+	if err := m.Set(irq, map[string]uint64{"en": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if a.regs[2] != 0x11 {
+		t.Fatalf("0x%X", a.regs[2])
+	}
+	if err := m.Set(irq, map[string]uint64{"flag": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if a.regs[2] != 0x91 {
+		t.Fatalf("0x%X", a.regs[2])
+	}
+	if err := m.Set(irq, map[string]uint64{"id": 1}); err == nil {
+		t.Fatal("read-only field")
+	}
+	if err := m.Set(irq, map[string]uint64{"en": 2}); err == nil {
+		t.Fatal("value too large")
+	}
+	if err := m.Set(irq, map[string]uint64{"foo": 1}); err == nil {
+		t.Fatal("unknown field")
+	}
+	if _, err := m.Get(irq, "foo"); err == nil {
+		t.Fatal("unknown field")
+	}
+	if err := m.Write(irq, 0x100); err == nil {
+		t.Fatal("value too large")
+	}
+	if a.writes != 4 {
+		t.Fatal(a.writes)
+	}
+}
+
+func TestMap_Order(t *testing.T) {
+	a := &accessor{regs: make([]byte, 16)}
+	le := &Register{Name: "le", Size: 2, Order: binary.LittleEndian}
+	be := &Register{Name: "be", Addr: 2, Size: 2}
+	le3 := &Register{Name: "le3", Addr: 4, Size: 3, Order: binary.LittleEndian}
+	be3 := &Register{Name: "be3", Addr: 7, Size: 3}
+	r8 := &Register{Name: "r8", Addr: 8, Size: 8}
+	m := Map{Dev: a}
+	for _, r := range []*Register{le, be} {
+		if err := m.Write(r, 0x1234); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Write(le3, 0x123456); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.regs[:7], []byte{0x34, 0x12, 0x12, 0x34, 0x56, 0x34, 0x12}) {
+		t.Fatalf("%#v", a.regs)
+	}
+	if err := m.Write(be3, 0xABCDEF); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.regs[7:10], []byte{0xAB, 0xCD, 0xEF}) {
+		t.Fatalf("%#v", a.regs)
+	}
+	if err := m.Write(r8, 0x0102030405060708); err != nil {
+		t.Fatal(err)
+	}
+	m.Invalidate()
+	if err := m.ReadBlock(le, be, le3); err != nil {
+		t.Fatal(err)
+	}
+	for r, e := range map[*Register]uint64{le: 0x1234, be: 0x1234, le3: 0x123456} {
+		if v, _ := m.Cached(r); v != e {
+			t.Fatalf("%s: 0x%X", r.Name, v)
+		}
+	}
+	if v, err := m.Read(r8); err != nil || v != 0x0102030405060708 {
+		t.Fatal(v, err)
+	}
+
+	// Word addressed registers.
+	a = &accessor{regs: []byte{0, 0, 0x12, 0x34, 0x56, 0x78}, wordSize: 2}
+	m = Map{Dev: a, WordSize: 2}
+	w1 := &Register{Name: "w1", Addr: 1, Size: 2}
+	w2 := &Register{Name: "w2", Addr: 2, Size: 2}
+	if err := m.ReadBlock(w2, w1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Cached(w2); v != 0x5678 {
+		t.Fatalf("0x%X", v)
+	}
+	if a.reads != 1 {
+		t.Fatal(a.reads)
+	}
+}
+
+func TestMap_errors(t *testing.T) {
+	a := &accessor{regs: make([]byte, 16), err: errors.New("bus")}
+	m := Map{Dev: a}
+	r := &Register{Name: "r", Fields: []Field{{Name: "f", Width: 1}}}
+	if _, err := m.Read(r); err == nil {
+		t.Fatal("bus error")
+	}
+	if err := m.Write(r, 1); err == nil {
+		t.Fatal("bus error")
+	}
+	if err := m.Set(r, map[string]uint64{"f": 1}); err == nil {
+		t.Fatal("bus error")
+	}
+	if _, err := m.Get(r, "f"); err == nil {
+		t.Fatal("bus error")
+	}
+	if err := m.ReadBlock(r); err == nil {
+		t.Fatal("bus error")
+	}
+	if err := m.ReadBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Cached(r); ok {
+		t.Fatal("unexpected cache")
+	}
+	a.err = nil
+	for _, bad := range []*Register{
+		{Name: "size", Size: 9},
+		{Name: "field", Fields: []Field{{Name: "f", Offset: 4, Width: 5}}},
+		{Name: "width", Fields: []Field{{Name: "f"}}},
+	} {
+		if _, err := m.Read(bad); err == nil {
+			t.Fatal(bad.Name)
+		}
+		if err := m.Write(bad, 0); err == nil {
+			t.Fatal(bad.Name)
+		}
+		if err := m.Set(bad, nil); err == nil {
+			t.Fatal(bad.Name)
+		}
+		if err := m.ReadBlock(bad); err == nil {
+			t.Fatal(bad.Name)
+		}
+	}
+}
+
+func TestDev8_Regs(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x10: {Regs: make([]byte, 4)}}}
+	d := Dev8{Conn: &i2c.Dev{Bus: s, Addr: 0x10}, Order: binary.BigEndian}
+	if err := d.WriteRegs(1, []byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 3)
+	if err := d.ReadRegs(0, b); err != nil || !bytes.Equal(b, []byte{0, 1, 2}) {
+		t.Fatal(b, err)
+	}
+	if err := d.ReadRegs(0x100, b); err == nil {
+		t.Fatal("address too large")
+	}
+	if err := d.WriteRegs(0x100, b); err == nil {
+		t.Fatal("address too large")
+	}
+}
+
+func TestDev16_Regs(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x10: {AddrWidth: 2, Regs: make([]byte, 4)}}}
+	d := Dev16{Conn: &i2c.Dev{Bus: s, Addr: 0x10}, Order: binary.BigEndian}
+	if err := d.WriteRegs(1, []byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 3)
+	if err := d.ReadRegs(0, b); err != nil || !bytes.Equal(b, []byte{0, 1, 2}) {
+		t.Fatal(b, err)
+	}
+}
+
+//
+
+// accessor is a fake Accessor, like a SPI device would be.
+type accessor struct {
+	regs     []byte
+	wordSize int
+	reads    int
+	writes   int
+	err      error
+}
+
+func (a *accessor) ReadRegs(reg uint16, b []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.reads++
+	copy(b, a.regs[a.offset(reg):])
+	return nil
+}
+
+func (a *accessor) WriteRegs(reg uint16, b []byte) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.writes++
+	copy(a.regs[a.offset(reg):], b)
+	return nil
+}
+
+func (a *accessor) offset(reg uint16) int {
+	if a.wordSize == 0 {
+		return int(reg)
+	}
+	return int(reg) * a.wordSize
+}
+
+var _ Accessor = &accessor{}
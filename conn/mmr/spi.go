@@ -0,0 +1,214 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mmr
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/spi"
+)
+
+// SPIFraming describes how a SPI device frames register accesses.
+//
+// Each transaction starts with the register address, with ReadBit, WriteBit
+// and MultiBit applied, followed by the dummy bytes on reads, then the data.
+//
+// Common framings:
+//  - bmx280, mpu9250: SPIFraming{ReadBit: 0x80}
+//  - lis3dh, adxl345: SPIFraming{ReadBit: 0x80, MultiBit: 0x40}
+//  - max31865: SPIFraming{WriteBit: 0x80}
+//  - mcp23S17: SPIFraming{AddrWidth: 2, ReadBit: 0x100}, with the opcode
+//    0x40|hw<<1 in the high byte of the register address.
+type SPIFraming struct {
+	// AddrWidth is the number of bytes of the register address, encoded as big
+	// endian. The default is 1.
+	AddrWidth int
+	// ReadBit is set in the address on reads and cleared on writes.
+	ReadBit uint16
+	// WriteBit is set in the address on writes and cleared on reads.
+	WriteBit uint16
+	// MultiBit is set in the address when more than one byte is transferred,
+	// to enable the auto-increment of the register address.
+	MultiBit uint16
+	// Dummy is the number of dummy bytes clocked between the address and the
+	// data on reads.
+	Dummy int
+}
+
+// SPIDev is a SPI connection that exposes memory mapped registers.
+//
+// It works over full-duplex connections, where the bytes read while the
+// address is sent are discarded, and over half-duplex (3-wire) connections.
+type SPIDev struct {
+	Conn    spi.Conn
+	Framing SPIFraming
+	// Order specifies the binary encoding of words. See Dev8.Order.
+	Order binary.ByteOrder
+}
+
+// String implements conn.Conn.
+func (d *SPIDev) String() string {
+	return d.Conn.String()
+}
+
+// Duplex implements conn.Conn.
+func (d *SPIDev) Duplex() conn.Duplex {
+	return d.Conn.Duplex()
+}
+
+// Tx implements conn.Conn.
+func (d *SPIDev) Tx(w, r []byte) error {
+	return d.Conn.Tx(w, r)
+}
+
+// ReadUint8 reads a 8 bit register.
+func (d *SPIDev) ReadUint8(reg uint16) (uint8, error) {
+	var v [1]byte
+	err := d.ReadRegs(reg, v[:])
+	return v[0], err
+}
+
+// ReadUint16 reads a 16 bit register.
+func (d *SPIDev) ReadUint16(reg uint16) (uint16, error) {
+	var v [2]byte
+	err := d.ReadRegs(reg, v[:])
+	return d.Order.Uint16(v[:]), err
+}
+
+// ReadUint32 reads a 32 bit register.
+func (d *SPIDev) ReadUint32(reg uint16) (uint32, error) {
+	var v [4]byte
+	err := d.ReadRegs(reg, v[:])
+	return d.Order.Uint32(v[:]), err
+}
+
+// ReadUint64 reads a 64 bit register.
+func (d *SPIDev) ReadUint64(reg uint16) (uint64, error) {
+	var v [8]byte
+	err := d.ReadRegs(reg, v[:])
+	return d.Order.Uint64(v[:]), err
+}
+
+// ReadStruct reads the data from the register into `b` and marshall it via
+// `.Order` as appropriate.
+//
+// It is expected to be called with a slice of integers, slice of structs,
+// pointer to an integer or to a struct.
+func (d *SPIDev) ReadStruct(reg uint16, b interface{}) error {
+	n, err := readSize(b)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, n)
+	if err := d.ReadRegs(reg, buf); err != nil {
+		return err
+	}
+	return decodeReg(d.Order, buf, b)
+}
+
+// WriteUint8 writes a 8 bit register.
+func (d *SPIDev) WriteUint8(reg uint16, v uint8) error {
+	return d.WriteRegs(reg, []byte{v})
+}
+
+// WriteUint16 writes a 16 bit register.
+func (d *SPIDev) WriteUint16(reg uint16, v uint16) error {
+	var b [2]byte
+	d.Order.PutUint16(b[:], v)
+	return d.WriteRegs(reg, b[:])
+}
+
+// WriteUint32 writes a 32 bit register.
+func (d *SPIDev) WriteUint32(reg uint16, v uint32) error {
+	var b [4]byte
+	d.Order.PutUint32(b[:], v)
+	return d.WriteRegs(reg, b[:])
+}
+
+// WriteUint64 writes a 64 bit register.
+func (d *SPIDev) WriteUint64(reg uint16, v uint64) error {
+	var b [8]byte
+	d.Order.PutUint64(b[:], v)
+	return d.WriteRegs(reg, b[:])
+}
+
+// WriteStruct writes the data `b` marshalled via `.Order` as appropriate to
+// the register.
+//
+// It is expected to be called with a slice of integers, slice of structs,
+// pointer to an integer or to a struct.
+func (d *SPIDev) WriteStruct(reg uint16, b interface{}) error {
+	buf, err := encodeReg(d.Order, nil, b)
+	if err != nil {
+		return err
+	}
+	return d.WriteRegs(reg, buf)
+}
+
+// ReadRegs implements Accessor.
+func (d *SPIDev) ReadRegs(reg uint16, b []byte) error {
+	hdr, err := d.header(reg, true, len(b))
+	if err != nil {
+		return err
+	}
+	if d.Conn.Duplex() == conn.Half {
+		return d.Conn.Tx(hdr, b)
+	}
+	w := make([]byte, len(hdr)+len(b))
+	copy(w, hdr)
+	r := make([]byte, len(w))
+	if err := d.Conn.Tx(w, r); err != nil {
+		return err
+	}
+	copy(b, r[len(hdr):])
+	return nil
+}
+
+// WriteRegs implements Accessor.
+func (d *SPIDev) WriteRegs(reg uint16, b []byte) error {
+	hdr, err := d.header(reg, false, len(b))
+	if err != nil {
+		return err
+	}
+	return d.Conn.Tx(append(hdr, b...), nil)
+}
+
+// header returns the address bytes and, for reads, the dummy bytes.
+func (d *SPIDev) header(reg uint16, read bool, n int) ([]byte, error) {
+	f := &d.Framing
+	w := f.AddrWidth
+	if w == 0 {
+		w = 1
+	}
+	if w != 1 && w != 2 {
+		return nil, errors.New("mmr: SPIFraming.AddrWidth must be 1 or 2")
+	}
+	if read {
+		reg = reg&^f.WriteBit | f.ReadBit
+	} else {
+		reg = reg&^f.ReadBit | f.WriteBit
+	}
+	if n > 1 {
+		reg |= f.MultiBit
+	}
+	if w == 1 && reg > 0xFF {
+		return nil, errRegAddr
+	}
+	hdr := make([]byte, w, w+f.Dummy+n)
+	if w == 1 {
+		hdr[0] = byte(reg)
+	} else {
+		binary.BigEndian.PutUint16(hdr, reg)
+	}
+	if read {
+		hdr = hdr[:w+f.Dummy]
+	}
+	return hdr, nil
+}
+
+var _ conn.Conn = &SPIDev{}
+var _ Accessor = &SPIDev{}
@@ -0,0 +1,171 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package mmr
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func TestSPIDev(t *testing.T) {
+	p := &spitest.Playback{
+		Playback: conntest.Playback{
+			D: conn.Full,
+			Ops: []conntest.IO{
+				{W: []byte{0xF5, 0x00}, R: []byte{0xFF, 0x71}},
+				{W: []byte{0xC3, 0x00, 0x00}, R: []byte{0xFF, 0x12, 0x34}},
+				{W: []byte{0xC3, 0x00, 0x00, 0x00, 0x00}, R: []byte{0xFF, 0x78, 0x56, 0x34, 0x12}},
+				{W: []byte{0xC3, 0, 0, 0, 0, 0, 0, 0, 0}, R: []byte{0xFF, 1, 0, 0, 0, 0, 0, 0, 0}},
+				{W: []byte{0xC3, 0x00, 0x00}, R: []byte{0xFF, 0x01, 0x02}},
+				{W: []byte{0x0B, 0x42}},
+				{W: []byte{0x4B, 0x12, 0x34}},
+				{W: []byte{0x4B, 0x78, 0x56, 0x34, 0x12}},
+				{W: []byte{0x4B, 1, 0, 0, 0, 0, 0, 0, 0}},
+				{W: []byte{0x4B, 0x03, 0x04}},
+			},
+		},
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := SPIDev{Conn: c, Framing: SPIFraming{ReadBit: 0x80, MultiBit: 0x40}, Order: binary.LittleEndian}
+	if s := d.String(); s != "playback" {
+		t.Fatal(s)
+	}
+	if v := d.Duplex(); v != conn.Full {
+		t.Fatal(v)
+	}
+	if v, err := d.ReadUint8(0x75); err != nil || v != 0x71 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadUint16(0x03); err != nil || v != 0x3412 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadUint32(0x03); err != nil || v != 0x12345678 {
+		t.Fatal(v, err)
+	}
+	if v, err := d.ReadUint64(0x03); err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	var s [2]uint8
+	if err := d.ReadStruct(0x03, &s); err != nil || s != [2]uint8{1, 2} {
+		t.Fatal(s, err)
+	}
+	if err := d.WriteUint8(0x8B, 0x42); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteUint16(0x0B, 0x3412); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteUint32(0x0B, 0x12345678); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteUint64(0x0B, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteStruct(0x0B, &[2]uint8{3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSPIDev_HalfDuplex(t *testing.T) {
+	p := &spitest.Playback{
+		Playback: conntest.Playback{
+			D: conn.Half,
+			Ops: []conntest.IO{
+				// mcp23S17 at hardware address 1 reading IODIRA and IODIRB.
+				{W: []byte{0x43, 0x00}, R: []byte{0xFF, 0xFE}},
+				{W: []byte{0x42, 0x0A, 0x80}},
+			},
+		},
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := SPIDev{Conn: c, Framing: SPIFraming{AddrWidth: 2, ReadBit: 0x100}, Order: binary.BigEndian}
+	if v, err := d.ReadUint16(0x4200); err != nil || v != 0xFFFE {
+		t.Fatal(v, err)
+	}
+	if err := d.WriteUint8(0x420A, 0x80); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSPIDev_Dummy(t *testing.T) {
+	p := &spitest.Playback{
+		Playback: conntest.Playback{
+			D: conn.Full,
+			Ops: []conntest.IO{
+				{W: []byte{0x02, 0x00, 0x00}, R: []byte{0xFF, 0xFF, 0x42}},
+				{W: []byte{0x82, 0x42}},
+			},
+		},
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode1, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Map{
+		Dev: &SPIDev{Conn: c, Framing: SPIFraming{WriteBit: 0x80, Dummy: 1}},
+	}
+	r := &Register{Name: "config", Addr: 2, Fields: []Field{{Name: "vbias", Offset: 7, Width: 1}}}
+	if v, err := m.Get(r, "vbias"); err != nil || v != 0 {
+		t.Fatal(v, err)
+	}
+	if err := m.Set(r, map[string]uint64{"vbias": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSPIDev_errors(t *testing.T) {
+	p := &spitest.Playback{Playback: conntest.Playback{D: conn.Full, DontPanic: true}}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := SPIDev{Conn: c, Order: binary.BigEndian}
+	if _, err := d.ReadUint8(0x100); err == nil {
+		t.Fatal("address too large")
+	}
+	if err := d.WriteUint8(0x100, 0); err == nil {
+		t.Fatal("address too large")
+	}
+	if _, err := d.ReadUint8(0); err == nil {
+		t.Fatal("playback error")
+	}
+	if err := d.ReadStruct(0, nil); err == nil {
+		t.Fatal("invalid struct")
+	}
+	if err := d.ReadStruct(0, new(uint8)); err == nil {
+		t.Fatal("playback error")
+	}
+	if err := d.WriteStruct(0, nil); err == nil {
+		t.Fatal("invalid struct")
+	}
+	d.Framing.AddrWidth = 3
+	if _, err := d.ReadUint8(0); err == nil {
+		t.Fatal("invalid address width")
+	}
+	if err := d.WriteUint8(0, 0); err == nil {
+		t.Fatal("invalid address width")
+	}
+}
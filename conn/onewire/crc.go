@@ -24,6 +24,38 @@ func CalcCRC(buf []byte) byte {
 	return crc
 }
 
+// CheckCRC16 verifies that the last two bytes of the buffer contain the
+// inverted 16-bit CRC of the previous bytes, least significant byte first.
+//
+// This is how devices send the CRC16 of the data they return.
+func CheckCRC16(buf []byte) bool {
+	if len(buf) < 2 {
+		return false
+	}
+	c := ^CalcCRC16(0, buf[:len(buf)-2])
+	return byte(c) == buf[len(buf)-2] && byte(c>>8) == buf[len(buf)-1]
+}
+
+// CalcCRC16 updates the 16-bit CRC crc with the buffer of bytes and returns
+// it.
+//
+// Start with a crc of 0. Devices send the inverted CRC; see CheckCRC16().
+//
+// The CRC16 calculation is described in App Note 27 referenced above.
+func CalcCRC16(crc uint16, buf []byte) uint16 {
+	for _, b := range buf {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
 // crcTable comes from https://www.maximintegrated.com/en/app-notes/index.mvp/id/27
 var crcTable = []byte{
 	0, 94, 188, 226, 97, 63, 221, 131, 194, 156, 126, 32, 163, 253, 31, 65,
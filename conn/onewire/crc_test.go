@@ -25,3 +25,26 @@ func TestCheckCRC(t *testing.T) {
 		t.Fatal("expected bad crc")
 	}
 }
+
+func TestCheckCRC16(t *testing.T) {
+	// The check value of CRC-16/ARC; CRC-16/MAXIM is its complement.
+	if c := CalcCRC16(0, []byte("123456789")); c != 0xBB3D {
+		t.Fatalf("0x%04X", c)
+	}
+	a := []byte{0x75, 0x8A, 0x6C, 0x02, 0xFF}
+	c := CalcCRC16(0, a)
+	if c2 := CalcCRC16(CalcCRC16(0, a[:2]), a[2:]); c2 != c {
+		t.Fatalf("0x%04X != 0x%04X", c2, c)
+	}
+	b := append(append([]byte{}, a...), byte(^c), byte(^c>>8))
+	if !CheckCRC16(b) {
+		t.Fatal("expected good crc")
+	}
+	b[len(b)-1]++
+	if CheckCRC16(b) {
+		t.Fatal("expected bad crc")
+	}
+	if CheckCRC16([]byte{0}) {
+		t.Fatal("expected bad crc")
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewire
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"periph.io/x/periph/conn"
+)
+
+// Family is the family code of a device, stored in the least significant byte
+// of its Address.
+type Family uint8
+
+// Well known family codes.
+const (
+	DS2401   Family = 0x01 // Silicon serial number
+	DS18S20  Family = 0x10 // Temperature sensor
+	DS1822   Family = 0x22 // Temperature sensor
+	DS2438   Family = 0x26 // Battery monitor
+	DS18B20  Family = 0x28 // Temperature sensor
+	DS2408   Family = 0x29 // 8 channels addressable switch
+	DS2431   Family = 0x2D // 1kb EEPROM
+	DS2413   Family = 0x3A // 2 channels addressable switch
+	DS1825   Family = 0x3B // Temperature sensor
+	DS28EC20 Family = 0x43 // 20kb EEPROM
+)
+
+// String returns the name registered for the family, the name of a well
+// known family or the family code in hexadecimal.
+func (f Family) String() string {
+	mu.Lock()
+	r := byFamily[f]
+	mu.Unlock()
+	if r.name != "" {
+		return r.name
+	}
+	if n := familyNames[f]; n != "" {
+		return n
+	}
+	return f.hex()
+}
+
+// Family returns the family code of the device.
+func (a Address) Family() Family {
+	return Family(a)
+}
+
+// CheckCRC verifies that the most significant byte of the address is the CRC
+// of the other bytes.
+func (a Address) CheckCRC() bool {
+	var b [8]byte
+	putUint64(b[:], a)
+	return CheckCRC(b[:])
+}
+
+// Opener instantiates the driver of a device.
+//
+// It is provided by the device package.
+type Opener func(b Bus, a Address) (conn.Resource, error)
+
+// RegisterFamily registers the Opener for the devices of family f.
+//
+// It is meant to be called in the init() function of device packages.
+// Registering the same family twice is an error.
+func RegisterFamily(f Family, name string, o Opener) error {
+	if len(name) == 0 {
+		return errors.New("onewire: can't register family " + f.hex() + " with no name")
+	}
+	if o == nil {
+		return errors.New("onewire: can't register family " + strconv.Quote(name) + " with nil Opener")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if r, ok := byFamily[f]; ok {
+		return errors.New("onewire: can't register family " + strconv.Quote(name) + "; family code is already registered as " + strconv.Quote(r.name))
+	}
+	byFamily[f] = family{name, o}
+	return nil
+}
+
+// MustRegisterFamily calls RegisterFamily() and panics if registration fails.
+func MustRegisterFamily(f Family, name string, o Opener) {
+	if err := RegisterFamily(f, name, o); err != nil {
+		panic(err)
+	}
+}
+
+// UnregisterFamily removes a previously registered family.
+func UnregisterFamily(f Family) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byFamily[f]; !ok {
+		return errors.New("onewire: can't unregister unknown family " + f.hex())
+	}
+	delete(byFamily, f)
+	return nil
+}
+
+// Open instantiates the driver of the device at address a on bus b, using the
+// Opener registered for its family.
+func Open(b Bus, a Address) (conn.Resource, error) {
+	mu.Lock()
+	r := byFamily[a.Family()]
+	mu.Unlock()
+	if r.open == nil {
+		return nil, errors.New("onewire: no driver registered for family " + a.Family().String())
+	}
+	return r.open(b, a)
+}
+
+// Device is a device found by Discover().
+type Device struct {
+	Addr Address
+	// Dev is the driver instance. It is nil when the address is invalid, no
+	// driver is registered for the family or the driver failed to open.
+	Dev conn.Resource
+	// Err is the error returned by the driver's Opener, if any.
+	Err error
+}
+
+// Discover searches the bus and opens the devices found with the drivers
+// registered for their family.
+//
+// Devices with an invalid address CRC or with no registered driver are
+// returned with a nil Dev. If the search fails, the devices already found
+// are returned with the error.
+func Discover(b Bus) ([]Device, error) {
+	addrs, err := b.Search(false)
+	out := make([]Device, 0, len(addrs))
+	for _, a := range addrs {
+		d := Device{Addr: a}
+		if a.CheckCRC() {
+			mu.Lock()
+			r := byFamily[a.Family()]
+			mu.Unlock()
+			if r.open != nil {
+				d.Dev, d.Err = r.open(b, a)
+			}
+		}
+		out = append(out, d)
+	}
+	return out, err
+}
+
+//
+
+type family struct {
+	name string
+	open Opener
+}
+
+var (
+	mu       sync.Mutex
+	byFamily = map[Family]family{}
+)
+
+// hex returns the family code in hexadecimal.
+func (f Family) hex() string {
+	s := strconv.FormatUint(uint64(f), 16)
+	if len(s) == 1 {
+		s = "0" + s
+	}
+	return "0x" + s
+}
+
+var familyNames = map[Family]string{
+	DS2401:   "DS2401",
+	DS18S20:  "DS18S20",
+	DS1822:   "DS1822",
+	DS2438:   "DS2438",
+	DS18B20:  "DS18B20",
+	DS2408:   "DS2408",
+	DS2431:   "DS2431",
+	DS2413:   "DS2413",
+	DS1825:   "DS1825",
+	DS28EC20: "DS28EC20",
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewire
+
+import (
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn"
+)
+
+func TestFamily(t *testing.T) {
+	var a Address = 0x740000070e41ac28
+	if f := a.Family(); f != DS18B20 || f.String() != "DS18B20" {
+		t.Fatal(f)
+	}
+	if !a.CheckCRC() || (a + 1<<8).CheckCRC() {
+		t.Fatal("unexpected CRC")
+	}
+	if s := Family(0x7E).String(); s != "0x7e" {
+		t.Fatal(s)
+	}
+	if s := Family(5).String(); s != "0x05" {
+		t.Fatal(s)
+	}
+}
+
+func TestRegisterFamily(t *testing.T) {
+	o := func(b Bus, a Address) (conn.Resource, error) { return &fakeDev{a}, nil }
+	if RegisterFamily(0x7E, "", o) == nil {
+		t.Fatal("missing name")
+	}
+	if RegisterFamily(0x7E, "EDS", nil) == nil {
+		t.Fatal("missing Opener")
+	}
+	if err := RegisterFamily(0x7E, "EDS", o); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := UnregisterFamily(0x7E); err != nil {
+			t.Fatal(err)
+		}
+		if UnregisterFamily(0x7E) == nil {
+			t.Fatal("already unregistered")
+		}
+	}()
+	if RegisterFamily(0x7E, "EDS", o) == nil {
+		t.Fatal("registered twice")
+	}
+	if s := Family(0x7E).String(); s != "EDS" {
+		t.Fatal(s)
+	}
+	d, err := Open(&listBus{}, 0x7E)
+	if err != nil || d.(*fakeDev).a != 0x7E {
+		t.Fatal(d, err)
+	}
+	if _, err := Open(&listBus{}, 0x740000070e41ac28); err == nil {
+		t.Fatal("no driver")
+	}
+}
+
+func TestMustRegisterFamily(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	MustRegisterFamily(0x7E, "", nil)
+}
+
+func TestDiscover(t *testing.T) {
+	good := mkAddr(0xFE, 1)
+	fail := mkAddr(0xFE, 2)
+	bad := good + 1<<8
+	unknown := mkAddr(0x7D, 1)
+	e := errors.New("oops")
+	opened := 0
+	MustRegisterFamily(0xFE, "FE", func(b Bus, a Address) (conn.Resource, error) {
+		opened++
+		if a == fail {
+			return nil, e
+		}
+		return &fakeDev{a}, nil
+	})
+	defer func() {
+		if err := UnregisterFamily(0xFE); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	b := &listBus{addrs: []Address{good, fail, bad, unknown}, err: e}
+	devs, err := Discover(b)
+	if err != e || len(devs) != 4 || opened != 2 {
+		t.Fatal(devs, err, opened)
+	}
+	if devs[0].Addr != good || devs[0].Dev.(*fakeDev).a != good || devs[0].Err != nil {
+		t.Fatal(devs[0])
+	}
+	if devs[1].Addr != fail || devs[1].Dev != nil || devs[1].Err != e {
+		t.Fatal(devs[1])
+	}
+	for _, d := range devs[2:] {
+		if d.Dev != nil || d.Err != nil {
+			t.Fatal(d)
+		}
+	}
+}
+
+//
+
+type fakeDev struct {
+	a Address
+}
+
+func (f *fakeDev) String() string {
+	return "fake"
+}
+
+func (f *fakeDev) Halt() error {
+	return nil
+}
+
+// listBus implements Bus and returns a fixed search result.
+type listBus struct {
+	nopBus
+	addrs []Address
+	err   error
+}
+
+func (l *listBus) Search(alarmOnly bool) ([]Address, error) {
+	return l.addrs, l.err
+}
+
+func mkAddr(f Family, serial byte) Address {
+	b := []byte{byte(f), serial, 0, 0, 0, 0, 0, 0}
+	b[7] = CalcCRC(b[:7])
+	var a Address
+	for i := 7; i >= 0; i-- {
+		a = a<<8 | Address(b[i])
+	}
+	return a
+}
@@ -25,6 +25,7 @@
 package onewire
 
 import (
+	"context"
 	"strconv"
 
 	"periph.io/x/periph/conn"
@@ -60,6 +61,37 @@ type Bus interface {
 	Search(alarmOnly bool) ([]Address, error)
 }
 
+// BusContext is optionally implemented by a Bus that supports aborting a
+// transaction via a context.Context.
+//
+// Use TxContext() to transparently support implementations that do not
+// implement this interface.
+type BusContext interface {
+	Bus
+	// TxContext performs a bus transaction like Tx().
+	//
+	// It returns ctx.Err() if ctx is done before the transaction is started or
+	// while it is waiting for exclusive access to the bus. A transaction that
+	// is already in progress on the wire may not be interruptible.
+	TxContext(ctx context.Context, w, r []byte, power Pullup) error
+}
+
+// TxContext performs a bus transaction on b like Tx() but aborts when ctx is
+// done.
+//
+// It uses b.TxContext() if b implements BusContext. Otherwise ctx is checked
+// before starting the transaction and b.Tx() is called; in this case, an
+// in-progress transaction cannot be aborted.
+func TxContext(ctx context.Context, b Bus, w, r []byte, power Pullup) error {
+	if bc, ok := b.(BusContext); ok {
+		return bc.TxContext(ctx, w, r, power)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Tx(w, r, power)
+}
+
 // Address represents a 1-wire device address in little-endian format.
 //
 // This means that the family code ends up in the lower byte, the CRC in the
@@ -6,6 +6,7 @@ package onewire
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
 
@@ -101,6 +102,24 @@ func TestDevTxPower(t *testing.T) {
 	}
 }
 
+func TestTxContext(t *testing.T) {
+	b := &fakeBus{}
+	if err := TxContext(context.Background(), b, []byte{1}, nil, StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, []byte{1}) || b.power != StrongPullup {
+		t.Fatal(b.w, b.power)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TxContext(ctx, b, []byte{2}, nil, WeakPullup); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.w, []byte{1}) {
+		t.Fatal(b.w)
+	}
+}
+
 //
 
 type fakeBus struct {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"testing"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/onewire"
 )
 
@@ -33,6 +34,26 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpen_trace(t *testing.T) {
+	defer reset()
+	defer bustrace.Enable(nil)
+	tr := &bustrace.Tracer{}
+	bustrace.Enable(tr)
+	if err := Register("a", nil, 1, fakeBuser); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx([]byte{0xCC}, nil, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.Stats(); len(s) != 1 || s[0].Kind != "onewire" || s[0].Count != 1 {
+		t.Fatal(s)
+	}
+}
+
 func TestDefault_NoNumber(t *testing.T) {
 	defer reset()
 	if err := Register("a", nil, -1, fakeBuser); err != nil {
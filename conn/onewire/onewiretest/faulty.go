@@ -0,0 +1,74 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// Faulty implements onewire.Bus and injects faults in the transactions done
+// on Bus.
+//
+// A NACK models a missing presence pulse. See conntest.Injector for the faults
+// that can be injected.
+//
+// When Bus implements onewire.BusSearcher, Search() is done via Tx() and
+// SearchTriplet() so faults are also injected in the search.
+type Faulty struct {
+	Bus      onewire.Bus
+	Injector *conntest.Injector
+}
+
+func (f *Faulty) String() string {
+	return "faulty"
+}
+
+// Tx implements onewire.Bus.
+func (f *Faulty) Tx(w, r []byte, pull onewire.Pullup) error {
+	return f.TxContext(context.Background(), w, r, pull)
+}
+
+// TxContext implements onewire.BusContext.
+func (f *Faulty) TxContext(ctx context.Context, w, r []byte, pull onewire.Pullup) error {
+	return f.Injector.Inject(f.Bus.String(), r, func() error {
+		return onewire.TxContext(ctx, f.Bus, w, r, pull)
+	})
+}
+
+// Q implements onewire.Pins.
+func (f *Faulty) Q() gpio.PinIO {
+	if p, ok := f.Bus.(onewire.Pins); ok {
+		return p.Q()
+	}
+	return gpio.INVALID
+}
+
+// Search implements onewire.Bus.
+func (f *Faulty) Search(alarmOnly bool) ([]onewire.Address, error) {
+	if _, ok := f.Bus.(onewire.BusSearcher); ok {
+		return onewire.Search(f, alarmOnly)
+	}
+	return f.Bus.Search(alarmOnly)
+}
+
+// SearchTriplet implements onewire.BusSearcher.
+//
+// Bus must implement onewire.BusSearcher.
+func (f *Faulty) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	if s, ok := f.Bus.(onewire.BusSearcher); ok {
+		return s.SearchTriplet(direction)
+	}
+	return onewire.TripletResult{}, conntest.Errorf("onewiretest: SearchTriplet requires a Bus implementing onewire.BusSearcher: %w", conn.ErrUnsupported)
+}
+
+var _ onewire.Bus = &Faulty{}
+var _ onewire.BusContext = &Faulty{}
+var _ onewire.BusSearcher = &Faulty{}
+var _ onewire.Pins = &Faulty{}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+func TestFaulty(t *testing.T) {
+	const dev = onewire.Address(0x2600000012345628)
+	p := &Playback{
+		Ops: []IO{
+			{W: []byte{0xf0}},
+			{W: []byte{0xbe}, R: []byte{0x01, 0x02}},
+		},
+		Devices: []onewire.Address{dev},
+	}
+	inj := &conntest.Injector{Schedule: map[int]conntest.Fault{0: conntest.NACK, 2: conntest.BitFlip}, Logf: t.Logf}
+	f := &Faulty{Bus: p, Injector: inj}
+	if s := f.String(); s != "faulty" {
+		t.Fatal(s)
+	}
+	if q := f.Q(); q != nil {
+		t.Fatal(q)
+	}
+	// The missing presence pulse fails the search; a retry succeeds.
+	if d, err := f.Search(false); err == nil {
+		t.Fatal(d)
+	}
+	if d, err := f.Search(false); err != nil || len(d) != 1 || d[0] != dev {
+		t.Fatal(d, err)
+	}
+	b := make([]byte, 2)
+	if err := f.Tx([]byte{0xbe}, b, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] == 0x01 && b[1] == 0x02 {
+		t.Fatal("expected a bit flip")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f = &Faulty{Bus: &Record{}}
+	if q := f.Q(); q != gpio.INVALID {
+		t.Fatal(q)
+	}
+}
@@ -8,7 +8,9 @@ package onewiretest
 import (
 	"bytes"
 	"sync"
+	"time"
 
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/conntest"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/onewire"
@@ -19,15 +21,24 @@ type IO struct {
 	W    []byte
 	R    []byte
 	Pull onewire.Pullup
+	// T is the time elapsed since the first transaction was recorded. It is
+	// informational and ignored by Playback.
+	T time.Duration
+	// Err is the error returned by the transaction. Playback returns it.
+	Err error
 }
 
 // Record implements onewire.Bus that records everything written to it.
 //
 // This can then be used to feed to Playback to do "replay" based unit tests.
+// Use Save() to serialize the recorded transactions.
 type Record struct {
 	sync.Mutex
 	Bus onewire.Bus // Bus can be nil if only writes are being recorded.
 	Ops []IO
+
+	clock   conntest.Stopwatch
+	devices []onewire.Address // devices found by the last search
 }
 
 func (r *Record) String() string {
@@ -43,21 +54,20 @@ func (r *Record) Tx(w, read []byte, pull onewire.Pullup) error {
 	}
 	r.Lock()
 	defer r.Unlock()
+	io.T = r.clock.Elapsed()
 	if r.Bus == nil {
 		if len(read) != 0 {
-			return conntest.Errorf("onewiretest: read unsupported when no bus is connected")
+			return conntest.Errorf("onewiretest: read when no bus is connected: %w", conn.ErrUnsupported)
 		}
 	} else {
-		if err := r.Bus.Tx(w, read, pull); err != nil {
-			return err
-		}
+		io.Err = r.Bus.Tx(w, read, pull)
 	}
 	if len(read) != 0 {
 		io.R = make([]byte, len(read))
 		copy(io.R, read)
 	}
 	r.Ops = append(r.Ops, io)
-	return nil
+	return io.Err
 }
 
 // Q implements onewire.Pins.
@@ -69,8 +79,33 @@ func (r *Record) Q() gpio.PinIO {
 }
 
 // Search implements onewire.Bus
+//
+// When Bus implements onewire.BusSearcher, the search is done via Tx() and
+// SearchTriplet() so it is recorded and can be played back.
 func (r *Record) Search(alarmOnly bool) ([]onewire.Address, error) {
-	return nil, nil
+	if _, ok := r.Bus.(onewire.BusSearcher); !ok {
+		if r.Bus != nil {
+			return r.Bus.Search(alarmOnly)
+		}
+		return nil, nil
+	}
+	d, err := onewire.Search(r, alarmOnly)
+	if err == nil && !alarmOnly {
+		r.Lock()
+		r.devices = d
+		r.Unlock()
+	}
+	return d, err
+}
+
+// SearchTriplet implements onewire.BusSearcher.
+//
+// Bus must implement onewire.BusSearcher.
+func (r *Record) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	if s, ok := r.Bus.(onewire.BusSearcher); ok {
+		return s.SearchTriplet(direction)
+	}
+	return onewire.TripletResult{}, conntest.Errorf("onewiretest: SearchTriplet requires a Bus implementing onewire.BusSearcher: %w", conn.ErrUnsupported)
 }
 
 // Playback implements onewire.Bus and plays back a recorded I/O flow.
@@ -85,6 +120,8 @@ func (r *Record) Search(alarmOnly bool) ([]onewire.Address, error) {
 //
 // Set DontPanic to true to return an error instead of panicking, which is the
 // default.
+//
+// Use Load() or LoadGolden() to play back a serialized trace.
 type Playback struct {
 	sync.Mutex
 	Ops       []IO // recorded operations
@@ -93,6 +130,12 @@ type Playback struct {
 	QPin      gpio.PinIO
 	DontPanic bool
 
+	// Golden is the path of the trace file the Ops were loaded from.
+	Golden string
+	// Update, when set, records the transactions instead of playing back Ops.
+	// Close() then writes them to Golden. See LoadGolden().
+	Update *Record
+
 	inactive  []bool // Devices that are no longer active in the search
 	searchBit uint   // which bit is being searched next
 }
@@ -102,9 +145,14 @@ func (p *Playback) String() string {
 }
 
 // Close implements onewire.BusCloser.
+//
+// When Update is set, it writes the recorded transactions to Golden instead.
 func (p *Playback) Close() error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return conntest.WriteTraceFile(p.Golden, p.Update.trace())
+	}
 	if len(p.Ops) != p.Count {
 		return errorf(p.DontPanic, "onewiretest: expected playback to be empty: I/O count %d; expected %d", p.Count, len(p.Ops))
 	}
@@ -115,6 +163,9 @@ func (p *Playback) Close() error {
 func (p *Playback) Tx(w, r []byte, pull onewire.Pullup) error {
 	p.Lock()
 	defer p.Unlock()
+	if p.Update != nil {
+		return p.Update.Tx(w, r, pull)
+	}
 	if len(p.Ops) <= p.Count {
 		return errorf(p.DontPanic, "onewiretest: unexpected Tx() (count #%d) W:%#v  R:%#v", p.Count, w, r)
 	}
@@ -127,15 +178,16 @@ func (p *Playback) Tx(w, r []byte, pull onewire.Pullup) error {
 	if pull != p.Ops[p.Count].Pull {
 		return errorf(p.DontPanic, "onewiretest: unexpected pullup (count #%d) %s != %s", p.Count, pull, p.Ops[p.Count].Pull)
 	}
-	// Determine whether this starts a search and reset search state.
-	if len(w) > 0 && w[0] == 0xf0 {
+	// Determine whether this starts a search or an alarm search and reset
+	// search state.
+	if len(w) > 0 && (w[0] == 0xf0 || w[0] == 0xec) {
 		p.searchBit = 0
 		p.inactive = make([]bool, len(p.Devices))
 	}
 	// Concoct response.
 	copy(r, p.Ops[p.Count].R)
 	p.Count++
-	return nil
+	return p.Ops[p.Count-1].Err
 }
 
 // Q implements onewire.Pins.
@@ -147,11 +199,17 @@ func (p *Playback) Q() gpio.PinIO {
 
 // Search implements onewire.Bus using the Search function (which calls SearchTriplet).
 func (p *Playback) Search(alarmOnly bool) ([]onewire.Address, error) {
+	if p.Update != nil {
+		return p.Update.Search(alarmOnly)
+	}
 	return onewire.Search(p, alarmOnly)
 }
 
 // SearchTriplet implements onewire.BusSearcher.
 func (p *Playback) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	if p.Update != nil {
+		return p.Update.SearchTriplet(direction)
+	}
 	tr := onewire.TripletResult{}
 	if p.searchBit > 63 {
 		return tr, errorf(p.DontPanic, "onewiretest: search performs more than 64 triplet operations")
@@ -203,6 +261,7 @@ func errorf(dontPanic bool, format string, a ...interface{}) error {
 }
 
 var _ onewire.Bus = &Record{}
+var _ onewire.BusSearcher = &Record{}
 var _ onewire.Pins = &Record{}
 var _ onewire.Bus = &Playback{}
 var _ onewire.BusSearcher = &Playback{}
@@ -0,0 +1,242 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"sync"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// SimFunc handles a function command sent to a SimDevice.
+//
+// w is the data written after the command byte. r is the buffer read by the
+// master; it is initialized to 0xFF, as read on an idle bus, and the handler
+// fills the bytes the device sends. pull is the pull-up requested for the
+// transaction.
+type SimFunc func(d *SimDevice, w, r []byte, pull onewire.Pullup) error
+
+// SimDevice is a simulated 1-wire device.
+//
+// The Sim handles the ROM commands. Once the device is selected, the function
+// command is dispatched to Funcs.
+//
+// The Sim lock is held while the handlers are called.
+type SimDevice struct {
+	// Alarm, when set, makes the device respond to an Alarm Search.
+	Alarm bool
+	// Funcs are the handlers of the function commands supported by the device.
+	Funcs map[byte]SimFunc
+	// Mem is a convenience state for the handlers, e.g. the scratchpad or the
+	// memory of the device.
+	Mem []byte
+}
+
+// Sim implements onewire.Bus and simulates devices on a 1-wire bus.
+//
+// Unlike Playback, the exact sequence of transactions doesn't matter, which
+// permits testing drivers by their behavior.
+//
+// It implements the ROM commands Read ROM (0x33), Match ROM (0x55), Skip ROM
+// (0xCC), Resume (0xA5), Search ROM (0xF0) and Alarm Search (0xEC). The search
+// does the bit level arbitration of the devices in SearchTriplet(). When
+// multiple devices answer, the bus returns the wired AND of their responses.
+//
+// A transaction on a bus without device fails with an error implementing
+// onewire.NoDevicesError.
+type Sim struct {
+	sync.Mutex
+	Devices map[onewire.Address]*SimDevice
+	QPin    gpio.PinIO
+	// Pull is the pull-up requested by the last transaction.
+	Pull onewire.Pullup
+
+	resume    onewire.Address   // device selected by the last Match ROM
+	search    bool              // a search is in progress
+	searching []onewire.Address // devices still participating to the search
+	searchBit uint              // next bit to be searched
+}
+
+func (s *Sim) String() string {
+	return "sim"
+}
+
+// Close implements onewire.BusCloser.
+func (s *Sim) Close() error {
+	return nil
+}
+
+// Tx implements onewire.Bus.
+func (s *Sim) Tx(w, r []byte, pull onewire.Pullup) error {
+	s.Lock()
+	defer s.Unlock()
+	s.Pull = pull
+	s.search = false
+	if len(s.Devices) == 0 {
+		return noDevicesError("onewiretest: no device present")
+	}
+	for i := range r {
+		r[i] = 0xFF
+	}
+	if len(w) == 0 {
+		return nil
+	}
+	switch cmd := w[0]; cmd {
+	case 0x33: // Read ROM
+		if len(w) != 1 {
+			return conntest.Errorf("onewiretest: unexpected write after Read ROM")
+		}
+		for a := range s.Devices {
+			for i := 0; i < len(r) && i < 8; i++ {
+				r[i] &= byte(a >> (8 * uint(i)))
+			}
+		}
+		return nil
+	case 0x55: // Match ROM
+		if len(w) < 9 {
+			return conntest.Errorf("onewiretest: Match ROM requires an address")
+		}
+		var a onewire.Address
+		for i := 8; i > 0; i-- {
+			a = a<<8 | onewire.Address(w[i])
+		}
+		s.resume = a
+		if d := s.Devices[a]; d != nil {
+			return d.function(a, w[9:], r, pull)
+		}
+		// No device answers.
+		return nil
+	case 0xA5: // Resume
+		if d := s.Devices[s.resume]; d != nil {
+			return d.function(s.resume, w[1:], r, pull)
+		}
+		return nil
+	case 0xCC: // Skip ROM
+		s.resume = 0
+		if len(s.Devices) == 1 {
+			for a, d := range s.Devices {
+				return d.function(a, w[1:], r, pull)
+			}
+		}
+		// All the devices process the command.
+		tmp := make([]byte, len(r))
+		for a, d := range s.Devices {
+			for i := range tmp {
+				tmp[i] = 0xFF
+			}
+			if err := d.function(a, w[1:], tmp, pull); err != nil {
+				return err
+			}
+			for i := range r {
+				r[i] &= tmp[i]
+			}
+		}
+		return nil
+	case 0xF0, 0xEC: // Search ROM, Alarm Search
+		if len(w) != 1 || len(r) != 0 {
+			return conntest.Errorf("onewiretest: unexpected I/O after a search command")
+		}
+		s.resume = 0
+		s.searching = s.searching[:0]
+		for a, d := range s.Devices {
+			if cmd == 0xF0 || d.Alarm {
+				s.searching = append(s.searching, a)
+			}
+		}
+		s.search = true
+		s.searchBit = 0
+		return nil
+	default:
+		return conntest.Errorf("onewiretest: unsupported ROM command 0x%02X", cmd)
+	}
+}
+
+// Q implements onewire.Pins.
+func (s *Sim) Q() gpio.PinIO {
+	return s.QPin
+}
+
+// Search implements onewire.Bus using onewire.Search.
+func (s *Sim) Search(alarmOnly bool) ([]onewire.Address, error) {
+	return onewire.Search(s, alarmOnly)
+}
+
+// SearchTriplet implements onewire.BusSearcher.
+func (s *Sim) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	s.Lock()
+	defer s.Unlock()
+	tr := onewire.TripletResult{}
+	if !s.search {
+		return tr, conntest.Errorf("onewiretest: SearchTriplet without a search command")
+	}
+	if s.searchBit > 63 {
+		return tr, conntest.Errorf("onewiretest: search performs more than 64 triplet operations")
+	}
+	for _, a := range s.searching {
+		if (a>>s.searchBit)&1 == 0 {
+			tr.GotZero = true
+		} else {
+			tr.GotOne = true
+		}
+	}
+	if (tr.GotZero && tr.GotOne && direction != 0) || (!tr.GotZero && tr.GotOne) {
+		tr.Taken = 1
+	}
+	// The devices with the bit not taken stop participating.
+	j := 0
+	for _, a := range s.searching {
+		if uint8((a>>s.searchBit)&1) == tr.Taken {
+			s.searching[j] = a
+			j++
+		}
+	}
+	s.searching = s.searching[:j]
+	s.searchBit++
+	return tr, nil
+}
+
+// MakeAddress returns the address of a device with the family code and the
+// 48 bits serial number, including its CRC.
+func MakeAddress(family byte, serial uint64) onewire.Address {
+	var b [8]byte
+	b[0] = family
+	for i := 1; i < 7; i++ {
+		b[i] = byte(serial >> (8 * uint(i-1)))
+	}
+	b[7] = onewire.CalcCRC(b[:7])
+	var a onewire.Address
+	for i := 7; i >= 0; i-- {
+		a = a<<8 | onewire.Address(b[i])
+	}
+	return a
+}
+
+//
+
+func (d *SimDevice) function(a onewire.Address, w, r []byte, pull onewire.Pullup) error {
+	if len(w) == 0 {
+		return nil
+	}
+	f := d.Funcs[w[0]]
+	if f == nil {
+		return conntest.Errorf("onewiretest: device 0x%016x doesn't support function command 0x%02X", uint64(a), w[0])
+	}
+	return f(d, w[1:], r, pull)
+}
+
+// noDevicesError implements error, onewire.NoDevicesError and
+// onewire.BusError.
+type noDevicesError string
+
+func (e noDevicesError) Error() string   { return string(e) }
+func (e noDevicesError) NoDevices() bool { return true }
+func (e noDevicesError) BusError() bool  { return true }
+
+var _ onewire.BusCloser = &Sim{}
+var _ onewire.BusSearcher = &Sim{}
+var _ onewire.Pins = &Sim{}
+var _ onewire.NoDevicesError = noDevicesError("")
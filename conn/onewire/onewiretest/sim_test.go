@@ -0,0 +1,175 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+func TestMakeAddress(t *testing.T) {
+	a := MakeAddress(0x28, 0x70e41ac)
+	if a != 0x740000070e41ac28 {
+		t.Fatalf("0x%016x", uint64(a))
+	}
+}
+
+func TestSim(t *testing.T) {
+	a1 := MakeAddress(0x28, 1)
+	a2 := MakeAddress(0x2D, 2)
+	// d1 returns its memory with a CRC16, d2 echoes what it receives.
+	d1 := &SimDevice{
+		Mem: []byte{1, 2, 3},
+		Funcs: map[byte]SimFunc{
+			0xF0: func(d *SimDevice, w, r []byte, pull onewire.Pullup) error {
+				n := copy(r, d.Mem)
+				c := ^onewire.CalcCRC16(onewire.CalcCRC16(0, []byte{0xF0}), d.Mem)
+				copy(r[n:], []byte{byte(c), byte(c >> 8)})
+				return nil
+			},
+		},
+	}
+	d2 := &SimDevice{
+		Alarm: true,
+		Funcs: map[byte]SimFunc{
+			0xF0: func(d *SimDevice, w, r []byte, pull onewire.Pullup) error {
+				d.Mem = append(d.Mem, w...)
+				r[0] = 0x0F
+				return nil
+			},
+		},
+	}
+	s := &Sim{Devices: map[onewire.Address]*SimDevice{a1: d1, a2: d2}, QPin: gpio.INVALID}
+	if s.String() != "sim" || s.Q() != gpio.INVALID {
+		t.Fatal("unexpected")
+	}
+
+	// Match ROM.
+	r := make([]byte, 5)
+	if err := (&onewire.Dev{Bus: s, Addr: a1}).Tx([]byte{0xF0}, r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r[:3], d1.Mem) || !onewire.CheckCRC16(append([]byte{0xF0}, r...)) {
+		t.Fatal(r)
+	}
+	// Resume.
+	r = make([]byte, 3)
+	if err := s.Tx([]byte{0xA5, 0xF0}, r, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, d1.Mem) || s.Pull != onewire.StrongPullup {
+		t.Fatal(r, s.Pull)
+	}
+	// Unknown device; nobody answers.
+	r = make([]byte, 1)
+	if err := (&onewire.Dev{Bus: s, Addr: 1}).Tx([]byte{0xF0}, r); err != nil || r[0] != 0xFF {
+		t.Fatal(r, err)
+	}
+	// Skip ROM; the devices answer at the same time.
+	r = make([]byte, 2)
+	if err := s.Tx([]byte{0xCC, 0xF0, 0xAA}, r, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{1 & 0x0F, 2}) || !bytes.Equal(d2.Mem, []byte{0xAA}) {
+		t.Fatal(r, d2.Mem)
+	}
+	// Read ROM with a single device.
+	delete(s.Devices, a1)
+	r = make([]byte, 8)
+	if err := s.Tx([]byte{0x33}, r, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r, []byte{0x2D, 2, 0, 0, 0, 0, 0, byte(a2 >> 56)}) || !onewire.CheckCRC(r) {
+		t.Fatal(r)
+	}
+	// Skip ROM with a single device.
+	r = make([]byte, 1)
+	if err := s.Tx([]byte{0xCC, 0xF0}, r, onewire.WeakPullup); err != nil || r[0] != 0x0F {
+		t.Fatal(r, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSim_Search(t *testing.T) {
+	var want []onewire.Address
+	s := &Sim{Devices: map[onewire.Address]*SimDevice{}}
+	for i := uint64(0); i < 20; i++ {
+		a := MakeAddress(byte(0x10+i%3), i*0x010203+i<<40)
+		want = append(want, a)
+		s.Devices[a] = &SimDevice{Alarm: i%4 == 0}
+	}
+	got, err := s.Search(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortAddr(got)
+	sortAddr(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	if got, err = s.Search(true); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 {
+		t.Fatal(got)
+	}
+	for _, a := range got {
+		if !s.Devices[a].Alarm {
+			t.Fatalf("0x%016x", uint64(a))
+		}
+	}
+	// No device in alarm state.
+	for _, d := range s.Devices {
+		d.Alarm = false
+	}
+	if got, err = s.Search(true); err != nil || len(got) != 0 {
+		t.Fatal(got, err)
+	}
+	// Read ROM returns the wired AND of all the addresses.
+	r := make([]byte, 8)
+	if err := s.Tx([]byte{0x33}, r, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if onewire.CheckCRC(r) {
+		t.Fatal("expected a collision", r)
+	}
+}
+
+func TestSim_errors(t *testing.T) {
+	s := &Sim{}
+	err := s.Tx([]byte{0xCC}, nil, onewire.WeakPullup)
+	if e, ok := err.(onewire.NoDevicesError); !ok || !e.NoDevices() {
+		t.Fatal(err)
+	}
+	if _, err := s.Search(false); err == nil {
+		t.Fatal("expected error")
+	}
+	s.Devices = map[onewire.Address]*SimDevice{MakeAddress(0x28, 1): {}}
+	if _, err := s.SearchTriplet(0); !conntest.IsErr(err) {
+		t.Fatal(err)
+	}
+	if err := s.Tx(nil, nil, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range [][]byte{{0x33, 1}, {0x55, 1}, {0xF0, 1}, {0x0F}, {0xCC, 0x44}} {
+		if err := s.Tx(w, nil, onewire.WeakPullup); !conntest.IsErr(err) {
+			t.Fatal(w, err)
+		}
+	}
+}
+
+//
+
+func sortAddr(a []onewire.Address) {
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// Save writes the transactions recorded by r as a trace.
+//
+// The devices found by the last search are saved along, so the search can be
+// played back. See conntest.Trace for the format.
+func Save(w io.Writer, r *Record) error {
+	return conntest.WriteTrace(w, r.trace())
+}
+
+// Load reads a trace written by Save() and returns it as a Playback.
+func Load(r io.Reader) (*Playback, error) {
+	t, err := conntest.ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	return playback(t)
+}
+
+// LoadGolden returns a Playback for the golden file path.
+//
+// When conntest.UpdateGolden is set, the file is not read; the returned
+// Playback forwards the transactions to b and rewrites the file on Close()
+// instead.
+func LoadGolden(path string, b onewire.Bus) (*Playback, error) {
+	if conntest.UpdateGolden {
+		if b == nil {
+			return nil, errors.New("onewiretest: a bus is required to update " + path)
+		}
+		return &Playback{Update: &Record{Bus: b}, Golden: path}, nil
+	}
+	t, err := conntest.ReadTraceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := playback(t)
+	if err != nil {
+		return nil, err
+	}
+	p.Golden = path
+	return p, nil
+}
+
+//
+
+func (r *Record) trace() *conntest.Trace {
+	r.Lock()
+	defer r.Unlock()
+	t := &conntest.Trace{Version: conntest.TraceVersion, Kind: "onewire", Ops: make([]conntest.TraceOp, len(r.Ops))}
+	for i, o := range r.Ops {
+		t.Ops[i] = conntest.TraceOp{T: o.T, W: o.W, R: o.R, Pull: o.Pull == onewire.StrongPullup}
+		if o.Err != nil {
+			t.Ops[i].Err = o.Err.Error()
+		}
+	}
+	if r.Bus != nil {
+		t.Bus = r.Bus.String()
+	}
+	if len(r.devices) != 0 {
+		d := make([]string, len(r.devices))
+		for i, a := range r.devices {
+			d[i] = fmt.Sprintf("0x%016x", uint64(a))
+		}
+		t.Meta = map[string]string{"devices": strings.Join(d, ",")}
+	}
+	return t
+}
+
+func playback(t *conntest.Trace) (*Playback, error) {
+	if t.Kind != "onewire" {
+		return nil, fmt.Errorf("onewiretest: expected a onewire trace, got %q", t.Kind)
+	}
+	p := &Playback{Ops: make([]IO, len(t.Ops))}
+	for i := range t.Ops {
+		o := &t.Ops[i]
+		p.Ops[i] = IO{W: o.W, R: o.R, Pull: onewire.Pullup(o.Pull), T: o.T, Err: o.Error()}
+	}
+	if d := t.Meta["devices"]; d != "" {
+		for _, s := range strings.Split(d, ",") {
+			a, err := strconv.ParseUint(s, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("onewiretest: invalid device address %q", s)
+			}
+			p.Devices = append(p.Devices, onewire.Address(a))
+		}
+	}
+	return p, nil
+}
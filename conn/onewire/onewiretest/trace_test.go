@@ -0,0 +1,131 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package onewiretest
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/onewire"
+)
+
+func TestSave_Load(t *testing.T) {
+	const dev = onewire.Address(0x2600000012345628)
+	b := &Playback{
+		Ops: []IO{
+			{W: []byte{0xf0}},
+			{W: []byte{0x44}, Pull: onewire.StrongPullup},
+			{W: []byte{0xbe}, R: []byte{0x01, 0x02}, Err: errors.New("crc")},
+		},
+		Devices: []onewire.Address{dev},
+	}
+	r := Record{Bus: b}
+	if d, err := r.Search(false); err != nil || len(d) != 1 || d[0] != dev {
+		t.Fatal(d, err)
+	}
+	if err := r.Tx([]byte{0x44}, nil, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if err := r.Tx([]byte{0xbe}, buf, onewire.WeakPullup); err == nil || err.Error() != "crc" {
+		t.Fatal(err)
+	}
+	for i := range r.Ops {
+		r.Ops[i].T = 0
+	}
+	out := bytes.Buffer{}
+	if err := Save(&out, &r); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "version": 1,
+  "kind": "onewire",
+  "bus": "playback",
+  "meta": {"devices":"0x2600000012345628"},
+  "ops": [
+    {"t":0,"w":"f0"},
+    {"t":0,"w":"44","pull":true},
+    {"t":0,"w":"be","r":"0102","err":"crc"}
+  ]
+}
+`
+	if s := out.String(); s != expected {
+		t.Fatalf("unexpected trace:\n%s", s)
+	}
+
+	p, err := Load(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := p.Search(false); err != nil || len(d) != 1 || d[0] != dev {
+		t.Fatal(d, err)
+	}
+	if err := p.Tx([]byte{0x44}, nil, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Tx([]byte{0xbe}, buf, onewire.WeakPullup); err == nil || err.Error() != "crc" {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(strings.NewReader(`{"version": 1, "kind": "i2c", "ops": []}`)); err == nil {
+		t.Fatal("wrong kind")
+	}
+	if _, err := Load(strings.NewReader(`{"version": 1, "kind": "onewire", "meta": {"devices": "x"}, "ops": []}`)); err == nil {
+		t.Fatal("invalid device")
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_onewiretest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+	defer func(u bool) {
+		conntest.UpdateGolden = u
+	}(conntest.UpdateGolden)
+
+	conntest.UpdateGolden = true
+	if _, err := LoadGolden(path, nil); err == nil {
+		t.Fatal("bus is required")
+	}
+	b := &Playback{Ops: []IO{{W: []byte{0xcc, 0x44}}}}
+	p, err := LoadGolden(path, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Tx([]byte{0xcc, 0x44}, nil, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conntest.UpdateGolden = false
+	if p, err = LoadGolden(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.Golden != path || len(p.Ops) != 1 {
+		t.Fatal(p.Golden, p.Ops)
+	}
+	if err := p.Tx([]byte{0xcc, 0x44}, nil, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGolden(filepath.Join(dir, "missing.json"), nil); err == nil {
+		t.Fatal("missing file")
+	}
+}
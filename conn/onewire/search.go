@@ -31,7 +31,8 @@ type TripletResult struct {
 // state if alarmOnly is true.
 //
 // If an error occurs during the search the already-discovered devices are
-// returned with the error.
+// returned with the error. An alarm search with no device in alarm state
+// returns no address and no error.
 //
 // For a description of the search algorithm, see Maxim's AppNote 187
 // https://www.maximintegrated.com/en/app-notes/index.mvp/id/187
@@ -83,6 +84,11 @@ func Search(bus BusSearcher, alarmOnly bool) ([]Address, error) {
 			// Check for the absence of devices on the bus. This is a 1-wire
 			// bus error condition and we return a partial result.
 			if !result.GotZero && !result.GotOne {
+				// Devices that are not in alarm state don't participate to an
+				// alarm search, so nobody answering means there is none.
+				if alarmOnly && bit == 0 && len(devices) == 0 {
+					return nil, nil
+				}
 				return devices, errors.New("onewire: devices disappeared during search")
 			}
 
@@ -69,6 +69,22 @@ match:
 	}
 }
 
+func TestSearch_alarm_none(t *testing.T) {
+	// Devices not in alarm state don't answer an alarm search.
+	p := playback{Ops: []IO{{Write: []byte{0xec}, Pull: WeakPullup}}}
+	if addrs, err := p.Search(true); addrs != nil || err != nil {
+		t.Fatal(addrs, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A plain search with no answer is a bus error.
+	p = playback{Ops: []IO{{Write: []byte{0xf0}, Pull: WeakPullup}}}
+	if addrs, err := p.Search(false); len(addrs) != 0 || err == nil {
+		t.Fatal("expected devices disappeared error")
+	}
+}
+
 func TestSearch_Tx_err(t *testing.T) {
 	p := playback{}
 	if addrs, err := p.Search(true); len(addrs) != 0 || err == nil {
@@ -117,7 +133,7 @@ func (p *playback) Tx(w, r []byte, pull Pullup) error {
 		return fmt.Errorf("onewiretest: unexpected pullup %s != %s", pull, p.Ops[0].Pull)
 	}
 	// Determine whether this starts a search and reset search state.
-	if len(w) > 0 && w[0] == 0xf0 {
+	if len(w) > 0 && (w[0] == 0xf0 || w[0] == 0xec) {
 		p.searchBit = 0
 		p.inactive = make([]bool, len(p.Devices))
 	}
@@ -22,8 +22,11 @@
 package spi
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"strconv"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
@@ -56,6 +59,22 @@ const (
 	// LSBFirst requests the words to be encoded in little endian instead of the
 	// default big endian.
 	LSBFirst = 0x10
+	// NoChunk requests the driver to fail transactions larger than
+	// conn.Limits.MaxTxSize() instead of splitting them in multiple chunks.
+	//
+	// CS stays asserted between chunks but the clock pauses for a short time,
+	// which some devices cannot tolerate.
+	NoChunk Mode = 0x20
+	// TxDual and TxQuad enable writing over 2 or 4 data lines. RxDual and
+	// RxQuad enable reading over 2 or 4 data lines. The width is then selected
+	// per packet with Packet.TxWidth and Packet.RxWidth.
+	//
+	// They are used by devices like SPI NOR flash memories to increase the
+	// throughput. Most ports do not support them and fail in Connect.
+	TxDual Mode = 0x40
+	TxQuad Mode = 0x80
+	RxDual Mode = 0x100
+	RxQuad Mode = 0x200
 )
 
 func (m Mode) String() string {
@@ -83,6 +102,23 @@ func (m Mode) String() string {
 		s += "|LSBFirst"
 	}
 	m &^= LSBFirst
+	if m&NoChunk != 0 {
+		s += "|NoChunk"
+	}
+	m &^= NoChunk
+	if m&TxDual != 0 {
+		s += "|TxDual"
+	}
+	if m&TxQuad != 0 {
+		s += "|TxQuad"
+	}
+	if m&RxDual != 0 {
+		s += "|RxDual"
+	}
+	if m&RxQuad != 0 {
+		s += "|RxQuad"
+	}
+	m &^= TxDual | TxQuad | RxDual | RxQuad
 	if m != 0 {
 		s += "|0x"
 		s += strconv.FormatUint(uint64(m), 16)
@@ -107,12 +143,28 @@ type Packet struct {
 	//
 	// KeepCS is ignored when NoCS was specified to Connect.
 	KeepCS bool
+	// Speed overrides the speed of the connection for this packet when
+	// non-zero. It is still capped by the port's LimitSpeed().
+	Speed physic.Frequency
+	// DelayAfter is the time to wait after this packet is completed, before
+	// changing CS as requested by KeepCS and starting the next packet.
+	DelayAfter time.Duration
+	// WordDelay is the time to wait between each word of this packet.
+	WordDelay time.Duration
+	// TxWidth and RxWidth are the number of data lines used to write W and to
+	// read R: 0 or 1 for single, 2 for dual and 4 for quad. Dual and quad
+	// require the corresponding Mode flags to be specified to Connect, and only
+	// one of W or R to be set.
+	TxWidth, RxWidth uint8
 }
 
 // Conn defines the interface a concrete SPI driver must implement.
 //
 // Implementers can optionally implement io.Writer and io.Reader for
 // unidirectional operation.
+//
+// A driver that cannot honour the Speed, DelayAfter or WordDelay of a Packet
+// must return an error from TxPackets() instead of ignoring them.
 type Conn interface {
 	conn.Conn
 	// TxPackets does multiple operations over the SPI connection.
@@ -131,6 +183,40 @@ type Conn interface {
 	TxPackets(p []Packet) error
 }
 
+// ConnContext is optionally implemented by a Conn that supports aborting a
+// transaction via a context.Context.
+//
+// Use TxPacketsContext() and conn.TxContext() to transparently support
+// implementations that do not implement this interface.
+type ConnContext interface {
+	Conn
+	// TxContext does a single transaction like Tx().
+	//
+	// It returns ctx.Err() if ctx is done before the transaction is started or
+	// while it is waiting for exclusive access to the port. A transaction that
+	// is already in progress on the wire may not be interruptible.
+	TxContext(ctx context.Context, w, r []byte) error
+	// TxPacketsContext does multiple operations like TxPackets() with the same
+	// cancellation semantics as TxContext().
+	TxPacketsContext(ctx context.Context, p []Packet) error
+}
+
+// TxPacketsContext does multiple operations on c like TxPackets() but aborts
+// when ctx is done.
+//
+// It uses c.TxPacketsContext() if c implements ConnContext. Otherwise ctx is
+// checked before starting the transaction and c.TxPackets() is called; in
+// this case, an in-progress transaction cannot be aborted.
+func TxPacketsContext(ctx context.Context, c Conn, p []Packet) error {
+	if cc, ok := c.(ConnContext); ok {
+		return cc.TxPacketsContext(ctx, p)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.TxPackets(p)
+}
+
 // Port is the interface to be provided to device drivers.
 //
 // The device driver, that is the driver for the peripheral connected over
@@ -185,3 +271,33 @@ type Pins interface {
 	// CS returns the CSN (chip select) pin.
 	CS() gpio.PinOut
 }
+
+// TooLargeError is returned when a transaction of Size bytes is larger than
+// the Max bytes supported by the port.
+//
+// errors.Is(err, conn.ErrTooLarge) returns true for a *TooLargeError.
+// Unsupported modes, bits or packet attributes are reported with
+// conn.ErrUnsupported.
+type TooLargeError struct {
+	Size int
+	Max  int
+	// Err is the driver specific error, if any. It is used as the message.
+	Err error
+}
+
+func (t *TooLargeError) Error() string {
+	if t.Err != nil {
+		return t.Err.Error()
+	}
+	return fmt.Sprintf("spi: transaction of %d bytes is larger than the maximum of %d bytes", t.Size, t.Max)
+}
+
+// Is returns true for conn.ErrTooLarge.
+func (t *TooLargeError) Is(target error) bool {
+	return target == conn.ErrTooLarge
+}
+
+// Unwrap returns Err.
+func (t *TooLargeError) Unwrap() error {
+	return t.Err
+}
@@ -5,11 +5,16 @@
 package spi
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+
+	"periph.io/x/periph/conn"
 )
 
 func TestMode_String(t *testing.T) {
-	if s := Mode(^int(0)).String(); s != "Mode3|HalfDuplex|NoCS|LSBFirst|0xffffffffffffffe0" {
+	if s := Mode(^int(0)).String(); s != "Mode3|HalfDuplex|NoCS|LSBFirst|NoChunk|TxDual|TxQuad|RxDual|RxQuad|0xfffffffffffffc00" {
 		t.Fatal(s)
 	}
 	if s := Mode0.String(); s != "Mode0" {
@@ -22,3 +27,81 @@ func TestMode_String(t *testing.T) {
 		t.Fatal(s)
 	}
 }
+
+func TestTxPacketsContext(t *testing.T) {
+	c := &fakeConn{}
+	if err := TxPacketsContext(context.Background(), c, []Packet{{W: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := TxPacketsContext(ctx, c, []Packet{{W: []byte{1}}}); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if c.packets != 1 {
+		t.Fatal(c.packets)
+	}
+	cc := &fakeConnContext{}
+	if err := TxPacketsContext(ctx, cc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cc.packets != 0 || cc.ctx != 1 {
+		t.Fatal(cc.packets, cc.ctx)
+	}
+}
+
+func TestTooLargeError(t *testing.T) {
+	var err error = &TooLargeError{Size: 5, Max: 4}
+	if s := err.Error(); s != "spi: transaction of 5 bytes is larger than the maximum of 4 bytes" {
+		t.Fatal(s)
+	}
+	err = fmt.Errorf("wrapped: %w", &TooLargeError{Size: 5, Max: 4, Err: errors.New("foo")})
+	if s := err.Error(); s != "wrapped: foo" {
+		t.Fatal(s)
+	}
+	var e *TooLargeError
+	if !errors.As(err, &e) || e.Size != 5 || e.Max != 4 {
+		t.Fatal("expected TooLargeError")
+	}
+	if !errors.Is(err, conn.ErrTooLarge) || errors.Is(err, conn.ErrTimeout) {
+		t.Fatal("unexpected Is")
+	}
+}
+
+//
+
+type fakeConn struct {
+	packets int
+}
+
+func (f *fakeConn) String() string {
+	return "fake"
+}
+
+func (f *fakeConn) Tx(w, r []byte) error {
+	return nil
+}
+
+func (f *fakeConn) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (f *fakeConn) TxPackets(p []Packet) error {
+	f.packets++
+	return nil
+}
+
+type fakeConnContext struct {
+	fakeConn
+	ctx int
+}
+
+func (f *fakeConnContext) TxContext(ctx context.Context, w, r []byte) error {
+	f.ctx++
+	return nil
+}
+
+func (f *fakeConnContext) TxPacketsContext(ctx context.Context, p []Packet) error {
+	f.ctx++
+	return nil
+}
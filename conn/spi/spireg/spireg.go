@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/spi"
 )
 
@@ -62,6 +63,9 @@ type Ref struct {
 //
 // When the SPI port is provided by an off board plug and play bus like USB via
 // a FT232H USB device, there can be no associated number.
+//
+// The port is wrapped with the bustrace.Tracer passed to bustrace.Enable(), if
+// any.
 func Open(name string) (spi.PortCloser, error) {
 	var r *Ref
 	var err error
@@ -91,7 +95,14 @@ func Open(name string) (spi.PortCloser, error) {
 	if r == nil {
 		return nil, errors.New("spireg: can't open unknown port: " + strconv.Quote(name))
 	}
-	return r.Open()
+	p, err := r.Open()
+	if err != nil {
+		return nil, err
+	}
+	if t := bustrace.Enabled(); t != nil {
+		return t.SPI(p), nil
+	}
+	return p, nil
 }
 
 // All returns a copy of all the registered references to all know SPI ports
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/physic"
 	"periph.io/x/periph/conn/spi"
 )
@@ -35,6 +36,23 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpen_trace(t *testing.T) {
+	defer reset()
+	defer bustrace.Enable(nil)
+	tr := &bustrace.Tracer{}
+	bustrace.Enable(tr)
+	if err := Register("a", nil, 1, getFakePort); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*fakePort); ok {
+		t.Fatal("expected the port to be traced")
+	}
+}
+
 func TestDefault_NoNumber(t *testing.T) {
 	defer reset()
 	if err := Register("a", nil, -1, getFakePort); err != nil {
@@ -0,0 +1,143 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Faulty implements spi.PortCloser and injects faults in the transactions
+// done on the connection returned by Port.
+//
+// A TxPackets() call is a single transaction; the data corrupting faults
+// apply to the last packet with a read buffer. See conntest.Injector for the
+// faults that can be injected.
+type Faulty struct {
+	Port     spi.PortCloser
+	Injector *conntest.Injector
+}
+
+func (f *Faulty) String() string {
+	return "faulty"
+}
+
+// Close implements spi.PortCloser.
+func (f *Faulty) Close() error {
+	return f.Port.Close()
+}
+
+// LimitSpeed implements spi.PortCloser.
+func (f *Faulty) LimitSpeed(freq physic.Frequency) error {
+	return f.Port.LimitSpeed(freq)
+}
+
+// Connect implements spi.PortCloser.
+func (f *Faulty) Connect(freq physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	c, err := f.Port.Connect(freq, mode, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyConn{f, c}, nil
+}
+
+// CLK implements spi.Pins.
+func (f *Faulty) CLK() gpio.PinOut {
+	if p, ok := f.Port.(spi.Pins); ok {
+		return p.CLK()
+	}
+	return gpio.INVALID
+}
+
+// MOSI implements spi.Pins.
+func (f *Faulty) MOSI() gpio.PinOut {
+	if p, ok := f.Port.(spi.Pins); ok {
+		return p.MOSI()
+	}
+	return gpio.INVALID
+}
+
+// MISO implements spi.Pins.
+func (f *Faulty) MISO() gpio.PinIn {
+	if p, ok := f.Port.(spi.Pins); ok {
+		return p.MISO()
+	}
+	return gpio.INVALID
+}
+
+// CS implements spi.Pins.
+func (f *Faulty) CS() gpio.PinOut {
+	if p, ok := f.Port.(spi.Pins); ok {
+		return p.CS()
+	}
+	return gpio.INVALID
+}
+
+//
+
+type faultyConn struct {
+	f *Faulty
+	c spi.Conn
+}
+
+func (f *faultyConn) String() string {
+	return f.f.String()
+}
+
+func (f *faultyConn) Duplex() conn.Duplex {
+	return f.c.Duplex()
+}
+
+func (f *faultyConn) Tx(w, r []byte) error {
+	return f.TxContext(context.Background(), w, r)
+}
+
+func (f *faultyConn) TxContext(ctx context.Context, w, r []byte) error {
+	return f.f.Injector.Inject(f.c.String(), r, func() error {
+		return conn.TxContext(ctx, f.c, w, r)
+	})
+}
+
+func (f *faultyConn) TxPackets(p []spi.Packet) error {
+	return f.TxPacketsContext(context.Background(), p)
+}
+
+func (f *faultyConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	var r []byte
+	for i := len(p) - 1; i >= 0; i-- {
+		if len(p[i].R) != 0 {
+			r = p[i].R
+			break
+		}
+	}
+	return f.f.Injector.Inject(f.c.String(), r, func() error {
+		return spi.TxPacketsContext(ctx, f.c, p)
+	})
+}
+
+func (f *faultyConn) CLK() gpio.PinOut {
+	return f.f.CLK()
+}
+
+func (f *faultyConn) MOSI() gpio.PinOut {
+	return f.f.MOSI()
+}
+
+func (f *faultyConn) MISO() gpio.PinIn {
+	return f.f.MISO()
+}
+
+func (f *faultyConn) CS() gpio.PinOut {
+	return f.f.CS()
+}
+
+var _ spi.PortCloser = &Faulty{}
+var _ spi.Pins = &Faulty{}
+var _ spi.ConnContext = &faultyConn{}
@@ -0,0 +1,57 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestFaulty(t *testing.T) {
+	p := &Playback{Playback: conntest.Playback{Ops: []conntest.IO{{W: []byte{0x80, 0}, R: []byte{0, 0x42}}}, D: conn.Full}}
+	inj := &conntest.Injector{Schedule: map[int]conntest.Fault{0: conntest.Timeout, 1: conntest.ShortRead, 2: conntest.NACK}, Logf: t.Logf}
+	f := &Faulty{Port: p, Injector: inj}
+	if s := f.String(); s != "faulty" {
+		t.Fatal(s)
+	}
+	if err := f.LimitSpeed(physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	c, err := f.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	if p := c.(spi.Pins).CS(); p != nil {
+		t.Fatal(p)
+	}
+	if p := f.CLK(); p != nil && p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	b := make([]byte, 2)
+	err = c.Tx([]byte{0x80, 0}, b)
+	if fe, ok := err.(*conntest.FaultError); !ok || !fe.Timeout() {
+		t.Fatal(err)
+	}
+	err = c.Tx([]byte{0x80, 0}, b)
+	if fe, ok := err.(*conntest.FaultError); !ok || fe.Fault != conntest.ShortRead || b[1] != 0 {
+		t.Fatal(b, err)
+	}
+	pkts := []spi.Packet{{W: []byte{1}}, {R: make([]byte, 1)}}
+	err = c.TxPackets(pkts)
+	if fe, ok := err.(*conntest.FaultError); !ok || fe.Fault != conntest.NACK {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
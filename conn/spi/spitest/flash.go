@@ -0,0 +1,321 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Flash simulates a JEDEC SPI NOR flash chip, like the Winbond W25Qxx or the
+// Macronix MX25 families.
+//
+// It implements spi.PortCloser and the spi.Conn returned by Connect().
+// Consecutive packets with KeepCS set form a single command. While the
+// command is sent, MISO floats high.
+//
+// The supported commands are read (0x03, 0x13), fast read (0x0B, 0x0C), page
+// program (0x02, 0x12), sector and block erase (0x20, 0x52, 0xD8 and 0x21,
+// 0x5C, 0xDC), chip erase (0xC7, 0x60), write enable and disable (0x06, 0x04),
+// read and write status register (0x05, 0x01), read JEDEC ID (0x9F), read SFDP
+// (0x5A), deep power-down (0xB9) and release from deep power-down (0xAB). The
+// second opcode of each pair uses 4-byte addresses.
+//
+// Operations complete immediately. Page program and erase are ignored unless
+// the write enable latch is set, and when any of the block protect bits are
+// set, in which case the whole array is protected. All commands but release
+// from deep power-down are ignored while powered down.
+type Flash struct {
+	sync.Mutex
+	// ID is the JEDEC ID: manufacturer, memory type and capacity.
+	ID [3]byte
+	// Data is the memory array; its length is the capacity of the chip.
+	Data []byte
+	// PageSize is the size of a program page. The default is 256.
+	PageSize int
+	// SFDP is the content returned by the read SFDP command. When empty, the
+	// command returns 0xFF like a chip without SFDP support.
+	SFDP []byte
+	// Status is the status register.
+	Status byte
+	// PoweredDown is true while the chip is in deep power-down.
+	PoweredDown bool
+
+	connected bool
+}
+
+// NewFlash returns a blank Flash of size bytes, with a Winbond JEDEC ID and a
+// SFDP basic flash parameter table describing 256 bytes pages and 4KiB, 32KiB
+// and 64KiB erase.
+//
+// size must be a power of two.
+func NewFlash(size int) *Flash {
+	f := &Flash{
+		ID:   [3]byte{0xEF, 0x40, byte(bits.TrailingZeros(uint(size)))},
+		Data: make([]byte, size),
+	}
+	for i := range f.Data {
+		f.Data[i] = 0xFF
+	}
+	// SFDP header with a single parameter header pointing to the basic flash
+	// parameter table (JESD216B) at 0x30.
+	f.SFDP = make([]byte, 0x30+16*4)
+	copy(f.SFDP, []byte{'S', 'F', 'D', 'P', 6, 1, 0, 0xFF, 0x00, 6, 1, 16, 0x30, 0, 0, 0xFF})
+	t := f.SFDP[0x30:]
+	addr := uint32(0)
+	if size > 1<<24 {
+		addr = 1
+	}
+	binary.LittleEndian.PutUint32(t[0:], 0xFF800000|addr<<17|0x20<<8|1<<2|1)
+	if n := uint64(size) * 8; n <= 1<<31 {
+		binary.LittleEndian.PutUint32(t[4:], uint32(n-1))
+	} else {
+		binary.LittleEndian.PutUint32(t[4:], 0x80000000|uint32(bits.TrailingZeros64(n)))
+	}
+	copy(t[7*4:], []byte{12, 0x20, 15, 0x52, 16, 0xD8, 0, 0})
+	t[10*4] = 8 << 4
+	return f
+}
+
+func (f *Flash) String() string {
+	return "flash"
+}
+
+// Close implements spi.PortCloser.
+//
+// The port can be connected again afterward.
+func (f *Flash) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	f.connected = false
+	return nil
+}
+
+// LimitSpeed implements spi.PortCloser.
+func (f *Flash) LimitSpeed(freq physic.Frequency) error {
+	return nil
+}
+
+// Connect implements spi.Port.
+func (f *Flash) Connect(freq physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	if bits != 8 {
+		return nil, conntest.Errorf("spitest: %d bits: %w", bits, conn.ErrUnsupported)
+	}
+	if mode&spi.HalfDuplex != 0 {
+		return nil, conntest.Errorf("spitest: half duplex with the flash: %w", conn.ErrUnsupported)
+	}
+	f.Lock()
+	defer f.Unlock()
+	if f.connected {
+		return nil, errors.New("spitest: Connect() can only be called exactly once")
+	}
+	f.connected = true
+	return &flashConn{f: f}, nil
+}
+
+//
+
+type flashConn struct {
+	f *Flash
+}
+
+func (c *flashConn) String() string {
+	return c.f.String()
+}
+
+func (c *flashConn) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+func (c *flashConn) Tx(w, r []byte) error {
+	return c.TxPacketsContext(context.Background(), []spi.Packet{{W: w, R: r}})
+}
+
+func (c *flashConn) TxContext(ctx context.Context, w, r []byte) error {
+	return c.TxPacketsContext(ctx, []spi.Packet{{W: w, R: r}})
+}
+
+func (c *flashConn) TxPackets(p []spi.Packet) error {
+	return c.TxPacketsContext(context.Background(), p)
+}
+
+func (c *flashConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.f.Lock()
+	defer c.f.Unlock()
+	var w []byte
+	start := 0
+	for i := range p {
+		lW, lR := len(p[i].W), len(p[i].R)
+		if lW != lR && lW != 0 && lR != 0 {
+			return conntest.Errorf("spitest: when both w and r are used, they must be the same size; got %d and %d bytes", lW, lR)
+		}
+		l := lW
+		if lR > l {
+			l = lR
+		}
+		w = append(w, p[i].W...)
+		w = append(w, make([]byte, l-lW)...)
+		if !p[i].KeepCS || i == len(p)-1 {
+			r := make([]byte, len(w))
+			if err := c.f.command(w, r); err != nil {
+				return err
+			}
+			for j := start; j <= i; j++ {
+				l := len(p[j].W)
+				if len(p[j].R) > l {
+					l = len(p[j].R)
+				}
+				copy(p[j].R, r[:l])
+				r = r[l:]
+			}
+			w = w[:0]
+			start = i + 1
+		}
+	}
+	return nil
+}
+
+// command processes a single command, from CS assertion to deassertion.
+func (f *Flash) command(w, r []byte) error {
+	for i := range r {
+		r[i] = 0xFF
+	}
+	if len(w) == 0 {
+		return nil
+	}
+	op := w[0]
+	if f.PoweredDown {
+		if op == 0xAB {
+			f.PoweredDown = false
+		}
+		return nil
+	}
+	switch op {
+	case 0x9F:
+		copy(r[1:], f.ID[:])
+	case 0x05:
+		for i := 1; i < len(r); i++ {
+			r[i] = f.Status
+		}
+	case 0x06:
+		f.Status |= flashWEL
+	case 0x04:
+		f.Status &^= flashWEL
+	case 0x01:
+		if f.Status&flashWEL != 0 && len(w) > 1 {
+			f.Status = w[1] &^ (flashBusy | flashWEL)
+		}
+		f.Status &^= flashWEL
+	case 0xB9:
+		f.PoweredDown = true
+	case 0xAB:
+	case 0x03, 0x13, 0x0B, 0x0C, 0x5A:
+		a, d, err := f.address(w)
+		if err != nil {
+			return err
+		}
+		if op == 0x0B || op == 0x0C || op == 0x5A {
+			d++
+		}
+		for i := d; i < len(r); i++ {
+			if op == 0x5A {
+				if j := a + i - d; j < len(f.SFDP) {
+					r[i] = f.SFDP[j]
+				}
+			} else if len(f.Data) != 0 {
+				r[i] = f.Data[(a+i-d)%len(f.Data)]
+			}
+		}
+	case 0x02, 0x12:
+		a, d, err := f.address(w)
+		if err != nil {
+			return err
+		}
+		if f.writable() {
+			ps := f.PageSize
+			if ps == 0 {
+				ps = 256
+			}
+			page := a &^ (ps - 1)
+			for i, v := range w[d:] {
+				f.Data[page+(a-page+i)%ps] &= v
+			}
+		}
+		f.Status &^= flashWEL
+	case 0x20, 0x21, 0x52, 0x5C, 0xD8, 0xDC, 0xC7, 0x60:
+		a, _, err := f.address(w)
+		if err != nil {
+			return err
+		}
+		if f.writable() {
+			s := len(f.Data)
+			switch op {
+			case 0x20, 0x21:
+				s = 4096
+			case 0x52, 0x5C:
+				s = 32768
+			case 0xD8, 0xDC:
+				s = 65536
+			}
+			base := a &^ (s - 1)
+			for i := base; i < base+s && i < len(f.Data); i++ {
+				f.Data[i] = 0xFF
+			}
+		}
+		f.Status &^= flashWEL
+	default:
+		return conntest.Errorf("spitest: flash command 0x%02X: %w", op, conn.ErrUnsupported)
+	}
+	return nil
+}
+
+// address decodes the address of a command and returns the offset of the
+// bytes following it.
+func (f *Flash) address(w []byte) (int, int, error) {
+	n := 0
+	switch w[0] {
+	case 0xC7, 0x60:
+		return 0, 1, nil
+	case 0x13, 0x0C, 0x12, 0x21, 0x5C, 0xDC:
+		n = 4
+	default:
+		n = 3
+	}
+	if len(w) < 1+n {
+		return 0, 0, conntest.Errorf("spitest: flash command 0x%02X is too short", w[0])
+	}
+	a := 0
+	for _, b := range w[1 : 1+n] {
+		a = a<<8 | int(b)
+	}
+	if w[0] != 0x5A && len(f.Data) != 0 {
+		a %= len(f.Data)
+	}
+	return a, 1 + n, nil
+}
+
+func (f *Flash) writable() bool {
+	return f.Status&flashWEL != 0 && f.Status&flashBP == 0 && len(f.Data) != 0
+}
+
+const (
+	flashBusy = 0x01
+	flashWEL  = 0x02
+	flashBP   = 0x1C
+)
+
+var _ spi.PortCloser = &Flash{}
+var _ spi.ConnContext = &flashConn{}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"bytes"
+	"testing"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestFlash(t *testing.T) {
+	f := NewFlash(1 << 16)
+	if _, err := f.Connect(physic.MegaHertz, spi.Mode0, 16); err == nil {
+		t.Fatal("16 bits")
+	}
+	c, err := f.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Connect(physic.MegaHertz, spi.Mode0, 8); err == nil {
+		t.Fatal("already connected")
+	}
+	r := make([]byte, 4)
+	if err := c.Tx([]byte{0x9F, 0, 0, 0}, r); err != nil || !bytes.Equal(r, []byte{0xFF, 0xEF, 0x40, 0x10}) {
+		t.Fatal(r, err)
+	}
+	sfdp := make([]byte, 4)
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0x5A, 0, 0, 0, 0}, KeepCS: true}, {R: sfdp}}); err != nil || string(sfdp) != "SFDP" {
+		t.Fatal(sfdp, err)
+	}
+	// Program is ignored without write enable.
+	if err := c.Tx([]byte{0x02, 0, 0, 0, 0x12}, nil); err != nil || f.Data[0] != 0xFF {
+		t.Fatal(f.Data[0], err)
+	}
+	// Program wraps around at the end of the page.
+	if err := c.Tx([]byte{0x06}, nil); err != nil || f.Status != 0x02 {
+		t.Fatal(f.Status, err)
+	}
+	if err := c.Tx([]byte{0x02, 0, 0, 0xFF, 0x12, 0x34}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.Data[0xFF] != 0x12 || f.Data[0] != 0x34 || f.Status != 0 {
+		t.Fatal(f.Data[0xFF], f.Data[0], f.Status)
+	}
+	r = make([]byte, 3)
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0x0B, 0, 0, 0xFF, 0}, KeepCS: true}, {R: r}}); err != nil || !bytes.Equal(r, []byte{0x12, 0xFF, 0xFF}) {
+		t.Fatal(r, err)
+	}
+	// Erase.
+	if err := c.Tx([]byte{0x06}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Tx([]byte{0x20, 0, 0, 0x10}, nil); err != nil || f.Data[0] != 0xFF || f.Data[0xFF] != 0xFF {
+		t.Fatal(err)
+	}
+	// Deep power-down.
+	if err := c.Tx([]byte{0xB9}, nil); err != nil || !f.PoweredDown {
+		t.Fatal(err)
+	}
+	r = make([]byte, 2)
+	if err := c.Tx([]byte{0x05, 0}, r); err != nil || r[1] != 0xFF {
+		t.Fatal(r, err)
+	}
+	if err := c.Tx([]byte{0xAB}, nil); err != nil || f.PoweredDown {
+		t.Fatal(err)
+	}
+	if err := c.Tx([]byte{0x42}, nil); !conntest.IsErr(err) {
+		t.Fatal(err)
+	}
+	if err := c.Tx([]byte{0x03, 0}, nil); !conntest.IsErr(err) {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
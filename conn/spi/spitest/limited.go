@@ -0,0 +1,168 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"context"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Limited implements spi.PortCloser and refuses transactions larger than Max
+// bytes on the connection returned by Port with an *spi.TooLargeError, like a
+// driver that cannot split transactions.
+//
+// It implements conn.Limits on the port and on the connection, so it can be
+// used to verify that a device driver respects conn.Limits.MaxTxSize().
+type Limited struct {
+	Port spi.PortCloser
+	Max  int
+}
+
+func (l *Limited) String() string {
+	return l.Port.String()
+}
+
+// Close implements spi.PortCloser.
+func (l *Limited) Close() error {
+	return l.Port.Close()
+}
+
+// LimitSpeed implements spi.PortCloser.
+func (l *Limited) LimitSpeed(f physic.Frequency) error {
+	return l.Port.LimitSpeed(f)
+}
+
+// Connect implements spi.PortCloser.
+func (l *Limited) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	c, err := l.Port.Connect(f, mode, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedConn{l, c}, nil
+}
+
+// MaxTxSize implements conn.Limits.
+func (l *Limited) MaxTxSize() int {
+	return l.Max
+}
+
+// CLK implements spi.Pins.
+func (l *Limited) CLK() gpio.PinOut {
+	if p, ok := l.Port.(spi.Pins); ok {
+		return p.CLK()
+	}
+	return gpio.INVALID
+}
+
+// MOSI implements spi.Pins.
+func (l *Limited) MOSI() gpio.PinOut {
+	if p, ok := l.Port.(spi.Pins); ok {
+		return p.MOSI()
+	}
+	return gpio.INVALID
+}
+
+// MISO implements spi.Pins.
+func (l *Limited) MISO() gpio.PinIn {
+	if p, ok := l.Port.(spi.Pins); ok {
+		return p.MISO()
+	}
+	return gpio.INVALID
+}
+
+// CS implements spi.Pins.
+func (l *Limited) CS() gpio.PinOut {
+	if p, ok := l.Port.(spi.Pins); ok {
+		return p.CS()
+	}
+	return gpio.INVALID
+}
+
+//
+
+type limitedConn struct {
+	l *Limited
+	c spi.Conn
+}
+
+func (l *limitedConn) String() string {
+	return l.c.String()
+}
+
+func (l *limitedConn) Duplex() conn.Duplex {
+	return l.c.Duplex()
+}
+
+func (l *limitedConn) MaxTxSize() int {
+	return l.l.Max
+}
+
+func (l *limitedConn) Tx(w, r []byte) error {
+	return l.TxContext(context.Background(), w, r)
+}
+
+func (l *limitedConn) TxContext(ctx context.Context, w, r []byte) error {
+	n := len(w)
+	if len(r) > n {
+		n = len(r)
+	}
+	if err := l.check("Tx", n); err != nil {
+		return err
+	}
+	return conn.TxContext(ctx, l.c, w, r)
+}
+
+func (l *limitedConn) TxPackets(p []spi.Packet) error {
+	return l.TxPacketsContext(context.Background(), p)
+}
+
+func (l *limitedConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	n := 0
+	for i := range p {
+		if len(p[i].W) > len(p[i].R) {
+			n += len(p[i].W)
+		} else {
+			n += len(p[i].R)
+		}
+	}
+	if err := l.check("TxPackets", n); err != nil {
+		return err
+	}
+	return spi.TxPacketsContext(ctx, l.c, p)
+}
+
+func (l *limitedConn) CLK() gpio.PinOut {
+	return l.l.CLK()
+}
+
+func (l *limitedConn) MOSI() gpio.PinOut {
+	return l.l.MOSI()
+}
+
+func (l *limitedConn) MISO() gpio.PinIn {
+	return l.l.MISO()
+}
+
+func (l *limitedConn) CS() gpio.PinOut {
+	return l.l.CS()
+}
+
+func (l *limitedConn) check(op string, n int) error {
+	if l.l.Max != 0 && n > l.l.Max {
+		return &spi.TooLargeError{Size: n, Max: l.l.Max, Err: conntest.Errorf("spitest: maximum %s length is %d, got %d bytes", op, l.l.Max, n)}
+	}
+	return nil
+}
+
+var _ conn.Limits = &Limited{}
+var _ spi.PortCloser = &Limited{}
+var _ spi.Pins = &Limited{}
+var _ conn.Limits = &limitedConn{}
+var _ spi.ConnContext = &limitedConn{}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"errors"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestLimited(t *testing.T) {
+	r := &Record{}
+	l := &Limited{Port: r, Max: 4}
+	if s := l.String(); s != "record" {
+		t.Fatal(s)
+	}
+	if m := l.MaxTxSize(); m != 4 {
+		t.Fatal(m)
+	}
+	if err := l.LimitSpeed(physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	c, err := l.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "record" {
+		t.Fatal(s)
+	}
+	if d := c.Duplex(); d != conn.DuplexUnknown {
+		t.Fatal(d)
+	}
+	if m := c.(conn.Limits).MaxTxSize(); m != 4 {
+		t.Fatal(m)
+	}
+	if p := c.(spi.Pins).CS(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if p := l.MISO(); p != gpio.INVALID {
+		t.Fatal(p)
+	}
+	if err := c.Tx([]byte{1, 2, 3, 4}, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Tx([]byte{1, 2, 3, 4, 5}, nil)
+	if !conntest.IsErr(err) || !errors.Is(err, conn.ErrTooLarge) || err.Error() != "spitest: maximum Tx length is 4, got 5 bytes" {
+		t.Fatal(err)
+	}
+	err = c.TxPackets([]spi.Packet{{W: []byte{1, 2, 3}}, {R: make([]byte, 2)}})
+	if !conntest.IsErr(err) || err.Error() != "spitest: maximum TxPackets length is 4, got 5 bytes" {
+		t.Fatal(err)
+	}
+	if err = c.TxPackets([]spi.Packet{{W: []byte{1, 2}}, {W: []byte{3, 4}}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Ops) != 3 {
+		t.Fatal(r.Ops)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
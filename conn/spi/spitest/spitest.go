@@ -6,9 +6,11 @@
 package spitest
 
 import (
+	"context"
 	"io"
 	"log"
 	"sync"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/conntest"
@@ -69,8 +71,16 @@ func (r *recordRawConn) Duplex() conn.Duplex {
 	return r.r.Duplex()
 }
 
+func (r *recordRawConn) TxContext(ctx context.Context, w, read []byte) error {
+	return r.r.TxContext(ctx, w, read)
+}
+
 func (r *recordRawConn) TxPackets(p []spi.Packet) error {
-	return conntest.Errorf("spitest: TxPackets is not implemented")
+	return conntest.Errorf("spitest: TxPackets: %w", conn.ErrUnsupported)
+}
+
+func (r *recordRawConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	return conntest.Errorf("spitest: TxPackets: %w", conn.ErrUnsupported)
 }
 
 //
@@ -78,11 +88,22 @@ func (r *recordRawConn) TxPackets(p []spi.Packet) error {
 // Record implements spi.PortCloser that records everything written to it.
 //
 // This can then be used to feed to Playback to do "replay" based unit tests.
+// Use Save() to serialize the recorded transactions.
 type Record struct {
 	sync.Mutex
-	Port        spi.PortCloser // Port can be nil if only writes are being recorded.
-	Ops         []conntest.IO
+	Port spi.PortCloser // Port can be nil if only writes are being recorded.
+	Ops  []conntest.IO
+	// Packets lists the packets sent via TxPackets(), in order. Their W and R
+	// are nil as the data is recorded in Ops, one IO per packet. They are not
+	// saved by Save().
+	Packets     []spi.Packet
 	Initialized bool
+
+	clock    conntest.Stopwatch
+	maxSpeed physic.Frequency
+	speed    physic.Frequency
+	mode     spi.Mode
+	bits     int
 }
 
 func (r *Record) String() string {
@@ -99,6 +120,9 @@ func (r *Record) Close() error {
 
 // LimitSpeed implements spi.PortCloser.
 func (r *Record) LimitSpeed(f physic.Frequency) error {
+	r.Lock()
+	r.maxSpeed = f
+	r.Unlock()
 	if r.Port != nil {
 		return r.Port.LimitSpeed(f)
 	}
@@ -113,6 +137,9 @@ func (r *Record) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn,
 		return nil, conntest.Errorf("spitest: Connect cannot be called twice")
 	}
 	r.Initialized = true
+	r.speed = f
+	r.mode = mode
+	r.bits = bits
 	if r.Port != nil {
 		c, err := r.Port.Connect(f, mode, bits)
 		if err != nil {
@@ -155,7 +182,10 @@ func (r *Record) CS() gpio.PinOut {
 	return gpio.INVALID
 }
 
-func (r *Record) txInternal(c spi.Conn, w, read []byte) error {
+func (r *Record) txInternal(ctx context.Context, c spi.Conn, w, read []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	io := conntest.IO{}
 	if len(w) != 0 {
 		io.W = make([]byte, len(w))
@@ -163,21 +193,63 @@ func (r *Record) txInternal(c spi.Conn, w, read []byte) error {
 	}
 	r.Lock()
 	defer r.Unlock()
+	io.T = r.clock.Elapsed()
 	if r.Port == nil {
 		if len(read) != 0 {
-			return conntest.Errorf("spitest: read unsupported when no port is connected")
+			return conntest.Errorf("spitest: read when no port is connected: %w", conn.ErrUnsupported)
 		}
 	} else {
-		if err := c.Tx(w, read); err != nil {
-			return err
-		}
+		io.Err = conn.TxContext(ctx, c, w, read)
 	}
 	if len(read) != 0 {
 		io.R = make([]byte, len(read))
 		copy(io.R, read)
 	}
 	r.Ops = append(r.Ops, io)
-	return nil
+	return io.Err
+}
+
+func (r *Record) txPacketsInternal(ctx context.Context, c spi.Conn, p []spi.Packet) error {
+	if len(p) == 0 {
+		return conntest.Errorf("spitest: empty packets")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ops := make([]conntest.IO, len(p))
+	for i := range p {
+		if len(p[i].W) != 0 {
+			ops[i].W = make([]byte, len(p[i].W))
+			copy(ops[i].W, p[i].W)
+		}
+	}
+	r.Lock()
+	defer r.Unlock()
+	t := r.clock.Elapsed()
+	var err error
+	if r.Port == nil {
+		for i := range p {
+			if len(p[i].R) != 0 {
+				return conntest.Errorf("spitest: read when no port is connected: %w", conn.ErrUnsupported)
+			}
+		}
+	} else {
+		err = spi.TxPacketsContext(ctx, c, p)
+	}
+	for i := range p {
+		ops[i].T = t
+		if len(p[i].R) != 0 {
+			ops[i].R = make([]byte, len(p[i].R))
+			copy(ops[i].R, p[i].R)
+		}
+		a := p[i]
+		a.W = nil
+		a.R = nil
+		r.Packets = append(r.Packets, a)
+	}
+	ops[len(ops)-1].Err = err
+	r.Ops = append(r.Ops, ops...)
+	return err
 }
 
 //
@@ -199,12 +271,19 @@ func (r *recordConn) Duplex() conn.Duplex {
 }
 
 func (r *recordConn) Tx(w, read []byte) error {
-	return r.r.txInternal(r.c, w, read)
+	return r.r.txInternal(context.Background(), r.c, w, read)
+}
+
+func (r *recordConn) TxContext(ctx context.Context, w, read []byte) error {
+	return r.r.txInternal(ctx, r.c, w, read)
 }
 
-// TxPackets is not yet implemented.
 func (r *recordConn) TxPackets(p []spi.Packet) error {
-	return conntest.Errorf("spitest: TxPackets is not implemented")
+	return r.r.txPacketsInternal(context.Background(), r.c, p)
+}
+
+func (r *recordConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	return r.r.txPacketsInternal(ctx, r.c, p)
 }
 
 // CLK implements spi.Pins.
@@ -233,6 +312,8 @@ func (r *recordConn) CS() gpio.PinOut {
 //
 // While "replay" type of unit tests are of limited value, they still present
 // an easy way to do basic code coverage.
+//
+// Use Load() or LoadGolden() to play back a serialized trace.
 type Playback struct {
 	conntest.Playback
 	CLKPin      gpio.PinIO
@@ -240,17 +321,50 @@ type Playback struct {
 	MISOPin     gpio.PinIO
 	CSPin       gpio.PinIO
 	Initialized bool
+	// Packets, when set, lists the packets expected by TxPackets(), in order.
+	// Their W and R are ignored; the data is verified via Ops, one IO per
+	// packet. When nil, the packets' attributes are not verified.
+	Packets     []spi.Packet
+	PacketCount int
+
+	// UpdateRecord, when set, records the transactions instead of playing back
+	// Ops. Close() then writes them to Golden. See LoadGolden().
+	//
+	// conntest.Playback.Update must not be used.
+	UpdateRecord *Record
+
+	updateConn spi.Conn
 }
 
 // Close implements spi.PortCloser.
 //
 // Close() verifies that all the expected Ops have been consumed.
+//
+// When UpdateRecord is set, it writes the recorded transactions to Golden
+// instead.
 func (p *Playback) Close() error {
-	return p.Playback.Close()
+	if p.UpdateRecord != nil {
+		if err := p.UpdateRecord.Close(); err != nil {
+			return err
+		}
+		return conntest.WriteTraceFile(p.Golden, p.UpdateRecord.trace())
+	}
+	if err := p.Playback.Close(); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.Packets != nil && len(p.Packets) != p.PacketCount {
+		return errorf(p.DontPanic, "spitest: expected all packets to be consumed: packet count %d; expected %d", p.PacketCount, len(p.Packets))
+	}
+	return nil
 }
 
 // LimitSpeed implements spi.PortCloser.
 func (p *Playback) LimitSpeed(f physic.Frequency) error {
+	if p.UpdateRecord != nil {
+		return p.UpdateRecord.LimitSpeed(f)
+	}
 	return nil
 }
 
@@ -262,6 +376,13 @@ func (p *Playback) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Con
 		return nil, conntest.Errorf("spitest: Connect cannot be called twice")
 	}
 	p.Initialized = true
+	if p.UpdateRecord != nil {
+		c, err := p.UpdateRecord.Connect(f, mode, bits)
+		if err != nil {
+			return nil, err
+		}
+		p.updateConn = c
+	}
 	return &playbackConn{p}, nil
 }
 
@@ -294,15 +415,46 @@ func (p *playbackConn) String() string {
 }
 
 func (p *playbackConn) Duplex() conn.Duplex {
+	if p.p.updateConn != nil {
+		return p.p.updateConn.Duplex()
+	}
 	return p.p.Duplex()
 }
 
 func (p *playbackConn) Tx(w, r []byte) error {
-	return p.p.Tx(w, r)
+	return p.TxContext(context.Background(), w, r)
+}
+
+func (p *playbackConn) TxContext(ctx context.Context, w, r []byte) error {
+	if p.p.updateConn != nil {
+		return conn.TxContext(ctx, p.p.updateConn, w, r)
+	}
+	return p.p.TxContext(ctx, w, r)
 }
 
 func (p *playbackConn) TxPackets(packets []spi.Packet) error {
-	return conntest.Errorf("spitest: TxPackets is not implemented")
+	return p.TxPacketsContext(context.Background(), packets)
+}
+
+func (p *playbackConn) TxPacketsContext(ctx context.Context, packets []spi.Packet) error {
+	if p.p.updateConn != nil {
+		return spi.TxPacketsContext(ctx, p.p.updateConn, packets)
+	}
+	if len(packets) == 0 {
+		return conntest.Errorf("spitest: empty packets")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for i := range packets {
+		if err := p.p.checkPacket(&packets[i]); err != nil {
+			return err
+		}
+		if err := p.p.Playback.Tx(packets[i].W, packets[i].R); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (p *playbackConn) CLK() gpio.PinOut {
@@ -363,16 +515,76 @@ func (l *LogConn) Tx(w, r []byte) error {
 	return err
 }
 
+// TxContext implements spi.ConnContext.
+func (l *LogConn) TxContext(ctx context.Context, w, r []byte) error {
+	err := conn.TxContext(ctx, l.Conn, w, r)
+	log.Printf("%s.TxContext(%#v, %#v) = %v", l.Conn, w, r, err)
+	return err
+}
+
 // TxPackets is not yet implemented.
 func (l *LogConn) TxPackets(p []spi.Packet) error {
-	return conntest.Errorf("spitest: TxPackets is not implemented")
+	return conntest.Errorf("spitest: TxPackets: %w", conn.ErrUnsupported)
+}
+
+// TxPacketsContext is not yet implemented.
+func (l *LogConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	return conntest.Errorf("spitest: TxPackets: %w", conn.ErrUnsupported)
 }
 
 //
 
+// checkPacket verifies the attributes of pkt against the next expected
+// packet.
+func (p *Playback) checkPacket(pkt *spi.Packet) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.Packets == nil {
+		return nil
+	}
+	if len(p.Packets) <= p.PacketCount {
+		return errorf(p.DontPanic, "spitest: unexpected packet (count #%d) %+v", p.PacketCount, attributes(pkt))
+	}
+	if a, e := attributes(pkt), attributes(&p.Packets[p.PacketCount]); a != e {
+		return errorf(p.DontPanic, "spitest: unexpected packet (count #%d) %+v != %+v", p.PacketCount, a, e)
+	}
+	p.PacketCount++
+	return nil
+}
+
+// packetAttributes is the comparable part of a spi.Packet.
+type packetAttributes struct {
+	BitsPerWord uint8
+	KeepCS      bool
+	Speed       physic.Frequency
+	DelayAfter  time.Duration
+	WordDelay   time.Duration
+	TxWidth     uint8
+	RxWidth     uint8
+}
+
+func attributes(p *spi.Packet) packetAttributes {
+	return packetAttributes{p.BitsPerWord, p.KeepCS, p.Speed, p.DelayAfter, p.WordDelay, p.TxWidth, p.RxWidth}
+}
+
+// errorf is the internal implementation that optionally panic.
+//
+// If dontPanic is false, it panics instead.
+func errorf(dontPanic bool, format string, a ...interface{}) error {
+	err := conntest.Errorf(format, a...)
+	if !dontPanic {
+		panic(err)
+	}
+	return err
+}
+
 var _ spi.PortCloser = &RecordRaw{}
 var _ spi.PortCloser = &Record{}
 var _ spi.PortCloser = &Playback{}
 var _ spi.PortCloser = &Log{}
 var _ spi.Pins = &Record{}
 var _ spi.Pins = &Playback{}
+var _ spi.ConnContext = &recordRawConn{}
+var _ spi.ConnContext = &recordConn{}
+var _ spi.ConnContext = &playbackConn{}
+var _ spi.ConnContext = &LogConn{}
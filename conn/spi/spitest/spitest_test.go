@@ -6,12 +6,14 @@ package spitest
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"io/ioutil"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/conntest"
@@ -79,7 +81,7 @@ func TestRecord_empty(t *testing.T) {
 		t.Fatal("Port is nil")
 	}
 	if err := c.TxPackets(nil); err == nil {
-		t.Fatal("not yet implemented")
+		t.Fatal("empty packets")
 	}
 	if d := c.Duplex(); d != conn.DuplexUnknown {
 		t.Fatal(d)
@@ -149,7 +151,7 @@ func TestPlayback(t *testing.T) {
 		t.Fatal("Can't call Connect twice")
 	}
 	if err := c.TxPackets(nil); err == nil {
-		t.Fatal("not yet implemented")
+		t.Fatal("empty packets")
 	}
 	if n := c.(spi.Pins).CLK().Name(); n != "CLK" {
 		t.Fatal(n)
@@ -313,7 +315,7 @@ func TestLog_Playback(t *testing.T) {
 		t.Fatal(err)
 	}
 	if err := c.TxPackets(nil); err == nil {
-		t.Fatal("not yet implemented")
+		t.Fatal("empty packets")
 	}
 	if d := c.Duplex(); d != conn.Full {
 		t.Fatal(d)
@@ -334,6 +336,37 @@ func TestLog_Playback(t *testing.T) {
 	}
 }
 
+func TestLog_TxContext(t *testing.T) {
+	r := Record{
+		Port: &Playback{
+			Playback: conntest.Playback{
+				Ops:       []conntest.IO{{W: []byte{10}, R: []byte{12}}},
+				DontPanic: true,
+			},
+		},
+	}
+	c, err := r.Connect(0, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &LogConn{Conn: c}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := conn.TxContext(ctx, l, []byte{10}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if err := spi.TxPacketsContext(context.Background(), l, nil); err == nil {
+		t.Fatal("not yet implemented")
+	}
+	v := [1]byte{}
+	if err := conn.TxContext(context.Background(), l, []byte{10}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+	if v[0] != 12 || len(r.Ops) != 1 {
+		t.Fatal(v, r.Ops)
+	}
+}
+
 //
 
 func TestMain(m *testing.M) {
@@ -343,3 +376,137 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(m.Run())
 }
+
+func TestRecord_Packets(t *testing.T) {
+	p := &Playback{
+		Playback: conntest.Playback{
+			Ops: []conntest.IO{
+				{W: []byte{0x80}},
+				{R: []byte{0x42, 0x43}},
+			},
+			D: conn.Full,
+		},
+		Packets: []spi.Packet{
+			{KeepCS: true, DelayAfter: time.Microsecond},
+			{Speed: physic.MegaHertz, WordDelay: 2 * time.Microsecond},
+		},
+	}
+	r := &Record{Port: p}
+	c, err := r.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkts := []spi.Packet{
+		{W: []byte{0x80}, KeepCS: true, DelayAfter: time.Microsecond},
+		{R: make([]byte, 2), Speed: physic.MegaHertz, WordDelay: 2 * time.Microsecond},
+	}
+	if err := c.TxPackets(pkts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkts[1].R, []byte{0x42, 0x43}) {
+		t.Fatal(pkts[1].R)
+	}
+	if len(r.Ops) != 2 || !bytes.Equal(r.Ops[0].W, []byte{0x80}) || !bytes.Equal(r.Ops[1].R, []byte{0x42, 0x43}) {
+		t.Fatal(r.Ops)
+	}
+	if len(r.Packets) != 2 || r.Packets[0].W != nil || r.Packets[1].R != nil || r.Packets[1].WordDelay != 2*time.Microsecond {
+		t.Fatal(r.Packets)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a port, only writes are supported.
+	r = &Record{}
+	if c, err = r.Connect(physic.MegaHertz, spi.Mode0, 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}, KeepCS: true}, {W: []byte{2}}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Ops) != 2 || !r.Packets[0].KeepCS {
+		t.Fatal(r.Ops, r.Packets)
+	}
+	if err := c.TxPackets([]spi.Packet{{R: []byte{1}}}); err == nil {
+		t.Fatal("Port is nil")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := spi.TxPacketsContext(ctx, c, []spi.Packet{{W: []byte{1}}}); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayback_Packets(t *testing.T) {
+	p := &Playback{
+		Playback: conntest.Playback{
+			Ops:       []conntest.IO{{W: []byte{1}}, {W: []byte{2}}},
+			D:         conn.Full,
+			DontPanic: true,
+		},
+		Packets: []spi.Packet{{DelayAfter: time.Millisecond}},
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}}}); err == nil {
+		t.Fatal("missing delay")
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}, DelayAfter: time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{2}}}); err == nil {
+		t.Fatal("unexpected packet")
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("Ops not consumed")
+	}
+
+	// Packets not set; attributes are not verified.
+	p = &Playback{Playback: conntest.Playback{Ops: []conntest.IO{{W: []byte{1}}}, D: conn.Full, DontPanic: true}}
+	if c, err = p.Connect(physic.MegaHertz, spi.Mode0, 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}, Speed: physic.KiloHertz}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayback_Packets_Close(t *testing.T) {
+	p := &Playback{
+		Playback: conntest.Playback{D: conn.Full, DontPanic: true},
+		Packets:  []spi.Packet{{}},
+	}
+	if err := p.Close(); err == nil {
+		t.Fatal("Packets not consumed")
+	}
+}
+
+func TestRecord_Packets_Width(t *testing.T) {
+	p := &Playback{
+		Playback: conntest.Playback{
+			Ops:       []conntest.IO{{W: []byte{0x6B}}, {R: []byte{1, 2}}, {R: []byte{3}}},
+			D:         conn.Half,
+			DontPanic: true,
+		},
+		Packets: []spi.Packet{{KeepCS: true}, {RxWidth: 4}, {RxWidth: 4}},
+	}
+	r := &Record{Port: p}
+	c, err := r.Connect(physic.MegaHertz, spi.Mode0|spi.HalfDuplex|spi.RxQuad, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0x6B}, KeepCS: true}, {R: make([]byte, 2), RxWidth: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Packets) != 2 || r.Packets[0].RxWidth != 0 || r.Packets[1].RxWidth != 4 {
+		t.Fatal(r.Packets)
+	}
+	if err := c.TxPackets([]spi.Packet{{R: make([]byte, 1), RxWidth: 2}}); err == nil {
+		t.Fatal("width mismatch")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Save writes the transactions recorded by r as a trace.
+//
+// See conntest.Trace for the format.
+func Save(w io.Writer, r *Record) error {
+	return conntest.WriteTrace(w, r.trace())
+}
+
+// Load reads a trace written by Save() and returns it as a Playback.
+func Load(r io.Reader) (*Playback, error) {
+	t, err := conntest.ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	return playback(t)
+}
+
+// LoadGolden returns a Playback for the golden file path.
+//
+// When conntest.UpdateGolden is set, the file is not read; the returned
+// Playback forwards the transactions to port and rewrites the file on Close()
+// instead.
+func LoadGolden(path string, port spi.PortCloser) (*Playback, error) {
+	if conntest.UpdateGolden {
+		if port == nil {
+			return nil, errors.New("spitest: a port is required to update " + path)
+		}
+		p := &Playback{UpdateRecord: &Record{Port: port}}
+		p.Golden = path
+		return p, nil
+	}
+	t, err := conntest.ReadTraceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := playback(t)
+	if err != nil {
+		return nil, err
+	}
+	p.Golden = path
+	return p, nil
+}
+
+//
+
+func (r *Record) trace() *conntest.Trace {
+	r.Lock()
+	defer r.Unlock()
+	t := &conntest.Trace{Version: conntest.TraceVersion, Kind: "spi", Ops: conntest.ToTrace(r.Ops)}
+	if r.Port != nil {
+		t.Bus = r.Port.String()
+	}
+	if r.Initialized {
+		t.Meta = map[string]string{
+			"speed": r.speed.String(),
+			"mode":  r.mode.String(),
+			"bits":  strconv.Itoa(r.bits),
+		}
+		if r.maxSpeed != 0 {
+			t.Meta["maxSpeed"] = r.maxSpeed.String()
+		}
+	}
+	return t
+}
+
+func playback(t *conntest.Trace) (*Playback, error) {
+	if t.Kind != "spi" {
+		return nil, fmt.Errorf("spitest: expected a spi trace, got %q", t.Kind)
+	}
+	p := &Playback{}
+	p.Ops = conntest.FromTrace(t.Ops)
+	if m, ok := t.Meta["mode"]; ok {
+		p.D = conn.Full
+		if strings.Contains(m, "HalfDuplex") {
+			p.D = conn.Half
+		}
+	}
+	return p, nil
+}
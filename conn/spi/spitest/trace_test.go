@@ -0,0 +1,132 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spitest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestSave_Load(t *testing.T) {
+	port := &Playback{Playback: conntest.Playback{Ops: []conntest.IO{{W: []byte{0x80, 0}, R: []byte{0, 0x42}}}, D: conn.Full}}
+	r := Record{Port: port}
+	c, err := r.Connect(physic.MegaHertz, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 2)
+	if err := c.Tx([]byte{0x80, 0}, b); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err := Save(&buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "version": 1,
+  "kind": "spi",
+  "bus": "playback",
+  "meta": {"bits":"8","mode":"Mode3","speed":"1MHz"},
+  "ops": [
+    {"t":0,"w":"8000","r":"0042"}
+  ]
+}
+`
+	if s := buf.String(); s != expected {
+		t.Fatalf("unexpected trace:\n%s", s)
+	}
+	p, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err = p.Connect(physic.MegaHertz, spi.Mode3, 8); err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Duplex(); d != conn.Full {
+		t.Fatal(d)
+	}
+	b[1] = 0
+	if err := c.Tx([]byte{0x80, 0}, b); err != nil || b[1] != 0x42 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(strings.NewReader(`{"version": 1, "kind": "i2c", "ops": []}`)); err == nil {
+		t.Fatal("wrong kind")
+	}
+	if _, err := Load(strings.NewReader(``)); err == nil {
+		t.Fatal("empty")
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_spitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+	defer func(u bool) {
+		conntest.UpdateGolden = u
+	}(conntest.UpdateGolden)
+
+	conntest.UpdateGolden = true
+	if _, err := LoadGolden(path, nil); err == nil {
+		t.Fatal("port is required")
+	}
+	port := &Playback{Playback: conntest.Playback{Ops: []conntest.IO{{W: []byte{1}, R: []byte{2}}}, D: conn.Half}}
+	p, err := LoadGolden(path, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LimitSpeed(physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0|spi.HalfDuplex, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	b := make([]byte, 1)
+	if err := c.Tx([]byte{1}, b); err != nil || b[0] != 2 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conntest.UpdateGolden = false
+	if p, err = LoadGolden(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = p.Connect(physic.MegaHertz, spi.Mode0|spi.HalfDuplex, 8); err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Duplex(); d != conn.Half {
+		t.Fatal(d)
+	}
+	b[0] = 0
+	if err := c.Tx([]byte{1}, b); err != nil || b[0] != 2 {
+		t.Fatal(b, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGolden(filepath.Join(dir, "missing.json"), nil); err == nil {
+		t.Fatal("missing file")
+	}
+}
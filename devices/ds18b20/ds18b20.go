@@ -145,6 +145,22 @@ func (d *Dev) LastTemp() (physic.Temperature, error) {
 
 //
 
+func init() {
+	onewire.MustRegisterFamily(onewire.DS18B20, "DS18B20", open)
+}
+
+// open instantiates the device with its current resolution, as registered in
+// the onewire family registry.
+func open(b onewire.Bus, a onewire.Address) (conn.Resource, error) {
+	d := &Dev{onewire: onewire.Dev{Bus: b, Addr: a}}
+	spad, err := d.readScratchpad()
+	if err != nil {
+		return nil, err
+	}
+	d.resolution = int(spad[4]>>5&3) + 9
+	return d, nil
+}
+
 // busError implements error and onewire.BusError.
 type busError string
 
@@ -5,6 +5,7 @@
 package ds18b20
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -123,10 +124,96 @@ func TestConvertAll_fail_io(t *testing.T) {
 	}
 }
 
+// TestSim tests the devices against simulated sensors.
+func TestSim(t *testing.T) {
+	a1 := onewiretest.MakeAddress(0x28, 1)
+	a2 := onewiretest.MakeAddress(0x28, 2)
+	s1 := simDS18B20(0x0191) // 25.0625°C
+	s2 := simDS18B20(0xFF5E) // -10.125°C
+	bus := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a1: s1, a2: s2}}
+	found, err := bus.Search(false)
+	if err != nil || len(found) != 2 {
+		t.Fatal(found, err)
+	}
+	d1, err := New(bus, a1, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The resolution was changed from the power up default of 12 bits.
+	d2, err := New(bus, a2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.Mem[4] != 0x3F {
+		t.Fatalf("0x%02X", s2.Mem[4])
+	}
+	if err := ConvertAll(bus, 12); err != nil {
+		t.Fatal(err)
+	}
+	want := 25062500*physic.MicroKelvin + physic.ZeroCelsius
+	if v, err := d1.LastTemp(); err != nil || v != want {
+		t.Fatal(v, err)
+	}
+	// Discover instantiates the devices with their current resolution.
+	devs, err := onewire.Discover(bus)
+	if err != nil || len(devs) != 2 {
+		t.Fatal(devs, err)
+	}
+	for _, d := range devs {
+		want := 12
+		if d.Addr == a2 {
+			want = 10
+		}
+		if dev, ok := d.Dev.(*Dev); !ok || d.Err != nil || dev.resolution != want {
+			t.Fatal(d)
+		}
+	}
+	e := physic.Env{}
+	if err := d2.Sense(&e); err != nil {
+		t.Fatal(err)
+	}
+	if want := physic.ZeroCelsius - 10125*physic.MilliKelvin; e.Temperature != want {
+		t.Fatal(e.Temperature)
+	}
+}
+
 func init() {
 	sleep = func(time.Duration) {}
 }
 
+// simDS18B20 returns a simulated DS18B20 that measures the temperature t, in
+// 1/16°C.
+func simDS18B20(t uint16) *onewiretest.SimDevice {
+	return &onewiretest.SimDevice{
+		// Power up state of the scratchpad: 85°C, 12 bits resolution.
+		Mem: []byte{0x50, 0x05, 0x4B, 0x46, 0x7F, 0xFF, 0x0C, 0x10},
+		Funcs: map[byte]onewiretest.SimFunc{
+			// Convert T
+			0x44: func(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+				if pull != onewire.StrongPullup {
+					return errors.New("conversion requires a strong pull-up")
+				}
+				d.Mem[0], d.Mem[1] = byte(t), byte(t>>8)
+				return nil
+			},
+			// Write scratchpad
+			0x4E: func(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+				copy(d.Mem[2:5], w)
+				return nil
+			},
+			// Copy scratchpad
+			0x48: func(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+				return nil
+			},
+			// Read scratchpad
+			0xBE: func(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+				copy(r, append(d.Mem, onewire.CalcCRC(d.Mem)))
+				return nil
+			},
+		},
+	}
+}
+
 /* Commented out in order not to import periph/host, need to move to smoke test
 // TestRecordTemp tests and records a temperature conversion. It outputs
 // the recording if the tests are run with the verbose option.
@@ -5,7 +5,10 @@
 package main
 
 import (
+	"time"
+
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn/bustrace"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/conn/i2c"
@@ -52,6 +55,8 @@ func (j *jsonAPI) getAPIs() []apiHandler {
 		{"/api/periph/v1/i2c/list", j.apiI2CList},
 		{"/api/periph/v1/spi/list", j.apiSPIList},
 		{"/api/periph/v1/server/state", j.apiServerState},
+		{"/api/periph/v1/trace/events", j.apiTraceEvents},
+		{"/api/periph/v1/trace/stats", j.apiTraceStats},
 	}
 }
 
@@ -288,3 +293,48 @@ func (j *jsonAPI) apiServerState() (*serverStateOut, int) {
 	}
 	return out, 200
 }
+
+// /api/periph/v1/trace/events
+
+type traceEvent struct {
+	Start    time.Time
+	Duration time.Duration
+	Kind     string
+	Bus      string
+	Addr     uint16
+	Op       string
+	W        int
+	R        int
+	Err      string
+}
+
+func (j *jsonAPI) apiTraceEvents() ([]traceEvent, int) {
+	t := bustrace.Enabled()
+	if t == nil {
+		return nil, 404
+	}
+	r, ok := t.Sink.(*bustrace.Ring)
+	if !ok {
+		return nil, 404
+	}
+	events := r.Events()
+	out := make([]traceEvent, 0, len(events))
+	for _, e := range events {
+		ev := traceEvent{e.Start, e.Duration, e.Kind, e.Bus, e.Addr, e.Op, e.W, e.R, ""}
+		if e.Err != nil {
+			ev.Err = e.Err.Error()
+		}
+		out = append(out, ev)
+	}
+	return out, 200
+}
+
+// /api/periph/v1/trace/stats
+
+func (j *jsonAPI) apiTraceStats() ([]bustrace.Stats, int) {
+	t := bustrace.Enabled()
+	if t == nil {
+		return nil, 404
+	}
+	return t.Stats(), 200
+}
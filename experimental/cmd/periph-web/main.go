@@ -13,11 +13,14 @@ import (
 	"log"
 	"os"
 	"os/signal"
+
+	"periph.io/x/periph/conn/bustrace"
 )
 
 func mainImpl() error {
 	port := flag.String("http", "localhost:7080", "IP and port to bind to; listens to localhost by default; use 0.0.0.0:<port> to listen on all ports")
 	verbose := flag.Bool("v", false, "verbose log")
+	trace := flag.Bool("trace", false, "trace the bus transactions and serve them at /api/periph/v1/trace/")
 	flag.Parse()
 	if flag.NArg() != 0 {
 		return errors.New("unsupported arguments")
@@ -26,6 +29,9 @@ func mainImpl() error {
 		log.SetOutput(ioutil.Discard)
 	}
 	log.SetFlags(log.Lmicroseconds)
+	if *trace {
+		bustrace.Enable(&bustrace.Tracer{Sink: &bustrace.Ring{}})
+	}
 	state, err := hostInit()
 	if err != nil {
 		return err
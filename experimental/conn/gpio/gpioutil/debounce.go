@@ -70,6 +70,15 @@ func (d *debounced) WaitForEdge(timeout time.Duration) bool {
 	return d.PinIO.WaitForEdge(timeout)
 }
 
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// It is the smoothed out value from the underlying gpio.PinIO. If the
+// underlying pin doesn't implement gpio.PinEdgeEvents, the edge is
+// timestamped when it is detected.
+func (d *debounced) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	return waitForEdgeEvent(d.PinIO, timeout)
+}
+
 // Halt implements gpio.PinIO.
 func (d *debounced) Halt() error {
 	return nil
@@ -83,5 +92,25 @@ func (d *debounced) Real() gpio.PinIO {
 	return d.PinIO
 }
 
+// waitForEdgeEvent calls WaitForEdgeEvent() on p if supported, otherwise
+// emulates it.
+func waitForEdgeEvent(p gpio.PinIn, timeout time.Duration) (gpio.EdgeEvent, bool) {
+	if e, ok := p.(gpio.PinEdgeEvents); ok {
+		return e.WaitForEdgeEvent(timeout)
+	}
+	if !p.WaitForEdge(timeout) {
+		return gpio.EdgeEvent{}, false
+	}
+	return gpio.EdgeEvent{Time: timestamp(), Level: p.Read()}, true
+}
+
+// timestamp returns the monotonic time used for edges detected by this
+// package.
+func timestamp() time.Duration {
+	return time.Since(epoch)
+}
+
 var now = time.Now
+var epoch = time.Now()
 var _ gpio.PinIO = &debounced{}
+var _ gpio.PinEdgeEvents = &debounced{}
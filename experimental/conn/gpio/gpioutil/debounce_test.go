@@ -104,6 +104,35 @@ func TestDebounce_WaitForEdge_Timeout(t *testing.T) {
 	}
 }
 
+func TestDebounce_WaitForEdgeEvent(t *testing.T) {
+	defer mocktime(t, []time.Duration{})()
+	f := gpiotest.Pin{EventsChan: make(chan gpio.EdgeEvent, 1)}
+	p, err := Debounce(&f, time.Second, 0, gpio.BothEdges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.EventsChan <- gpio.EdgeEvent{Level: gpio.High, Time: time.Second}
+	e, ok := p.(gpio.PinEdgeEvents).WaitForEdgeEvent(-1)
+	if !ok || e.Level != gpio.High || e.Time != time.Second {
+		t.Fatal(e, ok)
+	}
+	if _, ok := p.(gpio.PinEdgeEvents).WaitForEdgeEvent(0); ok {
+		t.Fatal("expected no edge")
+	}
+}
+
+func TestWaitForEdgeEvent_Emulated(t *testing.T) {
+	f := pinEdge{PinIO: &gpiotest.Pin{L: gpio.High}}
+	e, ok := waitForEdgeEvent(&f, -1)
+	if !ok || e.Level != gpio.High || e.Time <= 0 {
+		t.Fatal(e, ok)
+	}
+	f.noEdge = true
+	if _, ok := waitForEdgeEvent(&f, 0); ok {
+		t.Fatal("expected no edge")
+	}
+}
+
 func TestDebounce_RealPin(t *testing.T) {
 	defer mocktime(t, []time.Duration{})()
 	f := gpiotest.Pin{EdgesChan: make(chan gpio.Level)}
@@ -150,6 +179,16 @@ func TestDebounce_RealPin_Deep(t *testing.T) {
 
 //
 
+// pinEdge doesn't implement gpio.PinEdgeEvents.
+type pinEdge struct {
+	gpio.PinIO
+	noEdge bool
+}
+
+func (p *pinEdge) WaitForEdge(timeout time.Duration) bool {
+	return !p.noEdge
+}
+
 func init() {
 	resetNow()
 }
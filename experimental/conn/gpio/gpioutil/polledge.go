@@ -47,6 +47,16 @@ func (p *pollEdge) In(pull gpio.Pull, edge gpio.Edge) error {
 
 // WaitForEdge implements gpio.PinIO.
 func (p *pollEdge) WaitForEdge(timeout time.Duration) bool {
+	_, ok := p.WaitForEdgeEvent(timeout)
+	return ok
+}
+
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// The edge is timestamped when it is detected by the polling loop, so the
+// timestamp resolution is the polling period. Edges shorter than the polling
+// period are lost.
+func (p *pollEdge) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
 	select {
 	case <-p.die:
 	default:
@@ -73,23 +83,24 @@ func (p *pollEdge) WaitForEdge(timeout time.Duration) bool {
 		case <-t.C:
 			n := p.PinIO.Read()
 			if n != curr {
+				e := gpio.EdgeEvent{Level: n, Time: timestamp()}
 				switch p.edge {
 				case gpio.RisingEdge:
 					if n == gpio.High {
-						return true
+						return e, true
 					}
 					curr = n
 				case gpio.FallingEdge:
 					if n == gpio.Low {
-						return true
+						return e, true
 					}
 					curr = n
 				case gpio.BothEdges:
-					return true
+					return e, true
 				}
 			}
 		case <-p.die:
-			return false
+			return gpio.EdgeEvent{}, false
 		}
 	}
 }
@@ -115,3 +126,4 @@ func (p *pollEdge) Real() gpio.PinIO {
 }
 
 var _ gpio.PinIO = &pollEdge{}
+var _ gpio.PinEdgeEvents = &pollEdge{}
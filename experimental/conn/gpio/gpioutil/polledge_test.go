@@ -97,6 +97,21 @@ func TestPollEdge_BothEdges(t *testing.T) {
 	}
 }
 
+func TestPollEdge_WaitForEdgeEvent(t *testing.T) {
+	f := pinLevels{levels: []gpio.Level{gpio.Low, gpio.High}}
+	p := PollEdge(&f, physic.KiloHertz)
+	if err := p.In(gpio.PullNoChange, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := p.(gpio.PinEdgeEvents).WaitForEdgeEvent(-1)
+	if !ok || e.Level != gpio.High || e.Time <= 0 {
+		t.Fatal(e, ok)
+	}
+	if len(f.levels) != 0 {
+		t.Fatal("unconsumed level")
+	}
+}
+
 func TestPollEdge_RealPin(t *testing.T) {
 	f := gpiotest.Pin{}
 	p := PollEdge(&f, physic.Hertz)
@@ -0,0 +1,212 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package at24
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+)
+
+// Part describes an EEPROM part.
+type Part struct {
+	Name      string
+	Size      int // Capacity in bytes
+	PageSize  int // Maximum number of bytes written in a single write cycle
+	AddrWidth int // Number of bytes of the memory address, 1 or 2
+}
+
+// Parts of the AT24Cxx family.
+var (
+	AT24C01  = Part{"AT24C01", 128, 8, 1}
+	AT24C02  = Part{"AT24C02", 256, 8, 1}
+	AT24C04  = Part{"AT24C04", 512, 16, 1}
+	AT24C08  = Part{"AT24C08", 1024, 16, 1}
+	AT24C16  = Part{"AT24C16", 2048, 16, 1}
+	AT24C32  = Part{"AT24C32", 4096, 32, 2}
+	AT24C64  = Part{"AT24C64", 8192, 32, 2}
+	AT24C128 = Part{"AT24C128", 16384, 64, 2}
+	AT24C256 = Part{"AT24C256", 32768, 64, 2}
+	AT24C512 = Part{"AT24C512", 65536, 128, 2}
+	AT24CM01 = Part{"AT24CM01", 131072, 256, 2}
+	AT24CM02 = Part{"AT24CM02", 262144, 256, 2}
+)
+
+// Opts holds the configuration options.
+type Opts struct {
+	// Part is the EEPROM part. It is required.
+	Part Part
+	// Addr is the I²C address of the device. The default is 0x50.
+	//
+	// Parts that span multiple addresses use Addr and the following addresses;
+	// the corresponding low bits of Addr must be 0.
+	Addr uint16
+	// WriteCycle is the maximum duration of a write cycle. The default is 5ms,
+	// the tWR of the AT24Cxx parts.
+	WriteCycle time.Duration
+}
+
+// New opens a handle to an EEPROM.
+//
+// The device is not accessed.
+func New(b i2c.Bus, o *Opts) (*Dev, error) {
+	p := o.Part
+	if p.AddrWidth != 1 && p.AddrWidth != 2 {
+		return nil, fmt.Errorf("at24: invalid address width %d", p.AddrWidth)
+	}
+	if p.Size <= 0 || p.PageSize <= 0 || p.Size&(p.Size-1) != 0 || p.PageSize&(p.PageSize-1) != 0 {
+		return nil, fmt.Errorf("at24: invalid part %q; size %d and page size %d must be powers of two", p.Name, p.Size, p.PageSize)
+	}
+	block := 1 << uint(8*p.AddrWidth)
+	if block > p.Size {
+		block = p.Size
+	}
+	n := p.Size / block
+	if n > 8 {
+		return nil, fmt.Errorf("at24: invalid part %q; it would span %d addresses", p.Name, n)
+	}
+	addr := o.Addr
+	if addr == 0 {
+		addr = 0x50
+	}
+	if addr > 0x7F || int(addr)&(n-1) != 0 {
+		return nil, fmt.Errorf("at24: invalid address 0x%02X for %s", addr, p.Name)
+	}
+	d := &Dev{part: p, block: block, wc: o.WriteCycle}
+	if d.wc == 0 {
+		d.wc = 5 * time.Millisecond
+	}
+	for i := 0; i < n; i++ {
+		d.devs = append(d.devs, i2c.Dev{Bus: b, Addr: addr + uint16(i)})
+	}
+	return d, nil
+}
+
+// Dev is a handle to an EEPROM.
+//
+// It implements io.ReaderAt and io.WriterAt.
+type Dev struct {
+	part  Part
+	block int // Number of bytes reachable per I²C address
+	wc    time.Duration
+	devs  []i2c.Dev
+
+	mu sync.Mutex
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("%s{%s}", d.part.Name, &d.devs[0])
+}
+
+// Halt implements conn.Resource.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+// Part returns the EEPROM part.
+func (d *Dev) Part() Part {
+	return d.part
+}
+
+// ReadAt implements io.ReaderAt.
+func (d *Dev) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("at24: negative offset")
+	}
+	size := int64(d.part.Size)
+	if off >= size {
+		return 0, io.EOF
+	}
+	n := len(b)
+	if rem := size - off; int64(n) > rem {
+		n = int(rem)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; i < n; {
+		o := int(off) + i
+		l := d.block - o%d.block
+		if l > n-i {
+			l = n - i
+		}
+		if err := d.devs[o/d.block].Tx(d.addr(o), b[i:i+l]); err != nil {
+			return i, err
+		}
+		i += l
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt.
+//
+// The data is written one page at a time, waiting for the end of each write
+// cycle.
+func (d *Dev) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(b)) > int64(d.part.Size) {
+		return 0, fmt.Errorf("at24: write of %d bytes at 0x%X is outside of the %s", len(b), off, d.part.Name)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; i < len(b); {
+		o := int(off) + i
+		l := d.part.PageSize - o%d.part.PageSize
+		if l > len(b)-i {
+			l = len(b) - i
+		}
+		dev := &d.devs[o/d.block]
+		if err := dev.Tx(append(d.addr(o), b[i:i+l]...), nil); err != nil {
+			return i, err
+		}
+		if err := d.wait(dev); err != nil {
+			return i, err
+		}
+		i += l
+	}
+	return len(b), nil
+}
+
+//
+
+const pollInterval = 100 * time.Microsecond
+
+// addr returns the memory address of offset o, within its block.
+func (d *Dev) addr(o int) []byte {
+	o %= d.block
+	if d.part.AddrWidth == 2 {
+		return []byte{byte(o >> 8), byte(o)}
+	}
+	return []byte{byte(o)}
+}
+
+// wait polls the device until it acknowledges again, which signals the end of
+// the write cycle.
+//
+// It does a current address read, which doesn't modify the memory.
+func (d *Dev) wait(dev *i2c.Dev) error {
+	start := time.Now()
+	var b [1]byte
+	for {
+		err := dev.Tx(nil, b[:])
+		if err == nil {
+			return nil
+		}
+		if time.Since(start) > d.wc {
+			return fmt.Errorf("at24: write cycle not completed after %s: %v", d.wc, err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+var _ conn.Resource = &Dev{}
+var _ io.ReaderAt = &Dev{}
+var _ io.WriterAt = &Dev{}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package at24
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/conntest"
+	"periph.io/x/periph/conn/i2c/i2ctest"
+)
+
+func TestNew_err(t *testing.T) {
+	b := &i2ctest.Sim{}
+	data := []struct {
+		o Opts
+	}{
+		{Opts{}},
+		{Opts{Part: Part{"foo", 100, 8, 1}}},
+		{Opts{Part: Part{"foo", 128, 0, 1}}},
+		{Opts{Part: Part{"foo", 4096, 16, 1}}},
+		{Opts{Part: AT24C16, Addr: 0x51}},
+		{Opts{Part: AT24C02, Addr: 0x80}},
+	}
+	for i, line := range data {
+		if _, err := New(b, &line.o); err == nil {
+			t.Fatal(i)
+		}
+	}
+}
+
+func TestAT24C02(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x50: {Regs: make([]byte, 256)}}}
+	r := &i2ctest.Record{Bus: s}
+	d, err := New(r, &Opts{Part: AT24C02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str := d.String(); str != "AT24C02{record(80)}" {
+		t.Fatal(str)
+	}
+	if p := d.Part(); p != AT24C02 {
+		t.Fatal(p)
+	}
+	w := []byte("0123456789abcdefghij")
+	if n, err := d.WriteAt(w, 5); n != len(w) || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(s.Devices[0x50].Regs[5:25], w) {
+		t.Fatal(s.Devices[0x50].Regs)
+	}
+	// Pages are 8 bytes; 3+8+8+1 bytes are written, each followed by a poll.
+	var writes []int
+	for _, op := range r.Ops {
+		if len(op.W) != 0 {
+			writes = append(writes, len(op.W)-1)
+		}
+	}
+	if len(r.Ops) != 8 || len(writes) != 4 || writes[0] != 3 || writes[1] != 8 || writes[3] != 1 {
+		t.Fatal(r.Ops)
+	}
+	b := make([]byte, len(w))
+	if n, err := d.ReadAt(b, 5); n != len(b) || err != nil || !bytes.Equal(b, w) {
+		t.Fatal(n, err, b)
+	}
+	if n, err := d.ReadAt(b, 250); n != 6 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if n, err := d.ReadAt(b, 256); n != 0 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if _, err := d.ReadAt(b, -1); err == nil {
+		t.Fatal("negative offset")
+	}
+	if _, err := d.WriteAt(b, 250); err == nil {
+		t.Fatal("outside of the part")
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAT24C16(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{}}
+	for i := uint16(0); i < 8; i++ {
+		s.Devices[0x50+i] = &i2ctest.SimDevice{Regs: make([]byte, 256)}
+	}
+	d, err := New(s, &Opts{Part: AT24C16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spans the addresses 0x50 and 0x51.
+	w := []byte("abcdefghijklmnopqrst")
+	if _, err := d.WriteAt(w, 250); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Devices[0x50].Regs[250:], w[:6]) || !bytes.Equal(s.Devices[0x51].Regs[:14], w[6:]) {
+		t.Fatal("unexpected content")
+	}
+	if _, err := d.WriteAt([]byte{0xAA}, 2047); err != nil || s.Devices[0x57].Regs[255] != 0xAA {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(w))
+	if _, err := d.ReadAt(b, 250); err != nil || !bytes.Equal(b, w) {
+		t.Fatal(b, err)
+	}
+}
+
+func TestAT24C512(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x54: {AddrWidth: 2, Regs: make([]byte, 65536)}}}
+	r := &i2ctest.Record{Bus: s}
+	d, err := New(r, &Opts{Part: AT24C512, Addr: 0x54})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pages are 128 bytes; the period of 10 bytes doesn't line up with them.
+	w := bytes.Repeat([]byte("0123456789"), 20)
+	if _, err := d.WriteAt(w, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Devices[0x54].Regs[0x1234:0x1234+200], w) {
+		t.Fatal("unexpected content")
+	}
+	if op := r.Ops[0]; op.Addr != 0x54 || op.W[0] != 0x12 || op.W[1] != 0x34 || len(op.W) != 2+0x80-0x34 {
+		t.Fatal(op)
+	}
+}
+
+func TestWriteCycle(t *testing.T) {
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x50: {Regs: make([]byte, 256)}}}
+	// The device doesn't acknowledge the first two polls.
+	in := &conntest.Injector{
+		Schedule: map[int]conntest.Fault{1: conntest.NACK, 2: conntest.NACK},
+		Logf:     func(string, ...interface{}) {},
+	}
+	d, err := New(&i2ctest.Faulty{Bus: s, Injector: in}, &Opts{Part: AT24C02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WriteAt([]byte{1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if c := in.Count(); c != 4 {
+		t.Fatal(c)
+	}
+
+	// The write cycle never completes.
+	in = &conntest.Injector{
+		Probability: 1,
+		Faults:      []conntest.Fault{conntest.NACK},
+		Schedule:    map[int]conntest.Fault{0: conntest.NoFault},
+		Logf:        func(string, ...interface{}) {},
+	}
+	d, err = New(&i2ctest.Faulty{Bus: s, Injector: in}, &Opts{Part: AT24C02, WriteCycle: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.WriteAt([]byte{1}, 0); n != 0 || err == nil {
+		t.Fatal(n, err)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package at24 controls the AT24Cxx family of I²C EEPROMs, and the compatible
+// 24xx parts from other vendors.
+//
+// Dev implements io.ReaderAt and io.WriterAt. Writes are split on page
+// boundaries and the end of each write cycle is detected by polling the
+// device until it acknowledges its address again.
+//
+// Parts larger than what their memory address can reach, like the AT24C16 or
+// the AT24CM02, span multiple consecutive I²C addresses; this is handled
+// transparently.
+//
+// Datasheet
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/AT24C01C-AT24C02C-Data-Sheet-DS20006111A.pdf
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/AT24C16C-I2C-Compatible-Two-Wire-Serial-EEPROM-16-Kbit-20006110A.pdf
+//
+// http://ww1.microchip.com/downloads/en/DeviceDoc/AT24C256C-I2C-Compatible-Two-Wire-Serial-EEPROM-256-Kbit-32768x8-20006270A.pdf
+package at24
@@ -0,0 +1,41 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package at24_test
+
+import (
+	"fmt"
+	"log"
+
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/experimental/devices/at24"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Open default I²C bus.
+	bus, err := i2creg.Open("")
+	if err != nil {
+		log.Fatalf("failed to open I²C: %v", err)
+	}
+	defer bus.Close()
+
+	d, err := at24.New(bus, &at24.Opts{Part: at24.AT24C32})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := d.WriteAt([]byte("hello"), 0x100); err != nil {
+		log.Fatal(err)
+	}
+	b := make([]byte, 5)
+	if _, err := d.ReadAt(b, 0x100); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", b)
+}
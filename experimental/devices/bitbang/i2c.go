@@ -18,7 +18,6 @@ import (
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/physic"
-	"periph.io/x/periph/host/cpu"
 )
 
 // SkipAddr can be used to skip the address from being sent.
@@ -26,9 +25,6 @@ const SkipAddr uint16 = 0xFFFF
 
 // New returns an object that communicates I²C over two pins.
 //
-// BUG(maruel): It is close to working but not yet, the signal is incorrect
-// during ACK.
-//
 // It has two special features:
 // - Special address SkipAddr can be used to skip the address from being
 //   communicated
@@ -76,6 +72,9 @@ func (i *I2C) Close() error {
 
 // Tx implements i2c.Bus.
 func (i *I2C) Tx(addr uint16, w, r []byte) error {
+	if addr != SkipAddr && !i2c.ValidAddr(addr) {
+		return errors.New("bitbang-i2c: invalid address")
+	}
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	runtime.LockOSThread()
@@ -84,40 +83,11 @@ func (i *I2C) Tx(addr uint16, w, r []byte) error {
 
 	i.start()
 	defer i.stop()
-	if addr != SkipAddr {
-		if addr > 0xFF {
-			// Page 15, section 3.1.11 10-bit addressing
-			// TODO(maruel): Implement if desired; prefix 0b11110xx.
-			return errors.New("bitbang-i2c: invalid address")
-		}
-		// Page 13, section 3.1.10 The slave address and R/W bit
-		addr <<= 1
-		if len(r) == 0 {
-			addr |= 1
-		}
-		ack, err := i.writeByte(byte(addr))
-		if err != nil {
-			return err
-		}
-		if !ack {
-			return errors.New("bitbang-i2c: got NACK")
-		}
-	}
-	for _, b := range w {
-		ack, err := i.writeByte(b)
-		if err != nil {
-			return err
-		}
-		if !ack {
-			return errors.New("bitbang-i2c: got NACK")
-		}
-	}
-	for x := range r {
-		var err error
-		r[x], err = i.readByte()
-		if err != nil {
-			return err
+	if err := i.tx(addr, w, r); err != nil {
+		if err == errNACK {
+			return &i2c.NACKError{Addr: addr, Err: err}
 		}
+		return err
 	}
 	return nil
 }
@@ -142,6 +112,37 @@ func (i *I2C) SDA() gpio.PinIO {
 
 //
 
+var errNACK = errors.New("bitbang-i2c: got NACK")
+
+// tx does the transaction, after the START condition.
+func (i *I2C) tx(addr uint16, w, r []byte) error {
+	if addr == SkipAddr {
+		if err := i.writeBytes(w...); err != nil {
+			return err
+		}
+		return i.readBytes(r)
+	}
+	if len(w) != 0 || len(r) == 0 {
+		if err := i.writeAddr(addr); err != nil {
+			return err
+		}
+		if err := i.writeBytes(w...); err != nil {
+			return err
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		// Page 13, section 3.1.10; a read after a write needs a repeated START.
+		i.restart()
+		if err := i.readAddr(addr, true); err != nil {
+			return err
+		}
+	} else if err := i.readAddr(addr, false); err != nil {
+		return err
+	}
+	return i.readBytes(r)
+}
+
 // "When CLK is a high level and DIO changes from high to low level, data input
 // starts."
 //
@@ -156,6 +157,81 @@ func (i *I2C) start() {
 	_ = i.scl.Out(gpio.Low)
 }
 
+// restart sends a repeated START condition.
+//
+// Expects SCL low. Ends with SDA and SCL low.
+//
+// Lasts 3/2 cycle.
+func (i *I2C) restart() {
+	// Page 9, section 3.1.4 START and STOP conditions
+	_ = i.sda.Out(gpio.High)
+	i.sleepHalfCycle()
+	_ = i.scl.Out(gpio.High)
+	i.sleepHalfCycle()
+	i.start()
+}
+
+// writeAddr sends the slave address with the R/W bit set to write.
+func (i *I2C) writeAddr(addr uint16) error {
+	if !i2c.Is10Bit(addr) {
+		// Page 13, section 3.1.10 The slave address and R/W bit
+		return i.writeBytes(byte(addr << 1))
+	}
+	// Page 15, section 3.1.11 10-bit addressing
+	// The first byte is 11110XX0, where XX are the two most significant bits of
+	// the address. The second byte is the 8 least significant bits.
+	addr &^= i2c.TenBit
+	return i.writeBytes(0xF0|byte(addr>>7)&0x06, byte(addr))
+}
+
+// readAddr sends the slave address with the R/W bit set to read.
+//
+// A 10-bit slave is addressed for a read by sending the whole address for a
+// write, then a repeated START and the first byte with the R/W bit set to read.
+// When addressed is true, the whole address was already sent in the current
+// transaction.
+func (i *I2C) readAddr(addr uint16, addressed bool) error {
+	if !i2c.Is10Bit(addr) {
+		return i.writeBytes(byte(addr<<1) | 1)
+	}
+	if !addressed {
+		if err := i.writeAddr(addr); err != nil {
+			return err
+		}
+		i.restart()
+	}
+	addr &^= i2c.TenBit
+	return i.writeBytes(0xF1 | byte(addr>>7)&0x06)
+}
+
+// writeBytes writes the bytes b, expecting an ACK for each.
+func (i *I2C) writeBytes(b ...byte) error {
+	for _, c := range b {
+		ack, err := i.writeByte(c)
+		if err != nil {
+			return err
+		}
+		if !ack {
+			return errNACK
+		}
+	}
+	return nil
+}
+
+// readBytes reads len(r) bytes into r.
+//
+// Page 10, section 3.1.6; the last byte is NACKed to tell the slave to release
+// SDA.
+func (i *I2C) readBytes(r []byte) error {
+	for x := range r {
+		var err error
+		if r[x], err = i.readByte(x != len(r)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // "When CLK is a high level and DIO changes from low level to high level, data
 // input ends."
 //
@@ -163,6 +239,8 @@ func (i *I2C) start() {
 func (i *I2C) stop() {
 	// Page 9, section 3.1.4 START and STOP conditions
 	_ = i.scl.Out(gpio.Low)
+	// SDA is released after a NACK.
+	_ = i.sda.Out(gpio.Low)
 	i.sleepHalfCycle()
 	_ = i.scl.Out(gpio.High)
 	i.sleepHalfCycle()
@@ -176,7 +254,7 @@ func (i *I2C) stop() {
 //
 // Expects SDA and SCL low.
 //
-// Ends with SDA low and SCL high.
+// Ends with SDA and SCL low.
 //
 // Lasts 9 cycles.
 func (i *I2C) writeByte(b byte) (bool, error) {
@@ -194,16 +272,17 @@ func (i *I2C) writeByte(b byte) (bool, error) {
 		_ = i.scl.Out(gpio.Low)
 	}
 	// Page 10, section 3.1.6 ACK and NACK
-	// 9th clock is ACK.
+	// 9th clock is ACK. SDA must be released while SCL is low, otherwise a NACK
+	// after a 0 bit would be a STOP condition.
+	// SDA was already set as pull-up.
+	if err := i.sda.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		return false, err
+	}
 	i.sleepHalfCycle()
 	// SCL was already set as pull-up. PullNoChange
 	if err := i.scl.In(gpio.PullUp, gpio.NoEdge); err != nil {
 		return false, err
 	}
-	// SDA was already set as pull-up.
-	if err := i.sda.In(gpio.PullUp, gpio.NoEdge); err != nil {
-		return false, err
-	}
 	// Implement clock stretching, the device may keep the line low.
 	for i.scl.Read() == gpio.Low {
 		i.sleepHalfCycle()
@@ -219,14 +298,14 @@ func (i *I2C) writeByte(b byte) (bool, error) {
 	return ack, nil
 }
 
-// readByte reads 8 bits and an ACK.
+// readByte reads 8 bits then sends an ACK or a NACK.
 //
-// Expects SDA and SCL low.
+// Expects SCL low.
 //
-// Ends with SDA low and SCL high.
+// Ends with SCL low, and SDA low after an ACK.
 //
 // Lasts 9 cycles.
-func (i *I2C) readByte() (byte, error) {
+func (i *I2C) readByte(ack bool) (byte, error) {
 	var b byte
 	if err := i.sda.In(gpio.PullUp, gpio.NoEdge); err != nil {
 		return b, err
@@ -241,18 +320,21 @@ func (i *I2C) readByte() (byte, error) {
 		}
 		_ = i.scl.Out(gpio.Low)
 	}
-	if err := i.sda.Out(gpio.Low); err != nil {
+	// ACK == Low.
+	if err := i.sda.Out(gpio.Level(!ack)); err != nil {
 		return 0, err
 	}
 	i.sleepHalfCycle()
 	_ = i.scl.Out(gpio.High)
 	i.sleepHalfCycle()
+	// Releasing SDA while SCL is high would be a STOP condition.
+	_ = i.scl.Out(gpio.Low)
 	return b, nil
 }
 
 // sleep does a busy loop to act as fast as possible.
 func (i *I2C) sleepHalfCycle() {
-	cpu.Nanospin(i.halfCycle)
+	nanospin(i.halfCycle)
 }
 
 var _ i2c.Bus = &I2C{}
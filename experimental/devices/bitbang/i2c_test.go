@@ -0,0 +1,401 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bitbang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/physic"
+)
+
+func TestI2C_write(t *testing.T) {
+	s := newI2CSim(0x50)
+	b := s.open(t)
+	if err := b.Tx(0x50, []byte{0x01, 0xA5}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The R/W bit is 0 for a write.
+	if tr := s.String(); tr != "S A0 A 01 A A5 A P" {
+		t.Fatal(tr)
+	}
+	if !bytes.Equal(s.got, []byte{0x01, 0xA5}) {
+		t.Fatal(s.got)
+	}
+}
+
+func TestI2C_read(t *testing.T) {
+	s := newI2CSim(0x50)
+	s.data = []byte{0x42, 0x81}
+	b := s.open(t)
+	r := make([]byte, 2)
+	if err := b.Tx(0x50, nil, r); err != nil {
+		t.Fatal(err)
+	}
+	// The R/W bit is 1 for a read; the last byte is NACKed.
+	if tr := s.String(); tr != "S A1 A 42 A 81 N P" {
+		t.Fatal(tr)
+	}
+	if !bytes.Equal(r, s.data) {
+		t.Fatal(r)
+	}
+}
+
+func TestI2C_writeRead(t *testing.T) {
+	s := newI2CSim(0x50)
+	s.data = []byte{0x42}
+	b := s.open(t)
+	r := make([]byte, 1)
+	if err := b.Tx(0x50, []byte{0x10}, r); err != nil {
+		t.Fatal(err)
+	}
+	// A repeated START separates the write from the read.
+	if tr := s.String(); tr != "S A0 A 10 A S A1 A 42 N P" {
+		t.Fatal(tr)
+	}
+	if r[0] != 0x42 || !bytes.Equal(s.got, []byte{0x10}) {
+		t.Fatal(r, s.got)
+	}
+}
+
+func TestI2C_10bit_write(t *testing.T) {
+	s := newI2CSim(0x2A5 | i2c.TenBit)
+	b := s.open(t)
+	if err := b.Tx(0x2A5|i2c.TenBit, []byte{0x01}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 11110 followed by the two most significant bits of the address and the
+	// R/W bit, then the 8 least significant bits.
+	if tr := s.String(); tr != "S F4 A A5 A 01 A P" {
+		t.Fatal(tr)
+	}
+}
+
+func TestI2C_10bit_read(t *testing.T) {
+	s := newI2CSim(0x2A5 | i2c.TenBit)
+	s.data = []byte{0x42}
+	b := s.open(t)
+	r := make([]byte, 1)
+	if err := b.Tx(0x2A5|i2c.TenBit, nil, r); err != nil {
+		t.Fatal(err)
+	}
+	// The whole address is sent for a write, then a repeated START and the
+	// first byte with the R/W bit set to read.
+	if tr := s.String(); tr != "S F4 A A5 A S F5 A 42 N P" {
+		t.Fatal(tr)
+	}
+	if r[0] != 0x42 {
+		t.Fatal(r)
+	}
+}
+
+func TestI2C_10bit_writeRead(t *testing.T) {
+	s := newI2CSim(0x2A5 | i2c.TenBit)
+	s.data = []byte{0x42}
+	b := s.open(t)
+	r := make([]byte, 1)
+	if err := b.Tx(0x2A5|i2c.TenBit, []byte{0x10}, r); err != nil {
+		t.Fatal(err)
+	}
+	if tr := s.String(); tr != "S F4 A A5 A 10 A S F5 A 42 N P" {
+		t.Fatal(tr)
+	}
+	if r[0] != 0x42 || !bytes.Equal(s.got, []byte{0x10}) {
+		t.Fatal(r, s.got)
+	}
+}
+
+func TestI2C_NACK(t *testing.T) {
+	s := newI2CSim(0x50)
+	b := s.open(t)
+	err := b.Tx(0x51, []byte{0x01}, nil)
+	var nack *i2c.NACKError
+	if !errors.As(err, &nack) || nack.Addr != 0x51 {
+		t.Fatal(err)
+	}
+	// The transaction is aborted with a STOP.
+	if tr := s.String(); tr != "S A2 N P" {
+		t.Fatal(tr)
+	}
+
+	// A data byte NACKed by the slave.
+	s.trace = nil
+	s.nackData = true
+	if err := b.Tx(0x50, []byte{0x01, 0x02}, nil); !errors.As(err, &nack) || nack.Addr != 0x50 {
+		t.Fatal(err)
+	}
+	if tr := s.String(); tr != "S A0 A 01 N P" {
+		t.Fatal(tr)
+	}
+}
+
+func TestI2C_invalid_addr(t *testing.T) {
+	s := newI2CSim(0x50)
+	b := s.open(t)
+	if err := b.Tx(0x400, nil, nil); err == nil {
+		t.Fatal("invalid address")
+	}
+	if err := b.Tx(0x400|i2c.TenBit, nil, nil); err == nil {
+		t.Fatal("invalid 10-bit address")
+	}
+	if len(s.trace) != 0 {
+		t.Fatal(s.String())
+	}
+}
+
+//
+
+// i2cSim simulates an I²C slave on the bus driven by the master.
+//
+// SDA is open drain; the line is low when either the master or the slave pulls
+// it low.
+type i2cSim struct {
+	addr     uint16 // Slave address, with i2c.TenBit for a 10-bit address
+	data     []byte // Bytes sent to the master, in a loop
+	nackData bool   // NACK the data bytes written by the master
+	got      []byte // Bytes written by the master
+	// trace has S for START, P for STOP, the bytes in hex followed by A or N
+	// for the ACK bit.
+	trace []string
+
+	scl, sda  i2cPin
+	sclHigh   bool
+	masterLow bool // The master pulls SDA low
+	slaveLow  bool // The slave pulls SDA low
+
+	state     i2cState
+	next      i2cState // State after the 9th clock
+	bit       int      // Bits clocked in the current byte
+	b         byte     // Current byte
+	addressed bool     // A 10-bit address matched since the last STOP
+	pos       int      // Index in data of the byte being sent
+}
+
+type i2cState int
+
+const (
+	i2cIdle      i2cState = iota
+	i2cAddr               // Receiving the first address byte
+	i2cAddr2              // Receiving the second byte of a 10-bit address
+	i2cWrite              // Receiving data bytes
+	i2cRead               // Sending data bytes
+	i2cIgnore             // Not addressed
+	i2cSlaveAck           // 9th clock of a byte received by the slave
+	i2cMasterAck          // 9th clock of a byte sent by the slave
+)
+
+func newI2CSim(addr uint16) *i2cSim {
+	s := &i2cSim{addr: addr, sclHigh: true}
+	s.scl = i2cPin{Pin: gpiotest.Pin{N: "SCL"}, s: s, clk: true}
+	s.sda = i2cPin{Pin: gpiotest.Pin{N: "SDA"}, s: s}
+	nanospin = func(time.Duration) {}
+	return s
+}
+
+func (s *i2cSim) open(t *testing.T) *I2C {
+	b, err := New(&s.scl, &s.sda, 100*physic.KiloHertz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.trace = nil
+	return b
+}
+
+func (s *i2cSim) String() string {
+	return strings.Join(s.trace, " ")
+}
+
+func (s *i2cSim) line() gpio.Level {
+	return gpio.Level(!s.masterLow && !s.slaveLow)
+}
+
+// setSCL is called when the master changes SCL.
+func (s *i2cSim) setSCL(high bool) {
+	if high == s.sclHigh {
+		return
+	}
+	s.sclHigh = high
+	if high {
+		s.rise()
+	} else {
+		s.fall()
+	}
+}
+
+// setSDA is called when the master changes SDA.
+func (s *i2cSim) setSDA(low bool) {
+	before := s.line()
+	s.masterLow = low
+	after := s.line()
+	if !s.sclHigh || before == after {
+		return
+	}
+	if after == gpio.Low {
+		s.trace = append(s.trace, "S")
+		s.state = i2cAddr
+		s.bit = 0
+		s.b = 0
+		return
+	}
+	s.trace = append(s.trace, "P")
+	s.state = i2cIdle
+	s.slaveLow = false
+	s.addressed = false
+}
+
+// rise samples SDA.
+func (s *i2cSim) rise() {
+	switch s.state {
+	case i2cAddr, i2cAddr2, i2cWrite, i2cIgnore:
+		s.b = s.b<<1 | boolToByte(bool(s.line()))
+		s.bit++
+	case i2cRead:
+		s.bit++
+	case i2cSlaveAck, i2cMasterAck:
+		if s.line() == gpio.Low {
+			s.trace = append(s.trace, "A")
+		} else {
+			s.trace = append(s.trace, "N")
+		}
+	}
+}
+
+// fall drives SDA for the next bit.
+func (s *i2cSim) fall() {
+	switch s.state {
+	case i2cAddr, i2cAddr2, i2cWrite, i2cIgnore:
+		if s.bit == 8 {
+			s.trace = append(s.trace, fmt.Sprintf("%02X", s.b))
+			s.slaveLow = s.ack()
+			s.state = i2cSlaveAck
+		}
+	case i2cRead:
+		if s.bit == 8 {
+			s.trace = append(s.trace, fmt.Sprintf("%02X", s.data[s.pos%len(s.data)]))
+			s.slaveLow = false
+			s.state = i2cMasterAck
+			return
+		}
+		s.drive()
+	case i2cSlaveAck:
+		s.slaveLow = false
+		s.bit = 0
+		s.state = s.next
+		if s.state == i2cRead {
+			s.drive()
+		}
+	case i2cMasterAck:
+		s.pos++
+		s.bit = 0
+		if s.line() == gpio.Low {
+			s.state = i2cRead
+			s.drive()
+		} else {
+			s.state = i2cIgnore
+		}
+	}
+}
+
+// ack decides whether the byte received is ACKed and which state follows.
+func (s *i2cSim) ack() bool {
+	b := s.b
+	s.b = 0
+	switch s.state {
+	case i2cAddr:
+		read := b&1 != 0
+		if b&0xF8 == 0xF0 {
+			// 10-bit address prefix.
+			if !i2c.Is10Bit(s.addr) || uint16(b>>1&3) != (s.addr&^i2c.TenBit)>>8 {
+				s.next = i2cIgnore
+				return false
+			}
+			if read {
+				if !s.addressed {
+					s.next = i2cIgnore
+					return false
+				}
+				s.next = i2cRead
+				return true
+			}
+			s.next = i2cAddr2
+			return true
+		}
+		if i2c.Is10Bit(s.addr) || uint16(b>>1) != s.addr {
+			s.next = i2cIgnore
+			return false
+		}
+		s.next = i2cWrite
+		if read {
+			s.next = i2cRead
+		}
+		return true
+	case i2cAddr2:
+		if uint16(b) != s.addr&0xFF {
+			s.next = i2cIgnore
+			return false
+		}
+		s.addressed = true
+		s.next = i2cWrite
+		return true
+	case i2cWrite:
+		s.got = append(s.got, b)
+		s.next = i2cWrite
+		return !s.nackData
+	default:
+		s.next = i2cIgnore
+		return false
+	}
+}
+
+// drive sets SDA to the next bit sent to the master.
+func (s *i2cSim) drive() {
+	s.slaveLow = s.data[s.pos%len(s.data)]&(0x80>>uint(s.bit)) == 0
+}
+
+// i2cPin is one of the lines of the bus.
+type i2cPin struct {
+	gpiotest.Pin
+	s   *i2cSim
+	clk bool
+}
+
+func (p *i2cPin) In(pull gpio.Pull, edge gpio.Edge) error {
+	p.set(gpio.High)
+	return p.Pin.In(pull, edge)
+}
+
+func (p *i2cPin) Out(l gpio.Level) error {
+	p.set(l)
+	return p.Pin.Out(l)
+}
+
+func (p *i2cPin) Read() gpio.Level {
+	if p.clk {
+		return gpio.Level(p.s.sclHigh)
+	}
+	return p.s.line()
+}
+
+func (p *i2cPin) set(l gpio.Level) {
+	if p.clk {
+		p.s.setSCL(bool(l))
+	} else {
+		p.s.setSDA(!bool(l))
+	}
+}
+
+func boolToByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
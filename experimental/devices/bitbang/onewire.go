@@ -0,0 +1,264 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Specification
+//
+// https://www.maximintegrated.com/en/design/technical-documents/app-notes/1/126.html
+
+package bitbang
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/host/cpu"
+)
+
+// NewOneWire returns a 1-wire bus master that bit bangs the protocol on the
+// data pin q.
+//
+// The pin is used in open-drain style: it is driven low, or released as an
+// input with pull-up to let the bus float high. An external 4.7kΩ pull-up
+// resistor is still required on the data line. The strong pull-up requested
+// via onewire.StrongPullup is done by driving the pin high until the next
+// transaction.
+//
+// Only the standard speed is supported. The slots last 70µs and the host must
+// be able to toggle and read the pin within a few µs.
+func NewOneWire(q gpio.PinIO) (*OneWire, error) {
+	if err := q.In(gpio.PullUp, gpio.NoEdge); err != nil {
+		return nil, err
+	}
+	return &OneWire{q: q}, nil
+}
+
+// OneWire represents a 1-wire bus master implemented as bit-banging on a GPIO
+// pin.
+type OneWire struct {
+	mu sync.Mutex
+	q  gpio.PinIO // Data line
+}
+
+func (o *OneWire) String() string {
+	return fmt.Sprintf("bitbang/onewire(%s)", o.q)
+}
+
+// Close implements onewire.BusCloser.
+func (o *OneWire) Close() error {
+	return nil
+}
+
+// Tx implements onewire.Bus.
+//
+// It issues a reset, writes w then reads r. The bus is left strongly pulled
+// up at the end of the transaction if power is onewire.StrongPullup.
+func (o *OneWire) Tx(w, r []byte, power onewire.Pullup) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := o.reset(); err != nil {
+		return err
+	}
+	strong := power == onewire.StrongPullup
+	for i, b := range w {
+		if err := o.writeByte(b, strong && i == len(w)-1 && len(r) == 0); err != nil {
+			return err
+		}
+	}
+	for i := range r {
+		var err error
+		if r[i], err = o.readByte(strong && i == len(r)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search implements onewire.Bus.
+func (o *OneWire) Search(alarmOnly bool) ([]onewire.Address, error) {
+	return onewire.Search(o, alarmOnly)
+}
+
+// SearchTriplet implements onewire.BusSearcher.
+//
+// SearchTriplet should not be used directly, use Search instead.
+func (o *OneWire) SearchTriplet(direction byte) (onewire.TripletResult, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// The devices send their address bit, then its complement. The bus is a
+	// wired AND so a 0 means that at least one device sent a 0.
+	var tr onewire.TripletResult
+	b, err := o.readBit(false)
+	if err != nil {
+		return tr, err
+	}
+	c, err := o.readBit(false)
+	if err != nil {
+		return tr, err
+	}
+	tr.GotZero = !b
+	tr.GotOne = !c
+	if (tr.GotZero && tr.GotOne && direction != 0) || (!tr.GotZero && tr.GotOne) {
+		tr.Taken = 1
+	}
+	return tr, o.writeBit(tr.Taken != 0, false)
+}
+
+// Q implements onewire.Pins.
+func (o *OneWire) Q() gpio.PinIO {
+	return o.q
+}
+
+//
+
+// Standard speed timings, in the naming of the application note 126.
+const (
+	tA = 6 * time.Microsecond   // Write 1 or read low time
+	tB = 64 * time.Microsecond  // Write 1 recovery
+	tC = 60 * time.Microsecond  // Write 0 low time
+	tD = 10 * time.Microsecond  // Write 0 recovery
+	tE = 9 * time.Microsecond   // Read sample delay
+	tF = 55 * time.Microsecond  // Read recovery
+	tH = 480 * time.Microsecond // Reset low time
+	tI = 70 * time.Microsecond  // Presence sample delay
+	tJ = 410 * time.Microsecond // Reset recovery
+)
+
+// nanospin is replaced in unit tests.
+var nanospin = cpu.Nanospin
+
+// reset issues a reset pulse and returns an error if no device answered with
+// a presence pulse.
+func (o *OneWire) reset() error {
+	if err := o.release(); err != nil {
+		return err
+	}
+	if o.q.Read() == gpio.Low {
+		return shortedBusError("bitbang-onewire: bus is shorted")
+	}
+	if err := o.q.Out(gpio.Low); err != nil {
+		return err
+	}
+	nanospin(tH)
+	if err := o.release(); err != nil {
+		return err
+	}
+	nanospin(tI)
+	present := o.q.Read() == gpio.Low
+	nanospin(tJ)
+	if !present {
+		return noDevicesError("bitbang-onewire: no device present")
+	}
+	return nil
+}
+
+// writeByte writes the bits of b, least significant bit first.
+//
+// If strong is true, the bus is strongly pulled up after the last bit.
+func (o *OneWire) writeByte(b byte, strong bool) error {
+	for i := uint(0); i < 8; i++ {
+		if err := o.writeBit(b&(1<<i) != 0, strong && i == 7); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readByte reads 8 bits, least significant bit first.
+//
+// If strong is true, the bus is strongly pulled up after the last bit.
+func (o *OneWire) readByte(strong bool) (byte, error) {
+	var b byte
+	for i := uint(0); i < 8; i++ {
+		v, err := o.readBit(strong && i == 7)
+		if err != nil {
+			return 0, err
+		}
+		if v {
+			b |= 1 << i
+		}
+	}
+	return b, nil
+}
+
+// writeBit does a write slot.
+func (o *OneWire) writeBit(v, strong bool) error {
+	low, rec := tC, tD
+	if v {
+		low, rec = tA, tB
+	}
+	if err := o.q.Out(gpio.Low); err != nil {
+		return err
+	}
+	nanospin(low)
+	if err := o.end(strong); err != nil {
+		return err
+	}
+	nanospin(rec)
+	return nil
+}
+
+// readBit does a read slot.
+func (o *OneWire) readBit(strong bool) (bool, error) {
+	if err := o.q.Out(gpio.Low); err != nil {
+		return false, err
+	}
+	nanospin(tA)
+	if err := o.release(); err != nil {
+		return false, err
+	}
+	nanospin(tE)
+	v := o.q.Read() == gpio.High
+	if strong {
+		if err := o.q.Out(gpio.High); err != nil {
+			return false, err
+		}
+	}
+	nanospin(tF)
+	return v, nil
+}
+
+// end ends the low part of a slot by releasing the bus or by strongly pulling
+// it up.
+func (o *OneWire) end(strong bool) error {
+	if strong {
+		return o.q.Out(gpio.High)
+	}
+	return o.release()
+}
+
+// release lets the bus float high.
+func (o *OneWire) release() error {
+	return o.q.In(gpio.PullUp, gpio.NoEdge)
+}
+
+// noDevicesError implements error, onewire.NoDevicesError and
+// onewire.BusError.
+type noDevicesError string
+
+func (e noDevicesError) Error() string   { return string(e) }
+func (e noDevicesError) NoDevices() bool { return true }
+func (e noDevicesError) BusError() bool  { return true }
+
+// shortedBusError implements error and onewire.ShortedBusError.
+type shortedBusError string
+
+func (e shortedBusError) Error() string   { return string(e) }
+func (e shortedBusError) IsShorted() bool { return true }
+func (e shortedBusError) BusError() bool  { return true }
+
+var _ onewire.BusCloser = &OneWire{}
+var _ onewire.BusSearcher = &OneWire{}
+var _ onewire.Pins = &OneWire{}
+var _ onewire.NoDevicesError = noDevicesError("")
+var _ onewire.ShortedBusError = shortedBusError("")
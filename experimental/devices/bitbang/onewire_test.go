@@ -0,0 +1,452 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bitbang
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/host/cpu"
+)
+
+func TestOneWire(t *testing.T) {
+	d := &simDev{addr: makeAddr(0x28, 1), data: []byte{1, 2, 3}}
+	w := newWire(t, d)
+	defer w.check()
+	o, err := NewOneWire(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := o.String(); s != "bitbang/onewire(Q(0))" {
+		t.Fatal(s)
+	}
+	if o.Q() != w {
+		t.Fatal("unexpected pin")
+	}
+
+	// Read ROM.
+	var r [8]byte
+	if err := o.Tx([]byte{0x33}, r[:], onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	if a := onewire.Address(leUint64(r[:])); a != d.addr {
+		t.Fatalf("0x%016x", uint64(a))
+	}
+	if w.strong {
+		t.Fatal("unexpected strong pull-up")
+	}
+
+	// Skip ROM and a function command, with the strong pull-up.
+	var s [3]byte
+	if err := o.Tx([]byte{0xCC, 0xBE}, s[:], onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s[:], d.data) || !bytes.Equal(d.got, []byte{0xBE}) {
+		t.Fatal(s, d.got)
+	}
+	if !w.strong {
+		t.Fatal("expected strong pull-up")
+	}
+	if err := o.Tx([]byte{0xCC, 0x44}, nil, onewire.StrongPullup); err != nil {
+		t.Fatal(err)
+	}
+	if !w.strong || !bytes.Equal(d.got, []byte{0xBE, 0x44}) {
+		t.Fatal(w.strong, d.got)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOneWire_Search(t *testing.T) {
+	devs := []*simDev{
+		{addr: makeAddr(0x28, 0x123456)},
+		{addr: makeAddr(0x28, 0x123457), alarm: true},
+		{addr: makeAddr(0x3A, 0x00FF00)},
+		{addr: makeAddr(0x2D, 0x800001), alarm: true},
+	}
+	w := newWire(t, devs...)
+	defer w.check()
+	o, err := NewOneWire(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := o.Search(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortAddr(got)
+	want := []onewire.Address{devs[0].addr, devs[1].addr, devs[2].addr, devs[3].addr}
+	sortAddr(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	if got, err = o.Search(true); err != nil {
+		t.Fatal(err)
+	}
+	sortAddr(got)
+	want = []onewire.Address{devs[1].addr, devs[3].addr}
+	sortAddr(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestOneWire_Match(t *testing.T) {
+	devs := []*simDev{
+		{addr: makeAddr(0x28, 1), data: []byte{0x11}},
+		{addr: makeAddr(0x28, 2), data: []byte{0x22}},
+	}
+	w := newWire(t, devs...)
+	defer w.check()
+	o, err := NewOneWire(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := onewire.Dev{Bus: o, Addr: devs[1].addr}
+	var r [1]byte
+	if err := d.Tx([]byte{0xBE}, r[:]); err != nil {
+		t.Fatal(err)
+	}
+	if r[0] != 0x22 || devs[0].got != nil || !bytes.Equal(devs[1].got, []byte{0xBE}) {
+		t.Fatal(r, devs[0].got, devs[1].got)
+	}
+}
+
+func TestOneWire_errors(t *testing.T) {
+	w := newWire(t)
+	defer w.check()
+	o, err := NewOneWire(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Tx([]byte{0x33}, nil, onewire.WeakPullup)
+	if e, ok := err.(onewire.NoDevicesError); !ok || !e.NoDevices() {
+		t.Fatal(err)
+	}
+	if _, err := o.Search(false); err == nil {
+		t.Fatal("expected error")
+	}
+	w.short = true
+	err = o.Tx([]byte{0x33}, nil, onewire.WeakPullup)
+	if e, ok := err.(onewire.ShortedBusError); !ok || !e.IsShorted() {
+		t.Fatal(err)
+	}
+}
+
+func TestOneWire_timing(t *testing.T) {
+	w := newWire(t, &simDev{addr: makeAddr(0x28, 1)})
+	defer w.check()
+	o, err := NewOneWire(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r [8]byte
+	if err := o.Tx([]byte{0x33}, r[:], onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+	// A reset, 8 write slots and 64 read slots of 70µs.
+	if want := tH + tI + tJ + 72*70*time.Microsecond; w.now != want {
+		t.Fatal(w.now, want)
+	}
+	if w.slots != 72 {
+		t.Fatal(w.slots)
+	}
+}
+
+//
+
+// wire simulates a 1-wire bus with devices on the master's data pin.
+//
+// Time is virtual; it is advanced by nanospin. The timings of the slots
+// generated by the master are verified against the standard speed limits of
+// the specification.
+type wire struct {
+	gpiotest.Pin
+	t       *testing.T
+	devices []*simDev
+	short   bool // Simulates a bus shorted to ground
+
+	now     time.Duration
+	low     bool          // Master is driving the bus low
+	strong  bool          // Master is driving the bus high
+	fell    time.Duration // When the master last drove the bus low
+	rose    time.Duration // When the master last released the bus
+	reset   bool          // The last low pulse was a reset
+	holdLow bool          // Devices pull the bus low between from and to
+	from    time.Duration
+	to      time.Duration
+	slots   int
+	errs    []string
+}
+
+func newWire(t *testing.T, d ...*simDev) *wire {
+	w := &wire{Pin: gpiotest.Pin{N: "Q"}, t: t, devices: d}
+	nanospin = func(d time.Duration) { w.now += d }
+	return w
+}
+
+func (w *wire) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull != gpio.PullUp || edge != gpio.NoEdge {
+		w.errorf("unexpected In(%s, %s)", pull, edge)
+	}
+	w.strong = false
+	if w.low {
+		w.rise()
+	}
+	return w.Pin.In(pull, edge)
+}
+
+func (w *wire) Out(l gpio.Level) error {
+	if l == gpio.High {
+		w.strong = true
+		if w.low {
+			w.rise()
+		}
+	} else if !w.low {
+		w.strong = false
+		w.fall()
+	}
+	return w.Pin.Out(l)
+}
+
+func (w *wire) Read() gpio.Level {
+	if w.low || w.short {
+		return gpio.Low
+	}
+	if w.strong {
+		w.errorf("read while strongly pulling up")
+		return gpio.High
+	}
+	if w.holdLow && w.now >= w.from && w.now <= w.to {
+		return gpio.Low
+	}
+	return gpio.High
+}
+
+func (w *wire) fall() {
+	// The master must respect the recovery time after the previous slot or
+	// reset.
+	if w.fell != 0 || w.reset {
+		if w.reset && w.now-w.rose < 480*time.Microsecond {
+			w.errorf("reset high time too short: %s", w.now-w.rose)
+		} else if !w.reset && w.now-w.fell < 61*time.Microsecond {
+			w.errorf("slot too short: %s", w.now-w.fell)
+		}
+	}
+	w.low = true
+	w.fell = w.now
+}
+
+func (w *wire) rise() {
+	w.low = false
+	w.rose = w.now
+	w.holdLow = false
+	p := w.now - w.fell
+	switch {
+	case p >= 480*time.Microsecond:
+		w.reset = true
+		for _, d := range w.devices {
+			d.reset()
+		}
+		if len(w.devices) != 0 {
+			// Presence pulse, after 15µs for 60µs at least.
+			w.holdLow = true
+			w.from = w.now + 15*time.Microsecond
+			w.to = w.now + 75*time.Microsecond
+		}
+		return
+	case p >= time.Microsecond && p <= 15*time.Microsecond:
+		// Write 1 or read slot; devices may pull the bus low for 15µs.
+		w.slots++
+		w.reset = false
+		for _, d := range w.devices {
+			if d.slot(true) {
+				w.holdLow = true
+			}
+		}
+		w.from = w.fell
+		w.to = w.fell + 15*time.Microsecond
+	case p >= 60*time.Microsecond && p <= 120*time.Microsecond:
+		// Write 0 slot.
+		w.slots++
+		w.reset = false
+		for _, d := range w.devices {
+			d.slot(false)
+		}
+	default:
+		w.errorf("invalid low pulse of %s", p)
+	}
+}
+
+func (w *wire) errorf(format string, args ...interface{}) {
+	w.errs = append(w.errs, fmt.Sprintf("%s: ", w.now)+fmt.Sprintf(format, args...))
+}
+
+// check reports the timing violations and restores nanospin.
+func (w *wire) check() {
+	nanospin = cpu.Nanospin
+	for _, e := range w.errs {
+		w.t.Error(e)
+	}
+}
+
+// simDev is a 1-wire device supporting the ROM commands.
+//
+// Once addressed, it records the function command it receives and then
+// replies data.
+type simDev struct {
+	addr  onewire.Address
+	alarm bool
+	data  []byte
+	got   []byte // Function command received
+
+	state int
+	bits  uint64
+	n     uint
+	out   []byte
+	phase int
+}
+
+const (
+	stIdle = iota
+	stROM
+	stMatch
+	stSearch
+	stFunc
+	stSend
+)
+
+func (d *simDev) reset() {
+	d.state = stROM
+	d.bits = 0
+	d.n = 0
+	d.phase = 0
+}
+
+// slot processes a slot and returns true if the device pulls the bus low.
+//
+// v is false for a write 0 slot.
+func (d *simDev) slot(v bool) bool {
+	switch d.state {
+	case stROM:
+		if !d.recv(v, 8) {
+			return false
+		}
+		switch byte(d.bits) {
+		case 0x33: // Read ROM
+			d.send(le64(uint64(d.addr)))
+		case 0x55: // Match ROM
+			d.state = stMatch
+		case 0xCC: // Skip ROM
+			d.state = stFunc
+		case 0xF0: // Search ROM
+			d.state = stSearch
+		case 0xEC: // Alarm search
+			if d.alarm {
+				d.state = stSearch
+			} else {
+				d.state = stIdle
+			}
+		default:
+			d.state = stIdle
+		}
+		d.bits, d.n = 0, 0
+	case stMatch:
+		if !d.recv(v, 64) {
+			return false
+		}
+		if onewire.Address(d.bits) == d.addr {
+			d.state = stFunc
+		} else {
+			d.state = stIdle
+		}
+		d.bits, d.n = 0, 0
+	case stSearch:
+		b := uint64(d.addr)>>d.n&1 != 0
+		switch d.phase {
+		case 0:
+			d.phase = 1
+			return !b
+		case 1:
+			d.phase = 2
+			return b
+		default:
+			d.phase = 0
+			if v != b {
+				d.state = stIdle
+			} else if d.n++; d.n == 64 {
+				d.state = stIdle
+			}
+		}
+	case stFunc:
+		if !d.recv(v, 8) {
+			return false
+		}
+		d.got = append(d.got, byte(d.bits))
+		d.bits, d.n = 0, 0
+		if d.data != nil {
+			d.send(d.data)
+		}
+	case stSend:
+		b := d.out[0]>>d.n&1 != 0
+		if d.n++; d.n == 8 {
+			d.n = 0
+			if d.out = d.out[1:]; len(d.out) == 0 {
+				d.state = stIdle
+			}
+		}
+		return !b
+	}
+	return false
+}
+
+// recv accumulates a bit and returns true once n bits were received.
+func (d *simDev) recv(v bool, n uint) bool {
+	if v {
+		d.bits |= 1 << d.n
+	}
+	d.n++
+	return d.n == n
+}
+
+func (d *simDev) send(b []byte) {
+	d.out = b
+	d.state = stSend
+	d.n = 0
+}
+
+func makeAddr(family byte, serial uint64) onewire.Address {
+	b := le64(uint64(family) | serial<<8)
+	b[7] = onewire.CalcCRC(b[:7])
+	return onewire.Address(leUint64(b))
+}
+
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+	return b
+}
+
+func leUint64(b []byte) uint64 {
+	var v uint64
+	for i := range b {
+		v |= uint64(b[i]) << (8 * uint(i))
+	}
+	return v
+}
+
+func sortAddr(a []onewire.Address) {
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+}
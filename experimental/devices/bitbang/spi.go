@@ -21,7 +21,6 @@ import (
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/physic"
 	"periph.io/x/periph/conn/spi"
-	"periph.io/x/periph/host/cpu"
 )
 
 // NewSPI returns an spi.PortCloser that communicates SPI over 3 or 4 pins.
@@ -61,13 +60,16 @@ func (s *SPI) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, er
 		return nil, errors.New("bitbang-spi: invalid frequency")
 	}
 	if mode&spi.HalfDuplex == spi.HalfDuplex {
-		return nil, errors.New("bitbang-spi: half-duplex mode not supported")
+		return nil, fmt.Errorf("bitbang-spi: half-duplex mode: %w", conn.ErrUnsupported)
 	}
 	if mode&spi.LSBFirst == spi.LSBFirst {
-		return nil, errors.New("bitbang-spi: LSBFirst mode not supported")
+		return nil, fmt.Errorf("bitbang-spi: LSBFirst mode: %w", conn.ErrUnsupported)
 	}
-	if mode >= 0x20 {
-		return nil, fmt.Errorf("bitbang-spi: unhandled mode %d(%s)", mode, mode.String())
+	if mode&(spi.TxDual|spi.TxQuad|spi.RxDual|spi.RxQuad) != 0 {
+		return nil, fmt.Errorf("bitbang-spi: dual and quad modes: %w", conn.ErrUnsupported)
+	}
+	if mode&^(spi.Mode3|spi.NoCS|spi.NoChunk) != 0 {
+		return nil, fmt.Errorf("bitbang-spi: unhandled mode %d(%s): %w", mode, mode.String(), conn.ErrUnsupported)
 	}
 	s.spiConn.mu.Lock()
 	defer s.spiConn.mu.Unlock()
@@ -173,49 +175,65 @@ func (s *spiConn) Tx(w, r []byte) (err error) {
 	if err = s.assertCS(); err != nil {
 		return fmt.Errorf("bitbang-spi: failed to assert chip-select: %v", err)
 	}
+	if err = s.tx(w, r, s.halfCycle, 0); err != nil {
+		return err
+	}
+	if err = s.unassertCS(); err != nil {
+		return fmt.Errorf("bitbang-spi: failed to unassert chip-select: %v", err)
+	}
 
-	for i := uint(0); i < uint(len(w)*8); i++ {
-		if err = s.sdo.Out(w[i/8]&(1<<(i%8)) != 0); err != nil {
-			return fmt.Errorf("bitbang-spi: failed to send bit %d of word %d: %v", i%8, i/8, err)
-		}
+	return nil
+}
 
-		s.sleepHalfCycle()
-		if err = s.sck.Out(!s.clockIdle); err != nil {
-			return fmt.Errorf("bitbang-spi: failed to assert clock: %v", err)
+// TxPackets implements spi.Conn.
+//
+// Speed, DelayAfter and WordDelay are honoured by busy looping.
+func (s *spiConn) TxPackets(p []spi.Packet) error {
+	for i := range p {
+		if len(p[i].W) != 0 && len(p[i].R) != 0 && len(p[i].W) != len(p[i].R) {
+			return errors.New("bitbang-spi: write and read buffers must be the same length")
 		}
-		s.sleepHalfCycle()
-
-		if s.readAfterClockPulse {
-			if err = s.sck.Out(s.clockIdle); err != nil {
-				return fmt.Errorf("bitbang-spi: failed to idle clock: %v", err)
-			}
-			s.sleepHalfCycle()
+		if p[i].Speed < 0 || p[i].DelayAfter < 0 || p[i].WordDelay < 0 {
+			return errors.New("bitbang-spi: invalid packet timing")
 		}
+		if p[i].TxWidth > 1 || p[i].RxWidth > 1 {
+			return fmt.Errorf("bitbang-spi: dual and quad packets: %w", conn.ErrUnsupported)
+		}
+	}
 
-		if len(r) != 0 {
-			if s.sdi.Read() == gpio.High {
-				r[i/8] |= 1 << (i % 8)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.assertCS(); err != nil {
+		return fmt.Errorf("bitbang-spi: failed to assert chip-select: %v", err)
+	}
+	for i := range p {
+		halfCycle := s.halfCycle
+		if f := p[i].Speed; f != 0 {
+			if s.freqPort != 0 && f > s.freqPort {
+				f = s.freqPort
 			}
+			halfCycle = f.Period() / 2
 		}
-
-		if !s.readAfterClockPulse {
-			if err = s.sck.Out(s.clockIdle); err != nil {
-				return fmt.Errorf("bitbang-spi: failed to idle clock: %v", err)
+		if err := s.tx(p[i].W, p[i].R, halfCycle, p[i].WordDelay); err != nil {
+			return err
+		}
+		nanospin(p[i].DelayAfter)
+		if p[i].KeepCS {
+			continue
+		}
+		if err := s.unassertCS(); err != nil {
+			return fmt.Errorf("bitbang-spi: failed to unassert chip-select: %v", err)
+		}
+		if i != len(p)-1 {
+			if err := s.assertCS(); err != nil {
+				return fmt.Errorf("bitbang-spi: failed to assert chip-select: %v", err)
 			}
 		}
 	}
-	if err = s.unassertCS(); err != nil {
-		return fmt.Errorf("bitbang-spi: failed to unassert chip-select: %v", err)
-	}
-
 	return nil
 }
 
-// TxPackets implements spi.Conn.
-func (s *spiConn) TxPackets(p []spi.Packet) error {
-	return errors.New("bitbang-spi: not implemented")
-}
-
 // Write implements io.Writer.
 func (s *spiConn) Write(d []byte) (int, error) {
 	if err := s.Tx(d, nil); err != nil {
@@ -246,9 +264,53 @@ func (s *spiConn) CS() gpio.PinOut {
 
 //
 
+// tx clocks the bits of w and r. Either can be nil.
+func (s *spiConn) tx(w, r []byte, halfCycle, wordDelay time.Duration) (err error) {
+	n := len(w)
+	if n == 0 {
+		n = len(r)
+	}
+	for i := uint(0); i < uint(n*8); i++ {
+		if i != 0 && i%8 == 0 {
+			nanospin(wordDelay)
+		}
+		if len(w) != 0 {
+			if err = s.sdo.Out(w[i/8]&(1<<(i%8)) != 0); err != nil {
+				return fmt.Errorf("bitbang-spi: failed to send bit %d of word %d: %v", i%8, i/8, err)
+			}
+		}
+
+		nanospin(halfCycle)
+		if err = s.sck.Out(!s.clockIdle); err != nil {
+			return fmt.Errorf("bitbang-spi: failed to assert clock: %v", err)
+		}
+		nanospin(halfCycle)
+
+		if s.readAfterClockPulse {
+			if err = s.sck.Out(s.clockIdle); err != nil {
+				return fmt.Errorf("bitbang-spi: failed to idle clock: %v", err)
+			}
+			nanospin(halfCycle)
+		}
+
+		if len(r) != 0 {
+			if s.sdi.Read() == gpio.High {
+				r[i/8] |= 1 << (i % 8)
+			}
+		}
+
+		if !s.readAfterClockPulse {
+			if err = s.sck.Out(s.clockIdle); err != nil {
+				return fmt.Errorf("bitbang-spi: failed to idle clock: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
 // sleep does a busy loop to act as fast as possible.
 func (s *spiConn) sleepHalfCycle() {
-	cpu.Nanospin(s.halfCycle)
+	nanospin(s.halfCycle)
 }
 
 func (s *spiConn) assertCS() error {
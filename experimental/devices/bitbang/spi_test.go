@@ -0,0 +1,260 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package bitbang
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestSPI_Tx(t *testing.T) {
+	b := newSPIBus()
+	c := b.connect(t, physic.MegaHertz)
+	b.in = levels(0x66)
+	r := make([]byte, 1)
+	if err := c.Tx([]byte{0xA5}, r); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b.trace); s != "[10100101]" {
+		t.Fatal(s)
+	}
+	if r[0] != 0x66 {
+		t.Fatalf("0x%02X", r[0])
+	}
+	// The clock runs at the speed specified to Connect().
+	if d := b.rises[1] - b.rises[0]; d != time.Microsecond {
+		t.Fatal(d)
+	}
+	if err := c.Tx([]byte{1}, make([]byte, 2)); err == nil {
+		t.Fatal("buffers of different length")
+	}
+}
+
+func TestSPI_TxPackets(t *testing.T) {
+	b := newSPIBus()
+	c := b.connect(t, physic.MegaHertz)
+	b.in = levels(0x66)
+	r := make([]byte, 1)
+	p := []spi.Packet{
+		{W: []byte{0xA5}, KeepCS: true},
+		{W: []byte{0x81, 0x3C}, Speed: 500 * physic.KiloHertz, WordDelay: 3 * time.Microsecond, DelayAfter: 5 * time.Microsecond},
+		{R: r},
+	}
+	if err := c.TxPackets(p); err != nil {
+		t.Fatal(err)
+	}
+	// CS stays asserted after the first packet, it is toggled after the second
+	// one. MOSI is not driven for the read-only packet.
+	if s := string(b.trace); s != "[101001011000000100111100][........]" {
+		t.Fatal(s)
+	}
+	if r[0] != 0x66 {
+		t.Fatalf("0x%02X", r[0])
+	}
+
+	// Timings of the second packet.
+	rises := b.rises[8:24]
+	for i := 1; i < len(rises); i++ {
+		want := 2 * time.Microsecond
+		if i == 8 {
+			want += 3 * time.Microsecond
+		}
+		if d := rises[i] - rises[i-1]; d != want {
+			t.Fatalf("bit %d: %s != %s", i, d, want)
+		}
+	}
+	if d := b.csHigh[0] - rises[15]; d != 6*time.Microsecond {
+		t.Fatal(d)
+	}
+	// The third packet uses the speed specified to Connect() again.
+	if d := b.rises[25] - b.rises[24]; d != time.Microsecond {
+		t.Fatal(d)
+	}
+}
+
+func TestSPI_TxPackets_LimitSpeed(t *testing.T) {
+	b := newSPIBus()
+	c := b.connect(t, physic.MegaHertz)
+	if err := b.s.LimitSpeed(100 * physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0}, Speed: physic.MegaHertz}}); err != nil {
+		t.Fatal(err)
+	}
+	if d := b.rises[1] - b.rises[0]; d != 10*time.Microsecond {
+		t.Fatal(d)
+	}
+}
+
+func TestSPI_TxPackets_KeepCS(t *testing.T) {
+	b := newSPIBus()
+	c := b.connect(t, physic.MegaHertz)
+	p := []spi.Packet{
+		{W: []byte{0xFF}, KeepCS: true},
+		{W: []byte{0x00}, KeepCS: true},
+	}
+	if err := c.TxPackets(p); err != nil {
+		t.Fatal(err)
+	}
+	// CS is left asserted.
+	if s := string(b.trace); s != "[1111111100000000" {
+		t.Fatal(s)
+	}
+}
+
+func TestSPI_TxPackets_NoCS(t *testing.T) {
+	b := newSPIBus()
+	c, err := b.s.Connect(physic.MegaHertz, spi.Mode0|spi.NoCS, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.trace = nil
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0xFF}}, {W: []byte{0x00}}}); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b.trace); s != "1111111100000000" {
+		t.Fatal(s)
+	}
+}
+
+func TestSPI_TxPackets_err(t *testing.T) {
+	b := newSPIBus()
+	c := b.connect(t, physic.MegaHertz)
+	data := []spi.Packet{
+		{W: []byte{1}, R: make([]byte, 2)},
+		{W: []byte{1}, Speed: -1},
+		{W: []byte{1}, DelayAfter: -1},
+		{W: []byte{1}, WordDelay: -1},
+	}
+	for i, p := range data {
+		if err := c.TxPackets([]spi.Packet{p}); err == nil {
+			t.Fatalf("#%d: expected error", i)
+		}
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}, TxWidth: 2}}); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if len(b.trace) != 0 {
+		t.Fatal(string(b.trace))
+	}
+	b.cs.err = errors.New("oops")
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}}}); err == nil {
+		t.Fatal("expected CS error")
+	}
+}
+
+//
+
+// spiBus records the signals generated by the SPI master in mode 0.
+//
+// Time is virtual; it is advanced by nanospin.
+type spiBus struct {
+	s                   *SPI
+	clk, mosi, miso, cs spiPin
+	in                  []gpio.Level // Levels returned by MISO, one per clock pulse
+
+	now    time.Duration
+	driven bool // MOSI was set since the last clock pulse
+	// trace has '[' and ']' when CS is asserted and released, and the level of
+	// MOSI for each clock pulse, or '.' if MOSI was not driven.
+	trace  []byte
+	rises  []time.Duration // When the clock rose
+	csHigh []time.Duration // When CS was released
+}
+
+func newSPIBus() *spiBus {
+	b := &spiBus{}
+	b.clk.N = "CLK"
+	b.clk.out = func(l gpio.Level) {
+		if l == gpio.High {
+			c := byte('.')
+			if b.driven {
+				c = '0'
+				if b.mosi.L {
+					c = '1'
+				}
+			}
+			b.trace = append(b.trace, c)
+			b.rises = append(b.rises, b.now)
+			b.driven = false
+		}
+	}
+	b.mosi.N = "MOSI"
+	b.mosi.out = func(l gpio.Level) { b.driven = true }
+	b.miso.N = "MISO"
+	b.miso.read = func() gpio.Level {
+		if len(b.in) == 0 {
+			return gpio.Low
+		}
+		l := b.in[0]
+		b.in = b.in[1:]
+		return l
+	}
+	b.cs.N = "CS"
+	b.cs.out = func(l gpio.Level) {
+		if l == gpio.Low {
+			b.trace = append(b.trace, '[')
+		} else {
+			b.trace = append(b.trace, ']')
+			b.csHigh = append(b.csHigh, b.now)
+		}
+	}
+	b.s, _ = NewSPI(&b.clk, &b.mosi, &b.miso, &b.cs)
+	nanospin = func(d time.Duration) { b.now += d }
+	return b
+}
+
+// connect connects in mode 0 and clears the trace of the pins initialization.
+func (b *spiBus) connect(t *testing.T, f physic.Frequency) spi.Conn {
+	c, err := b.s.Connect(f, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.trace = nil
+	b.rises = nil
+	b.csHigh = nil
+	return c
+}
+
+// spiPin is a gpiotest.Pin with hooks.
+type spiPin struct {
+	gpiotest.Pin
+	out  func(l gpio.Level)
+	read func() gpio.Level
+	err  error // Returned by Out()
+}
+
+func (p *spiPin) Out(l gpio.Level) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.out != nil {
+		p.out(l)
+	}
+	return p.Pin.Out(l)
+}
+
+func (p *spiPin) Read() gpio.Level {
+	if p.read != nil {
+		return p.read()
+	}
+	return p.Pin.Read()
+}
+
+// levels returns the bits of v in the order they are clocked in.
+func levels(v byte) []gpio.Level {
+	out := make([]gpio.Level, 8)
+	for i := range out {
+		out[i] = v&(1<<uint(i)) != 0
+	}
+	return out
+}
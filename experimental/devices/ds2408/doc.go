@@ -0,0 +1,25 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ds2408 controls a Maxim DS2408 8 channel addressable switch on a
+// 1-wire bus.
+//
+// Each channel is exposed as a gpio.PinIO, optionally registered in gpioreg.
+// The pins of a device opened via onewire.Open() are not registered.
+//
+// The PIO are open drain: Out(gpio.Low) turns the output transistor on and
+// Out(gpio.High) turns it off, letting the external pull-up resistor pull the
+// pin high. A pin used as an input must have its output transistor off, which
+// is what In() does.
+//
+// The device latches the activity of each channel, so short pulses are not
+// lost between two polls. When edge detection is enabled on a pin, the driver
+// configures the conditional search on the activity latches and WaitForEdge()
+// only reads the device when it answers an alarm search, which keeps the bus
+// traffic low when many devices are polled.
+//
+// Datasheet
+//
+// https://datasheets.maximintegrated.com/en/ds/DS2408.pdf
+package ds2408
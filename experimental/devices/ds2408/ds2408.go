@@ -0,0 +1,529 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2408
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/experimental/devices/internal/pio"
+)
+
+// Opts holds the configuration options.
+type Opts struct {
+	// Name is the prefix of the names of the pins; the pins are named
+	// <Name>_P0 to <Name>_P7. The default is "DS2408_" followed by the
+	// device address in hexadecimal.
+	Name string
+	// Register registers the pins in gpioreg. Call Close() to unregister them.
+	Register bool
+	// PollInterval is the interval at which WaitForEdge() polls the device.
+	PollInterval time.Duration
+}
+
+// DefaultOpts is the recommended default options.
+var DefaultOpts = Opts{
+	PollInterval: 10 * time.Millisecond,
+}
+
+// ConditionalSearch is the configuration of the conditional search.
+//
+// The device answers an alarm search when the selected channels match their
+// polarity.
+type ConditionalSearch struct {
+	// Mask selects the channels participating to the search; bit 0 is P0.
+	Mask uint8
+	// Polarity is the state a selected channel must have to match.
+	Polarity uint8
+	// Activity selects the activity latches instead of the pins states.
+	Activity bool
+	// And requires all the selected channels to match instead of any.
+	And bool
+}
+
+// New opens a handle to a DS2408.
+//
+// It clears the power-on reset flag of the device so it can take part to
+// alarm searches. The pins are registered in gpioreg if opts.Register is true.
+func New(b onewire.Bus, a onewire.Address, opts *Opts) (*Dev, error) {
+	if f := a.Family(); f != onewire.DS2408 {
+		return nil, fmt.Errorf("ds2408: invalid family %s", f)
+	}
+	d := &Dev{onewire: onewire.Dev{Bus: b, Addr: a}, poll: opts.PollInterval}
+	if d.poll <= 0 {
+		d.poll = DefaultOpts.PollInterval
+	}
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("DS2408_%016x", uint64(a))
+	}
+	regs, err := d.readRegs()
+	if err != nil {
+		return nil, err
+	}
+	d.last = regs[regPIO]
+	d.latch = regs[regLatch]
+	d.ctrl = regs[regControl] & ctrlROS
+	d.cond = ConditionalSearch{
+		Mask:     regs[regMask],
+		Polarity: regs[regPolarity],
+		Activity: regs[regControl]&ctrlPLS != 0,
+		And:      regs[regControl]&ctrlCT != 0,
+	}
+	if regs[regControl]&ctrlPORL != 0 {
+		if err := d.writeSearch(d.cond); err != nil {
+			return nil, err
+		}
+	}
+	for i := range d.Pins {
+		d.Pins[i] = &Pin{dev: d, ch: uint8(i), name: fmt.Sprintf("%s_P%d", name, i)}
+	}
+	if opts.Register {
+		if err := pio.Register(d.pins()); err != nil {
+			return nil, err
+		}
+		d.registered = true
+	}
+	return d, nil
+}
+
+// Dev is a handle to a DS2408.
+type Dev struct {
+	// Pins are P0 to P7.
+	Pins [8]*Pin
+
+	onewire    onewire.Dev
+	poll       time.Duration
+	registered bool
+
+	mu       sync.Mutex
+	latch    uint8             // Output latches; bit 0 is P0
+	last     uint8             // Last pins states read from the device
+	edges    uint8             // Channels that changed since they were last waited for
+	edgeMask uint8             // Channels with edge detection enabled
+	ctrl     uint8             // Control bits preserved when writing the control register
+	cond     ConditionalSearch // Current conditional search configuration
+}
+
+func (d *Dev) String() string {
+	return "DS2408{" + d.onewire.String() + "}"
+}
+
+// Halt implements conn.Resource.
+//
+// It doesn't change the outputs.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+// Close unregisters the pins from gpioreg, if they were registered.
+func (d *Dev) Close() error {
+	if !d.registered {
+		return nil
+	}
+	return pio.Unregister(d.pins())
+}
+
+// Activity returns the channels that changed state since the last call and
+// resets the activity latches of the device.
+//
+// Changes consumed by a call to Activity() are still reported by
+// WaitForEdge().
+func (d *Dev) Activity() (uint8, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	regs, err := d.sample()
+	if err != nil {
+		return 0, err
+	}
+	return regs[regActivity], nil
+}
+
+// ConditionalSearch returns the current conditional search configuration.
+func (d *Dev) ConditionalSearch() ConditionalSearch {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cond
+}
+
+// SetConditionalSearch configures the conditions for which the device
+// answers an alarm search.
+//
+// Enabling edge detection on a pin with In() replaces the configuration with
+// a search on the activity latches of the pins with edge detection enabled.
+func (d *Dev) SetConditionalSearch(c ConditionalSearch) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeSearch(c)
+}
+
+// Pin is a PIO of a DS2408.
+type Pin struct {
+	dev  *Dev
+	ch   uint8
+	name string
+
+	edge pio.Edge
+
+	mu  sync.Mutex
+	out bool
+}
+
+// String implements conn.Resource.
+func (p *Pin) String() string {
+	return p.name
+}
+
+// Halt implements conn.Resource.
+//
+// It stops WaitForEdge() and removes the pin from the conditional search used
+// for edge detection.
+func (p *Pin) Halt() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edge.Set(gpio.NoEdge)
+	d := p.dev
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setEdge(p.ch, false)
+}
+
+// Name implements pin.Pin.
+func (p *Pin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+func (p *Pin) Number() int {
+	return int(p.ch)
+}
+
+// Function implements pin.Pin.
+func (p *Pin) Function() string {
+	return string(p.Func())
+}
+
+// Func implements pin.PinFunc.
+func (p *Pin) Func() pin.Func {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.out {
+		return gpio.IN
+	}
+	p.dev.mu.Lock()
+	defer p.dev.mu.Unlock()
+	if p.dev.latch&(1<<p.ch) == 0 {
+		return gpio.OUT_LOW
+	}
+	return gpio.OUT_HIGH
+}
+
+// SupportedFuncs implements pin.PinFunc.
+func (p *Pin) SupportedFuncs() []pin.Func {
+	return []pin.Func{gpio.IN, gpio.OUT}
+}
+
+// SetFunc implements pin.PinFunc.
+func (p *Pin) SetFunc(f pin.Func) error {
+	switch f {
+	case gpio.IN:
+		return p.In(gpio.PullNoChange, gpio.NoEdge)
+	case gpio.OUT_HIGH:
+		return p.Out(gpio.High)
+	case gpio.OUT, gpio.OUT_LOW:
+		return p.Out(gpio.Low)
+	default:
+		return fmt.Errorf("ds2408: function %s: %w", f, conn.ErrUnsupported)
+	}
+}
+
+// In implements gpio.PinIn.
+//
+// It turns the output transistor off. The pin has no internal pull resistor
+// so only gpio.PullNoChange and gpio.Float are supported.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull != gpio.PullNoChange && pull != gpio.Float {
+		return fmt.Errorf("ds2408: pull %s: %w", pull, conn.ErrUnsupported)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := p.dev
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.setLatch(p.ch, true); err != nil {
+		return err
+	}
+	p.out = false
+	p.edge.Set(edge)
+	if err := d.setEdge(p.ch, edge != gpio.NoEdge); err != nil {
+		return err
+	}
+	// Forget about the changes that happened before.
+	_, err := d.sample()
+	d.edges &^= 1 << p.ch
+	return err
+}
+
+// Read implements gpio.PinIn.
+//
+// It returns gpio.Low if the device cannot be read.
+func (p *Pin) Read() gpio.Level {
+	d := p.dev
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	regs, err := d.sample()
+	if err != nil {
+		return gpio.Low
+	}
+	return regs[regPIO]&(1<<p.ch) != 0
+}
+
+// WaitForEdge implements gpio.PinIn.
+//
+// It polls the device at the interval specified in Opts.PollInterval. The
+// device is only read when it answers an alarm search on its activity
+// latches. The direction of the edge is determined by the state of the pin
+// when the activity is detected.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	return pio.WaitForEdge(p, timeout)
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// It returns false as soon as edge detection is changed by Halt(), In() or
+// Out().
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	return p.edge.Wait(ctx, p.dev.poll, func(edge gpio.Edge) bool {
+		l, ok := p.dev.takeEdge(p.ch)
+		return ok && pio.Match(edge, l)
+	})
+}
+
+// Pull implements gpio.PinIn.
+func (p *Pin) Pull() gpio.Pull {
+	return gpio.Float
+}
+
+// DefaultPull implements gpio.PinIn.
+func (p *Pin) DefaultPull() gpio.Pull {
+	return gpio.Float
+}
+
+// Out implements gpio.PinOut.
+//
+// gpio.High turns the output transistor off.
+func (p *Pin) Out(l gpio.Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := p.dev
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.setLatch(p.ch, bool(l)); err != nil {
+		return err
+	}
+	p.out = true
+	p.edge.Set(gpio.NoEdge)
+	return d.setEdge(p.ch, false)
+}
+
+// PWM implements gpio.PinOut.
+func (p *Pin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	return fmt.Errorf("ds2408: PWM: %w", conn.ErrUnsupported)
+}
+
+//
+
+const (
+	cmdReadRegs      = 0xF0 // Read PIO Registers
+	cmdChannelWrite  = 0x5A // Channel-Access Write
+	cmdResetActivity = 0xC3 // Reset Activity Latches
+	cmdWriteSearch   = 0xCC // Write Conditional Search Register
+	confirm          = 0xAA // Confirmation byte
+
+	addrPIO  = 0x88 // Address of the first register
+	addrMask = 0x8B // Address of the conditional search channel selection mask
+)
+
+// Index of the registers read by readRegs.
+const (
+	regPIO      = 0 // PIO logic state
+	regLatch    = 1 // PIO output latch state
+	regActivity = 2 // PIO activity latch state
+	regMask     = 3 // Conditional search channel selection mask
+	regPolarity = 4 // Conditional search channel polarity selection
+	regControl  = 5 // Control/status
+)
+
+// Bits of the control/status register.
+const (
+	ctrlPLS  = 0x01 // Pin or activity latch select
+	ctrlCT   = 0x02 // Conditional search logical term
+	ctrlROS  = 0x04 // RSTZ pin configuration
+	ctrlPORL = 0x08 // Power-on reset latch
+)
+
+func init() {
+	onewire.MustRegisterFamily(onewire.DS2408, "DS2408", func(b onewire.Bus, a onewire.Address) (conn.Resource, error) {
+		return New(b, a, &DefaultOpts)
+	})
+}
+
+// readRegs reads the 8 PIO registers.
+func (d *Dev) readRegs() ([8]byte, error) {
+	var regs [8]byte
+	w := []byte{cmdReadRegs, addrPIO, 0}
+	var r [10]byte
+	if err := d.onewire.Tx(w, r[:]); err != nil {
+		return regs, err
+	}
+	if !onewire.CheckCRC16(append(w, r[:]...)) {
+		return regs, pio.BusError("ds2408: invalid CRC reading the PIO registers")
+	}
+	copy(regs[:], r[:])
+	return regs, nil
+}
+
+// sample reads the registers, records the channels that changed and resets
+// the activity latches.
+//
+// d.mu must be held.
+func (d *Dev) sample() ([8]byte, error) {
+	regs, err := d.readRegs()
+	if err != nil {
+		return regs, err
+	}
+	d.edges |= regs[regActivity] | (regs[regPIO] ^ d.last)
+	d.last = regs[regPIO]
+	if regs[regActivity] != 0 {
+		var r [1]byte
+		if err := d.onewire.Tx([]byte{cmdResetActivity}, r[:]); err != nil {
+			return regs, err
+		}
+		if r[0] != confirm {
+			return regs, pio.BusError("ds2408: activity latches reset was not confirmed")
+		}
+	}
+	return regs, nil
+}
+
+// takeEdge polls the device and returns the state of the channel if it
+// changed since the last call.
+func (d *Dev) takeEdge(ch uint8) (gpio.Level, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.edges&(1<<ch) == 0 && d.activity() {
+		if _, err := d.sample(); err != nil {
+			return gpio.Low, false
+		}
+	}
+	if d.edges&(1<<ch) == 0 {
+		return gpio.Low, false
+	}
+	d.edges &^= 1 << ch
+	return d.last&(1<<ch) != 0, true
+}
+
+// activity returns false when the device doesn't answer an alarm search for
+// its activity latches.
+//
+// It returns true when the conditional search is not configured by the
+// driver or the search fails, so the device is read instead.
+//
+// d.mu must be held.
+func (d *Dev) activity() bool {
+	if d.edgeMask == 0 || d.cond != d.edgeSearch() {
+		return true
+	}
+	addrs, err := d.onewire.Bus.Search(true)
+	if err != nil {
+		return true
+	}
+	for _, a := range addrs {
+		if a == d.onewire.Addr {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeSearch returns the conditional search used for edge detection.
+//
+// d.mu must be held.
+func (d *Dev) edgeSearch() ConditionalSearch {
+	return ConditionalSearch{Mask: d.edgeMask, Polarity: d.edgeMask, Activity: true}
+}
+
+// setEdge enables or disables edge detection on a channel.
+//
+// d.mu must be held.
+func (d *Dev) setEdge(ch uint8, enable bool) error {
+	m := d.edgeMask &^ (1 << ch)
+	if enable {
+		m |= 1 << ch
+	}
+	if m == d.edgeMask {
+		return nil
+	}
+	d.edgeMask = m
+	return d.writeSearch(d.edgeSearch())
+}
+
+// writeSearch writes the conditional search registers and clears the
+// power-on reset flag.
+//
+// d.mu must be held.
+func (d *Dev) writeSearch(c ConditionalSearch) error {
+	ctrl := d.ctrl
+	if c.Activity {
+		ctrl |= ctrlPLS
+	}
+	if c.And {
+		ctrl |= ctrlCT
+	}
+	if err := d.onewire.Tx([]byte{cmdWriteSearch, addrMask, 0, c.Mask, c.Polarity, ctrl}, nil); err != nil {
+		return err
+	}
+	d.cond = c
+	return nil
+}
+
+// setLatch sets the output latch of a channel.
+//
+// d.mu must be held.
+func (d *Dev) setLatch(ch uint8, v bool) error {
+	l := d.latch &^ (1 << ch)
+	if v {
+		l |= 1 << ch
+	}
+	var r [2]byte
+	if err := d.onewire.Tx([]byte{cmdChannelWrite, l, ^l}, r[:]); err != nil {
+		return err
+	}
+	if r[0] != confirm {
+		return pio.BusError("ds2408: channel write was not confirmed")
+	}
+	d.latch = l
+	return nil
+}
+
+func (d *Dev) pins() []gpio.PinIO {
+	out := make([]gpio.PinIO, len(d.Pins))
+	for i, p := range d.Pins {
+		out[i] = p
+	}
+	return out
+}
+
+var _ conn.Resource = &Dev{}
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinInContext = &Pin{}
+var _ pin.PinFunc = &Pin{}
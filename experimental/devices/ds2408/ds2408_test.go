@@ -0,0 +1,345 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2408
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewiretest"
+)
+
+func TestNew(t *testing.T) {
+	data := []struct {
+		name string
+		ops  []onewiretest.IO
+	}{
+		{
+			"power-on reset",
+			[]onewiretest.IO{
+				// Match ROM + Read PIO Registers; the power-on reset flag is set.
+				{
+					W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+					R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x08, 0xff, 0xff, 0xba, 0x87},
+				},
+				// Match ROM + Write Conditional Search Register clears it.
+				{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xcc, 0x8b, 0x00, 0x00, 0x00, 0x00}},
+			},
+		},
+		{
+			"RSTZ configured as strobe",
+			[]onewiretest.IO{
+				{
+					W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+					R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x0c, 0xff, 0xff, 0xfb, 0x46},
+				},
+				// The RSTZ configuration is preserved.
+				{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xcc, 0x8b, 0x00, 0x00, 0x00, 0x04}},
+			},
+		},
+		{
+			"initialized",
+			[]onewiretest.IO{
+				{
+					W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+					R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x45},
+				},
+			},
+		},
+	}
+	for _, line := range data {
+		bus := onewiretest.Playback{Ops: line.ops}
+		d, err := New(&bus, 0x1300000000567829, &Opts{Name: "relays", Register: true})
+		if err != nil {
+			t.Fatal(line.name, err)
+		}
+		if s := d.String(); s != "DS2408{playback(0x1300000000567829)}" {
+			t.Fatal(line.name, s)
+		}
+		if p := gpioreg.ByName("relays_P7"); p != d.Pins[7] {
+			t.Fatal(line.name, p)
+		}
+		if err := d.Halt(); err != nil {
+			t.Fatal(line.name, err)
+		}
+		if err := d.Close(); err != nil {
+			t.Fatal(line.name, err)
+		}
+		if err := bus.Close(); err != nil {
+			t.Fatal(line.name, err)
+		}
+	}
+	if gpioreg.ByName("relays_P0") != nil {
+		t.Fatal("expected pins to be unregistered")
+	}
+}
+
+func TestNew_err(t *testing.T) {
+	data := []struct {
+		name string
+		addr onewire.Address
+		ops  []onewiretest.IO
+	}{
+		{"invalid family", 0x510000000012343a, nil},
+		{"bus error", 0x1300000000567829, nil},
+		{"invalid CRC", 0x1300000000567829, []onewiretest.IO{
+			{
+				W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+				R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x46},
+			},
+		}},
+	}
+	for _, line := range data {
+		bus := &onewiretest.Playback{Ops: line.ops, DontPanic: true}
+		if _, err := New(bus, line.addr, &DefaultOpts); err == nil {
+			t.Fatal(line.name)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	ops := []onewiretest.IO{
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x45},
+		},
+		// Channel-Access Write turning P5's transistor on.
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0x5a, 0xdf, 0x20}, R: []byte{0xaa, 0xdf}},
+		// Read PIO Registers; P5 is low and its activity latch is set.
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xdf, 0xdf, 0x20, 0x00, 0x00, 0x00, 0xff, 0xff, 0x1f, 0xff},
+		},
+		// Reset Activity Latches.
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xc3}, R: []byte{0xaa}},
+		// Channel-Access Write turning P5's transistor off.
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0x5a, 0xff, 0x00}, R: []byte{0xaa, 0xff}},
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x45},
+		},
+	}
+	bus := onewiretest.Playback{Ops: ops}
+	d, err := New(&bus, 0x1300000000567829, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.Pins[5]
+	if p.String() != p.Name() || p.Number() != 5 || p.Pull() != gpio.Float || p.DefaultPull() != gpio.Float {
+		t.Fatal(p)
+	}
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if p.Func() != gpio.OUT_LOW || p.Function() != "Out/Low" {
+		t.Fatal(p.Func())
+	}
+	if p.Read() != gpio.Low {
+		t.Fatal("expected Low")
+	}
+	if err := p.SetFunc(gpio.IN); err != nil {
+		t.Fatal(err)
+	}
+	if p.Func() != gpio.IN || len(p.SupportedFuncs()) != 2 {
+		t.Fatal(p.Func())
+	}
+	// Unsupported, without I/O.
+	if err := p.SetFunc(gpio.FLOAT); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := p.PWM(gpio.DutyHalf, 0); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPin_WaitForEdge(t *testing.T) {
+	ops := []onewiretest.IO{
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x45},
+		},
+		// In(): Channel-Access Write, the conditional search on the activity
+		// latch of P3, then Read PIO Registers.
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0x5a, 0xff, 0x00}, R: []byte{0xaa, 0xff}},
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xcc, 0x8b, 0x00, 0x08, 0x08, 0x01}},
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x00, 0x08, 0x08, 0x01, 0xff, 0xff, 0x89, 0x24},
+		},
+		// Alarm search without answer; the device is not read.
+		{W: []byte{0xec}},
+		// Alarm search answered by the device, which latched a pulse on P3.
+		{W: []byte{0xec}},
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x08, 0x08, 0x08, 0x01, 0xff, 0xff, 0x88, 0x6c},
+		},
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xc3}, R: []byte{0xaa}},
+		// Alarm search by the aborted wait.
+		{W: []byte{0xec}},
+		// Halt() removes P3 from the conditional search.
+		{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xcc, 0x8b, 0x00, 0x00, 0x00, 0x01}},
+	}
+	bus := onewiretest.Playback{Ops: ops}
+	// The poll interval is never reached.
+	d, err := New(&bus, 0x1300000000567829, &Opts{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.Pins[3]
+	if p.WaitForEdge(-1) {
+		t.Fatal("edge detection not enabled")
+	}
+	if err := p.In(gpio.Float, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if c := d.ConditionalSearch(); c != (ConditionalSearch{Mask: 0x08, Polarity: 0x08, Activity: true}) {
+		t.Fatal(c)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("no alarm")
+	}
+	bus.Lock()
+	bus.Devices = []onewire.Address{0x1300000000567829}
+	bus.Unlock()
+	if !p.WaitForEdge(0) {
+		t.Fatal("expected rising edge")
+	}
+
+	// Halt() aborts a wait right away.
+	bus.Lock()
+	bus.Devices = nil
+	bus.Unlock()
+	done := make(chan bool)
+	go func() {
+		done <- p.WaitForEdgeContext(context.Background())
+	}()
+	for {
+		bus.Lock()
+		c := bus.Count
+		bus.Unlock()
+		if c == len(ops)-1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if <-done {
+		t.Fatal("halted")
+	}
+	if gpio.WaitForEdgeContext(context.Background(), p) {
+		t.Fatal("halted")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConditionalSearch(t *testing.T) {
+	data := []struct {
+		c    ConditionalSearch
+		ctrl byte
+	}{
+		{ConditionalSearch{Mask: 0x81, Polarity: 0x01, And: true}, 0x06},
+		{ConditionalSearch{Mask: 0xff, Activity: true}, 0x05},
+	}
+	// RSTZ is configured as a strobe output.
+	ops := []onewiretest.IO{
+		{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x04, 0xff, 0xff, 0x7a, 0x84},
+		},
+	}
+	for _, line := range data {
+		ops = append(ops, onewiretest.IO{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xcc, 0x8b, 0x00, line.c.Mask, line.c.Polarity, line.ctrl}})
+	}
+	ops = append(ops,
+		// Activity() reads the activity latches and resets them.
+		onewiretest.IO{
+			W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+			R: []byte{0xff, 0xff, 0x80, 0xff, 0x00, 0x05, 0xff, 0xff, 0x20, 0x90},
+		},
+		onewiretest.IO{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xc3}, R: []byte{0xaa}},
+	)
+	bus := onewiretest.Playback{Ops: ops}
+	d, err := New(&bus, 0x1300000000567829, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range data {
+		if err := d.SetConditionalSearch(line.c); err != nil {
+			t.Fatal(err)
+		}
+		if c := d.ConditionalSearch(); c != line.c {
+			t.Fatal(c)
+		}
+	}
+	if a, err := d.Activity(); a != 0x80 || err != nil {
+		t.Fatal(a, err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	regs := onewiretest.IO{
+		W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xf0, 0x88, 0x00},
+		R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x45},
+	}
+	data := []struct {
+		name string
+		ops  []onewiretest.IO
+		f    func(d *Dev) error
+	}{
+		{
+			"unconfirmed write",
+			[]onewiretest.IO{{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0x5a, 0xfe, 0x01}, R: []byte{0xff, 0xff}}},
+			func(d *Dev) error { return d.Pins[0].Out(gpio.Low) },
+		},
+		{
+			"invalid CRC",
+			[]onewiretest.IO{{W: regs.W, R: []byte{0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3b, 0x46}}},
+			func(d *Dev) error { _, err := d.Activity(); return err },
+		},
+		{
+			"unconfirmed reset",
+			[]onewiretest.IO{
+				{W: regs.W, R: []byte{0xff, 0xff, 0x20, 0x00, 0x00, 0x00, 0xff, 0xff, 0x3c, 0x25}},
+				{W: []byte{0x55, 0x29, 0x78, 0x56, 0x00, 0x00, 0x00, 0x00, 0x13, 0xc3}, R: []byte{0xff}},
+			},
+			func(d *Dev) error { _, err := d.Activity(); return err },
+		},
+		{
+			"bus error",
+			nil,
+			func(d *Dev) error { return d.SetConditionalSearch(ConditionalSearch{}) },
+		},
+	}
+	for _, line := range data {
+		bus := onewiretest.Playback{Ops: append([]onewiretest.IO{regs}, line.ops...), DontPanic: true}
+		d, err := New(&bus, 0x1300000000567829, &DefaultOpts)
+		if err != nil {
+			t.Fatal(line.name, err)
+		}
+		if err := line.f(d); err == nil {
+			t.Fatal(line.name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2408_test
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/experimental/devices/ds2408"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Open the first available 1-wire bus.
+	b, err := onewirereg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+
+	// The address of the DS2408 is usually found with b.Search(false).
+	d, err := ds2408.New(b, 0x2b00000012345629, &ds2408.Opts{Name: "panel", Register: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Close()
+
+	// The pins are registered in gpioreg.
+	relay := gpioreg.ByName("panel_P0")
+	if err := relay.Out(gpio.Low); err != nil {
+		log.Fatal(err)
+	}
+
+	// Edge detection uses the alarm search, so the device is only read when
+	// one of the inputs changed.
+	button := d.Pins[7]
+	if err := button.In(gpio.Float, gpio.FallingEdge); err != nil {
+		log.Fatal(err)
+	}
+	for button.WaitForEdge(time.Minute) {
+		fmt.Println("button pressed")
+	}
+}
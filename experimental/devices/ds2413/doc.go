@@ -0,0 +1,22 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ds2413 controls a Maxim DS2413 dual channel addressable switch on
+// a 1-wire bus.
+//
+// Each channel is exposed as a gpio.PinIO, optionally registered in gpioreg.
+// The pins of a device opened via onewire.Open() are not registered.
+//
+// The PIO are open drain: Out(gpio.Low) turns the output transistor on and
+// Out(gpio.High) turns it off, letting the external pull-up resistor pull the
+// pin high. A pin used as an input must have its output transistor off, which
+// is what In() does.
+//
+// The device has no interrupt capability so WaitForEdge() polls the state of
+// the pins; pulses shorter than the poll interval can be missed.
+//
+// Datasheet
+//
+// https://datasheets.maximintegrated.com/en/ds/DS2413.pdf
+package ds2413
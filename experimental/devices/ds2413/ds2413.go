@@ -0,0 +1,365 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2413
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/experimental/devices/internal/pio"
+)
+
+// Opts holds the configuration options.
+type Opts struct {
+	// Name is the prefix of the names of the pins; the pins are named
+	// <Name>_PIOA and <Name>_PIOB. The default is "DS2413_" followed by the
+	// device address in hexadecimal.
+	Name string
+	// Register registers the pins in gpioreg. Call Close() to unregister them.
+	Register bool
+	// PollInterval is the interval at which WaitForEdge() polls the state of
+	// the pins.
+	PollInterval time.Duration
+}
+
+// DefaultOpts is the recommended default options.
+var DefaultOpts = Opts{
+	PollInterval: 10 * time.Millisecond,
+}
+
+// New opens a handle to a DS2413.
+//
+// The pins are registered in gpioreg if opts.Register is true.
+func New(b onewire.Bus, a onewire.Address, opts *Opts) (*Dev, error) {
+	if f := a.Family(); f != onewire.DS2413 {
+		return nil, fmt.Errorf("ds2413: invalid family %s", f)
+	}
+	d := &Dev{onewire: onewire.Dev{Bus: b, Addr: a}, poll: opts.PollInterval}
+	if d.poll <= 0 {
+		d.poll = DefaultOpts.PollInterval
+	}
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("DS2413_%016x", uint64(a))
+	}
+	s, err := d.readState()
+	if err != nil {
+		return nil, err
+	}
+	d.last = s
+	d.latch = (s>>1)&1 | (s>>2)&2
+	for i := range d.Pins {
+		d.Pins[i] = &Pin{dev: d, ch: uint8(i), name: name + "_PIO" + string('A'+rune(i))}
+	}
+	if opts.Register {
+		if err := pio.Register(d.pins()); err != nil {
+			return nil, err
+		}
+		d.registered = true
+	}
+	return d, nil
+}
+
+// Dev is a handle to a DS2413.
+type Dev struct {
+	// Pins are PIOA and PIOB.
+	Pins [2]*Pin
+
+	onewire    onewire.Dev
+	poll       time.Duration
+	registered bool
+
+	mu    sync.Mutex
+	latch uint8 // Output latches; bit 0 is PIOA
+	last  uint8 // Last state read from the device
+	edges uint8 // Channels that changed since they were last waited for
+}
+
+func (d *Dev) String() string {
+	return "DS2413{" + d.onewire.String() + "}"
+}
+
+// Halt implements conn.Resource.
+//
+// It doesn't change the outputs.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+// Close unregisters the pins from gpioreg, if they were registered.
+func (d *Dev) Close() error {
+	if !d.registered {
+		return nil
+	}
+	return pio.Unregister(d.pins())
+}
+
+// Pin is a PIO of a DS2413.
+type Pin struct {
+	dev  *Dev
+	ch   uint8
+	name string
+
+	edge pio.Edge
+
+	mu  sync.Mutex
+	out bool
+}
+
+// String implements conn.Resource.
+func (p *Pin) String() string {
+	return p.name
+}
+
+// Halt implements conn.Resource.
+//
+// It stops WaitForEdge().
+func (p *Pin) Halt() error {
+	p.edge.Set(gpio.NoEdge)
+	return nil
+}
+
+// Name implements pin.Pin.
+func (p *Pin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+//
+// It is 0 for PIOA and 1 for PIOB.
+func (p *Pin) Number() int {
+	return int(p.ch)
+}
+
+// Function implements pin.Pin.
+func (p *Pin) Function() string {
+	return string(p.Func())
+}
+
+// Func implements pin.PinFunc.
+func (p *Pin) Func() pin.Func {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.out {
+		return gpio.IN
+	}
+	p.dev.mu.Lock()
+	defer p.dev.mu.Unlock()
+	if p.dev.latch&(1<<p.ch) == 0 {
+		return gpio.OUT_LOW
+	}
+	return gpio.OUT_HIGH
+}
+
+// SupportedFuncs implements pin.PinFunc.
+func (p *Pin) SupportedFuncs() []pin.Func {
+	return []pin.Func{gpio.IN, gpio.OUT}
+}
+
+// SetFunc implements pin.PinFunc.
+func (p *Pin) SetFunc(f pin.Func) error {
+	switch f {
+	case gpio.IN:
+		return p.In(gpio.PullNoChange, gpio.NoEdge)
+	case gpio.OUT_HIGH:
+		return p.Out(gpio.High)
+	case gpio.OUT, gpio.OUT_LOW:
+		return p.Out(gpio.Low)
+	default:
+		return fmt.Errorf("ds2413: function %s: %w", f, conn.ErrUnsupported)
+	}
+}
+
+// In implements gpio.PinIn.
+//
+// It turns the output transistor off. The pin has no internal pull resistor
+// so only gpio.PullNoChange and gpio.Float are supported.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull != gpio.PullNoChange && pull != gpio.Float {
+		return fmt.Errorf("ds2413: pull %s: %w", pull, conn.ErrUnsupported)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.dev.setLatch(p.ch, true); err != nil {
+		return err
+	}
+	p.out = false
+	p.edge.Set(edge)
+	// Forget about the changes that happened before.
+	_, err := p.dev.sample()
+	p.dev.mu.Lock()
+	p.dev.edges &^= 1 << p.ch
+	p.dev.mu.Unlock()
+	return err
+}
+
+// Read implements gpio.PinIn.
+//
+// It returns gpio.Low if the device cannot be read.
+func (p *Pin) Read() gpio.Level {
+	s, err := p.dev.sample()
+	if err != nil {
+		return gpio.Low
+	}
+	return pinLevel(s, p.ch)
+}
+
+// WaitForEdge implements gpio.PinIn.
+//
+// It polls the device at the interval specified in Opts.PollInterval.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	return pio.WaitForEdge(p, timeout)
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// It returns false as soon as edge detection is changed by Halt(), In() or
+// Out().
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	return p.edge.Wait(ctx, p.dev.poll, func(edge gpio.Edge) bool {
+		s, err := p.dev.sample()
+		return err == nil && p.dev.takeEdge(p.ch) && pio.Match(edge, pinLevel(s, p.ch))
+	})
+}
+
+// Pull implements gpio.PinIn.
+func (p *Pin) Pull() gpio.Pull {
+	return gpio.Float
+}
+
+// DefaultPull implements gpio.PinIn.
+func (p *Pin) DefaultPull() gpio.Pull {
+	return gpio.Float
+}
+
+// Out implements gpio.PinOut.
+//
+// gpio.High turns the output transistor off.
+func (p *Pin) Out(l gpio.Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.dev.setLatch(p.ch, bool(l)); err != nil {
+		return err
+	}
+	p.out = true
+	p.edge.Set(gpio.NoEdge)
+	return nil
+}
+
+// PWM implements gpio.PinOut.
+func (p *Pin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	return fmt.Errorf("ds2413: PWM: %w", conn.ErrUnsupported)
+}
+
+//
+
+const (
+	cmdPIORead  = 0xF5 // PIO Access Read
+	cmdPIOWrite = 0x5A // PIO Access Write
+	confirm     = 0xAA // Confirmation byte of a PIO Access Write
+)
+
+func init() {
+	onewire.MustRegisterFamily(onewire.DS2413, "DS2413", func(b onewire.Bus, a onewire.Address) (conn.Resource, error) {
+		return New(b, a, &DefaultOpts)
+	})
+}
+
+// readState reads the PIO status.
+func (d *Dev) readState() (uint8, error) {
+	var r [1]byte
+	if err := d.onewire.Tx([]byte{cmdPIORead}, r[:]); err != nil {
+		return 0, err
+	}
+	return decodeState(r[0])
+}
+
+// sample reads the PIO status and records which pins changed.
+func (d *Dev) sample() (uint8, error) {
+	s, err := d.readState()
+	if err != nil {
+		return 0, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record(s)
+	return s, nil
+}
+
+// record records which pins changed since the last PIO status.
+//
+// d.mu must be held.
+func (d *Dev) record(s uint8) {
+	c := s ^ d.last
+	d.edges |= c&1 | (c>>1)&2
+	d.last = s
+}
+
+// takeEdge returns true if the channel changed since the last call.
+func (d *Dev) takeEdge(ch uint8) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e := d.edges&(1<<ch) != 0
+	d.edges &^= 1 << ch
+	return e
+}
+
+// setLatch sets the output latch of a channel.
+//
+// The device confirms the write with the resulting PIO status, so it is
+// recorded without reading the device again.
+func (d *Dev) setLatch(ch uint8, v bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l := d.latch &^ (1 << ch)
+	if v {
+		l |= 1 << ch
+	}
+	// The unused bits must be 1.
+	w := l | 0xFC
+	var r [2]byte
+	if err := d.onewire.Tx([]byte{cmdPIOWrite, w, ^w}, r[:]); err != nil {
+		return err
+	}
+	if r[0] != confirm {
+		return pio.BusError("ds2413: PIO write was not confirmed")
+	}
+	d.latch = l
+	if s, err := decodeState(r[1]); err == nil {
+		d.record(s)
+	}
+	return nil
+}
+
+func (d *Dev) pins() []gpio.PinIO {
+	return []gpio.PinIO{d.Pins[0], d.Pins[1]}
+}
+
+// decodeState decodes a PIO status byte: bit 0 and 2 are the pins states of
+// PIOA and PIOB, bit 1 and 3 their output latches.
+func decodeState(b byte) (uint8, error) {
+	// The upper nibble is the complement of the lower nibble.
+	if b>>4 != ^b&0x0F {
+		return 0, pio.BusError("ds2413: invalid PIO status 0x" + strconv.FormatUint(uint64(b), 16))
+	}
+	return b & 0x0F, nil
+}
+
+func pinLevel(s, ch uint8) gpio.Level {
+	return s&(1<<(2*ch)) != 0
+}
+
+var _ conn.Resource = &Dev{}
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinInContext = &Pin{}
+var _ pin.PinFunc = &Pin{}
@@ -0,0 +1,244 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2413
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewiretest"
+)
+
+func TestNew(t *testing.T) {
+	ops := []onewiretest.IO{
+		// Match ROM + PIO Access Read; both PIO high.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+	}
+	bus := onewiretest.Playback{Ops: ops}
+	var addr onewire.Address = 0x510000000012343a
+	d, err := New(&bus, addr, &Opts{Name: "door", Register: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "DS2413{playback(0x510000000012343a)}" {
+		t.Fatal(s)
+	}
+	if p := gpioreg.ByName("door_PIOB"); p != d.Pins[1] {
+		t.Fatal(p)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gpioreg.ByName("door_PIOA") != nil {
+		t.Fatal("expected pins to be unregistered")
+	}
+	// Through the family registry; the pins are not registered.
+	r, err := onewire.Open(&bus, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = r.(*Dev)
+	if n := d.Pins[0].Name(); n != "DS2413_510000000012343a_PIOA" || gpioreg.ByName(n) != nil {
+		t.Fatal(n)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew_err(t *testing.T) {
+	data := []struct {
+		name string
+		addr onewire.Address
+		ops  []onewiretest.IO
+	}{
+		{"invalid family", 0x1300000000567829, nil},
+		{"bus error", 0x510000000012343a, nil},
+		// The upper nibble is not the complement of the lower nibble.
+		{"invalid status", 0x510000000012343a, []onewiretest.IO{
+			{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x1f}},
+		}},
+	}
+	for _, line := range data {
+		bus := &onewiretest.Playback{Ops: line.ops, DontPanic: true}
+		if _, err := New(bus, line.addr, &DefaultOpts); err == nil {
+			t.Fatal(line.name)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	ops := []onewiretest.IO{
+		// PIO Access Read; both PIO high.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		// PIO Access Write turning PIOA's transistor on, confirmed with the new
+		// status.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xfe, 0x01}, R: []byte{0xaa, 0x3c}},
+		// PIO Access Read; PIOA low.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x3c}},
+		// PIO Access Write turning PIOA's transistor off.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xff, 0x00}, R: []byte{0xaa, 0x0f}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+	}
+	bus := onewiretest.Playback{Ops: ops}
+	d, err := New(&bus, 0x510000000012343a, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.Pins[0]
+	if p.String() != p.Name() || p.Number() != 0 || d.Pins[1].Number() != 1 || p.Pull() != gpio.Float || p.DefaultPull() != gpio.Float {
+		t.Fatal(p)
+	}
+	if f := p.Func(); f != gpio.IN {
+		t.Fatal(f)
+	}
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if p.Func() != gpio.OUT_LOW || p.Function() != "Out/Low" {
+		t.Fatal(p.Func())
+	}
+	if p.Read() != gpio.Low {
+		t.Fatal("expected Low")
+	}
+	if err := p.SetFunc(gpio.IN); err != nil {
+		t.Fatal(err)
+	}
+	if p.Func() != gpio.IN || len(p.SupportedFuncs()) != 2 {
+		t.Fatal(p.Func())
+	}
+	// Unsupported, without I/O.
+	if err := p.SetFunc(gpio.FLOAT); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := p.In(gpio.PullUp, gpio.NoEdge); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := p.PWM(gpio.DutyHalf, 0); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPin_WaitForEdge(t *testing.T) {
+	ops := []onewiretest.IO{
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		// In(): PIO Access Write then Read.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xff, 0x00}, R: []byte{0xaa, 0x0f}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		// One poll per WaitForEdge(); PIOB pulled low on the second one.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x4b}},
+		// PIOA Out(); the confirmation shows PIOB was released.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xfe, 0x01}, R: []byte{0xaa, 0x3c}},
+		// PIOB pulled low again.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x78}},
+		// In(), then a poll by the aborted wait.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xfe, 0x01}, R: []byte{0xaa, 0x78}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x78}},
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x78}},
+	}
+	bus := onewiretest.Playback{Ops: ops}
+	// The poll interval is never reached.
+	d, err := New(&bus, 0x510000000012343a, &Opts{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.Pins[1]
+	if p.WaitForEdge(-1) {
+		t.Fatal("edge detection not enabled")
+	}
+	if err := p.In(gpio.Float, gpio.FallingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("no edge")
+	}
+	if !p.WaitForEdge(-1) {
+		t.Fatal("expected falling edge")
+	}
+	if err := d.Pins[0].Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	// The rising edge seen in the PIO write confirmation is followed by a
+	// falling edge.
+	if !p.WaitForEdge(0) {
+		t.Fatal("expected falling edge")
+	}
+
+	// Halt() aborts a wait right away.
+	if err := p.In(gpio.Float, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		done <- p.WaitForEdgeContext(context.Background())
+	}()
+	for {
+		bus.Lock()
+		c := bus.Count
+		bus.Unlock()
+		if c == len(ops) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if <-done {
+		t.Fatal("halted")
+	}
+	if gpio.WaitForEdgeContext(context.Background(), p) {
+		t.Fatal("halted")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ops := []onewiretest.IO{
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0x0f}},
+		// Not confirmed.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0x5a, 0xfe, 0x01}, R: []byte{0xff, 0xff}},
+		// Invalid status.
+		{W: []byte{0x55, 0x3a, 0x34, 0x12, 0x00, 0x00, 0x00, 0x00, 0x51, 0xf5}, R: []byte{0xff}},
+	}
+	bus := onewiretest.Playback{Ops: ops, DontPanic: true}
+	d, err := New(&bus, 0x510000000012343a, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := d.Pins[0]
+	if err := p.Out(gpio.Low); err == nil {
+		t.Fatal("expected unconfirmed write")
+	}
+	if p.Func() != gpio.IN {
+		t.Fatal(p.Func())
+	}
+	if p.Read() != gpio.Low {
+		t.Fatal("expected Low on error")
+	}
+	// The playback is exhausted.
+	if err := p.Out(gpio.Low); err == nil {
+		t.Fatal("expected bus error")
+	}
+}
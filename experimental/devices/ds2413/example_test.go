@@ -0,0 +1,51 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2413_test
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/experimental/devices/ds2413"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Open the first available 1-wire bus.
+	b, err := onewirereg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+
+	// The address of the DS2413 is usually found with b.Search(false).
+	d, err := ds2413.New(b, 0x5e0000001234563a, &ds2413.Opts{Name: "door", Register: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Close()
+
+	// The pins are registered in gpioreg.
+	relay := gpioreg.ByName("door_PIOA")
+	if err := relay.Out(gpio.Low); err != nil {
+		log.Fatal(err)
+	}
+	contact := d.Pins[1]
+	if err := contact.In(gpio.Float, gpio.BothEdges); err != nil {
+		log.Fatal(err)
+	}
+	for contact.WaitForEdge(time.Minute) {
+		fmt.Printf("door contact: %s\n", contact.Read())
+	}
+}
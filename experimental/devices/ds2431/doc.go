@@ -0,0 +1,21 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package ds2431 controls the Maxim DS2431 1kb and DS28EC20 20kb EEPROMs on a
+// 1-wire bus.
+//
+// Dev implements io.ReaderAt and io.WriterAt. Writes go through the
+// scratchpad of the device one row at a time: the row is written to the
+// scratchpad, read back and verified with its CRC16, then copied to the
+// memory and read back again.
+//
+// The memory is split in blocks that can be permanently write protected or
+// put in EPROM mode, where bits can only be changed from 1 to 0.
+//
+// Datasheet
+//
+// https://datasheets.maximintegrated.com/en/ds/DS2431.pdf
+//
+// https://datasheets.maximintegrated.com/en/ds/DS28EC20.pdf
+package ds2431
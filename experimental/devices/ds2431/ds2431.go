@@ -0,0 +1,327 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2431
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// Part describes an EEPROM part.
+type Part struct {
+	Name   string
+	Family onewire.Family
+	Size   int // Capacity in bytes
+	// RowSize is the size of the scratchpad; the memory is written one row at
+	// a time.
+	RowSize int
+	// BlockSize is the number of bytes covered by a protection control byte.
+	BlockSize int
+	// ProtectAddr is the address of the write protection control byte of the
+	// first block.
+	ProtectAddr int
+	// EPROMAddr is the address of the EPROM mode control byte of the first
+	// block. It is equal to ProtectAddr when the same byte controls both.
+	EPROMAddr int
+}
+
+// Supported parts.
+var (
+	DS2431   = Part{"DS2431", onewire.DS2431, 128, 8, 32, 0x80, 0x80}
+	DS28EC20 = Part{"DS28EC20", onewire.DS28EC20, 2560, 32, 256, 0xA00, 0xA0A}
+)
+
+// Protection is the protection mode of a block.
+type Protection uint8
+
+// Protection modes.
+const (
+	Unprotected    Protection = iota
+	WriteProtected            // The block cannot be written anymore
+	EPROM                     // Bits of the block can only be changed from 1 to 0
+)
+
+func (p Protection) String() string {
+	switch p {
+	case Unprotected:
+		return "Unprotected"
+	case WriteProtected:
+		return "WriteProtected"
+	case EPROM:
+		return "EPROM"
+	default:
+		return fmt.Sprintf("Protection(%d)", uint8(p))
+	}
+}
+
+// New opens a handle to a DS2431 or a DS28EC20, depending on the family code
+// of the address.
+//
+// The device is not accessed.
+func New(b onewire.Bus, a onewire.Address) (*Dev, error) {
+	for _, p := range []Part{DS2431, DS28EC20} {
+		if a.Family() == p.Family {
+			return &Dev{part: p, onewire: onewire.Dev{Bus: b, Addr: a}}, nil
+		}
+	}
+	return nil, fmt.Errorf("ds2431: unsupported family %s", a.Family())
+}
+
+// Dev is a handle to a 1-wire EEPROM.
+//
+// It implements io.ReaderAt and io.WriterAt.
+type Dev struct {
+	part    Part
+	onewire onewire.Dev
+
+	mu sync.Mutex
+}
+
+func (d *Dev) String() string {
+	return d.part.Name + "{" + d.onewire.String() + "}"
+}
+
+// Halt implements conn.Resource.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+// Part returns the EEPROM part.
+func (d *Dev) Part() Part {
+	return d.part
+}
+
+// ReadAt implements io.ReaderAt.
+func (d *Dev) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ds2431: negative offset")
+	}
+	size := int64(d.part.Size)
+	if off >= size {
+		return 0, io.EOF
+	}
+	n := len(b)
+	if rem := size - off; int64(n) > rem {
+		n = int(rem)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.readMem(int(off), b[:n]); err != nil {
+		return 0, err
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt.
+//
+// The data is written one row at a time; partially written rows are read
+// first. Writing to a write protected block fails, and so does setting a bit
+// to 1 in a block in EPROM mode.
+func (d *Dev) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(b)) > int64(d.part.Size) {
+		return 0, fmt.Errorf("ds2431: write of %d bytes at 0x%X is outside of the %s", len(b), off, d.part.Name)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row := make([]byte, d.part.RowSize)
+	for i := 0; i < len(b); {
+		o := int(off) + i
+		start := o - o%d.part.RowSize
+		l := d.part.RowSize - o%d.part.RowSize
+		if l > len(b)-i {
+			l = len(b) - i
+		}
+		if l != d.part.RowSize {
+			if err := d.readMem(start, row); err != nil {
+				return i, err
+			}
+		}
+		copy(row[o-start:], b[i:i+l])
+		if err := d.writeRow(start, row); err != nil {
+			return i, err
+		}
+		i += l
+	}
+	return len(b), nil
+}
+
+// Protection returns the protection mode of a block.
+func (d *Dev) Protection(block int) (Protection, error) {
+	if err := d.checkBlock(block); err != nil {
+		return Unprotected, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var p, e [1]byte
+	if err := d.readMem(d.part.ProtectAddr+block, p[:]); err != nil {
+		return Unprotected, err
+	}
+	if d.part.EPROMAddr == d.part.ProtectAddr {
+		switch p[0] {
+		case ctrlProtect:
+			return WriteProtected, nil
+		case ctrlEPROM:
+			return EPROM, nil
+		default:
+			return Unprotected, nil
+		}
+	}
+	if isSet(p[0]) {
+		return WriteProtected, nil
+	}
+	if err := d.readMem(d.part.EPROMAddr+block, e[:]); err != nil {
+		return Unprotected, err
+	}
+	if isSet(e[0]) {
+		return EPROM, nil
+	}
+	return Unprotected, nil
+}
+
+// Protect write protects a block.
+//
+// This is permanent.
+func (d *Dev) Protect(block int) error {
+	if err := d.checkBlock(block); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeCtrl(d.part.ProtectAddr+block, ctrlProtect)
+}
+
+// SetEPROM puts a block in EPROM mode.
+//
+// This is permanent.
+func (d *Dev) SetEPROM(block int) error {
+	if err := d.checkBlock(block); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeCtrl(d.part.EPROMAddr+block, ctrlEPROM)
+}
+
+//
+
+const (
+	cmdWriteScratchpad = 0x0F // Write Scratchpad
+	cmdReadScratchpad  = 0xAA // Read Scratchpad
+	cmdCopyScratchpad  = 0x55 // Copy Scratchpad
+	cmdReadMemory      = 0xF0 // Read Memory
+
+	ctrlProtect = 0x55 // Control byte value that enables write protection
+	ctrlEPROM   = 0xAA // Control byte value that enables EPROM mode
+
+	esPF = 0x20 // Partial byte flag of the E/S register
+
+	// tProg is the maximum duration of the copy of the scratchpad to the
+	// memory.
+	tProg = 10 * time.Millisecond
+)
+
+var sleep = time.Sleep
+
+func init() {
+	for _, p := range []Part{DS2431, DS28EC20} {
+		onewire.MustRegisterFamily(p.Family, p.Name, open)
+	}
+}
+
+func open(b onewire.Bus, a onewire.Address) (conn.Resource, error) {
+	return New(b, a)
+}
+
+func (d *Dev) checkBlock(block int) error {
+	if n := d.part.Size / d.part.BlockSize; block < 0 || block >= n {
+		return fmt.Errorf("ds2431: invalid block %d; %s has %d blocks", block, d.part.Name, n)
+	}
+	return nil
+}
+
+// readMem reads the memory starting at addr, including the registers located
+// after the data memory.
+func (d *Dev) readMem(addr int, b []byte) error {
+	return d.onewire.Tx([]byte{cmdReadMemory, byte(addr), byte(addr >> 8)}, b)
+}
+
+// writeCtrl writes a protection control byte.
+func (d *Dev) writeCtrl(addr int, v byte) error {
+	row := make([]byte, d.part.RowSize)
+	start := addr - addr%d.part.RowSize
+	if err := d.readMem(start, row); err != nil {
+		return err
+	}
+	row[addr-start] = v
+	return d.writeRow(start, row)
+}
+
+// writeRow writes a complete row through the scratchpad.
+func (d *Dev) writeRow(addr int, row []byte) error {
+	ta := []byte{byte(addr), byte(addr >> 8)}
+	w := append([]byte{cmdWriteScratchpad}, ta...)
+	w = append(w, row...)
+	var crc [2]byte
+	if err := d.onewire.Tx(w, crc[:]); err != nil {
+		return err
+	}
+	if !onewire.CheckCRC16(append(w, crc[:]...)) {
+		return busError(fmt.Sprintf("ds2431: invalid CRC writing the scratchpad at 0x%X", addr))
+	}
+
+	// Read back the scratchpad, the device returns the target address, the
+	// E/S register and the data.
+	r := make([]byte, 3+len(row)+2)
+	if err := d.onewire.Tx([]byte{cmdReadScratchpad}, r); err != nil {
+		return err
+	}
+	if !onewire.CheckCRC16(append([]byte{cmdReadScratchpad}, r...)) {
+		return busError(fmt.Sprintf("ds2431: invalid CRC reading the scratchpad at 0x%X", addr))
+	}
+	es := r[2]
+	if !bytes.Equal(r[:2], ta) || es&esPF != 0 || int(es)&(len(row)-1) != len(row)-1 || !bytes.Equal(r[3:3+len(row)], row) {
+		return fmt.Errorf("ds2431: scratchpad verification failed at 0x%X; the block may be protected", addr)
+	}
+
+	// Copy the scratchpad to the memory; the target address and the E/S
+	// register authorize the copy.
+	if err := d.onewire.TxPower([]byte{cmdCopyScratchpad, ta[0], ta[1], es}, nil); err != nil {
+		return err
+	}
+	sleep(tProg)
+	got := make([]byte, len(row))
+	if err := d.readMem(addr, got); err != nil {
+		return err
+	}
+	if !bytes.Equal(got, row) {
+		return fmt.Errorf("ds2431: copy of the scratchpad to 0x%X failed", addr)
+	}
+	return nil
+}
+
+// isSet returns true if a control byte of a DS28EC20 is enabled.
+func isSet(v byte) bool {
+	return v == 0x55 || v == 0xAA
+}
+
+// busError implements error and onewire.BusError.
+type busError string
+
+func (e busError) Error() string  { return string(e) }
+func (e busError) BusError() bool { return true }
+
+var _ conn.Resource = &Dev{}
+var _ io.ReaderAt = &Dev{}
+var _ io.WriterAt = &Dev{}
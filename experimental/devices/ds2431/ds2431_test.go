@@ -0,0 +1,346 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2431
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewiretest"
+)
+
+func TestNew(t *testing.T) {
+	a := onewiretest.MakeAddress(byte(onewire.DS2431), 0x42)
+	s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {}}}
+	if _, err := New(s, onewiretest.MakeAddress(0x28, 1)); err == nil {
+		t.Fatal("unsupported family")
+	}
+	d, err := New(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str := d.String(); str != "DS2431{"+(&onewire.Dev{Bus: s, Addr: a}).String()+"}" {
+		t.Fatal(str)
+	}
+	if p := d.Part(); p != DS2431 {
+		t.Fatal(p)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	// Through the family registry.
+	a = onewiretest.MakeAddress(byte(onewire.DS28EC20), 1)
+	r, err := onewire.Open(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := r.(*Dev).Part(); p != DS28EC20 {
+		t.Fatal(p)
+	}
+}
+
+func TestDS2431(t *testing.T) {
+	var sleeps []time.Duration
+	sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { sleep = time.Sleep }()
+	e := &eeprom{part: DS2431, mem: bytes.Repeat([]byte{0xFF}, DS2431.Size+32)}
+	a := onewiretest.MakeAddress(byte(DS2431.Family), 0x42)
+	s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {Funcs: e.funcs()}}}
+	d, err := New(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := []byte("periph.io DS2431 row")
+	if n, err := d.WriteAt(w, 5); n != len(w) || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(e.mem[5:25], w) || e.mem[4] != 0xFF || e.mem[25] != 0xFF {
+		t.Fatal(e.mem)
+	}
+	// Rows are 8 bytes; 3+8+8+1 bytes are written, each row copied with a
+	// strong pull-up.
+	if !reflect.DeepEqual(e.copies, []int{0, 8, 16, 24}) || !reflect.DeepEqual(sleeps, []time.Duration{tProg, tProg, tProg, tProg}) {
+		t.Fatal(e.copies, sleeps)
+	}
+	r := make([]byte, 20)
+	if n, err := d.ReadAt(r, 5); n != len(r) || err != nil || !bytes.Equal(r, w) {
+		t.Fatal(n, err, r)
+	}
+	// Reads stop at the end of the memory.
+	r = make([]byte, 10)
+	if n, err := d.ReadAt(r, 120); n != 8 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if n, err := d.ReadAt(r, 128); n != 0 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if _, err := d.ReadAt(r, -1); err == nil {
+		t.Fatal("negative offset")
+	}
+	if _, err := d.WriteAt(r, 120); err == nil {
+		t.Fatal("write outside of the memory")
+	}
+	if _, err := d.WriteAt(r, -1); err == nil {
+		t.Fatal("negative offset")
+	}
+}
+
+func TestDS28EC20(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	e := &eeprom{part: DS28EC20, mem: bytes.Repeat([]byte{0xFF}, DS28EC20.Size+32)}
+	a := onewiretest.MakeAddress(byte(DS28EC20.Family), 0x42)
+	s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {Funcs: e.funcs()}}}
+	d, err := New(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rows are 32 bytes; the period of 5 bytes doesn't line up with them.
+	w := bytes.Repeat([]byte{0x00, 0xFF, 0x55, 0xAA, 0x0F}, 8)
+	if n, err := d.WriteAt(w, 2520); n != len(w) || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(e.mem[2520:2560], w) || len(e.copies) != 2 {
+		t.Fatal(e.copies)
+	}
+	r := make([]byte, 40)
+	if n, err := d.ReadAt(r, 2520); n != len(r) || err != nil || !bytes.Equal(r, w) {
+		t.Fatal(n, err, r)
+	}
+}
+
+func TestProtection(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	for _, part := range []Part{DS2431, DS28EC20} {
+		e := &eeprom{part: part, mem: bytes.Repeat([]byte{0xFF}, part.Size+32)}
+		a := onewiretest.MakeAddress(byte(part.Family), 0x42)
+		s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {Funcs: e.funcs()}}}
+		d, err := New(s, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := part.Size/part.BlockSize - 1
+		for _, b := range []int{-1, last + 1} {
+			if _, err := d.Protection(b); err == nil {
+				t.Fatal(part.Name, b)
+			}
+			if d.Protect(b) == nil || d.SetEPROM(b) == nil {
+				t.Fatal(part.Name, b)
+			}
+		}
+		if p, err := d.Protection(last); p != Unprotected || err != nil {
+			t.Fatal(part.Name, p, err)
+		}
+
+		// Write protection.
+		if err := d.Protect(last); err != nil {
+			t.Fatal(part.Name, err)
+		}
+		if e.mem[part.ProtectAddr+last] != ctrlProtect {
+			t.Fatal(part.Name, e.mem[part.Size:])
+		}
+		if p, err := d.Protection(last); p != WriteProtected || err != nil {
+			t.Fatal(part.Name, p, err)
+		}
+		if _, err := d.WriteAt([]byte{1}, int64(part.Size-1)); err == nil {
+			t.Fatal(part.Name, "block is write protected")
+		}
+		if e.mem[part.Size-1] != 0xFF {
+			t.Fatal(part.Name, "memory was changed")
+		}
+		if _, err := d.WriteAt([]byte{1}, 0); err != nil {
+			t.Fatal(part.Name, err)
+		}
+
+		// EPROM mode.
+		if err := d.SetEPROM(0); err != nil {
+			t.Fatal(part.Name, err)
+		}
+		if p, err := d.Protection(0); p != EPROM || err != nil {
+			t.Fatal(part.Name, p, err)
+		}
+		if _, err := d.WriteAt([]byte{0x0F}, 1); err != nil {
+			t.Fatal(part.Name, err)
+		}
+		if _, err := d.WriteAt([]byte{0xF0}, 1); err == nil {
+			t.Fatal(part.Name, "bits can't be set in EPROM mode")
+		}
+		if e.mem[1] != 0x0F {
+			t.Fatal(part.Name, e.mem[1])
+		}
+	}
+	// The control byte of a DS2431 can't be changed once set.
+	e := &eeprom{part: DS2431, mem: bytes.Repeat([]byte{0xFF}, DS2431.Size+32)}
+	a := onewiretest.MakeAddress(byte(onewire.DS2431), 0x42)
+	s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {Funcs: e.funcs()}}}
+	d, err := New(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Protect(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetEPROM(2); err == nil {
+		t.Fatal("block is write protected")
+	}
+	if p := WriteProtected.String(); p != "WriteProtected" {
+		t.Fatal(p)
+	}
+	if p := Protection(10).String(); p != "Protection(10)" {
+		t.Fatal(p)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+	e := &eeprom{part: DS2431, mem: bytes.Repeat([]byte{0xFF}, DS2431.Size+32)}
+	a := onewiretest.MakeAddress(byte(DS2431.Family), 0x42)
+	s := &onewiretest.Sim{Devices: map[onewire.Address]*onewiretest.SimDevice{a: {Funcs: e.funcs()}}}
+	d, err := New(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	e.corrupt = cmdWriteScratchpad
+	if _, err := d.WriteAt(w, 0); err == nil {
+		t.Fatal("expected invalid CRC")
+	}
+	e.corrupt = cmdReadScratchpad
+	if _, err := d.WriteAt(w, 0); err == nil {
+		t.Fatal("expected invalid CRC")
+	}
+	e.corrupt = cmdCopyScratchpad
+	if _, err := d.WriteAt(w, 0); err == nil {
+		t.Fatal("expected failed copy")
+	}
+	e.corrupt = 0
+	if len(e.copies) != 0 || e.mem[0] != 0xFF {
+		t.Fatal("unexpected copy")
+	}
+	delete(s.Devices, a)
+	if _, err := d.ReadAt(w, 0); err == nil {
+		t.Fatal("expected bus error")
+	}
+	if _, err := d.WriteAt(w, 4); err == nil {
+		t.Fatal("expected bus error")
+	}
+	if _, err := d.Protection(0); err == nil {
+		t.Fatal("expected bus error")
+	}
+}
+
+//
+
+// eeprom simulates the memory and the scratchpad of a part.
+type eeprom struct {
+	part    Part
+	mem     []byte // Data memory followed by the registers
+	pad     []byte
+	ta      int
+	es      byte
+	copies  []int // Addresses of the rows copied from the scratchpad
+	corrupt byte  // Command for which the response is corrupted
+}
+
+// protection returns the protection of the byte at address a.
+func (e *eeprom) protection(a int) Protection {
+	if a >= e.part.Size {
+		// Protection control bytes can't be changed once set.
+		if isSet(e.mem[a]) {
+			return WriteProtected
+		}
+		return Unprotected
+	}
+	b := a / e.part.BlockSize
+	p := e.mem[e.part.ProtectAddr+b]
+	if e.part.ProtectAddr == e.part.EPROMAddr {
+		switch p {
+		case ctrlProtect:
+			return WriteProtected
+		case ctrlEPROM:
+			return EPROM
+		}
+		return Unprotected
+	}
+	if isSet(p) {
+		return WriteProtected
+	}
+	if isSet(e.mem[e.part.EPROMAddr+b]) {
+		return EPROM
+	}
+	return Unprotected
+}
+
+func (e *eeprom) writeScratchpad(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+	e.ta = int(w[0]) | int(w[1])<<8
+	data := w[2:]
+	e.pad = make([]byte, len(data))
+	for i, v := range data {
+		switch e.protection(e.ta + i) {
+		case WriteProtected:
+			v = e.mem[e.ta+i]
+		case EPROM:
+			v &= e.mem[e.ta+i]
+		}
+		e.pad[i] = v
+	}
+	e.es = byte(len(data) - 1)
+	if e.ta%e.part.RowSize != 0 || len(data) != e.part.RowSize {
+		e.es |= esPF
+	}
+	crc := ^onewire.CalcCRC16(0, append([]byte{cmdWriteScratchpad}, w...))
+	r[0], r[1] = byte(crc), byte(crc>>8)
+	if e.corrupt == cmdWriteScratchpad {
+		r[0] ^= 1
+	}
+	return nil
+}
+
+func (e *eeprom) readScratchpad(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+	b := append([]byte{byte(e.ta), byte(e.ta >> 8), e.es}, e.pad...)
+	crc := ^onewire.CalcCRC16(0, append([]byte{cmdReadScratchpad}, b...))
+	b = append(b, byte(crc), byte(crc>>8))
+	copy(r, b)
+	if e.corrupt == cmdReadScratchpad {
+		r[3] ^= 1
+	}
+	return nil
+}
+
+func (e *eeprom) copyScratchpad(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+	if pull != onewire.StrongPullup {
+		return errors.New("copy without strong pull-up")
+	}
+	if e.corrupt == cmdCopyScratchpad || !bytes.Equal(w, []byte{byte(e.ta), byte(e.ta >> 8), e.es}) || e.es&esPF != 0 {
+		// Not authorized.
+		return nil
+	}
+	copy(e.mem[e.ta:], e.pad)
+	e.es |= 0x80
+	e.copies = append(e.copies, e.ta)
+	return nil
+}
+
+func (e *eeprom) readMemory(d *onewiretest.SimDevice, w, r []byte, pull onewire.Pullup) error {
+	copy(r, e.mem[int(w[0])|int(w[1])<<8:])
+	return nil
+}
+
+// funcs returns the function commands of the simulated device.
+func (e *eeprom) funcs() map[byte]onewiretest.SimFunc {
+	return map[byte]onewiretest.SimFunc{
+		cmdWriteScratchpad: e.writeScratchpad,
+		cmdReadScratchpad:  e.readScratchpad,
+		cmdCopyScratchpad:  e.copyScratchpad,
+		cmdReadMemory:      e.readMemory,
+	}
+}
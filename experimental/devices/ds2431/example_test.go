@@ -0,0 +1,53 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package ds2431_test
+
+import (
+	"fmt"
+	"log"
+
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/experimental/devices/ds2431"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Open the first available 1-wire bus.
+	b, err := onewirereg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Close()
+
+	// Find the first EEPROM on the bus.
+	addrs, err := b.Search(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, a := range addrs {
+		if a.Family() != onewire.DS2431 && a.Family() != onewire.DS28EC20 {
+			continue
+		}
+		d, err := ds2431.New(b, a)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := d.WriteAt([]byte("hello"), 0x10); err != nil {
+			log.Fatal(err)
+		}
+		buf := make([]byte, 5)
+		if _, err := d.ReadAt(buf, 0x10); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: %s\n", d, buf)
+		return
+	}
+}
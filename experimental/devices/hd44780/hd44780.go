@@ -24,6 +24,9 @@ const lineTwo = 0x40
 type Dev struct {
 	// data pins
 	dataPins []gpio.PinOut
+	// dataGroup drives the data pins in one operation, if they implement
+	// gpio.PinIO.
+	dataGroup gpio.Group
 
 	// register select pin
 	rsPin gpio.PinOut
@@ -45,6 +48,11 @@ func New(data []gpio.PinOut, rs, e gpio.PinOut) (*Dev, error) {
 		enablePin: e,
 		rsPin:     rs,
 	}
+	if pins, ok := pinIOs(data); ok {
+		if g, err := gpio.NewGroup(pins...); err == nil {
+			dev.dataGroup = g
+		}
+	}
 	if err := dev.Reset(); err != nil {
 		return nil, err
 	}
@@ -153,15 +161,21 @@ func (r *Dev) bulkSendData(seq [][]uint, f func(_data uint8) error) error {
 }
 
 func (r *Dev) clearBits() error {
-	for _, v := range r.dataPins {
-		if err := v.Out(gpio.Low); err != nil {
-			return err
-		}
-	}
-	return nil
+	return r.writeBits(0)
 }
 
 func (r *Dev) write4Bits(data uint8) error {
+	if err := r.writeBits(data); err != nil {
+		return err
+	}
+	return r.strobe()
+}
+
+// writeBits sets the data pins to the lower 4 bits of data.
+func (r *Dev) writeBits(data uint8) error {
+	if r.dataGroup != nil {
+		return r.dataGroup.Out(uint64(data), 0xF)
+	}
 	for i, v := range r.dataPins {
 		if data&(1<<uint(i)) > 0 {
 			if err := v.Out(gpio.High); err != nil {
@@ -173,7 +187,7 @@ func (r *Dev) write4Bits(data uint8) error {
 			}
 		}
 	}
-	return r.strobe()
+	return nil
 }
 
 func (r *Dev) sendInstruction() error {
@@ -214,6 +228,18 @@ func (r *Dev) strobe() error {
 	return r.enablePin.Out(gpio.Low)
 }
 
+// pinIOs returns pins as gpio.PinIO if they all implement it.
+func pinIOs(pins []gpio.PinOut) ([]gpio.PinIO, bool) {
+	out := make([]gpio.PinIO, len(pins))
+	for i, p := range pins {
+		var ok bool
+		if out[i], ok = p.(gpio.PinIO); !ok {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
 func delayUs(ms uint) {
 	time.Sleep(time.Duration(ms) * time.Microsecond)
 }
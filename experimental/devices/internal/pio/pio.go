@@ -0,0 +1,126 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package pio contains code shared between the ds2408 and ds2413 1-wire
+// addressable switch drivers.
+package pio
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+)
+
+// Register registers the pins in gpioreg.
+//
+// On failure, the pins already registered are unregistered.
+func Register(pins []gpio.PinIO) error {
+	for i, p := range pins {
+		if err := gpioreg.Register(p); err != nil {
+			_ = Unregister(pins[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// Unregister unregisters the pins from gpioreg.
+//
+// It returns the first error.
+func Unregister(pins []gpio.PinIO) error {
+	var err error
+	for _, p := range pins {
+		if err2 := gpioreg.Unregister(p.Name()); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// WaitForEdge implements gpio.PinIn.WaitForEdge() on top of
+// gpio.PinInContext.WaitForEdgeContext().
+//
+// Specify -1 to disable the timeout.
+func WaitForEdge(p gpio.PinInContext, timeout time.Duration) bool {
+	ctx := context.Background()
+	if timeout >= 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return p.WaitForEdgeContext(ctx)
+}
+
+// Edge is the edge detection of a pin.
+//
+// The zero value is gpio.NoEdge.
+type Edge struct {
+	mu    sync.Mutex
+	edge  gpio.Edge
+	abort chan struct{} // Closed when edge is changed
+}
+
+// Get returns the edge detection.
+func (e *Edge) Get() gpio.Edge {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.edge
+}
+
+// Set changes the edge detection and aborts the pending Wait() calls.
+func (e *Edge) Set(edge gpio.Edge) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.edge = edge
+	if e.abort != nil {
+		close(e.abort)
+		e.abort = nil
+	}
+}
+
+// Wait calls poll at the specified interval until it returns true.
+//
+// It returns false right away if edge detection is disabled, and as soon as
+// ctx is done or Set() is called.
+func (e *Edge) Wait(ctx context.Context, interval time.Duration, poll func(edge gpio.Edge) bool) bool {
+	e.mu.Lock()
+	edge := e.edge
+	if edge == gpio.NoEdge {
+		e.mu.Unlock()
+		return false
+	}
+	if e.abort == nil {
+		e.abort = make(chan struct{})
+	}
+	abort := e.abort
+	e.mu.Unlock()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if poll(edge) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-abort:
+			return false
+		case <-t.C:
+		}
+	}
+}
+
+// Match returns true if a pin that changed to level l matches edge.
+func Match(edge gpio.Edge, l gpio.Level) bool {
+	return edge == gpio.BothEdges || (edge == gpio.RisingEdge) == bool(l)
+}
+
+// BusError implements error and onewire.BusError.
+type BusError string
+
+func (e BusError) Error() string  { return string(e) }
+func (e BusError) BusError() bool { return true }
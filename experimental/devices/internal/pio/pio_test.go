@@ -0,0 +1,118 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package pio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/onewire"
+)
+
+func TestRegister(t *testing.T) {
+	a := &gpiotest.Pin{N: "pio_a", Num: -1}
+	b := &gpiotest.Pin{N: "pio_b", Num: -1}
+	if err := Register([]gpio.PinIO{a}); err != nil {
+		t.Fatal(err)
+	}
+	// b is unregistered when a conflicts.
+	if err := Register([]gpio.PinIO{b, a}); err == nil {
+		t.Fatal("a is already registered")
+	}
+	if gpioreg.ByName("pio_b") != nil {
+		t.Fatal("b was not unregistered")
+	}
+	if err := Unregister([]gpio.PinIO{a}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unregister([]gpio.PinIO{a, b}); err == nil {
+		t.Fatal("not registered")
+	}
+}
+
+func TestWaitForEdge(t *testing.T) {
+	p := &gpiotest.Pin{EdgesChan: make(chan gpio.Level, 1)}
+	p.EdgesChan <- gpio.High
+	if !WaitForEdge(p, -1) {
+		t.Fatal("expected edge")
+	}
+	if WaitForEdge(p, 0) {
+		t.Fatal("timeout")
+	}
+}
+
+func TestEdge(t *testing.T) {
+	var e Edge
+	polls := 0
+	poll := func(edge gpio.Edge) bool {
+		polls++
+		return polls == 3
+	}
+	if e.Get() != gpio.NoEdge || e.Wait(context.Background(), time.Millisecond, poll) || polls != 0 {
+		t.Fatal("edge detection is not enabled")
+	}
+	e.Set(gpio.RisingEdge)
+	if !e.Wait(context.Background(), time.Millisecond, poll) || polls != 3 {
+		t.Fatal(polls)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if e.Wait(ctx, time.Hour, poll) || polls != 4 {
+		t.Fatal(polls)
+	}
+	// Set() aborts the wait right away.
+	e.Set(gpio.BothEdges)
+	done := make(chan bool)
+	go func() {
+		done <- e.Wait(context.Background(), time.Hour, func(gpio.Edge) bool { return false })
+	}()
+	for {
+		e.mu.Lock()
+		waiting := e.abort != nil
+		e.mu.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	e.Set(gpio.FallingEdge)
+	if <-done {
+		t.Fatal("aborted")
+	}
+	if e.Get() != gpio.FallingEdge {
+		t.Fatal(e.Get())
+	}
+}
+
+func TestMatch(t *testing.T) {
+	data := []struct {
+		edge gpio.Edge
+		l    gpio.Level
+		want bool
+	}{
+		{gpio.RisingEdge, gpio.High, true},
+		{gpio.RisingEdge, gpio.Low, false},
+		{gpio.FallingEdge, gpio.Low, true},
+		{gpio.FallingEdge, gpio.High, false},
+		{gpio.BothEdges, gpio.Low, true},
+		{gpio.BothEdges, gpio.High, true},
+	}
+	for i, line := range data {
+		if got := Match(line.edge, line.l); got != line.want {
+			t.Fatal(i, got)
+		}
+	}
+}
+
+func TestBusError(t *testing.T) {
+	var err error = BusError("pio: oops")
+	if b, ok := err.(onewire.BusError); !ok || !b.BusError() || err.Error() != "pio: oops" {
+		t.Fatal(err)
+	}
+}
@@ -137,6 +137,27 @@ func TestSense(t *testing.T) {
 	}
 }
 
+func TestSense_Sim(t *testing.T) {
+	d := &i2ctest.SimDevice{RegWidth: 2, Regs: make([]byte, 2*(resolutionConfig+1))}
+	// 25°C.
+	d.Regs[2*temperature], d.Regs[2*temperature+1] = 0x01, 0x90
+	bus := i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{0x18: d}}
+	dev, err := New(&bus, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Regs[2*resolutionConfig] != 0x03 {
+		t.Fatalf("resolution not set: %#x", d.Regs[2*resolutionConfig])
+	}
+	e := physic.Env{}
+	if err := dev.Sense(&e); err != nil {
+		t.Fatal(err)
+	}
+	if want := physic.ZeroCelsius + 25*physic.Kelvin; e.Temperature != want {
+		t.Fatalf("expected %s, got %s", want, e.Temperature)
+	}
+}
+
 func TestSenseContinuous(t *testing.T) {
 	tests := []struct {
 		name     string
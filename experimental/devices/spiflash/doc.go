@@ -0,0 +1,22 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package spiflash controls JEDEC compatible SPI NOR flash chips, like the
+// Winbond W25Qxx and the Macronix MX25 families.
+//
+// The geometry of the chip is read from its SFDP (Serial Flash Discoverable
+// Parameters) tables when available, otherwise it is derived from its JEDEC ID.
+//
+// Dev implements io.ReaderAt and io.WriterAt. WriteAt() transparently erases
+// the sectors that need it, preserving the data around the written range.
+//
+// Datasheets
+//
+// JESD216 Serial Flash Discoverable Parameters:
+// https://www.jedec.org/standards-documents/docs/jesd216b
+//
+// https://www.winbond.com/resource-files/w25q128jv%20revf%2003272018%20plus.pdf
+//
+// https://www.macronix.com/Lists/Datasheet/Attachments/7425/MX25L12835F,%203V,%20128Mb,%20v1.6.pdf
+package spiflash
@@ -0,0 +1,47 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spiflash_test
+
+import (
+	"fmt"
+	"log"
+
+	"periph.io/x/periph/conn/spi/spireg"
+	"periph.io/x/periph/experimental/devices/spiflash"
+	"periph.io/x/periph/host"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Use spireg SPI port registry to find the first available SPI bus.
+	p, err := spireg.Open("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer p.Close()
+
+	d, err := spiflash.New(p, &spiflash.DefaultOpts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := d.Geometry()
+	fmt.Printf("%s: %d bytes\n", d.ID(), g.Size)
+
+	// Store a configuration block in the last sector; the sector is erased as
+	// needed.
+	off := g.Size - g.Erase[0].Size
+	if _, err := d.WriteAt([]byte("hello"), off); err != nil {
+		log.Fatal(err)
+	}
+	b := make([]byte, 5)
+	if _, err := d.ReadAt(b, off); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", b)
+}
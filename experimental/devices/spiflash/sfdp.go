@@ -0,0 +1,99 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spiflash
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// readSFDP reads the geometry from the SFDP basic flash parameter table.
+//
+// Returns false if the chip doesn't support SFDP.
+func (d *Dev) readSFDP() (Geometry, bool, error) {
+	var hdr [8]byte
+	if err := d.readSFDPBytes(0, hdr[:]); err != nil {
+		return Geometry{}, false, err
+	}
+	if string(hdr[:4]) != "SFDP" {
+		return Geometry{}, false, nil
+	}
+	ph := make([]byte, 8*(int(hdr[6])+1))
+	if err := d.readSFDPBytes(8, ph); err != nil {
+		return Geometry{}, false, err
+	}
+	for ; len(ph) != 0; ph = ph[8:] {
+		// The basic flash parameter table has ID 0xFF00.
+		if ph[0] != 0x00 || ph[7] != 0xFF {
+			continue
+		}
+		l := 4 * int(ph[3])
+		if l < 4*9 {
+			return Geometry{}, false, fmt.Errorf("spiflash: SFDP basic flash parameter table is too short; %d bytes", l)
+		}
+		t := make([]byte, l)
+		if err := d.readSFDPBytes(uint32(ph[4])|uint32(ph[5])<<8|uint32(ph[6])<<16, t); err != nil {
+			return Geometry{}, false, err
+		}
+		g, err := parseBFPT(t)
+		return g, err == nil, err
+	}
+	return Geometry{}, false, errors.New("spiflash: SFDP basic flash parameter table not found")
+}
+
+func (d *Dev) readSFDPBytes(off uint32, b []byte) error {
+	for len(b) != 0 {
+		hdr := []byte{0x5A, byte(off >> 16), byte(off >> 8), byte(off), 0}
+		n := d.chunk(len(hdr), len(b))
+		if err := d.tx(hdr, nil, b[:n]); err != nil {
+			return err
+		}
+		off += uint32(n)
+		b = b[n:]
+	}
+	return nil
+}
+
+// parseBFPT decodes the basic flash parameter table as defined in JESD216.
+//
+// The DWORDs are numbered from 1 like in the specification.
+func parseBFPT(t []byte) (Geometry, error) {
+	dw := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(t[4*(i-1):])
+	}
+	g := Geometry{PageSize: 256, SFDP: true}
+	if d := dw(2); d&0x80000000 == 0 {
+		g.Size = (int64(d) + 1) / 8
+	} else if n := d &^ 0x80000000; n >= 3 && n < 63 {
+		g.Size = 1 << (n - 3)
+	}
+	if g.Size == 0 {
+		return g, fmt.Errorf("spiflash: invalid SFDP density 0x%08X", dw(2))
+	}
+	switch (dw(1) >> 17) & 3 {
+	case 1:
+		// 3 or 4 bytes addresses.
+		g.Addr4 = g.Size > 1<<24
+	case 2:
+		// 4 bytes addresses only.
+		g.Addr4 = true
+	}
+	for _, v := range []uint32{dw(8), dw(9)} {
+		for _, x := range []uint32{v & 0xFFFF, v >> 16} {
+			if n := x & 0xFF; n != 0 && n < 32 {
+				g.Erase = append(g.Erase, EraseOp{Size: 1 << n, Opcode: byte(x >> 8)})
+			}
+		}
+	}
+	sort.Slice(g.Erase, func(i, j int) bool { return g.Erase[i].Size < g.Erase[j].Size })
+	if len(t) >= 4*11 {
+		if n := (dw(11) >> 4) & 0xF; n != 0 {
+			g.PageSize = 1 << n
+		}
+	}
+	return g, nil
+}
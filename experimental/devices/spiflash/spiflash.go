@@ -0,0 +1,597 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spiflash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Opts holds the configuration options.
+type Opts struct {
+	// Freq is the SPI clock frequency. Fast read is always used, so the chip
+	// can be clocked at its maximum speed.
+	Freq physic.Frequency
+}
+
+// DefaultOpts is the recommended default options.
+var DefaultOpts = Opts{
+	Freq: 20 * physic.MegaHertz,
+}
+
+// Status register bits common to most chips.
+const (
+	StatusBusy byte = 0x01 // Program, erase or status write in progress
+	StatusWEL  byte = 0x02 // Write enable latch
+	StatusBP   byte = 0x1C // Block protect bits BP0 to BP2
+	StatusSRP  byte = 0x80 // Status register protect, effective with /WP low
+)
+
+// ID is the JEDEC ID of a chip.
+type ID struct {
+	Manufacturer byte
+	Type         byte
+	Capacity     byte
+}
+
+func (i ID) String() string {
+	if n, ok := manufacturers[i.Manufacturer]; ok {
+		return fmt.Sprintf("%s 0x%02X%02X", n, i.Type, i.Capacity)
+	}
+	return fmt.Sprintf("0x%02X%02X%02X", i.Manufacturer, i.Type, i.Capacity)
+}
+
+// EraseOp is a sector or block erase operation supported by a chip.
+type EraseOp struct {
+	Size   int64
+	Opcode byte
+}
+
+// Geometry describes the memory organization of a chip.
+type Geometry struct {
+	// Size is the capacity in bytes.
+	Size int64
+	// PageSize is the maximum number of bytes programmed at once.
+	PageSize int
+	// Erase lists the supported sector and block erase operations, smallest
+	// first.
+	Erase []EraseOp
+	// Addr4 is true when the chip is accessed with 4 bytes addresses.
+	Addr4 bool
+	// SFDP is true when the geometry was read from the SFDP tables, false when
+	// it was derived from the JEDEC ID.
+	SFDP bool
+}
+
+// New opens a handle to a SPI NOR flash chip.
+//
+// The chip is released from deep power-down in case it was left in this
+// state, then its JEDEC ID and SFDP tables are read.
+func New(p spi.Port, o *Opts) (*Dev, error) {
+	f := o.Freq
+	if f == 0 {
+		f = DefaultOpts.Freq
+	}
+	c, err := p.Connect(f, spi.Mode0, 8)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dev{c: c}
+	if l, ok := c.(conn.Limits); ok {
+		if d.max = l.MaxTxSize(); d.max != 0 && d.max <= 8 {
+			return nil, fmt.Errorf("spiflash: maximum transaction size of %d bytes is too small", d.max)
+		}
+	}
+	if err := d.wakeUp(); err != nil {
+		return nil, err
+	}
+	if err := d.readID(); err != nil {
+		return nil, err
+	}
+	if err := d.readGeometry(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Dev is a handle to a SPI NOR flash chip.
+//
+// It implements io.ReaderAt and io.WriterAt.
+type Dev struct {
+	c   spi.Conn
+	max int
+	id  ID
+	g   Geometry
+
+	mu        sync.Mutex
+	opRead    byte
+	opProgram byte
+	down      bool
+}
+
+func (d *Dev) String() string {
+	return fmt.Sprintf("spiflash{%s, %s}", d.id, d.c)
+}
+
+// Halt implements conn.Resource.
+func (d *Dev) Halt() error {
+	return nil
+}
+
+// ID returns the JEDEC ID of the chip.
+func (d *Dev) ID() ID {
+	return d.id
+}
+
+// Geometry returns the memory organization of the chip.
+func (d *Dev) Geometry() Geometry {
+	return d.g
+}
+
+// ReadAt implements io.ReaderAt.
+//
+// It uses the fast read command.
+func (d *Dev) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("spiflash: negative offset")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return 0, errPoweredDown
+	}
+	if off >= d.g.Size {
+		return 0, io.EOF
+	}
+	n := len(b)
+	if rem := d.g.Size - off; int64(n) > rem {
+		n = int(rem)
+	}
+	if err := d.read(off, b[:n]); err != nil {
+		return 0, err
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt.
+//
+// Data is programmed directly when it only clears bits. Otherwise each
+// smallest erase unit that is touched is read, erased and programmed back with
+// the merged data; the pages left blank are not programmed.
+func (d *Dev) WriteAt(b []byte, off int64) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return 0, errPoweredDown
+	}
+	if off < 0 || off+int64(len(b)) > d.g.Size {
+		return 0, fmt.Errorf("spiflash: write of %d bytes at 0x%X is outside of the chip", len(b), off)
+	}
+	e := d.g.Erase[0]
+	buf := make([]byte, e.Size)
+	for n := 0; n < len(b); {
+		base := (off + int64(n)) &^ (e.Size - 1)
+		start := int(off + int64(n) - base)
+		l := len(buf) - start
+		if l > len(b)-n {
+			l = len(b) - n
+		}
+		data := b[n : n+l]
+		old := buf[start : start+l]
+		if err := d.read(base+int64(start), old); err != nil {
+			return n, err
+		}
+		if needsErase(old, data) {
+			if err := d.read(base, buf[:start]); err != nil {
+				return n, err
+			}
+			if err := d.read(base+int64(start+l), buf[start+l:]); err != nil {
+				return n, err
+			}
+			copy(old, data)
+			if err := d.erase(e, base); err != nil {
+				return n, err
+			}
+			if err := d.programPages(base, buf); err != nil {
+				return n, err
+			}
+		} else if !bytes.Equal(old, data) {
+			if err := d.program(base+int64(start), data); err != nil {
+				return n, err
+			}
+		}
+		n += l
+	}
+	return len(b), nil
+}
+
+// Program programs b at off without erasing first.
+//
+// Programming can only clear bits; the result is the AND of the current
+// content and b.
+func (d *Dev) Program(off int64, b []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errPoweredDown
+	}
+	if off < 0 || off+int64(len(b)) > d.g.Size {
+		return fmt.Errorf("spiflash: program of %d bytes at 0x%X is outside of the chip", len(b), off)
+	}
+	return d.program(off, b)
+}
+
+// Erase erases n bytes starting at off, using the largest erase operations
+// possible.
+//
+// off and n must be multiples of the smallest erase size.
+func (d *Dev) Erase(off, n int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errPoweredDown
+	}
+	min := d.g.Erase[0].Size
+	if off < 0 || n < 0 || off%min != 0 || n%min != 0 || off+n > d.g.Size {
+		return fmt.Errorf("spiflash: erase of %d bytes at 0x%X must be aligned on %d bytes and inside the chip", n, off, min)
+	}
+	for n > 0 {
+		e := d.g.Erase[0]
+		for _, x := range d.g.Erase[1:] {
+			if off%x.Size == 0 && n >= x.Size {
+				e = x
+			}
+		}
+		if err := d.erase(e, off); err != nil {
+			return err
+		}
+		off += e.Size
+		n -= e.Size
+	}
+	return nil
+}
+
+// EraseChip erases the whole chip.
+//
+// This can take minutes on large chips.
+func (d *Dev) EraseChip() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errPoweredDown
+	}
+	if err := d.writeEnable(); err != nil {
+		return err
+	}
+	if err := d.tx([]byte{0xC7}, nil, nil); err != nil {
+		return err
+	}
+	return d.wait(chipEraseTimeout)
+}
+
+// Status returns the status register.
+func (d *Dev) Status() (byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return 0, errPoweredDown
+	}
+	return d.status()
+}
+
+// WriteStatus writes the status register.
+//
+// The write is ignored by the chip when StatusSRP is set and the /WP pin is
+// held low.
+func (d *Dev) WriteStatus(s byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errPoweredDown
+	}
+	return d.writeStatus(s)
+}
+
+// Protect sets the block protect bits BP0 to BP2 of the status register.
+//
+// The region protected by each value is chip specific; 0 unprotects the whole
+// chip and 7 protects it on most chips.
+func (d *Dev) Protect(bp byte) error {
+	if bp > 7 {
+		return fmt.Errorf("spiflash: invalid block protect value %d", bp)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errPoweredDown
+	}
+	s, err := d.status()
+	if err != nil {
+		return err
+	}
+	return d.writeStatus(s&^StatusBP | bp<<2)
+}
+
+// PowerDown puts the chip in deep power-down.
+//
+// All operations but WakeUp() fail until WakeUp() is called.
+func (d *Dev) PowerDown() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.tx([]byte{0xB9}, nil, nil); err != nil {
+		return err
+	}
+	d.down = true
+	return nil
+}
+
+// WakeUp releases the chip from deep power-down.
+func (d *Dev) WakeUp() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.wakeUp()
+}
+
+//
+
+// manufacturers maps the JEDEC manufacturer IDs of common flash vendors.
+var manufacturers = map[byte]string{
+	0x01: "Spansion",
+	0x1F: "Adesto",
+	0x20: "Micron",
+	0x9D: "ISSI",
+	0xBF: "SST",
+	0xC2: "Macronix",
+	0xC8: "GigaDevice",
+	0xEF: "Winbond",
+}
+
+const (
+	pollInterval     = 100 * time.Microsecond
+	wakeUpDelay      = 50 * time.Microsecond
+	programTimeout   = 50 * time.Millisecond
+	statusTimeout    = 100 * time.Millisecond
+	sectorTimeout    = time.Second
+	blockTimeout     = 5 * time.Second
+	chipEraseTimeout = 400 * time.Second
+)
+
+var errPoweredDown = errors.New("spiflash: the chip is in deep power-down; call WakeUp()")
+
+func (d *Dev) wakeUp() error {
+	if err := d.tx([]byte{0xAB}, nil, nil); err != nil {
+		return err
+	}
+	time.Sleep(wakeUpDelay)
+	d.down = false
+	return nil
+}
+
+func (d *Dev) readID() error {
+	var r [3]byte
+	if err := d.tx([]byte{0x9F}, nil, r[:]); err != nil {
+		return err
+	}
+	d.id = ID{r[0], r[1], r[2]}
+	if (r[0] == 0 && r[1] == 0 && r[2] == 0) || (r[0] == 0xFF && r[1] == 0xFF && r[2] == 0xFF) {
+		return fmt.Errorf("spiflash: no chip detected; got JEDEC ID %s", d.id)
+	}
+	return nil
+}
+
+func (d *Dev) readGeometry() error {
+	g, ok, err := d.readSFDP()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if g, err = fromID(d.id); err != nil {
+			return err
+		}
+	}
+	d.opRead, d.opProgram = 0x0B, 0x02
+	if g.Addr4 {
+		d.opRead, d.opProgram = 0x0C, 0x12
+		var e []EraseOp
+		for _, x := range g.Erase {
+			if op, ok := erase4[x.Opcode]; ok {
+				e = append(e, EraseOp{x.Size, op})
+			}
+		}
+		g.Erase = e
+	}
+	if len(g.Erase) == 0 {
+		return errors.New("spiflash: the chip has no supported erase operation")
+	}
+	d.g = g
+	return nil
+}
+
+// erase4 maps the erase opcodes to their 4 bytes address variants.
+var erase4 = map[byte]byte{0x20: 0x21, 0x52: 0x5C, 0xD8: 0xDC}
+
+// fromID derives the geometry from the capacity of the JEDEC ID, which
+// encodes the size as a power of two on the common chips.
+func fromID(id ID) (Geometry, error) {
+	if id.Capacity < 0x10 || id.Capacity > 0x21 {
+		return Geometry{}, fmt.Errorf("spiflash: unsupported JEDEC ID %s without SFDP", id)
+	}
+	g := Geometry{
+		Size:     1 << id.Capacity,
+		PageSize: 256,
+		Erase:    []EraseOp{{4096, 0x20}, {32768, 0x52}, {65536, 0xD8}},
+	}
+	g.Addr4 = g.Size > 1<<24
+	return g, nil
+}
+
+// header returns a command with its address followed by dummy bytes.
+func (d *Dev) header(op byte, off int64, dummy int) []byte {
+	h := make([]byte, 0, 5+dummy)
+	h = append(h, op)
+	if d.g.Addr4 {
+		h = append(h, byte(off>>24))
+	}
+	h = append(h, byte(off>>16), byte(off>>8), byte(off))
+	return append(h, make([]byte, dummy)...)
+}
+
+// tx sends a command, then writes w or reads r while CS stays asserted.
+func (d *Dev) tx(hdr, w, r []byte) error {
+	p := []spi.Packet{{W: hdr}}
+	if len(w) != 0 {
+		p = append(p, spi.Packet{W: w})
+	}
+	if len(r) != 0 {
+		p = append(p, spi.Packet{R: r})
+	}
+	p[0].KeepCS = len(p) > 1
+	return d.c.TxPackets(p)
+}
+
+// chunk returns how many of the n bytes fit in a transaction after hdr bytes.
+func (d *Dev) chunk(hdr, n int) int {
+	if d.max != 0 && n > d.max-hdr {
+		return d.max - hdr
+	}
+	return n
+}
+
+func (d *Dev) read(off int64, b []byte) error {
+	for len(b) != 0 {
+		hdr := d.header(d.opRead, off, 1)
+		n := d.chunk(len(hdr), len(b))
+		if err := d.tx(hdr, nil, b[:n]); err != nil {
+			return err
+		}
+		off += int64(n)
+		b = b[n:]
+	}
+	return nil
+}
+
+func (d *Dev) program(off int64, b []byte) error {
+	for len(b) != 0 {
+		n := d.g.PageSize - int(off%int64(d.g.PageSize))
+		if n > len(b) {
+			n = len(b)
+		}
+		hdr := d.header(d.opProgram, off, 0)
+		n = d.chunk(len(hdr), n)
+		if err := d.writeEnable(); err != nil {
+			return err
+		}
+		if err := d.tx(hdr, b[:n], nil); err != nil {
+			return err
+		}
+		if err := d.wait(programTimeout); err != nil {
+			return err
+		}
+		off += int64(n)
+		b = b[n:]
+	}
+	return nil
+}
+
+// programPages programs the pages of b that are not blank.
+func (d *Dev) programPages(off int64, b []byte) error {
+	for i := 0; i < len(b); i += d.g.PageSize {
+		end := i + d.g.PageSize
+		if end > len(b) {
+			end = len(b)
+		}
+		if !blank(b[i:end]) {
+			if err := d.program(off+int64(i), b[i:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Dev) erase(e EraseOp, off int64) error {
+	if err := d.writeEnable(); err != nil {
+		return err
+	}
+	if err := d.tx(d.header(e.Opcode, off, 0), nil, nil); err != nil {
+		return err
+	}
+	if e.Size <= 4096 {
+		return d.wait(sectorTimeout)
+	}
+	return d.wait(blockTimeout)
+}
+
+func (d *Dev) writeEnable() error {
+	return d.tx([]byte{0x06}, nil, nil)
+}
+
+func (d *Dev) status() (byte, error) {
+	var r [1]byte
+	err := d.tx([]byte{0x05}, nil, r[:])
+	return r[0], err
+}
+
+func (d *Dev) writeStatus(s byte) error {
+	if err := d.writeEnable(); err != nil {
+		return err
+	}
+	if err := d.tx([]byte{0x01, s &^ (StatusBusy | StatusWEL)}, nil, nil); err != nil {
+		return err
+	}
+	return d.wait(statusTimeout)
+}
+
+// wait polls the status register until the chip is not busy anymore.
+func (d *Dev) wait(timeout time.Duration) error {
+	start := time.Now()
+	for {
+		s, err := d.status()
+		if err != nil {
+			return err
+		}
+		if s&StatusBusy == 0 {
+			return nil
+		}
+		if time.Since(start) > timeout {
+			return fmt.Errorf("spiflash: the chip is still busy after %s", timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// needsErase returns true if writing b over old requires to set bits.
+func needsErase(old, b []byte) bool {
+	for i := range b {
+		if old[i]&b[i] != b[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func blank(b []byte) bool {
+	for _, v := range b {
+		if v != 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+var _ conn.Resource = &Dev{}
+var _ io.ReaderAt = &Dev{}
+var _ io.WriterAt = &Dev{}
@@ -0,0 +1,308 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package spiflash
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func TestNew_SFDP(t *testing.T) {
+	f := spitest.NewFlash(1 << 20)
+	d, err := New(f, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "spiflash{Winbond 0x4014, flash}" {
+		t.Fatal(s)
+	}
+	g := d.Geometry()
+	if g.Size != 1<<20 || g.PageSize != 256 || g.Addr4 || !g.SFDP {
+		t.Fatalf("%#v", g)
+	}
+	if len(g.Erase) != 3 || g.Erase[0] != (EraseOp{4096, 0x20}) || g.Erase[2] != (EraseOp{65536, 0xD8}) {
+		t.Fatal(g.Erase)
+	}
+	if err := d.Halt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew_ID(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	f.SFDP = nil
+	f.ID = [3]byte{0xC2, 0x20, 0x10}
+	d, err := New(f, &Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := d.ID(); id != (ID{0xC2, 0x20, 0x10}) || id.String() != "Macronix 0x2010" {
+		t.Fatal(id)
+	}
+	if g := d.Geometry(); g.Size != 1<<16 || g.SFDP || len(g.Erase) != 3 {
+		t.Fatalf("%#v", g)
+	}
+}
+
+func TestNew_err(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	f.ID = [3]byte{}
+	if _, err := New(f, &DefaultOpts); err == nil {
+		t.Fatal("no chip")
+	}
+	f = spitest.NewFlash(1 << 16)
+	f.SFDP = nil
+	f.ID = [3]byte{0x12, 0x34, 0x56}
+	if _, err := New(f, &DefaultOpts); err == nil || (ID{0x12, 0x34, 0x56}).String() != "0x123456" {
+		t.Fatal("unknown capacity")
+	}
+	f = spitest.NewFlash(1 << 16)
+	f.SFDP[0x30+4*7] = 0
+	f.SFDP[0x30+4*7+2] = 0
+	f.SFDP[0x30+4*8] = 0
+	if _, err := New(f, &DefaultOpts); err == nil {
+		t.Fatal("no erase")
+	}
+	if _, err := New(&spitest.Limited{Port: spitest.NewFlash(1 << 16), Max: 8}, &DefaultOpts); err == nil {
+		t.Fatal("too small")
+	}
+}
+
+func TestReadAt_WriteAt(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	d, err := New(f, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Data[4096-2] = 0x55
+	f.Data[4096+300] = 0x66
+	// Spans two sectors and needs an erase. The period of 3 bytes doesn't line
+	// up with pages nor sectors.
+	w := bytes.Repeat([]byte{0x00, 0x5A, 0xA5}, 100)
+	if n, err := d.WriteAt(w, 4096-100); n != len(w) || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(f.Data[4096-100:4096+200], w) || f.Data[4096+300] != 0x66 || f.Data[4096-101] != 0xFF {
+		t.Fatal("unexpected content")
+	}
+	r := make([]byte, len(w))
+	if n, err := d.ReadAt(r, 4096-100); n != len(r) || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(r, w) {
+		t.Fatal(r)
+	}
+	if n, err := d.ReadAt(r, 1<<16-10); n != 10 || err != io.EOF || r[0] != 0xFF {
+		t.Fatal(n, err)
+	}
+	if n, err := d.ReadAt(r, 1<<16); n != 0 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+	if _, err := d.ReadAt(r, -1); err == nil {
+		t.Fatal("negative offset")
+	}
+	if _, err := d.WriteAt(r, 1<<16-10); err == nil {
+		t.Fatal("outside of the chip")
+	}
+}
+
+func TestWriteAt_noErase(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	f.Data[10] = 0x0F
+	rec := &spitest.Record{Port: f}
+	d, err := New(rec, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reset(rec)
+	if _, err := d.WriteAt([]byte{0x03, 0xFF}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if opcodes(rec)[0x20] != 0 || opcodes(rec)[0x02] != 1 || f.Data[10] != 0x03 || f.Data[11] != 0xFF {
+		t.Fatal(opcodes(rec), f.Data[10:12])
+	}
+	// Unchanged data isn't programmed.
+	reset(rec)
+	if _, err := d.WriteAt([]byte{0x03}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if opcodes(rec)[0x02] != 0 {
+		t.Fatal(opcodes(rec))
+	}
+	// Setting bits erases the sector and only programs the non blank pages.
+	reset(rec)
+	if _, err := d.WriteAt([]byte{0xF0}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if opcodes(rec)[0x20] != 1 || opcodes(rec)[0x02] != 1 || f.Data[10] != 0xF0 {
+		t.Fatal(opcodes(rec), f.Data[10])
+	}
+}
+
+func TestProgram_Erase(t *testing.T) {
+	f := spitest.NewFlash(1 << 20)
+	rec := &spitest.Record{Port: f}
+	d, err := New(rec, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Program(250, []byte{1, 2, 3, 4, 5, 6, 7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f.Data[250:258], []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatal(f.Data[250:258])
+	}
+	if err := d.Program(1<<20-1, []byte{1, 2}); err == nil {
+		t.Fatal("outside of the chip")
+	}
+	reset(rec)
+	if err := d.Erase(0, 65536+32768+4096); err != nil {
+		t.Fatal(err)
+	}
+	if o := opcodes(rec); o[0xD8] != 1 || o[0x52] != 1 || o[0x20] != 1 {
+		t.Fatal(o)
+	}
+	if f.Data[250] != 0xFF {
+		t.Fatal("not erased")
+	}
+	if err := d.Erase(100, 4096); err == nil {
+		t.Fatal("unaligned")
+	}
+	f.Data[1<<19] = 0
+	if err := d.EraseChip(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Data[1<<19] != 0xFF {
+		t.Fatal("not erased")
+	}
+}
+
+func TestProtect(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	d, err := New(f, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Protect(7); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := d.Status(); s != StatusBP || err != nil {
+		t.Fatal(s, err)
+	}
+	if _, err := d.WriteAt([]byte{0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if f.Data[0] != 0xFF {
+		t.Fatal("protected")
+	}
+	if err := d.Protect(8); err == nil {
+		t.Fatal("invalid value")
+	}
+	if err := d.WriteStatus(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WriteAt([]byte{0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if f.Data[0] != 0 {
+		t.Fatal("not protected")
+	}
+}
+
+func TestPowerDown(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	f.PoweredDown = true
+	d, err := New(f, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PowerDown(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.PoweredDown {
+		t.Fatal("expected powered down")
+	}
+	var b [1]byte
+	if _, err := d.ReadAt(b[:], 0); err == nil {
+		t.Fatal("powered down")
+	}
+	if _, err := d.WriteAt(b[:], 0); err == nil {
+		t.Fatal("powered down")
+	}
+	if _, err := d.Status(); err == nil {
+		t.Fatal("powered down")
+	}
+	if err := d.WakeUp(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ReadAt(b[:], 0); err != nil || f.PoweredDown {
+		t.Fatal(err)
+	}
+}
+
+func TestAddr4(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	// Declare 4 bytes addresses only.
+	f.SFDP[0x30+2] |= 0x04
+	rec := &spitest.Record{Port: f}
+	d, err := New(rec, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := d.Geometry()
+	if !g.Addr4 || g.Erase[0].Opcode != 0x21 {
+		t.Fatalf("%#v", g)
+	}
+	reset(rec)
+	if _, err := d.WriteAt([]byte{1, 2, 3}, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 3)
+	if _, err := d.ReadAt(b, 0x1234); err != nil || !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatal(b, err)
+	}
+	if o := opcodes(rec); o[0x12] != 1 || o[0x0C] == 0 || o[0x02] != 0 || o[0x0B] != 0 {
+		t.Fatal(o)
+	}
+}
+
+func TestLimited(t *testing.T) {
+	f := spitest.NewFlash(1 << 16)
+	d, err := New(&spitest.Limited{Port: f, Max: 64}, &DefaultOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each page is split in multiple transfers.
+	w := bytes.Repeat([]byte{0x00, 0x5A, 0xA5}, 333)
+	if _, err := d.WriteAt(w, 4000); err != nil {
+		t.Fatal(err)
+	}
+	r := make([]byte, len(w))
+	if _, err := d.ReadAt(r, 4000); err != nil || !bytes.Equal(r, w) {
+		t.Fatal(err)
+	}
+}
+
+//
+
+// opcodes counts the commands sent, ignoring the data packets.
+func opcodes(r *spitest.Record) map[byte]int {
+	m := map[byte]int{}
+	for i, io := range r.Ops {
+		if i != 0 && r.Packets[i-1].KeepCS {
+			continue
+		}
+		m[io.W[0]]++
+	}
+	return m
+}
+
+func reset(r *spitest.Record) {
+	r.Ops = nil
+	r.Packets = nil
+}
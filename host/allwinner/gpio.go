@@ -442,6 +442,26 @@ func (p *Pin) PWM(gpio.Duty, physic.Frequency) error {
 	return p.wrap(errors.New("not available on this CPU architecture"))
 }
 
+// Group implements gpio.Grouper.
+//
+// All the pins must be available Allwinner pins and the GPIO registers must be
+// memory mapped. Each port (PA, PB, ...) is then read or written as a single
+// data register access.
+func (p *Pin) Group(pins []gpio.PinIO) gpio.Group {
+	if drvGPIO.gpioMemory == nil {
+		return nil
+	}
+	g := &pinGroup{pins: pins, cpu: make([]*Pin, len(pins))}
+	for i := range pins {
+		c, ok := pins[i].(*Pin)
+		if !ok || !c.available {
+			return nil
+		}
+		g.cpu[i] = c
+	}
+	return g
+}
+
 //
 
 // drive returns the configured output current drive strength for this GPIO.
@@ -491,6 +511,78 @@ func (p *Pin) wrap(err error) error {
 	return fmt.Errorf("allwinner-gpio (%s): %v", p, err)
 }
 
+// pinGroup implements gpio.Group for Allwinner pins.
+type pinGroup struct {
+	pins []gpio.PinIO
+	cpu  []*Pin
+}
+
+func (g *pinGroup) String() string {
+	return gpio.GroupString(g.pins)
+}
+
+// Halt implements conn.Resource.
+//
+// It is a no-op.
+func (g *pinGroup) Halt() error {
+	return nil
+}
+
+// Pins implements gpio.Group.
+func (g *pinGroup) Pins() []gpio.PinIO {
+	return g.pins
+}
+
+// Out implements gpio.Group.
+func (g *pinGroup) Out(bits, mask uint64) error {
+	var set, clear, used [9]uint32
+	for i, p := range g.cpu {
+		bit := uint64(1) << uint(i)
+		if mask&bit == 0 {
+			continue
+		}
+		l := gpio.Level(bits&bit != 0)
+		if p.function() != out {
+			if err := p.Out(l); err != nil {
+				return err
+			}
+		}
+		used[p.group] |= 1 << p.offset
+		if l {
+			set[p.group] |= 1 << p.offset
+		} else {
+			clear[p.group] |= 1 << p.offset
+		}
+	}
+	for i := range used {
+		if used[i] != 0 {
+			drvGPIO.gpioMemory.groups[i].data = (drvGPIO.gpioMemory.groups[i].data &^ clear[i]) | set[i]
+		}
+	}
+	return nil
+}
+
+// Read implements gpio.Group.
+func (g *pinGroup) Read(mask uint64) (uint64, error) {
+	var data [9]uint32
+	var read [9]bool
+	var bits uint64
+	for i, p := range g.cpu {
+		bit := uint64(1) << uint(i)
+		if mask&bit == 0 {
+			continue
+		}
+		if !read[p.group] {
+			data[p.group] = drvGPIO.gpioMemory.groups[p.group].data
+			read[p.group] = true
+		}
+		if data[p.group]&(1<<p.offset) != 0 {
+			bits |= bit
+		}
+	}
+	return bits, nil
+}
+
 //
 
 // A64: Page 23~24
@@ -986,7 +1078,7 @@ func (d *driverGPIO) Prerequisites() []string {
 }
 
 func (d *driverGPIO) After() []string {
-	return []string{"sysfs-gpio"}
+	return []string{"sysfs-gpio", "sysfs-gpiochip"}
 }
 
 // Init does nothing if an allwinner processor is not detected. If one is
@@ -1063,3 +1155,5 @@ var _ gpio.PinIO = &Pin{}
 var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
 var _ pin.PinFunc = &Pin{}
+var _ gpio.Grouper = &Pin{}
+var _ gpio.Group = &pinGroup{}
@@ -5,6 +5,7 @@
 package bcm283x
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -504,6 +505,28 @@ func (p *Pin) WaitForEdge(timeout time.Duration) bool {
 	return false
 }
 
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// Edge detection is done via gpio sysfs, see sysfs.Pin.WaitForEdgeEvent() for
+// its limitations.
+func (p *Pin) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	if p.sysfsPin != nil {
+		return p.sysfsPin.WaitForEdgeEvent(timeout)
+	}
+	return gpio.EdgeEvent{}, false
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// Edge detection is done via gpio sysfs, see sysfs.Pin.WaitForEdgeContext()
+// for its limitations.
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	if p.sysfsPin != nil {
+		return p.sysfsPin.WaitForEdgeContext(ctx)
+	}
+	return false
+}
+
 // Pull implements gpio.PinIn.
 //
 // bcm2711/bcm2838 support querying the pull resistor of all GPIO pins. Prior
@@ -579,6 +602,27 @@ func (p *Pin) FastOut(l gpio.Level) {
 	}
 }
 
+// Group implements gpio.Grouper.
+//
+// All the pins must be bcm283x pins and the GPIO registers must be memory
+// mapped. Each bank of 32 pins is then read or written as a single register
+// access; when setting outputs, the pins going high are set before the pins
+// going low are cleared.
+func (p *Pin) Group(pins []gpio.PinIO) gpio.Group {
+	if drvGPIO.gpioMemory == nil {
+		return nil
+	}
+	g := &pinGroup{pins: pins, cpu: make([]*Pin, len(pins))}
+	for i := range pins {
+		c, ok := pins[i].(*Pin)
+		if !ok {
+			return nil
+		}
+		g.cpu[i] = c
+	}
+	return g
+}
+
 // BUG(maruel): PWM(): There is no conflict verification when multiple pins are
 // used simultaneously. The last call to PWM() will affect all pins of the same
 // type (CLK0, CLK2, PWM0 or PWM1).
@@ -900,6 +944,81 @@ func (p *Pin) wrap(err error) error {
 	return fmt.Errorf("bcm283x-gpio (%s): %v", p, err)
 }
 
+// pinGroup implements gpio.Group for bcm283x pins.
+type pinGroup struct {
+	pins []gpio.PinIO
+	cpu  []*Pin
+}
+
+func (g *pinGroup) String() string {
+	return gpio.GroupString(g.pins)
+}
+
+// Halt implements conn.Resource.
+//
+// It is a no-op.
+func (g *pinGroup) Halt() error {
+	return nil
+}
+
+// Pins implements gpio.Group.
+func (g *pinGroup) Pins() []gpio.PinIO {
+	return g.pins
+}
+
+// Out implements gpio.Group.
+func (g *pinGroup) Out(bits, mask uint64) error {
+	var set, clear [2]uint32
+	for i, p := range g.cpu {
+		bit := uint64(1) << uint(i)
+		if mask&bit == 0 {
+			continue
+		}
+		l := gpio.Level(bits&bit != 0)
+		if p.function() != out {
+			if err := p.Out(l); err != nil {
+				return err
+			}
+		}
+		if l {
+			set[p.number/32] |= 1 << uint(p.number&31)
+		} else {
+			clear[p.number/32] |= 1 << uint(p.number&31)
+		}
+	}
+	for i := range set {
+		if set[i] != 0 {
+			drvGPIO.gpioMemory.outputSet[i] = set[i]
+		}
+		if clear[i] != 0 {
+			drvGPIO.gpioMemory.outputClear[i] = clear[i]
+		}
+	}
+	return nil
+}
+
+// Read implements gpio.Group.
+func (g *pinGroup) Read(mask uint64) (uint64, error) {
+	var level [2]uint32
+	var read [2]bool
+	var bits uint64
+	for i, p := range g.cpu {
+		bit := uint64(1) << uint(i)
+		if mask&bit == 0 {
+			continue
+		}
+		bank := p.number / 32
+		if !read[bank] {
+			level[bank] = drvGPIO.gpioMemory.level[bank]
+			read[bank] = true
+		}
+		if level[bank]&(1<<uint(p.number&31)) != 0 {
+			bits |= bit
+		}
+	}
+	return bits, nil
+}
+
 //
 
 // Each pin can have one of 7 functions.
@@ -1271,7 +1390,7 @@ func (d *driverGPIO) Prerequisites() []string {
 }
 
 func (d *driverGPIO) After() []string {
-	return []string{"sysfs-gpio"}
+	return []string{"sysfs-gpio", "sysfs-gpiochip"}
 }
 
 func (d *driverGPIO) Init() (bool, error) {
@@ -1438,6 +1557,10 @@ var drvGPIO driverGPIO
 var _ gpio.PinIO = &Pin{}
 var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
+var _ gpio.PinEdgeEvents = &Pin{}
+var _ gpio.PinInContext = &Pin{}
+var _ gpio.Grouper = &Pin{}
+var _ gpio.Group = &pinGroup{}
 var _ gpiostream.PinIn = &Pin{}
 var _ gpiostream.PinOut = &Pin{}
 var _ pin.PinFunc = &Pin{}
@@ -289,6 +289,56 @@ func TestPinPWM(t *testing.T) {
 	}
 }
 
+func TestPin_Group(t *testing.T) {
+	defer reset()
+	setMemory()
+	p4 := &Pin{name: "GPIO4", number: 4}
+	p40 := &Pin{name: "GPIO40", number: 40}
+	p5 := &Pin{name: "GPIO5", number: 5}
+	if g := p4.Group([]gpio.PinIO{p4, gpio.INVALID}); g != nil {
+		t.Fatal("expected nil group")
+	}
+	g, err := gpio.NewGroup(p4, p40, p5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*pinGroup); !ok {
+		t.Fatalf("expected native group, got %T", g)
+	}
+	if s := g.String(); s != "Group(GPIO4,GPIO40,GPIO5)" {
+		t.Fatal(s)
+	}
+	if len(g.Pins()) != 3 {
+		t.Fatal(g.Pins())
+	}
+	if v, err := g.Read(0x7); err != nil || v != 0x3 {
+		t.Fatal(v, err)
+	}
+	if v, err := g.Read(0x4); err != nil || v != 0 {
+		t.Fatal(v, err)
+	}
+	if err := g.Out(0x5, 0x7); err != nil {
+		t.Fatal(err)
+	}
+	if f := p5.function(); f != out {
+		t.Fatal(f)
+	}
+	if v := drvGPIO.gpioMemory.outputSet[0]; v != 0x30 {
+		t.Fatalf("0x%x", v)
+	}
+	if v := drvGPIO.gpioMemory.outputClear[1]; v != 0x100 {
+		t.Fatalf("0x%x", v)
+	}
+	if err := g.Halt(); err != nil {
+		t.Fatal(err)
+	}
+
+	drvGPIO.gpioMemory = nil
+	if g := p4.Group([]gpio.PinIO{p4}); g != nil {
+		t.Fatal("expected nil group")
+	}
+}
+
 func TestPinStreamIn(t *testing.T) {
 	defer reset()
 	p := Pin{name: "C1", number: 4, defaultPull: gpio.PullDown}
@@ -255,7 +255,7 @@ func (d *driver) Prerequisites() []string {
 
 func (d *driver) After() []string {
 	// has allwinner cpu, needs sysfs for XIO0-XIO7 "gpio" pins
-	return []string{"allwinner-gpio", "sysfs-gpio"}
+	return []string{"allwinner-gpio", "sysfs-gpio", "sysfs-gpiochip"}
 }
 
 func (d *driver) Init() (bool, error) {
@@ -4,7 +4,13 @@
 
 package host
 
-import "periph.io/x/periph"
+import (
+	"periph.io/x/periph"
+
+	// Make sure the simulated host driver is registered. It is only loaded
+	// when configured.
+	_ "periph.io/x/periph/host/sim"
+)
 
 // Init calls periph.Init() and returns it as-is.
 //
@@ -0,0 +1,19 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package hat reads the ID EEPROM of Raspberry Pi HATs and instantiates the
+// driver of the attached HAT.
+//
+// A HAT carries an EEPROM on the ID_SD and ID_SC pins (I²C bus 0) describing
+// its vendor, product and GPIO bank setup. The firmware reads it at boot and
+// exposes a copy in the device tree under /proc/device-tree/hat; the GPIO
+// setup is not part of this copy.
+//
+// Device packages call Register() in their init() function so applications
+// can call Open() to instantiate the driver of the HAT that is attached.
+//
+// Specification
+//
+// https://github.com/raspberrypi/hats/blob/master/eeprom-format.md
+package hat
@@ -0,0 +1,34 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat_test
+
+import (
+	"fmt"
+	"log"
+
+	"periph.io/x/periph/host"
+	"periph.io/x/periph/host/rpi/hat"
+)
+
+func Example() {
+	// Make sure periph is initialized.
+	if _, err := host.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	h, err := hat.ReadDeviceTree()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s, UUID %s\n", h, h.UUID)
+
+	// The device package of the HAT must be imported, so it registers itself.
+	d, err := hat.Open(h)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer d.Halt()
+	fmt.Printf("%s\n", d)
+}
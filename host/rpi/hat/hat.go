@@ -0,0 +1,351 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// HAT describes a HAT as stored in its ID EEPROM.
+type HAT struct {
+	Vendor     string
+	Product    string
+	ProductID  uint16
+	ProductVer uint16
+	UUID       UUID
+	// GPIO is the GPIO bank setup. It is nil when the HAT was read from the
+	// device tree, which doesn't expose it.
+	GPIO *GPIOMap
+	// DeviceTree is the Linux device tree overlay blob, if any.
+	DeviceTree []byte
+	// Custom lists the manufacturer custom data atoms.
+	Custom [][]byte
+}
+
+func (h *HAT) String() string {
+	return fmt.Sprintf("%s %s (0x%04X v%d)", h.Vendor, h.Product, h.ProductID, h.ProductVer)
+}
+
+// UUID uniquely identifies a single board.
+type UUID [16]byte
+
+// ParseUUID parses the canonical form of a UUID, e.g.
+// "01234567-89ab-cdef-0123-456789abcdef".
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("hat: invalid UUID %q", s)
+	}
+	h := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("hat: invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// GPIOMap is the GPIO bank setup requested by a HAT.
+type GPIOMap struct {
+	// Drive is the drive strength in mA, an even value between 2 and 16, or 0
+	// for the default.
+	Drive      int
+	Slew       Slew
+	Hysteresis Hysteresis
+	BackPower  BackPower
+	// Pins is the setup of GPIO0 to GPIO27, indexed by GPIO number.
+	Pins [28]Pin
+}
+
+// Slew is the slew rate setting of the GPIO bank.
+type Slew uint8
+
+// Valid Slew values.
+const (
+	SlewDefault   Slew = 0
+	SlewLimited   Slew = 1
+	SlewUnlimited Slew = 2
+)
+
+// Hysteresis is the input hysteresis setting of the GPIO bank.
+type Hysteresis uint8
+
+// Valid Hysteresis values.
+const (
+	HysteresisDefault  Hysteresis = 0
+	HysteresisDisabled Hysteresis = 1
+	HysteresisEnabled  Hysteresis = 2
+)
+
+// BackPower declares whether the HAT powers the Raspberry Pi.
+type BackPower uint8
+
+// Valid BackPower values.
+const (
+	BackPowerNone   BackPower = 0
+	BackPower1300mA BackPower = 1 // The HAT supplies at least 1.3A
+	BackPower2000mA BackPower = 2 // The HAT supplies at least 2A
+)
+
+// Pin is the setup of a GPIO.
+type Pin struct {
+	// Used is true when the HAT uses the GPIO. The other fields are meaningful
+	// only when it is true.
+	Used bool
+	Func Func
+	// Pull is gpio.PullNoChange for the default, gpio.PullUp, gpio.PullDown or
+	// gpio.Float.
+	Pull gpio.Pull
+}
+
+// Func is the function of a GPIO, with the encoding of the GPIO map.
+type Func uint8
+
+// Valid Func values.
+const (
+	Input  Func = 0
+	Output Func = 1
+	Alt5   Func = 2
+	Alt4   Func = 3
+	Alt0   Func = 4
+	Alt1   Func = 5
+	Alt2   Func = 6
+	Alt3   Func = 7
+)
+
+const funcName = "InputOutputALT5ALT4ALT0ALT1ALT2ALT3"
+
+var funcIndex = [...]uint8{0, 5, 11, 15, 19, 23, 27, 31, 35}
+
+func (f Func) String() string {
+	if int(f) >= len(funcIndex)-1 {
+		return "Func(" + strconv.Itoa(int(f)) + ")"
+	}
+	return funcName[funcIndex[f]:funcIndex[f+1]]
+}
+
+// Parse decodes the content of a HAT ID EEPROM.
+//
+// The CRC of each atom is verified. Trailing bytes after the length declared
+// in the header are ignored.
+func Parse(b []byte) (*HAT, error) {
+	if len(b) < headerLen || string(b[:4]) != signature {
+		return nil, errors.New("hat: invalid EEPROM signature")
+	}
+	if b[4] != version {
+		return nil, fmt.Errorf("hat: unsupported EEPROM format version %d", b[4])
+	}
+	n := int(binary.LittleEndian.Uint16(b[6:]))
+	l := binary.LittleEndian.Uint32(b[8:])
+	if l < headerLen || uint64(l) > uint64(len(b)) {
+		return nil, fmt.Errorf("hat: invalid EEPROM length %d", l)
+	}
+	b = b[headerLen:l]
+	h := &HAT{}
+	vendor := false
+	for i := 0; i < n; i++ {
+		if len(b) < atomHeaderLen {
+			return nil, fmt.Errorf("hat: atom #%d is truncated", i)
+		}
+		t := binary.LittleEndian.Uint16(b)
+		if c := binary.LittleEndian.Uint16(b[2:]); int(c) != i {
+			return nil, fmt.Errorf("hat: atom #%d has count %d", i, c)
+		}
+		dl := binary.LittleEndian.Uint32(b[4:])
+		if dl < 2 || uint64(dl) > uint64(len(b)-atomHeaderLen) {
+			return nil, fmt.Errorf("hat: atom #%d has invalid length %d", i, dl)
+		}
+		end := atomHeaderLen + int(dl) - 2
+		if crc := binary.LittleEndian.Uint16(b[end:]); crc != crc16(b[:end]) {
+			return nil, fmt.Errorf("hat: atom #%d has invalid CRC 0x%04X", i, crc)
+		}
+		d := b[atomHeaderLen:end]
+		switch t {
+		case atomVendor:
+			if err := h.parseVendor(d); err != nil {
+				return nil, err
+			}
+			vendor = true
+		case atomGPIO:
+			if len(d) != gpioMapLen {
+				return nil, fmt.Errorf("hat: GPIO map atom has invalid length %d", len(d))
+			}
+			h.GPIO = parseGPIO(d)
+		case atomDeviceTree:
+			h.DeviceTree = append([]byte(nil), d...)
+		case atomCustom:
+			h.Custom = append(h.Custom, append([]byte(nil), d...))
+		default:
+			return nil, fmt.Errorf("hat: atom #%d has invalid type 0x%04X", i, t)
+		}
+		b = b[end+2:]
+	}
+	if !vendor {
+		return nil, errors.New("hat: vendor info atom is missing")
+	}
+	return h, nil
+}
+
+// Marshal encodes h in the HAT ID EEPROM format.
+//
+// The GPIO map atom is required by the specification; the default setup with
+// no GPIO used is written when GPIO is nil.
+func (h *HAT) Marshal() ([]byte, error) {
+	if len(h.Vendor) > 255 || len(h.Product) > 255 {
+		return nil, errors.New("hat: vendor and product must be at most 255 bytes")
+	}
+	g := h.GPIO
+	if g == nil {
+		g = &GPIOMap{}
+	}
+	gd, err := g.marshal()
+	if err != nil {
+		return nil, err
+	}
+	v := make([]byte, 22, 22+len(h.Vendor)+len(h.Product))
+	// The UUID is stored as a little endian 128 bits integer.
+	for i := range h.UUID {
+		v[i] = h.UUID[15-i]
+	}
+	binary.LittleEndian.PutUint16(v[16:], h.ProductID)
+	binary.LittleEndian.PutUint16(v[18:], h.ProductVer)
+	v[20] = byte(len(h.Vendor))
+	v[21] = byte(len(h.Product))
+	v = append(append(v, h.Vendor...), h.Product...)
+
+	atoms := [][]byte{v, gd}
+	types := []uint16{atomVendor, atomGPIO}
+	if len(h.DeviceTree) != 0 {
+		atoms = append(atoms, h.DeviceTree)
+		types = append(types, atomDeviceTree)
+	}
+	for _, c := range h.Custom {
+		atoms = append(atoms, c)
+		types = append(types, atomCustom)
+	}
+	b := make([]byte, headerLen)
+	copy(b, signature)
+	b[4] = version
+	binary.LittleEndian.PutUint16(b[6:], uint16(len(atoms)))
+	for i, d := range atoms {
+		start := len(b)
+		var hdr [atomHeaderLen]byte
+		binary.LittleEndian.PutUint16(hdr[0:], types[i])
+		binary.LittleEndian.PutUint16(hdr[2:], uint16(i))
+		binary.LittleEndian.PutUint32(hdr[4:], uint32(len(d)+2))
+		b = append(append(b, hdr[:]...), d...)
+		var crc [2]byte
+		binary.LittleEndian.PutUint16(crc[:], crc16(b[start:]))
+		b = append(b, crc[:]...)
+	}
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+	return b, nil
+}
+
+//
+
+const (
+	signature     = "R-Pi"
+	version       = 1
+	headerLen     = 12
+	atomHeaderLen = 8
+	gpioMapLen    = 30
+)
+
+// Atom types.
+const (
+	atomVendor     = 1
+	atomGPIO       = 2
+	atomDeviceTree = 3
+	atomCustom     = 4
+)
+
+func (h *HAT) parseVendor(d []byte) error {
+	if len(d) < 22 || len(d) < 22+int(d[20])+int(d[21]) {
+		return fmt.Errorf("hat: vendor info atom has invalid length %d", len(d))
+	}
+	for i := range h.UUID {
+		h.UUID[i] = d[15-i]
+	}
+	h.ProductID = binary.LittleEndian.Uint16(d[16:])
+	h.ProductVer = binary.LittleEndian.Uint16(d[18:])
+	h.Vendor = string(d[22 : 22+d[20]])
+	h.Product = string(d[22+int(d[20]) : 22+int(d[20])+int(d[21])])
+	return nil
+}
+
+// pulls maps the pull type encoding of the GPIO map.
+var pulls = [4]gpio.Pull{gpio.PullNoChange, gpio.PullUp, gpio.PullDown, gpio.Float}
+
+func parseGPIO(d []byte) *GPIOMap {
+	g := &GPIOMap{
+		Drive:      2 * int(d[0]&0x0F),
+		Slew:       Slew(d[0] >> 4 & 3),
+		Hysteresis: Hysteresis(d[0] >> 6),
+		BackPower:  BackPower(d[1] & 3),
+	}
+	for i := range g.Pins {
+		v := d[2+i]
+		g.Pins[i] = Pin{Used: v&0x80 != 0, Func: Func(v & 7), Pull: pulls[v>>5&3]}
+	}
+	return g
+}
+
+func (g *GPIOMap) marshal() ([]byte, error) {
+	if g.Drive < 0 || g.Drive > 16 || g.Drive&1 != 0 {
+		return nil, fmt.Errorf("hat: invalid drive strength %dmA", g.Drive)
+	}
+	if g.Slew > SlewUnlimited || g.Hysteresis > HysteresisEnabled || g.BackPower > BackPower2000mA {
+		return nil, errors.New("hat: invalid GPIO bank setting")
+	}
+	d := make([]byte, gpioMapLen)
+	d[0] = byte(g.Drive/2) | byte(g.Slew)<<4 | byte(g.Hysteresis)<<6
+	d[1] = byte(g.BackPower)
+	for i, p := range g.Pins {
+		if !p.Used {
+			continue
+		}
+		if p.Func > Alt3 {
+			return nil, fmt.Errorf("hat: GPIO%d has invalid function %s", i, p.Func)
+		}
+		pull := -1
+		for j, x := range pulls {
+			if x == p.Pull {
+				pull = j
+			}
+		}
+		if pull == -1 {
+			return nil, fmt.Errorf("hat: GPIO%d has invalid pull %s", i, p.Pull)
+		}
+		d[2+i] = 0x80 | byte(pull)<<5 | byte(p.Func)
+	}
+	return d, nil
+}
+
+// crc16 is the CRC-16/ARC used by the atoms: polynomial 0x8005, reflected,
+// initial value 0.
+func crc16(b []byte) uint16 {
+	c := uint16(0)
+	for _, v := range b {
+		c ^= uint16(v)
+		for i := 0; i < 8; i++ {
+			if c&1 != 0 {
+				c = c>>1 ^ 0xA001
+			} else {
+				c >>= 1
+			}
+		}
+	}
+	return c
+}
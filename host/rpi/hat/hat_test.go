@@ -0,0 +1,161 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestMarshal_Parse(t *testing.T) {
+	h := testHAT()
+	b, err := h.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:4]) != "R-Pi" || b[4] != 1 || b[6] != 4 || int(b[8])|int(b[9])<<8 != len(b) {
+		t.Fatal(b[:12])
+	}
+	// The UUID is stored little endian.
+	if b[12+8] != 0xEF || b[12+8+15] != 0x01 {
+		t.Fatal(b[20:36])
+	}
+	// Trailing bytes, like the rest of the EEPROM, are ignored.
+	got, err := Parse(append(b, 0xFF, 0xFF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, h) {
+		t.Fatalf("%#v", got)
+	}
+	if s := got.String(); s != "ACME Inc. Widget HAT (0x0042 v3)" {
+		t.Fatal(s)
+	}
+	if p := got.GPIO.Pins[4]; !p.Used || p.Func != Alt0 || p.Pull != gpio.PullUp || p.Func.String() != "ALT0" {
+		t.Fatal(p)
+	}
+}
+
+func TestMarshal_defaultGPIO(t *testing.T) {
+	b, err := (&HAT{Vendor: "v", Product: "p"}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.GPIO == nil || h.GPIO.Pins[0].Used || h.DeviceTree != nil || h.Custom != nil {
+		t.Fatalf("%#v", h)
+	}
+}
+
+func TestMarshal_err(t *testing.T) {
+	data := []*HAT{
+		{Vendor: string(make([]byte, 256))},
+		{GPIO: &GPIOMap{Drive: 3}},
+		{GPIO: &GPIOMap{Drive: 18}},
+		{GPIO: &GPIOMap{Slew: 3}},
+		{GPIO: &GPIOMap{Pins: [28]Pin{{Used: true, Func: 8}}}},
+		{GPIO: &GPIOMap{Pins: [28]Pin{{Used: true, Pull: 10}}}},
+	}
+	for i, h := range data {
+		if _, err := h.Marshal(); err == nil {
+			t.Fatal(i)
+		}
+	}
+}
+
+func TestParse_err(t *testing.T) {
+	b, err := testHAT().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), b...))
+	}
+	data := [][]byte{
+		nil,
+		[]byte("R-Pi"),
+		corrupt(func(b []byte) []byte { b[0] = 'X'; return b }),
+		corrupt(func(b []byte) []byte { b[4] = 2; return b }),
+		corrupt(func(b []byte) []byte { b[8]++; return b }),
+		corrupt(func(b []byte) []byte { b[6]++; return b }),
+		// CRC of the first atom.
+		corrupt(func(b []byte) []byte { b[12+8]++; return b }),
+		// Count of the first atom.
+		corrupt(func(b []byte) []byte { b[12+2] = 1; return b }),
+		// Length of the first atom.
+		corrupt(func(b []byte) []byte { b[12+4] = 0xFF; return b }),
+	}
+	for i, line := range data {
+		if _, err := Parse(line); err == nil {
+			t.Fatal(i)
+		}
+	}
+	// No vendor info.
+	b = []byte{'R', '-', 'P', 'i', 1, 0, 0, 0, 12, 0, 0, 0}
+	if _, err := Parse(b); err == nil {
+		t.Fatal("vendor info is required")
+	}
+}
+
+func TestUUID(t *testing.T) {
+	const s = "01234567-89ab-cdef-0123-456789abcdef"
+	u, err := ParseUUID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u[0] != 0x01 || u[15] != 0xEF || u.String() != s {
+		t.Fatal(u)
+	}
+	for _, line := range []string{"", "01234567-89ab-cdef-0123-456789abcdeg", "0123456789ab-cdef-0123-456789abcdef-"} {
+		if _, err := ParseUUID(line); err == nil {
+			t.Fatal(line)
+		}
+	}
+}
+
+func TestFunc_String(t *testing.T) {
+	if s := Alt5.String(); s != "ALT5" {
+		t.Fatal(s)
+	}
+	if s := Func(8).String(); s != "Func(8)" {
+		t.Fatal(s)
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	if c := crc16([]byte("123456789")); c != 0xBB3D {
+		t.Fatalf("0x%04X", c)
+	}
+}
+
+//
+
+func testHAT() *HAT {
+	u, _ := ParseUUID("01234567-89ab-cdef-0123-456789abcdef")
+	h := &HAT{
+		Vendor:     "ACME Inc.",
+		Product:    "Widget HAT",
+		ProductID:  0x42,
+		ProductVer: 3,
+		UUID:       u,
+		GPIO: &GPIOMap{
+			Drive:      8,
+			Slew:       SlewLimited,
+			Hysteresis: HysteresisEnabled,
+			BackPower:  BackPower1300mA,
+		},
+		DeviceTree: []byte{0xD0, 0x0D, 0xFE, 0xED},
+		Custom:     [][]byte{[]byte("calibration")},
+	}
+	h.GPIO.Pins[4] = Pin{Used: true, Func: Alt0, Pull: gpio.PullUp}
+	h.GPIO.Pins[17] = Pin{Used: true, Func: Output, Pull: gpio.PullNoChange}
+	h.GPIO.Pins[27] = Pin{Used: true, Func: Input, Pull: gpio.Float}
+	return h
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"periph.io/x/periph/conn"
+)
+
+// Opener instantiates the driver of a HAT.
+//
+// It is provided by the device package.
+type Opener func(h *HAT) (conn.Resource, error)
+
+// Register registers the Opener of the HAT with the vendor and product
+// strings stored in its EEPROM.
+//
+// It is meant to be called in the init() function of device packages.
+// Registering the same HAT twice is an error.
+func Register(vendor, product string, o Opener) error {
+	if len(vendor) == 0 || len(product) == 0 {
+		return errors.New("hat: can't register a HAT with no vendor or product")
+	}
+	if o == nil {
+		return errors.New("hat: can't register HAT " + strconv.Quote(product) + " with nil Opener")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	k := key{vendor, product}
+	if _, ok := byProduct[k]; ok {
+		return errors.New("hat: can't register HAT " + strconv.Quote(product) + " twice")
+	}
+	byProduct[k] = o
+	return nil
+}
+
+// MustRegister calls Register() and panics if registration fails.
+func MustRegister(vendor, product string, o Opener) {
+	if err := Register(vendor, product, o); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes a previously registered HAT.
+func Unregister(vendor, product string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	k := key{vendor, product}
+	if _, ok := byProduct[k]; !ok {
+		return errors.New("hat: can't unregister unknown HAT " + strconv.Quote(product))
+	}
+	delete(byProduct, k)
+	return nil
+}
+
+// Open instantiates the driver of the HAT h.
+//
+// Use ReadDeviceTree() or ReadEEPROM() to find the attached HAT.
+func Open(h *HAT) (conn.Resource, error) {
+	mu.Lock()
+	o := byProduct[key{h.Vendor, h.Product}]
+	mu.Unlock()
+	if o == nil {
+		return nil, errors.New("hat: no driver registered for " + strconv.Quote(h.Vendor) + " " + strconv.Quote(h.Product))
+	}
+	return o(h)
+}
+
+//
+
+type key struct {
+	vendor  string
+	product string
+}
+
+var (
+	mu        sync.Mutex
+	byProduct = map[key]Opener{}
+)
@@ -0,0 +1,68 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"testing"
+
+	"periph.io/x/periph/conn"
+)
+
+func TestRegister(t *testing.T) {
+	h := testHAT()
+	if _, err := Open(h); err == nil {
+		t.Fatal("not registered")
+	}
+	var got *HAT
+	o := func(h *HAT) (conn.Resource, error) {
+		got = h
+		return &resource{}, nil
+	}
+	if err := Register("", "p", o); err == nil {
+		t.Fatal("no vendor")
+	}
+	if err := Register("v", "p", nil); err == nil {
+		t.Fatal("nil Opener")
+	}
+	MustRegister(h.Vendor, h.Product, o)
+	defer Unregister(h.Vendor, h.Product)
+	if err := Register(h.Vendor, h.Product, o); err == nil {
+		t.Fatal("registered twice")
+	}
+	r, err := Open(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "resource" || got != h {
+		t.Fatal(r, got)
+	}
+	if err := Unregister(h.Vendor, h.Product); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unregister(h.Vendor, h.Product); err == nil {
+		t.Fatal("not registered")
+	}
+}
+
+func TestMustRegister_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	MustRegister("v", "p", nil)
+}
+
+//
+
+type resource struct{}
+
+func (r *resource) String() string {
+	return "resource"
+}
+
+func (r *resource) Halt() error {
+	return nil
+}
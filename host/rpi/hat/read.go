@@ -0,0 +1,122 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"periph.io/x/periph/conn/i2c"
+)
+
+// ErrNotFound is returned when no HAT is detected.
+var ErrNotFound = errors.New("hat: no HAT detected")
+
+// EEPROMAddr is the I²C address of the HAT ID EEPROM.
+const EEPROMAddr = 0x50
+
+// ReadEEPROM reads and parses the ID EEPROM on bus.
+//
+// bus is the I²C bus 0 on the ID_SD and ID_SC pins. It is usually not exposed
+// by the kernel; it requires dtparam=i2c_vc=on in /boot/config.txt. The EEPROM
+// uses 16 bits memory addresses, like a 24C32.
+func ReadEEPROM(bus i2c.Bus) (*HAT, error) {
+	d := i2c.Dev{Bus: bus, Addr: EEPROMAddr}
+	var hdr [headerLen]byte
+	if err := readAt(&d, 0, hdr[:]); err != nil {
+		return nil, err
+	}
+	if string(hdr[:4]) != signature {
+		return nil, ErrNotFound
+	}
+	l := binary.LittleEndian.Uint32(hdr[8:])
+	if l < headerLen || l > maxEEPROMLen {
+		return nil, fmt.Errorf("hat: invalid EEPROM length %d", l)
+	}
+	b := make([]byte, l)
+	copy(b, hdr[:])
+	if err := readAt(&d, headerLen, b[headerLen:]); err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// ReadDeviceTree reads the copy of the ID EEPROM exposed by the firmware in
+// the device tree.
+//
+// Only the vendor info is available; GPIO is nil. Returns ErrNotFound when no
+// HAT was detected at boot.
+func ReadDeviceTree() (*HAT, error) {
+	return readDeviceTree(deviceTreeRoot)
+}
+
+//
+
+const (
+	deviceTreeRoot = "/proc/device-tree/hat"
+	maxEEPROMLen   = 65536
+	readChunk      = 1024
+)
+
+func readAt(d *i2c.Dev, off int, b []byte) error {
+	for len(b) != 0 {
+		n := len(b)
+		if n > readChunk {
+			n = readChunk
+		}
+		if err := d.Tx([]byte{byte(off >> 8), byte(off)}, b[:n]); err != nil {
+			return err
+		}
+		off += n
+		b = b[n:]
+	}
+	return nil
+}
+
+func readDeviceTree(root string) (*HAT, error) {
+	read := func(name string) (string, error) {
+		b, err := ioutil.ReadFile(filepath.Join(root, name))
+		return strings.TrimRight(string(b), "\x00\n"), err
+	}
+	h := &HAT{}
+	var err error
+	if h.Vendor, err = read("vendor"); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if h.Product, err = read("product"); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name string
+		v    *uint16
+	}{{"product_id", &h.ProductID}, {"product_ver", &h.ProductVer}} {
+		s, err := read(f.name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseUint(s, 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("hat: invalid %s %q", f.name, s)
+		}
+		*f.v = uint16(v)
+	}
+	s, err := read("uuid")
+	if err != nil {
+		return nil, err
+	}
+	if h.UUID, err = ParseUUID(s); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
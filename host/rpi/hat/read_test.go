@@ -0,0 +1,94 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package hat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"periph.io/x/periph/conn/i2c/i2ctest"
+)
+
+func TestReadEEPROM(t *testing.T) {
+	h := testHAT()
+	// Larger than a read chunk.
+	h.DeviceTree = make([]byte, 3000)
+	b, err := h.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	regs := make([]byte, 4096)
+	copy(regs, b)
+	s := &i2ctest.Sim{Devices: map[uint16]*i2ctest.SimDevice{EEPROMAddr: {AddrWidth: 2, Regs: regs}}}
+	got, err := ReadEEPROM(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, h) {
+		t.Fatalf("%#v", got)
+	}
+
+	// Blank EEPROM.
+	for i := range regs {
+		regs[i] = 0xFF
+	}
+	if _, err := ReadEEPROM(s); err != ErrNotFound {
+		t.Fatal(err)
+	}
+	// No EEPROM.
+	if _, err := ReadEEPROM(&i2ctest.Sim{}); err == nil {
+		t.Fatal("no device")
+	}
+}
+
+func TestReadDeviceTree(t *testing.T) {
+	root, err := ioutil.TempDir("", "periph_hat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if _, err := readDeviceTree(filepath.Join(root, "hat")); err != ErrNotFound {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"vendor":      "ACME Inc.\x00",
+		"product":     "Widget HAT\x00",
+		"product_id":  "0x0042\x00",
+		"product_ver": "0x0003\x00",
+		"uuid":        "01234567-89ab-cdef-0123-456789abcdef\x00",
+	}
+	for k, v := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, k), []byte(v), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := readDeviceTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testHAT()
+	want.GPIO = nil
+	want.DeviceTree = nil
+	want.Custom = nil
+	if !reflect.DeepEqual(h, want) {
+		t.Fatalf("%#v", h)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "product_id"), []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDeviceTree(root); err == nil {
+		t.Fatal("invalid product_id")
+	}
+	if err := os.Remove(filepath.Join(root, "product")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDeviceTree(root); err == nil {
+		t.Fatal("missing product")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Config describes a simulated host.
+//
+// See the package documentation for an example.
+type Config struct {
+	// Pins are the GPIO pins registered in gpioreg.
+	Pins []PinConfig `json:"pins"`
+	// Aliases maps alias names to pin names.
+	Aliases map[string]string `json:"aliases"`
+	// Wires is a list of sets of pins connected together.
+	Wires [][]string `json:"wires"`
+	// Headers maps a header name to its rows of pins, registered in pinreg.
+	//
+	// Pin names can be any GPIO pin or alias, or one of "GROUND", "1.8V",
+	// "2.8V", "3.3V", "5V", "DC_IN", "BAT+" or "INVALID".
+	Headers map[string][][]string `json:"headers"`
+	I2C     []I2CConfig           `json:"i2c"`
+	SPI     []SPIConfig           `json:"spi"`
+	OneWire []OneWireConfig       `json:"onewire"`
+}
+
+// PinConfig describes a simulated GPIO pin.
+type PinConfig struct {
+	Name string `json:"name"`
+	// Number defaults to the index of the pin in Config.Pins.
+	Number *int `json:"number"`
+	// Pull is the default pull resistor, one of "Float", "PullDown" or
+	// "PullUp". It defaults to "Float".
+	Pull string `json:"pull"`
+}
+
+// DeviceConfig describes a simulated register file device.
+type DeviceConfig struct {
+	// Addr is the I²C address. It is ignored on SPI.
+	Addr uint16 `json:"addr"`
+	// AddrWidth is the number of bytes of a register address, 1 or 2. It
+	// defaults to 1.
+	AddrWidth int `json:"addrWidth"`
+	// Size is the number of registers. It defaults to 256.
+	Size int `json:"size"`
+	// Regs are the initial values of the registers; the others are 0.
+	Regs []RegConfig `json:"regs"`
+}
+
+// RegConfig sets the initial values of consecutive registers.
+type RegConfig struct {
+	Reg    int     `json:"reg"`
+	Values []uint8 `json:"values"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// It is needed so Values is decoded as a list of numbers instead of base64.
+func (r *RegConfig) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Reg    int   `json:"reg"`
+		Values []int `json:"values"`
+	}
+	if err := strictUnmarshal(b, &v); err != nil {
+		return err
+	}
+	r.Reg = v.Reg
+	r.Values = make([]uint8, len(v.Values))
+	for i, x := range v.Values {
+		if x < 0 || x > 255 {
+			return fmt.Errorf("sim: invalid register value %d", x)
+		}
+		r.Values[i] = uint8(x)
+	}
+	return nil
+}
+
+// I2CConfig describes a simulated I²C bus.
+type I2CConfig struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// Number defaults to -1, meaning no bus number.
+	Number  *int           `json:"number"`
+	SCL     string         `json:"scl"`
+	SDA     string         `json:"sda"`
+	Devices []DeviceConfig `json:"devices"`
+}
+
+// SPIConfig describes a simulated SPI port.
+type SPIConfig struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// Number defaults to -1, meaning no port number.
+	Number *int          `json:"number"`
+	CLK    string        `json:"clk"`
+	MOSI   string        `json:"mosi"`
+	MISO   string        `json:"miso"`
+	CS     string        `json:"cs"`
+	Device *DeviceConfig `json:"device"`
+}
+
+// OneWireConfig describes a simulated 1-wire bus.
+type OneWireConfig struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// Number defaults to -1, meaning no bus number.
+	Number *int   `json:"number"`
+	Q      string `json:"q"`
+	// Devices are the 64 bits ROM addresses of the devices on the bus.
+	Devices []uint64 `json:"devices"`
+}
+
+// LoadConfig reads a JSON configuration file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := strictUnmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("sim: failed to parse %s: %v", path, err)
+	}
+	return c, nil
+}
+
+//
+
+func strictUnmarshal(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+func parsePull(s string) (gpio.Pull, error) {
+	switch s {
+	case "", gpio.Float.String():
+		return gpio.Float, nil
+	case gpio.PullDown.String():
+		return gpio.PullDown, nil
+	case gpio.PullUp.String():
+		return gpio.PullUp, nil
+	default:
+		return gpio.PullNoChange, errors.New("sim: invalid pull " + s)
+	}
+}
+
+func (d *DeviceConfig) regMap() (*RegMap, error) {
+	w := d.AddrWidth
+	if w == 0 {
+		w = 1
+	}
+	size := d.Size
+	if size == 0 {
+		size = 256
+	}
+	r, err := NewRegMap(size, w)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range d.Regs {
+		if err := r.Poke(v.Reg, v.Values); err != nil {
+			return nil, fmt.Errorf("sim: invalid initial value for register 0x%X: %v", v.Reg, err)
+		}
+	}
+	return r, nil
+}
+
+func number(n *int, def int) int {
+	if n == nil {
+		return def
+	}
+	return *n
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package sim implements a simulated host with virtual pins, headers and
+// buses.
+//
+// It is meant to develop and test applications on a workstation or in CI
+// without the actual hardware. Applications calling host.Init() run unchanged
+// against it.
+//
+// The driver is only loaded when the environment variable PERIPH_SIM is set
+// to the path of a JSON configuration file describing the simulated host. It
+// populates gpioreg, pinreg, i2creg, spireg and onewirereg. The names used
+// must not conflict with the ones registered by other drivers.
+//
+// Configuration
+//
+// Example of a configuration file:
+//
+//   {
+//     "pins": [
+//       {"name": "GPIO2", "number": 2, "pull": "PullUp"},
+//       {"name": "GPIO3", "number": 3, "pull": "PullUp"},
+//       {"name": "GPIO17", "number": 17},
+//       {"name": "GPIO27", "number": 27, "pull": "PullDown"}
+//     ],
+//     "aliases": {"LED": "GPIO17"},
+//     "wires": [["GPIO17", "GPIO27"]],
+//     "headers": {
+//       "P1": [["3.3V", "5V"], ["GPIO2", "5V"], ["GPIO3", "GROUND"]]
+//     },
+//     "i2c": [
+//       {
+//         "name": "I2C1", "number": 1, "scl": "GPIO3", "sda": "GPIO2",
+//         "devices": [
+//           {"addr": 118, "size": 256, "regs": [{"reg": 208, "values": [96]}]}
+//         ]
+//       }
+//     ],
+//     "spi": [
+//       {"name": "SPI0.0", "number": 0, "device": {"size": 128}}
+//     ],
+//     "onewire": [
+//       {"name": "OW0", "devices": [2882303761517117440]}
+//     ]
+//   }
+//
+// Pins
+//
+// Pins listed in the same wire are electrically connected: a pin set as
+// output drives the level read by the other pins on the wire and triggers
+// their edge detection. When no pin drives a wire, its level is set by the
+// pull resistors of the pins, or keeps its last level if floating.
+//
+// Devices
+//
+// Simulated I²C and SPI devices are register files that follow the protocol
+// described in package conn/mmr; the register address is written first, then
+// the register values are read or written. The address auto-increments for
+// each byte transferred.
+//
+// On SPI, the transfer is full duplex so the most significant bit of the
+// register address selects a read when set. The values read are returned in
+// the bytes following the address.
+//
+// Use I2C.Device() or SPI.Device() to access the registers of a simulated
+// device from a test.
+package sim
@@ -0,0 +1,338 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+)
+
+// Pin is a simulated GPIO pin.
+//
+// It can be connected to other pins with Connect().
+type Pin struct {
+	// Immutable.
+	name        string
+	number      int
+	defaultPull gpio.Pull
+
+	// Mutable; protected by mu.
+	w      *wire
+	out    bool       // true if the pin drives its wire
+	level  gpio.Level // level driven when out is true
+	pull   gpio.Pull
+	edge   gpio.Edge
+	events chan gpio.EdgeEvent
+	reset  chan struct{} // closed when In() or Halt() is called
+}
+
+// NewPin returns a simulated pin set as input.
+//
+// The pin is not registered in gpioreg.
+func NewPin(name string, number int, defaultPull gpio.Pull) *Pin {
+	p := &Pin{
+		name:        name,
+		number:      number,
+		defaultPull: defaultPull,
+		pull:        defaultPull,
+		events:      make(chan gpio.EdgeEvent, edgeQueueSize),
+		reset:       make(chan struct{}),
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	p.w = &wire{pins: []*Pin{p}}
+	p.w.update()
+	return p
+}
+
+// Connect electrically connects pins together.
+//
+// Pins already connected to other pins stay connected to them.
+func Connect(pins ...*Pin) {
+	if len(pins) == 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	w := pins[0].w
+	for _, p := range pins[1:] {
+		if p.w == w {
+			continue
+		}
+		for _, o := range p.w.pins {
+			o.w = w
+			w.pins = append(w.pins, o)
+		}
+	}
+	w.update()
+}
+
+// String implements conn.Resource.
+func (p *Pin) String() string {
+	return p.name
+}
+
+// Halt implements conn.Resource.
+//
+// It stops edge detection and unblocks any pending WaitForEdge() call.
+func (p *Pin) Halt() error {
+	mu.Lock()
+	defer mu.Unlock()
+	p.edge = gpio.NoEdge
+	p.flush()
+	return nil
+}
+
+// Name implements pin.Pin.
+func (p *Pin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+func (p *Pin) Number() int {
+	return p.number
+}
+
+// Function implements pin.Pin.
+func (p *Pin) Function() string {
+	return string(p.Func())
+}
+
+// Func implements pin.PinFunc.
+func (p *Pin) Func() pin.Func {
+	mu.Lock()
+	defer mu.Unlock()
+	if p.out {
+		if p.w.level {
+			return gpio.OUT_HIGH
+		}
+		return gpio.OUT_LOW
+	}
+	if p.w.level {
+		return gpio.IN_HIGH
+	}
+	return gpio.IN_LOW
+}
+
+// SupportedFuncs implements pin.PinFunc.
+func (p *Pin) SupportedFuncs() []pin.Func {
+	return []pin.Func{gpio.IN, gpio.OUT}
+}
+
+// SetFunc implements pin.PinFunc.
+func (p *Pin) SetFunc(f pin.Func) error {
+	switch f {
+	case gpio.IN:
+		return p.In(gpio.PullNoChange, gpio.NoEdge)
+	case gpio.OUT_HIGH:
+		return p.Out(gpio.High)
+	case gpio.OUT, gpio.OUT_LOW:
+		return p.Out(gpio.Low)
+	default:
+		return errors.New("sim: unsupported function")
+	}
+}
+
+// In implements gpio.PinIn.
+//
+// It flushes the queued edges and unblocks any pending WaitForEdge() call.
+func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	mu.Lock()
+	defer mu.Unlock()
+	p.out = false
+	if pull != gpio.PullNoChange {
+		p.pull = pull
+	}
+	p.edge = edge
+	p.flush()
+	p.w.update()
+	return nil
+}
+
+// Read implements gpio.PinIn.
+//
+// It returns the level of the wire, which may differ from the level driven
+// when another pin on the same wire drives it Low.
+func (p *Pin) Read() gpio.Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return p.w.level
+}
+
+// WaitForEdge implements gpio.PinIn.
+func (p *Pin) WaitForEdge(timeout time.Duration) bool {
+	_, ok := p.WaitForEdgeEvent(timeout)
+	return ok
+}
+
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+func (p *Pin) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	var t <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		t = timer.C
+	}
+	return p.waitForEdgeEvent(nil, t)
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	_, ok := p.waitForEdgeEvent(ctx.Done(), nil)
+	return ok
+}
+
+// Pull implements gpio.PinIn.
+func (p *Pin) Pull() gpio.Pull {
+	mu.Lock()
+	defer mu.Unlock()
+	return p.pull
+}
+
+// DefaultPull implements gpio.PinIn.
+func (p *Pin) DefaultPull() gpio.Pull {
+	return p.defaultPull
+}
+
+// Out implements gpio.PinOut.
+//
+// When several pins on the same wire are set as output with different
+// levels, Low wins, like on an open drain bus.
+func (p *Pin) Out(l gpio.Level) error {
+	mu.Lock()
+	defer mu.Unlock()
+	p.out = true
+	p.level = l
+	p.w.update()
+	return nil
+}
+
+// PWM implements gpio.PinOut.
+//
+// Only 0% and 100% duty cycles are supported.
+func (p *Pin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	switch duty {
+	case 0:
+		return p.Out(gpio.Low)
+	case gpio.DutyMax:
+		return p.Out(gpio.High)
+	default:
+		return errors.New("sim: PWM is not supported")
+	}
+}
+
+//
+
+func (p *Pin) waitForEdgeEvent(done <-chan struct{}, t <-chan time.Time) (gpio.EdgeEvent, bool) {
+	mu.Lock()
+	events, reset := p.events, p.reset
+	mu.Unlock()
+	// Return a queued edge even if the timeout is already expired.
+	select {
+	case e := <-events:
+		return e, true
+	default:
+	}
+	select {
+	case e := <-events:
+		return e, true
+	case <-reset:
+	case <-done:
+	case <-t:
+	}
+	return gpio.EdgeEvent{}, false
+}
+
+// flush discards the queued edges and unblocks pending waiters.
+//
+// mu must be held.
+func (p *Pin) flush() {
+	close(p.reset)
+	p.reset = make(chan struct{})
+	for {
+		select {
+		case <-p.events:
+		default:
+			return
+		}
+	}
+}
+
+// wire is a set of electrically connected pins.
+type wire struct {
+	pins  []*Pin
+	level gpio.Level
+}
+
+// update recalculates the level of the wire and queues edges on the pins
+// detecting them.
+//
+// mu must be held.
+func (w *wire) update() {
+	driven := false
+	level := gpio.High
+	up, down := false, false
+	for _, p := range w.pins {
+		if p.out {
+			driven = true
+			level = level && p.level
+		} else if p.pull == gpio.PullUp {
+			up = true
+		} else if p.pull == gpio.PullDown {
+			down = true
+		}
+	}
+	if !driven {
+		switch {
+		case up && !down:
+			level = gpio.High
+		case down && !up:
+			level = gpio.Low
+		default:
+			// Floating; keep the last level.
+			level = w.level
+		}
+	}
+	if level == w.level {
+		return
+	}
+	w.level = level
+	e := gpio.EdgeEvent{Level: level, Time: time.Since(epoch)}
+	for _, p := range w.pins {
+		if p.out || p.edge == gpio.NoEdge {
+			continue
+		}
+		if p.edge == gpio.RisingEdge && level == gpio.Low || p.edge == gpio.FallingEdge && level == gpio.High {
+			continue
+		}
+		select {
+		case p.events <- e:
+		default:
+			// The queue is full; the edge is lost.
+		}
+	}
+}
+
+// edgeQueueSize is the number of edges queued per pin.
+const edgeQueueSize = 64
+
+// mu protects the state of all the pins.
+//
+// A single lock is used since the wires can be connected arbitrarily.
+var mu sync.Mutex
+
+// epoch is the reference for the timestamps of edges.
+var epoch = time.Now()
+
+var _ gpio.PinIO = &Pin{}
+var _ gpio.PinEdgeEvents = &Pin{}
+var _ gpio.PinInContext = &Pin{}
+var _ pin.PinFunc = &Pin{}
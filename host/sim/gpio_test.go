@@ -0,0 +1,170 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestPin(t *testing.T) {
+	p := NewPin("GPIO1", 1, gpio.PullUp)
+	if s := p.String(); s != "GPIO1" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != 1 {
+		t.Fatal(n)
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatal("pull-up")
+	}
+	if f := p.Func(); f != gpio.IN_HIGH {
+		t.Fatal(f)
+	}
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if f := p.Function(); f != string(gpio.OUT_LOW) {
+		t.Fatal(f)
+	}
+	if err := p.PWM(gpio.DutyMax, 0); err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Read(); l != gpio.High {
+		t.Fatal("PWM 100%")
+	}
+	if err := p.PWM(gpio.DutyHalf, 0); err == nil {
+		t.Fatal("PWM 50%")
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if l := p.Read(); l != gpio.Low {
+		t.Fatal("pull-down")
+	}
+	if pull := p.Pull(); pull != gpio.PullDown {
+		t.Fatal(pull)
+	}
+	if pull := p.DefaultPull(); pull != gpio.PullUp {
+		t.Fatal(pull)
+	}
+	if err := p.SetFunc(gpio.OUT_HIGH); err != nil || p.Read() != gpio.High {
+		t.Fatal(err)
+	}
+	if err := p.SetFunc(gpio.FLOAT); err == nil {
+		t.Fatal("unsupported function")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	out := NewPin("OUT", 1, gpio.Float)
+	in := NewPin("IN", 2, gpio.PullDown)
+	other := NewPin("OTHER", 3, gpio.Float)
+	Connect(out, in)
+	Connect(in, other)
+	if err := in.In(gpio.PullDown, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.In(gpio.PullNoChange, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := in.WaitForEdgeEvent(0)
+	if !ok || e.Level != gpio.High {
+		t.Fatal(e, ok)
+	}
+	if !other.WaitForEdge(0) {
+		t.Fatal("rising edge")
+	}
+	if err := out.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	e2, ok := in.WaitForEdgeEvent(0)
+	if !ok || e2.Level != gpio.Low || e2.Time < e.Time {
+		t.Fatal(e2, ok)
+	}
+	if other.WaitForEdge(0) {
+		t.Fatal("falling edge must be ignored")
+	}
+
+	// Two outputs; Low wins.
+	if err := other.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if l := out.Read(); l != gpio.Low {
+		t.Fatal("Low must win")
+	}
+
+	// Nothing drives the wire; the pull-down sets the level.
+	if err := out.In(gpio.Float, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if !in.WaitForEdge(0) {
+		t.Fatal("expected rising edge")
+	}
+	if err := other.In(gpio.Float, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if l := out.Read(); l != gpio.Low {
+		t.Fatal("pull-down")
+	}
+}
+
+func TestPin_WaitForEdge(t *testing.T) {
+	out := NewPin("OUT", 1, gpio.Float)
+	in := NewPin("IN", 2, gpio.Float)
+	Connect(out, in)
+	if err := in.In(gpio.PullNoChange, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	if in.WaitForEdge(time.Millisecond) {
+		t.Fatal("no edge")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan bool)
+	go func() {
+		done <- in.WaitForEdgeContext(ctx)
+	}()
+	if err := out.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if !<-done {
+		t.Fatal("expected edge")
+	}
+
+	go func() {
+		done <- in.WaitForEdgeContext(ctx)
+	}()
+	cancel()
+	if <-done {
+		t.Fatal("context canceled")
+	}
+
+	go func() {
+		done <- in.WaitForEdge(-1)
+	}()
+	// Halt() may be called before the goroutine starts waiting; retry until it
+	// is unblocked.
+	for {
+		if err := in.Halt(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case ok := <-done:
+			if ok {
+				t.Fatal("halted")
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/physic"
+)
+
+// I2C is a simulated I²C bus.
+//
+// Transactions to an address without a device fail, like when the address is
+// not acknowledged.
+type I2C struct {
+	name     string
+	scl, sda gpio.PinIO
+
+	mu      sync.Mutex
+	devices map[uint16]*RegMap
+	speed   physic.Frequency
+}
+
+// NewI2C returns a simulated I²C bus without devices.
+//
+// scl and sda are optional.
+func NewI2C(name string, scl, sda gpio.PinIO) *I2C {
+	if scl == nil {
+		scl = gpio.INVALID
+	}
+	if sda == nil {
+		sda = gpio.INVALID
+	}
+	return &I2C{name: name, scl: scl, sda: sda, devices: map[uint16]*RegMap{}}
+}
+
+// Attach connects a simulated device at address addr.
+func (i *I2C) Attach(addr uint16, d *RegMap) error {
+	if addr >= 0x80 {
+		return fmt.Errorf("sim: invalid I²C address 0x%X", addr)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.devices[addr]; ok {
+		return fmt.Errorf("sim: a device is already attached at address 0x%02X on %s", addr, i.name)
+	}
+	i.devices[addr] = d
+	return nil
+}
+
+// Device returns the simulated device at address addr, or nil if none.
+func (i *I2C) Device(addr uint16) *RegMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.devices[addr]
+}
+
+// Close implements i2c.BusCloser.
+//
+// It is a no-op; the bus stays available to be opened again.
+func (i *I2C) Close() error {
+	return nil
+}
+
+func (i *I2C) String() string {
+	return i.name
+}
+
+// Tx implements i2c.Bus.
+func (i *I2C) Tx(addr uint16, w, r []byte) error {
+	return i.TxContext(context.Background(), addr, w, r)
+}
+
+// TxContext implements i2c.BusContext.
+func (i *I2C) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d := i.devices[addr]
+	if d == nil {
+		return &i2c.NACKError{Addr: addr, Err: fmt.Errorf("sim: no device at address 0x%02X on %s", addr, i.name)}
+	}
+	return d.Tx(w, r)
+}
+
+// SetSpeed implements i2c.Bus.
+func (i *I2C) SetSpeed(f physic.Frequency) error {
+	if f <= 0 {
+		return fmt.Errorf("sim: invalid speed %s", f)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.speed = f
+	return nil
+}
+
+// SCL implements i2c.Pins.
+func (i *I2C) SCL() gpio.PinIO {
+	return i.scl
+}
+
+// SDA implements i2c.Pins.
+func (i *I2C) SDA() gpio.PinIO {
+	return i.sda
+}
+
+var _ i2c.BusCloser = &I2C{}
+var _ i2c.BusContext = &I2C{}
+var _ i2c.Pins = &I2C{}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"sort"
+	"sync"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/onewire"
+)
+
+// OneWire is a simulated 1-wire bus.
+//
+// The devices on the bus answer the search and the presence pulse but don't
+// otherwise respond; the data read back is all ones, like an idle bus.
+type OneWire struct {
+	name string
+	q    gpio.PinIO
+
+	mu      sync.Mutex
+	devices []onewire.Address
+}
+
+// NewOneWire returns a simulated 1-wire bus with devices.
+//
+// q is optional.
+func NewOneWire(name string, q gpio.PinIO, devices ...onewire.Address) *OneWire {
+	if q == nil {
+		q = gpio.INVALID
+	}
+	d := make([]onewire.Address, len(devices))
+	copy(d, devices)
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return &OneWire{name: name, q: q, devices: d}
+}
+
+// Close implements onewire.BusCloser.
+//
+// It is a no-op; the bus stays available to be opened again.
+func (o *OneWire) Close() error {
+	return nil
+}
+
+func (o *OneWire) String() string {
+	return o.name
+}
+
+// Tx implements onewire.Bus.
+func (o *OneWire) Tx(w, r []byte, power onewire.Pullup) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.devices) == 0 {
+		return noDevicesError("sim: no device on the 1-wire bus")
+	}
+	for i := range r {
+		r[i] = 0xFF
+	}
+	return nil
+}
+
+// Search implements onewire.Bus.
+//
+// None of the devices are in alarm state.
+func (o *OneWire) Search(alarmOnly bool) ([]onewire.Address, error) {
+	if alarmOnly {
+		return nil, nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	d := make([]onewire.Address, len(o.devices))
+	copy(d, o.devices)
+	return d, nil
+}
+
+// Q implements onewire.Pins.
+func (o *OneWire) Q() gpio.PinIO {
+	return o.q
+}
+
+// noDevicesError implements error and onewire.NoDevicesError.
+type noDevicesError string
+
+func (e noDevicesError) Error() string   { return string(e) }
+func (e noDevicesError) NoDevices() bool { return true }
+
+var _ onewire.BusCloser = &OneWire{}
+var _ onewire.Pins = &OneWire{}
+var _ onewire.NoDevicesError = noDevicesError("")
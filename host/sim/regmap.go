@@ -0,0 +1,137 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RegMap is a simulated device exposing a register file.
+//
+// Registers are 8 bits wide. Register addresses are encoded as big endian on
+// the wire and auto-increment for each byte transferred, wrapping around at
+// the end of the register file.
+type RegMap struct {
+	mu        sync.Mutex
+	regs      []byte
+	addrWidth int
+	ptr       int
+}
+
+// NewRegMap returns a register file of size registers, initialized to 0.
+//
+// addrWidth is the number of bytes used to encode a register address; it must
+// be 1 or 2.
+func NewRegMap(size, addrWidth int) (*RegMap, error) {
+	if addrWidth != 1 && addrWidth != 2 {
+		return nil, fmt.Errorf("sim: invalid register address width %d", addrWidth)
+	}
+	if size <= 0 || size > 1<<uint(8*addrWidth) {
+		return nil, fmt.Errorf("sim: invalid register file size %d", size)
+	}
+	return &RegMap{regs: make([]byte, size), addrWidth: addrWidth}, nil
+}
+
+// Peek copies the registers starting at reg into b.
+func (r *RegMap) Peek(reg int, b []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reg < 0 || reg+len(b) > len(r.regs) {
+		return errors.New("sim: register out of range")
+	}
+	copy(b, r.regs[reg:])
+	return nil
+}
+
+// Poke sets the registers starting at reg to b.
+func (r *RegMap) Poke(reg int, b []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reg < 0 || reg+len(b) > len(r.regs) {
+		return errors.New("sim: register out of range")
+	}
+	copy(r.regs[reg:], b)
+	return nil
+}
+
+// Tx processes a half duplex transaction.
+//
+// If w is not empty, it starts with the register address followed by the
+// values to write. r is then read from the current register address. When w
+// is empty, the read continues where the last transaction stopped.
+func (r *RegMap) Tx(w, rd []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(w) != 0 {
+		if err := r.setPtr(w, 0); err != nil {
+			return err
+		}
+		for _, b := range w[r.addrWidth:] {
+			r.regs[r.ptr] = b
+			r.inc()
+		}
+	}
+	for i := range rd {
+		rd[i] = r.regs[r.ptr]
+		r.inc()
+	}
+	return nil
+}
+
+// txFull processes a full duplex transaction.
+//
+// w starts with the register address. If readBit is set in the first byte,
+// the register values are read into r after the address bytes, otherwise the
+// bytes of w following the address are written.
+func (r *RegMap) txFull(w, rd []byte, readBit byte) error {
+	if len(rd) != 0 && len(rd) != len(w) {
+		return errors.New("sim: w and r must have the same length")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(w) == 0 {
+		return nil
+	}
+	read := w[0]&readBit != 0
+	if err := r.setPtr(w, readBit); err != nil {
+		return err
+	}
+	for i := r.addrWidth; i < len(w); i++ {
+		if read {
+			if len(rd) != 0 {
+				rd[i] = r.regs[r.ptr]
+			}
+		} else {
+			r.regs[r.ptr] = w[i]
+		}
+		r.inc()
+	}
+	return nil
+}
+
+// setPtr sets the current register address from the first bytes of w,
+// ignoring the bits in mask.
+func (r *RegMap) setPtr(w []byte, mask byte) error {
+	if len(w) < r.addrWidth {
+		return errors.New("sim: register address is too short")
+	}
+	reg := int(w[0] &^ mask)
+	if r.addrWidth == 2 {
+		reg = reg<<8 | int(w[1])
+	}
+	if reg >= len(r.regs) {
+		return fmt.Errorf("sim: invalid register 0x%X", reg)
+	}
+	r.ptr = reg
+	return nil
+}
+
+func (r *RegMap) inc() {
+	if r.ptr++; r.ptr == len(r.regs) {
+		r.ptr = 0
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/mmr"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestNewRegMap(t *testing.T) {
+	if _, err := NewRegMap(16, 3); err == nil {
+		t.Fatal("invalid address width")
+	}
+	if _, err := NewRegMap(257, 1); err == nil {
+		t.Fatal("too large")
+	}
+	r, err := NewRegMap(16, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Poke(15, []byte{1, 2}); err == nil {
+		t.Fatal("out of range")
+	}
+	if err := r.Peek(-1, nil); err == nil {
+		t.Fatal("out of range")
+	}
+}
+
+func TestI2C(t *testing.T) {
+	b := NewI2C("I2C9", nil, nil)
+	r, err := NewRegMap(4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Attach(0x40, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Attach(0x40, r); err == nil {
+		t.Fatal("already attached")
+	}
+	if b.Device(0x40) != r {
+		t.Fatal("Device")
+	}
+	if err := b.SetSpeed(100 * physic.KiloHertz); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Tx(0x41, []byte{0}, nil); err == nil {
+		t.Fatal("no device")
+	}
+	d := mmr.Dev8{Conn: &i2c.Dev{Bus: b, Addr: 0x40}, Order: binary.BigEndian}
+	if err := d.WriteUint16(2, 0x1234); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.ReadUint8(3); err != nil || v != 0x34 {
+		t.Fatal(v, err)
+	}
+	// Auto-increment wraps around.
+	var v [3]byte
+	if err := b.Tx(0x40, []byte{3}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v[:], []byte{0x34, 0, 0}) {
+		t.Fatal(v)
+	}
+	// Continues where the last read stopped.
+	if err := b.Tx(0x40, nil, v[:1]); err != nil || v[0] != 0x12 {
+		t.Fatal(v, err)
+	}
+	if err := b.Tx(0x40, []byte{4}, nil); err == nil {
+		t.Fatal("invalid register")
+	}
+}
+
+func TestSPI(t *testing.T) {
+	r, err := NewRegMap(512, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Poke(0x100, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	p := NewSPI("SPI9.0", r, nil, nil, nil, nil)
+	if p.Device() != r {
+		t.Fatal("Device")
+	}
+	if err := p.Tx([]byte{0x81, 0, 0}, nil); err == nil {
+		t.Fatal("not connected")
+	}
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0, 9); err == nil {
+		t.Fatal("9 bits")
+	}
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0|spi.RxQuad, 8); err == nil {
+		t.Fatal("quad")
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0, 8); err == nil {
+		t.Fatal("already connected")
+	}
+	v := make([]byte, 4)
+	if err := c.Tx([]byte{0x81, 0x01, 0, 0}, v); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, []byte{0, 0, 2, 3}) {
+		t.Fatal(v)
+	}
+	// Write, then read back as a single transaction across packets.
+	if err := c.Tx([]byte{0x01, 0x00, 0xAA}, nil); err != nil {
+		t.Fatal(err)
+	}
+	v = make([]byte, 2)
+	if err := c.TxPackets([]spi.Packet{{W: []byte{0x81, 0x00}, KeepCS: true}, {R: v}}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, []byte{0xAA, 2}) {
+		t.Fatal(v)
+	}
+	if err := c.TxPackets([]spi.Packet{{R: v, RxWidth: 2}}); err == nil {
+		t.Fatal("dual packet")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// No device; MISO floats high.
+	p = NewSPI("SPI9.1", nil, nil, nil, nil, nil)
+	if c, err = p.Connect(physic.MegaHertz, spi.Mode0, 8); err != nil {
+		t.Fatal(err)
+	}
+	v = make([]byte, 2)
+	if err := c.Tx([]byte{0x80, 0}, v); err != nil || !bytes.Equal(v, []byte{0xFF, 0xFF}) {
+		t.Fatal(v, err)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+// EnvVar is the environment variable containing the path to the
+// configuration file. The driver is skipped when it is not set.
+const EnvVar = "PERIPH_SIM"
+
+// driver implements periph.Driver.
+type driver struct {
+	pins    map[string]*Pin
+	gpios   []string
+	aliases []string
+	headers []string
+	i2c     []string
+	spi     []string
+	onewire []string
+}
+
+func (d *driver) String() string {
+	return "sim"
+}
+
+func (d *driver) Prerequisites() []string {
+	return nil
+}
+
+func (d *driver) After() []string {
+	return nil
+}
+
+func (d *driver) Init() (bool, error) {
+	path := os.Getenv(EnvVar)
+	if path == "" {
+		return false, errors.New(EnvVar + " environment variable is not set")
+	}
+	c, err := LoadConfig(path)
+	if err != nil {
+		return true, err
+	}
+	return true, d.register(c)
+}
+
+// register registers the pins, headers and buses described by c.
+func (d *driver) register(c *Config) error {
+	d.pins = map[string]*Pin{}
+	for i, pc := range c.Pins {
+		if pc.Name == "" {
+			return fmt.Errorf("sim: pin #%d has no name", i)
+		}
+		pull, err := parsePull(pc.Pull)
+		if err != nil {
+			return err
+		}
+		p := NewPin(pc.Name, number(pc.Number, i), pull)
+		if err := gpioreg.Register(p); err != nil {
+			return err
+		}
+		d.gpios = append(d.gpios, p.name)
+		d.pins[p.name] = p
+	}
+	for alias, dest := range c.Aliases {
+		if err := gpioreg.RegisterAlias(alias, dest); err != nil {
+			return err
+		}
+		d.aliases = append(d.aliases, alias)
+	}
+	for _, names := range c.Wires {
+		pins := make([]*Pin, len(names))
+		for i, n := range names {
+			if pins[i] = d.pins[n]; pins[i] == nil {
+				return fmt.Errorf("sim: can't wire unknown pin %q", n)
+			}
+		}
+		Connect(pins...)
+	}
+	for name, rows := range c.Headers {
+		if err := d.registerHeader(name, rows); err != nil {
+			return err
+		}
+	}
+	for _, bc := range c.I2C {
+		if err := d.registerI2C(&bc); err != nil {
+			return err
+		}
+	}
+	for _, pc := range c.SPI {
+		if err := d.registerSPI(&pc); err != nil {
+			return err
+		}
+	}
+	for _, bc := range c.OneWire {
+		if err := d.registerOneWire(&bc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unregister undoes register.
+func (d *driver) unregister() {
+	for _, n := range d.onewire {
+		_ = onewirereg.Unregister(n)
+	}
+	for _, n := range d.spi {
+		_ = spireg.Unregister(n)
+	}
+	for _, n := range d.i2c {
+		_ = i2creg.Unregister(n)
+	}
+	for _, n := range d.headers {
+		_ = pinreg.Unregister(n)
+	}
+	for _, n := range d.aliases {
+		_ = gpioreg.Unregister(n)
+	}
+	for _, n := range d.gpios {
+		_ = gpioreg.Unregister(n)
+	}
+	*d = driver{}
+}
+
+func (d *driver) registerHeader(name string, rows [][]string) error {
+	h := make([][]pin.Pin, len(rows))
+	for i, row := range rows {
+		h[i] = make([]pin.Pin, len(row))
+		for j, n := range row {
+			if h[i][j] = wellKnownPins[n]; h[i][j] != nil {
+				continue
+			}
+			p := gpioreg.ByName(n)
+			if p == nil {
+				return fmt.Errorf("sim: unknown pin %q in header %s", n, name)
+			}
+			h[i][j] = p
+		}
+	}
+	if err := pinreg.Register(name, h); err != nil {
+		return err
+	}
+	d.headers = append(d.headers, name)
+	return nil
+}
+
+func (d *driver) registerI2C(c *I2CConfig) error {
+	scl, err := optionalPin(c.SCL)
+	if err != nil {
+		return err
+	}
+	sda, err := optionalPin(c.SDA)
+	if err != nil {
+		return err
+	}
+	b := NewI2C(c.Name, scl, sda)
+	for i := range c.Devices {
+		r, err := c.Devices[i].regMap()
+		if err != nil {
+			return err
+		}
+		if err := b.Attach(c.Devices[i].Addr, r); err != nil {
+			return err
+		}
+	}
+	o := func() (i2c.BusCloser, error) { return b, nil }
+	if err := i2creg.Register(c.Name, c.Aliases, number(c.Number, -1), o); err != nil {
+		return err
+	}
+	d.i2c = append(d.i2c, c.Name)
+	return nil
+}
+
+func (d *driver) registerSPI(c *SPIConfig) error {
+	var pins [4]gpio.PinIO
+	for i, n := range []string{c.CLK, c.MOSI, c.MISO, c.CS} {
+		p, err := optionalPin(n)
+		if err != nil {
+			return err
+		}
+		pins[i] = p
+	}
+	var r *RegMap
+	if c.Device != nil {
+		var err error
+		if r, err = c.Device.regMap(); err != nil {
+			return err
+		}
+	}
+	s := NewSPI(c.Name, r, pins[0], pins[1], pins[2], pins[3])
+	o := func() (spi.PortCloser, error) { return s, nil }
+	if err := spireg.Register(c.Name, c.Aliases, number(c.Number, -1), o); err != nil {
+		return err
+	}
+	d.spi = append(d.spi, c.Name)
+	return nil
+}
+
+func (d *driver) registerOneWire(c *OneWireConfig) error {
+	q, err := optionalPin(c.Q)
+	if err != nil {
+		return err
+	}
+	devices := make([]onewire.Address, len(c.Devices))
+	for i, a := range c.Devices {
+		devices[i] = onewire.Address(a)
+	}
+	b := NewOneWire(c.Name, q, devices...)
+	o := func() (onewire.BusCloser, error) { return b, nil }
+	if err := onewirereg.Register(c.Name, c.Aliases, number(c.Number, -1), o); err != nil {
+		return err
+	}
+	d.onewire = append(d.onewire, c.Name)
+	return nil
+}
+
+// optionalPin returns the GPIO pin named n or nil if n is empty.
+func optionalPin(n string) (gpio.PinIO, error) {
+	if n == "" {
+		return nil, nil
+	}
+	p := gpioreg.ByName(n)
+	if p == nil {
+		return nil, fmt.Errorf("sim: unknown pin %q", n)
+	}
+	return p, nil
+}
+
+// wellKnownPins are the non-GPIO pins that can be used in headers.
+var wellKnownPins = map[string]pin.Pin{
+	"INVALID": pin.INVALID,
+	"GROUND":  pin.GROUND,
+	"1.8V":    pin.V1_8,
+	"2.8V":    pin.V2_8,
+	"3.3V":    pin.V3_3,
+	"5V":      pin.V5,
+	"DC_IN":   pin.DC_IN,
+	"BAT+":    pin.BAT_PLUS,
+}
+
+func init() {
+	periph.MustRegister(&drv)
+}
+
+var drv driver
@@ -0,0 +1,211 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/onewire"
+	"periph.io/x/periph/conn/onewire/onewirereg"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/conn/pin/pinreg"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spireg"
+)
+
+func TestDriver(t *testing.T) {
+	d := driver{}
+	if s := d.String(); s != "sim" {
+		t.Fatal(s)
+	}
+	if d.Prerequisites() != nil || d.After() != nil {
+		t.Fatal("unexpected dependencies")
+	}
+	old, ok := os.LookupEnv(EnvVar)
+	os.Unsetenv(EnvVar)
+	defer func() {
+		if ok {
+			os.Setenv(EnvVar, old)
+		}
+	}()
+	if ok, err := d.Init(); ok || err == nil {
+		t.Fatal("expected skip")
+	}
+}
+
+func TestDriver_Init(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sim.json")
+	if err := ioutil.WriteFile(path, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old, ok := os.LookupEnv(EnvVar)
+	os.Setenv(EnvVar, path)
+	defer func() {
+		if ok {
+			os.Setenv(EnvVar, old)
+		} else {
+			os.Unsetenv(EnvVar)
+		}
+	}()
+	d := driver{}
+	defer d.unregister()
+	if ok, err := d.Init(); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+
+	// GPIO and wires.
+	led := gpioreg.ByName("SIM_LED")
+	in := gpioreg.ByName("SIM_IN")
+	if led == nil || in == nil {
+		t.Fatal("pins not registered")
+	}
+	if n := in.Number(); n != 3 {
+		t.Fatal(n)
+	}
+	if err := in.In(gpio.PullNoChange, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if err := led.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if !in.WaitForEdge(0) || in.Read() != gpio.High {
+		t.Fatal("wire")
+	}
+
+	// Headers.
+	h := pinreg.All()["SIM_P1"]
+	if len(h) != 2 || h[0][0] != pin.V3_3 || h[1][0].Name() != "SIM_IN" {
+		t.Fatal(h)
+	}
+
+	// I²C.
+	b, err := i2creg.Open("SIM_I2C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var v [2]byte
+	if err := b.Tx(0x76, []byte{0xD0}, v[:]); err != nil {
+		t.Fatal(err)
+	}
+	if v != [2]byte{0x60, 0x01} {
+		t.Fatal(v)
+	}
+	if p := b.(*I2C).SDA(); p.Name() != "SIM_SDA" {
+		t.Fatal(p)
+	}
+
+	// SPI.
+	s, err := spireg.Open("SIM_SPI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := s.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := make([]byte, 2)
+	if err := c.Tx([]byte{0x82, 0}, r); err != nil || r[1] != 0x42 {
+		t.Fatal(r, err)
+	}
+
+	// 1-Wire.
+	o, err := onewirereg.Open("SIM_OW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	a, err := o.Search(false)
+	if err != nil || len(a) != 2 || a[0] != 0x10 {
+		t.Fatal(a, err)
+	}
+	if err := o.Tx([]byte{0xCC}, nil, onewire.WeakPullup); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriver_register_errors(t *testing.T) {
+	data := []Config{
+		{Pins: []PinConfig{{}}},
+		{Pins: []PinConfig{{Name: "SIM_X", Pull: "Up"}}},
+		{Wires: [][]string{{"SIM_X"}}},
+		{Headers: map[string][][]string{"SIM_H": {{"SIM_X"}}}},
+		{I2C: []I2CConfig{{Name: "SIM_I2C", SCL: "SIM_X"}}},
+		{I2C: []I2CConfig{{Name: "SIM_I2C", Devices: []DeviceConfig{{Addr: 1}, {Addr: 1}}}}},
+		{I2C: []I2CConfig{{Name: "SIM_I2C", Devices: []DeviceConfig{{Size: 2, Regs: []RegConfig{{Reg: 1, Values: []uint8{1, 2}}}}}}}},
+		{SPI: []SPIConfig{{Name: "SIM_SPI", Device: &DeviceConfig{AddrWidth: 3}}}},
+		{OneWire: []OneWireConfig{{Name: "SIM_OW", Q: "SIM_X"}}},
+	}
+	for i, c := range data {
+		d := driver{}
+		if err := d.register(&c); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+		d.unregister()
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "periph_sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("missing file")
+	}
+	data := []string{
+		`{"unknown": 1}`,
+		`{"i2c": [{"devices": [{"regs": [{"reg": 0, "values": [256]}]}]}]}`,
+	}
+	for i, s := range data {
+		path := filepath.Join(dir, "sim.json")
+		if err := ioutil.WriteFile(path, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+	}
+}
+
+//
+
+const testConfig = `{
+  "pins": [
+    {"name": "SIM_SDA", "pull": "PullUp"},
+    {"name": "SIM_SCL", "pull": "PullUp"},
+    {"name": "SIM_OUT"},
+    {"name": "SIM_IN", "pull": "PullDown"}
+  ],
+  "aliases": {"SIM_LED": "SIM_OUT"},
+  "wires": [["SIM_OUT", "SIM_IN"]],
+  "headers": {"SIM_P1": [["3.3V", "GROUND"], ["SIM_IN", "SIM_SDA"]]},
+  "i2c": [
+    {
+      "name": "SIM_I2C", "scl": "SIM_SCL", "sda": "SIM_SDA",
+      "devices": [{"addr": 118, "regs": [{"reg": 208, "values": [96, 1]}]}]
+    }
+  ],
+  "spi": [
+    {"name": "SIM_SPI", "device": {"size": 16, "regs": [{"reg": 2, "values": [66]}]}}
+  ],
+  "onewire": [
+    {"name": "SIM_OW", "devices": [32, 16]}
+  ]
+}`
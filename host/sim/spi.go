@@ -0,0 +1,228 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// SPI is a simulated SPI port with an optional device.
+//
+// It implements both spi.PortCloser and the spi.Conn returned by Connect().
+type SPI struct {
+	name                string
+	clk, mosi, miso, cs gpio.PinIO
+	device              *RegMap
+
+	mu        sync.Mutex
+	connected bool
+	maxSpeed  physic.Frequency
+	speed     physic.Frequency
+	mode      spi.Mode
+}
+
+// NewSPI returns a simulated SPI port.
+//
+// d is the device connected to the port, it may be nil. The pins are
+// optional.
+func NewSPI(name string, d *RegMap, clk, mosi, miso, cs gpio.PinIO) *SPI {
+	s := &SPI{name: name, clk: clk, mosi: mosi, miso: miso, cs: cs, device: d}
+	for _, p := range []*gpio.PinIO{&s.clk, &s.mosi, &s.miso, &s.cs} {
+		if *p == nil {
+			*p = gpio.INVALID
+		}
+	}
+	return s
+}
+
+// Device returns the simulated device connected to the port, or nil if none.
+func (s *SPI) Device() *RegMap {
+	return s.device
+}
+
+// Close implements spi.PortCloser.
+//
+// The port can be connected again afterward.
+func (s *SPI) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = false
+	return nil
+}
+
+func (s *SPI) String() string {
+	return s.name
+}
+
+// LimitSpeed implements spi.PortCloser.
+func (s *SPI) LimitSpeed(f physic.Frequency) error {
+	if f <= 0 {
+		return fmt.Errorf("sim: invalid speed %s", f)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSpeed = f
+	return nil
+}
+
+// Connect implements spi.Port.
+//
+// Only 8 bits words are supported.
+func (s *SPI) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	if f < 0 {
+		return nil, fmt.Errorf("sim: invalid speed %s", f)
+	}
+	if mode&(spi.TxDual|spi.TxQuad|spi.RxDual|spi.RxQuad) != 0 {
+		return nil, fmt.Errorf("sim: dual and quad modes; got %v: %w", mode, conn.ErrUnsupported)
+	}
+	if mode&^(spi.Mode3|spi.HalfDuplex|spi.NoCS|spi.LSBFirst|spi.NoChunk) != 0 {
+		return nil, fmt.Errorf("sim: invalid mode %v", mode)
+	}
+	if bits != 8 {
+		return nil, fmt.Errorf("sim: %d bits: %w", bits, conn.ErrUnsupported)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.connected {
+		return nil, errors.New("sim: Connect() can only be called exactly once")
+	}
+	s.connected = true
+	s.speed = f
+	s.mode = mode
+	return s, nil
+}
+
+// Tx implements conn.Conn.
+func (s *SPI) Tx(w, r []byte) error {
+	return s.TxContext(context.Background(), w, r)
+}
+
+// TxContext implements spi.ConnContext.
+func (s *SPI) TxContext(ctx context.Context, w, r []byte) error {
+	return s.TxPacketsContext(ctx, []spi.Packet{{W: w, R: r}})
+}
+
+// TxPackets implements spi.Conn.
+func (s *SPI) TxPackets(p []spi.Packet) error {
+	return s.TxPacketsContext(context.Background(), p)
+}
+
+// TxPacketsContext implements spi.ConnContext.
+//
+// Consecutive packets with KeepCS set are processed as a single transaction
+// by the device. The Speed, DelayAfter and WordDelay of the packets have no
+// effect on the simulated devices.
+func (s *SPI) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		return errors.New("sim: Connect() must be called first")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var w, r []byte
+	start := 0
+	for i := range p {
+		lW, lR := len(p[i].W), len(p[i].R)
+		if lW != lR && lW != 0 && lR != 0 {
+			return fmt.Errorf("sim: when both w and r are used, they must be the same size; got %d and %d bytes", lW, lR)
+		}
+		if s.mode&spi.HalfDuplex != 0 && lW != 0 && lR != 0 {
+			return errors.New("sim: can only specify one of w or r when in half duplex")
+		}
+		if p[i].TxWidth > 1 || p[i].RxWidth > 1 {
+			return fmt.Errorf("sim: dual and quad packets: %w", conn.ErrUnsupported)
+		}
+		w = append(w, p[i].W...)
+		w = append(w, make([]byte, packetLen(&p[i])-lW)...)
+		if !p[i].KeepCS || i == len(p)-1 {
+			r = make([]byte, len(w))
+			if err := s.tx(w, r); err != nil {
+				return err
+			}
+			for j := start; j <= i; j++ {
+				l := packetLen(&p[j])
+				copy(p[j].R, r[:l])
+				r = r[l:]
+			}
+			w = w[:0]
+			start = i + 1
+		}
+	}
+	return nil
+}
+
+// Duplex implements conn.Conn.
+func (s *SPI) Duplex() conn.Duplex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mode&spi.HalfDuplex != 0 {
+		return conn.Half
+	}
+	return conn.Full
+}
+
+// CLK implements spi.Pins.
+func (s *SPI) CLK() gpio.PinOut {
+	return s.clk
+}
+
+// MOSI implements spi.Pins.
+func (s *SPI) MOSI() gpio.PinOut {
+	return s.mosi
+}
+
+// MISO implements spi.Pins.
+func (s *SPI) MISO() gpio.PinIn {
+	return s.miso
+}
+
+// CS implements spi.Pins.
+func (s *SPI) CS() gpio.PinOut {
+	return s.cs
+}
+
+//
+
+// tx processes a transaction with the device, if any.
+//
+// When no device is connected, MISO floats high.
+func (s *SPI) tx(w, r []byte) error {
+	if s.device == nil {
+		for i := range r {
+			r[i] = 0xFF
+		}
+		return nil
+	}
+	if s.mode&spi.HalfDuplex != 0 {
+		return fmt.Errorf("sim: half duplex with the device: %w", conn.ErrUnsupported)
+	}
+	return s.device.txFull(w, r, spiReadBit)
+}
+
+// packetLen returns the number of bytes transferred by a packet.
+func packetLen(p *spi.Packet) int {
+	if len(p.W) != 0 {
+		return len(p.W)
+	}
+	return len(p.R)
+}
+
+// spiReadBit is the bit set in the first address byte to read registers.
+const spiReadBit = 0x80
+
+var _ spi.PortCloser = &SPI{}
+var _ spi.Conn = &SPI{}
+var _ spi.ConnContext = &SPI{}
+var _ spi.Pins = &SPI{}
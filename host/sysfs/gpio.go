@@ -5,6 +5,7 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"periph.io/x/periph"
@@ -48,6 +50,7 @@ type Pin struct {
 	fValue     fileIO    // handle to /sys/class/gpio/gpio*/value; never closed
 	event      fs.Event  // Initialized once
 	buf        [4]byte   // scratch buffer for Func(), Read() and Out()
+	gen        uint32    // incremented by In() and Halt() to abort WaitForEdgeContext()
 }
 
 // String implements conn.Resource.
@@ -136,6 +139,7 @@ func (p *Pin) In(pull gpio.Pull, edge gpio.Edge) error {
 	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	atomic.AddUint32(&p.gen, 1)
 	if p.direction != dIn {
 		if err := p.open(); err != nil {
 			return p.wrap(err)
@@ -248,6 +252,58 @@ func (p *Pin) WaitForEdge(timeout time.Duration) bool {
 	}
 }
 
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// The edge is timestamped when the process is woken up so the timestamp
+// includes the scheduling latency. gpio sysfs coalesces edges happening in
+// quick succession, so the queue is effectively one edge deep. Use LinePin for
+// a kernel timestamped and queued edge capture.
+func (p *Pin) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	if !p.WaitForEdge(timeout) {
+		return gpio.EdgeEvent{}, false
+	}
+	e := gpio.EdgeEvent{Time: monotonic()}
+	// Run lockless, like WaitForEdge().
+	switch p.edge {
+	case gpio.RisingEdge:
+		e.Level = gpio.High
+	case gpio.FallingEdge:
+		e.Level = gpio.Low
+	default:
+		e.Level = p.Read()
+	}
+	return e, true
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// The kernel wait cannot be interrupted, so ctx is polled every 100ms while
+// waiting. For the same reason, calling In() or Halt() while waiting causes
+// the function to exit with the same latency. It returns false right away if
+// edge detection is not enabled.
+func (p *Pin) WaitForEdgeContext(ctx context.Context) bool {
+	gen := atomic.LoadUint32(&p.gen)
+	for ctx.Err() == nil {
+		timeout := edgePollPeriod
+		if d, ok := ctx.Deadline(); ok {
+			if r := time.Until(d); r < timeout {
+				if r <= 0 {
+					return false
+				}
+				timeout = r
+			}
+		}
+		start := time.Now()
+		if p.WaitForEdge(timeout) {
+			return true
+		}
+		if time.Since(start) < timeout || atomic.LoadUint32(&p.gen) != gen {
+			return false
+		}
+	}
+	return false
+}
+
 // Pull implements gpio.PinIn.
 //
 // It returns gpio.PullNoChange since gpio sysfs has no support for input pull
@@ -366,6 +422,7 @@ direction:
 
 // haltEdge stops any on-going edge detection.
 func (p *Pin) haltEdge() error {
+	atomic.AddUint32(&p.gen, 1)
 	if p.edge != gpio.NoEdge {
 		if err := seekWrite(p.fEdge, bNone); err != nil {
 			return p.wrap(err)
@@ -391,6 +448,10 @@ const (
 	dOut     direction = 2
 )
 
+// edgePollPeriod is the maximum latency to detect a canceled context in
+// WaitForEdgeContext().
+const edgePollPeriod = 100 * time.Millisecond
+
 var (
 	bIn      = []byte("in")
 	bLow     = []byte("low")
@@ -421,9 +482,32 @@ func readInt(path string) (int, error) {
 	return strconv.Atoi(string(raw[:len(raw)-1]))
 }
 
+// readString reads a pseudo-file (sysfs) that is known to contain a single
+// line of text and returns it without the trailing new line.
+func readString(path string) (string, error) {
+	f, err := fileIOOpen(path, os.O_RDONLY)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var b [64]byte
+	n, err := f.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	raw := b[:n]
+	if len(raw) == 0 || raw[len(raw)-1] != '\n' {
+		return "", errors.New("invalid value")
+	}
+	return string(raw[:len(raw)-1]), nil
+}
+
 // driverGPIO implements periph.Driver.
 type driverGPIO struct {
 	exportHandle io.Writer // handle to /sys/class/gpio/export
+	// bases maps the GPIO chip labels to their base GPIO number. It is -1 when
+	// the label is ambiguous.
+	bases map[string]int
 }
 
 func (d *driverGPIO) String() string {
@@ -460,6 +544,7 @@ func (d *driverGPIO) Init() (bool, error) {
 	// There are hosts that use non-continuous pin numbering so use a map instead
 	// of an array.
 	Pins = map[int]*Pin{}
+	d.bases = map[string]int{}
 	for _, item := range items {
 		if err := d.parseGPIOChip(item + "/"); err != nil {
 			return true, err
@@ -481,6 +566,15 @@ func (d *driverGPIO) parseGPIOChip(path string) error {
 	if err != nil {
 		return err
 	}
+	// The label is only used to map the pins to the GPIO character device, it
+	// is not fatal if it can't be read.
+	if label, err := readString(path + "label"); err == nil {
+		if _, ok := d.bases[label]; ok {
+			d.bases[label] = -1
+		} else {
+			d.bases[label] = base
+		}
+	}
 	// TODO(maruel): The chip driver may lie and lists GPIO pins that cannot be
 	// exported. The only way to know about it is to export it before opening.
 	for i := base; i < base+number; i++ {
@@ -505,6 +599,13 @@ func (d *driverGPIO) parseGPIOChip(path string) error {
 	return nil
 }
 
+// chipBase returns the base GPIO number of the GPIO chip with this label, if
+// it is known.
+func (d *driverGPIO) chipBase(label string) (int, bool) {
+	b, ok := d.bases[label]
+	return b, ok && b >= 0
+}
+
 func init() {
 	if isLinux {
 		periph.MustRegister(&drvGPIO)
@@ -517,4 +618,6 @@ var _ conn.Resource = &Pin{}
 var _ gpio.PinIn = &Pin{}
 var _ gpio.PinOut = &Pin{}
 var _ gpio.PinIO = &Pin{}
+var _ gpio.PinEdgeEvents = &Pin{}
+var _ gpio.PinInContext = &Pin{}
 var _ pin.PinFunc = &Pin{}
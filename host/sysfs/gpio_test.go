@@ -5,8 +5,11 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
+	"os"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/physic"
@@ -137,6 +140,66 @@ func TestPin_WaitForEdges(t *testing.T) {
 	if p.WaitForEdge(-1) {
 		t.Fatal("broken pin doesn't have edge triggered")
 	}
+	if _, ok := p.WaitForEdgeEvent(-1); ok {
+		t.Fatal("broken pin doesn't have edge triggered")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if p.WaitForEdgeContext(ctx) {
+		t.Fatal("broken pin doesn't have edge triggered")
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if p.WaitForEdgeContext(ctx) {
+		t.Fatal("context is canceled")
+	}
+}
+
+func TestPin_WaitForEdgeContext_NoEdge(t *testing.T) {
+	p := Pin{number: 42, name: "foo", root: "/tmp/gpio/priv/", direction: dIn, edge: gpio.NoEdge}
+	// Returns right away instead of polling until ctx is done.
+	if p.WaitForEdgeContext(context.Background()) {
+		t.Fatal("edge detection is not enabled")
+	}
+}
+
+func TestPin_WaitForEdgeContext_In(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	p := Pin{
+		number:     42,
+		name:       "foo",
+		root:       "/tmp/gpio/priv/",
+		direction:  dIn,
+		edge:       gpio.RisingEdge,
+		fDirection: &fakeGPIOFile{data: []byte("in")},
+		fEdge:      &fakeGPIOFile{data: []byte("rising")},
+		fValue:     &fakeGPIOFile{data: []byte("0")},
+	}
+	// The pipe never triggers an edge.
+	if err := p.event.MakeEvent(r.Fd()); err != nil {
+		t.Skip(err)
+	}
+	done := make(chan bool)
+	go func() {
+		done <- p.WaitForEdgeContext(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := p.In(gpio.PullNoChange, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case b := <-done:
+		if b {
+			t.Fatal("unexpected edge")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("In() didn't abort the wait")
+	}
 }
 
 func TestPin_Pull(t *testing.T) {
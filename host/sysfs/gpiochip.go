@@ -0,0 +1,1139 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+	"periph.io/x/periph/host/fs"
+)
+
+// GPIOChips is all the GPIO controllers exposed by the GPIO character device
+// interface.
+//
+// This global variable is initialized once at driver initialization and isn't
+// mutated afterward. Do not modify it.
+var GPIOChips []*GPIOChip
+
+// GPIOChip is a GPIO controller as exposed via /dev/gpiochipN.
+type GPIOChip struct {
+	name  string // Something like "gpiochip0"
+	label string // Something like "pinctrl-bcm2835"
+	path  string // Something like /dev/gpiochip0
+
+	f     ioctlCloser // handle to /dev/gpiochipN; never closed
+	lines []*LinePin
+}
+
+// String implements fmt.Stringer.
+func (c *GPIOChip) String() string {
+	return c.name
+}
+
+// Name returns the kernel name of the chip, e.g. "gpiochip0".
+func (c *GPIOChip) Name() string {
+	return c.name
+}
+
+// Label returns the label of the chip as reported by the kernel driver, e.g.
+// "pinctrl-bcm2835".
+func (c *GPIOChip) Label() string {
+	return c.label
+}
+
+// Lines returns all the lines exposed by this chip, ordered by offset.
+func (c *GPIOChip) Lines() []*LinePin {
+	return c.lines
+}
+
+// Drive specifies the output drive mode of a LinePin.
+type Drive uint8
+
+// Acceptable drive values.
+const (
+	PushPull   Drive = 0 // Actively drive both levels
+	OpenDrain  Drive = 1 // Only drive low, let the line float when high
+	OpenSource Drive = 2 // Only drive high, let the line float when low
+)
+
+const driveName = "PushPullOpenDrainOpenSource"
+
+var driveIndex = [...]uint8{0, 8, 17, 27}
+
+func (i Drive) String() string {
+	if i >= Drive(len(driveIndex)-1) {
+		return "Drive(" + strconv.Itoa(int(i)) + ")"
+	}
+	return driveName[driveIndex[i]:driveIndex[i+1]]
+}
+
+// LinePin represents one GPIO line as exposed by the GPIO character device
+// interface.
+//
+// Unlike Pin, it supports internal pull resistors, open drain and open source
+// outputs and kernel debouncing.
+//
+// The line is requested from the kernel on the first call to In() or Out() and
+// is kept until the process exits, unless the line is used by a gpio.Group.
+type LinePin struct {
+	chip     *GPIOChip
+	offset   uint32
+	number   int
+	name     string
+	lineName string // Name as provided by the kernel driver, if any
+
+	mu        sync.Mutex
+	f         gpioLineFile // handle to the line request; nil until requested
+	direction direction
+	pull      gpio.Pull
+	edge      gpio.Edge
+	drive     Drive
+	debounce  time.Duration
+	level     gpio.Level // Last level set via Out()
+}
+
+// String implements conn.Resource.
+func (p *LinePin) String() string {
+	return p.name
+}
+
+// Halt implements conn.Resource.
+//
+// It stops edge detection if enabled.
+func (p *LinePin) Halt() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.edge == gpio.NoEdge {
+		return nil
+	}
+	p.edge = gpio.NoEdge
+	if err := p.apply(); err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// Name implements pin.Pin.
+func (p *LinePin) Name() string {
+	return p.name
+}
+
+// Number implements pin.Pin.
+//
+// It is the global GPIO number if it could be determined, otherwise the line
+// offset on the chip.
+func (p *LinePin) Number() int {
+	return p.number
+}
+
+// Function implements pin.Pin.
+func (p *LinePin) Function() string {
+	return string(p.Func())
+}
+
+// Func implements pin.PinFunc.
+func (p *LinePin) Func() pin.Func {
+	p.mu.Lock()
+	d := p.direction
+	p.mu.Unlock()
+	if d == dUnknown {
+		// The line was not requested yet, ask the kernel about its current
+		// state.
+		var info gpioV2LineInfo
+		if err := p.chip.lineInfo(p.offset, &info); err != nil {
+			return pin.FuncNone
+		}
+		if info.flags&gpioV2LineFlagOutput != 0 {
+			return gpio.OUT
+		}
+		if info.flags&gpioV2LineFlagInput != 0 {
+			return gpio.IN
+		}
+		return pin.FuncNone
+	}
+	if d == dIn {
+		if p.Read() {
+			return gpio.IN_HIGH
+		}
+		return gpio.IN_LOW
+	}
+	if p.Read() {
+		return gpio.OUT_HIGH
+	}
+	return gpio.OUT_LOW
+}
+
+// SupportedFuncs implements pin.PinFunc.
+func (p *LinePin) SupportedFuncs() []pin.Func {
+	return []pin.Func{gpio.IN, gpio.OUT, gpio.OUT_OC}
+}
+
+// SetFunc implements pin.PinFunc.
+func (p *LinePin) SetFunc(f pin.Func) error {
+	switch f {
+	case gpio.IN:
+		return p.In(gpio.PullNoChange, gpio.NoEdge)
+	case gpio.OUT_HIGH:
+		return p.Out(gpio.High)
+	case gpio.OUT, gpio.OUT_LOW:
+		return p.Out(gpio.Low)
+	case gpio.OUT_OC:
+		if err := p.SetDrive(OpenDrain); err != nil {
+			return err
+		}
+		return p.Out(gpio.Low)
+	default:
+		return p.wrap(errors.New("unsupported function"))
+	}
+}
+
+// In implements gpio.PinIn.
+//
+// Contrary to Pin.In(), pull resistors are supported.
+func (p *LinePin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if pull > gpio.PullUp {
+		return p.wrap(fmt.Errorf("invalid pull %s", pull))
+	}
+	if edge > gpio.BothEdges {
+		return p.wrap(fmt.Errorf("invalid edge %s", edge))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Wake up any pending WaitForEdge() call.
+	if p.f != nil {
+		_ = p.f.SetReadDeadline(time.Now())
+	}
+	p.direction = dIn
+	if pull != gpio.PullNoChange {
+		p.pull = pull
+	}
+	p.edge = edge
+	if err := p.apply(); err != nil {
+		return p.wrap(err)
+	}
+	// Flush the events accumulated before the reconfiguration.
+	if edge != gpio.NoEdge {
+		p.flushEvents()
+	}
+	return nil
+}
+
+// Read implements gpio.PinIn.
+//
+// It returns gpio.Low if the line was not requested yet.
+func (p *LinePin) Read() gpio.Level {
+	p.mu.Lock()
+	f := p.f
+	p.mu.Unlock()
+	if f == nil {
+		return gpio.Low
+	}
+	v := gpioV2LineValues{mask: 1}
+	if err := ioctlPtr(f, gpioV2LineGetValuesIOCTL, unsafe.Pointer(&v), unsafe.Sizeof(v)); err != nil {
+		return gpio.Low
+	}
+	return v.bits&1 != 0
+}
+
+// WaitForEdge implements gpio.PinIn.
+func (p *LinePin) WaitForEdge(timeout time.Duration) bool {
+	_, ok := p.WaitForEdgeEvent(timeout)
+	return ok
+}
+
+// WaitForEdgeEvent implements gpio.PinEdgeEvents.
+//
+// Edges are timestamped and queued by the kernel.
+func (p *LinePin) WaitForEdgeEvent(timeout time.Duration) (gpio.EdgeEvent, bool) {
+	var deadline time.Time
+	if timeout != -1 {
+		deadline = time.Now().Add(timeout)
+	}
+	return p.waitForEdgeEvent(context.Background(), deadline)
+}
+
+// WaitForEdgeContext implements gpio.PinInContext.
+//
+// Canceling ctx immediately wakes up the pending read.
+func (p *LinePin) WaitForEdgeContext(ctx context.Context) bool {
+	deadline, _ := ctx.Deadline()
+	_, ok := p.waitForEdgeEvent(ctx, deadline)
+	return ok
+}
+
+func (p *LinePin) waitForEdgeEvent(ctx context.Context, deadline time.Time) (gpio.EdgeEvent, bool) {
+	// Only hold the lock to retrieve the handle, so that In() can be called
+	// concurrently to wake up this function.
+	p.mu.Lock()
+	f := p.f
+	edge := p.edge
+	p.mu.Unlock()
+	if f == nil || edge == gpio.NoEdge {
+		return gpio.EdgeEvent{}, false
+	}
+	if err := f.SetReadDeadline(deadline); err != nil {
+		return gpio.EdgeEvent{}, false
+	}
+	if done := ctx.Done(); done != nil {
+		// Wake up the read below when ctx is done, the same way In() does.
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-done:
+				_ = f.SetReadDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+		if ctx.Err() != nil {
+			return gpio.EdgeEvent{}, false
+		}
+	}
+	var e gpioV2LineEvent
+	if n, err := f.Read(e.bytes()); err != nil || n != len(e.bytes()) {
+		return gpio.EdgeEvent{}, false
+	}
+	return gpio.EdgeEvent{
+		Level: e.id == gpioV2LineEventRisingEdge,
+		Time:  time.Duration(e.timestampNs),
+	}, true
+}
+
+// Pull implements gpio.PinIn.
+//
+// It returns the bias last requested or the one reported by the kernel when
+// the driver was initialized.
+func (p *LinePin) Pull() gpio.Pull {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pull
+}
+
+// DefaultPull implements gpio.PinIn.
+//
+// It returns gpio.PullNoChange since the GPIO character device interface
+// doesn't expose the pull resistor used on reset.
+func (p *LinePin) DefaultPull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out implements gpio.PinOut.
+func (p *LinePin) Out(l gpio.Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.level = l
+	if p.direction == dOut && p.f != nil {
+		// Fast path.
+		v := gpioV2LineValues{mask: 1}
+		if l {
+			v.bits = 1
+		}
+		if err := ioctlPtr(p.f, gpioV2LineSetValuesIOCTL, unsafe.Pointer(&v), unsafe.Sizeof(v)); err != nil {
+			return p.wrap(err)
+		}
+		return nil
+	}
+	// Wake up any pending WaitForEdge() call.
+	if p.f != nil {
+		_ = p.f.SetReadDeadline(time.Now())
+	}
+	p.direction = dOut
+	p.edge = gpio.NoEdge
+	if err := p.apply(); err != nil {
+		return p.wrap(err)
+	}
+	return nil
+}
+
+// PWM implements gpio.PinOut.
+//
+// This is not supported on the GPIO character device interface.
+func (p *LinePin) PWM(gpio.Duty, physic.Frequency) error {
+	return p.wrap(errors.New("pwm is not supported via gpiochip"))
+}
+
+// Offset returns the line offset within its chip.
+func (p *LinePin) Offset() int {
+	return int(p.offset)
+}
+
+// Chip returns the chip the line belongs to.
+func (p *LinePin) Chip() *GPIOChip {
+	return p.chip
+}
+
+// LineName returns the name of the line as reported by the kernel driver, if
+// any.
+func (p *LinePin) LineName() string {
+	return p.lineName
+}
+
+// Drive returns the drive mode used when the line is an output.
+func (p *LinePin) Drive() Drive {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.drive
+}
+
+// SetDrive sets the drive mode used when the line is an output.
+//
+// If the line is currently an output, the change is effective immediately,
+// otherwise it is effective on the next call to Out().
+func (p *LinePin) SetDrive(d Drive) error {
+	if d > OpenSource {
+		return p.wrap(fmt.Errorf("invalid drive %s", d))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drive = d
+	if p.direction == dOut {
+		if err := p.apply(); err != nil {
+			return p.wrap(err)
+		}
+	}
+	return nil
+}
+
+// Debounce returns the kernel debounce period used when the line is an input.
+func (p *LinePin) Debounce() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.debounce
+}
+
+// SetDebounce sets the kernel debounce period used when the line is an input.
+// Use 0 to disable debouncing.
+//
+// The kernel has a microsecond resolution. If the line is currently an input,
+// the change is effective immediately, otherwise it is effective on the next
+// call to In().
+func (p *LinePin) SetDebounce(d time.Duration) error {
+	if d < 0 || d/time.Microsecond > 0xFFFFFFFF {
+		return p.wrap(fmt.Errorf("invalid debounce period %s", d))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.debounce = d
+	if p.direction == dIn {
+		if err := p.apply(); err != nil {
+			return p.wrap(err)
+		}
+	}
+	return nil
+}
+
+// Group implements gpio.Grouper.
+//
+// All the pins must be lines of the same chip. The lines are requested from
+// the kernel as a single line request upon the first call to Read() or Out()
+// on the group, so that they are read or written atomically. Until the group
+// is halted, the lines cannot be used individually.
+//
+// The pull and drive of each line are the ones last set on the LinePin. Lines
+// that are not set as output by the group are configured as input without edge
+// detection.
+func (p *LinePin) Group(pins []gpio.PinIO) gpio.Group {
+	if len(pins) > gpioV2LinesMax {
+		return nil
+	}
+	g := &lineGroup{chip: p.chip, pins: pins, lines: make([]*LinePin, len(pins))}
+	for i := range pins {
+		l, ok := pins[i].(*LinePin)
+		if !ok || l.chip != p.chip {
+			return nil
+		}
+		for j := 0; j < i; j++ {
+			if g.lines[j] == l {
+				return nil
+			}
+		}
+		g.lines[i] = l
+	}
+	return g
+}
+
+//
+
+// apply requests the line or updates its configuration to match the cached
+// state.
+//
+// lock must be held.
+func (p *LinePin) apply() error {
+	if p.f != nil {
+		var c gpioV2LineConfig
+		p.config(&c)
+		return ioctlPtr(p.f, gpioV2LineSetConfigIOCTL, unsafe.Pointer(&c), unsafe.Sizeof(c))
+	}
+	if p.direction == dUnknown {
+		// Nothing to configure yet.
+		return nil
+	}
+	var r gpioV2LineRequest
+	r.offsets[0] = p.offset
+	r.numLines = 1
+	r.eventBufferSize = gpioEventBufferSize
+	copy(r.consumer[:], gpioConsumer)
+	p.config(&r.config)
+	if err := ioctlPtr(p.chip.f, gpioV2GetLineIOCTL, unsafe.Pointer(&r), unsafe.Sizeof(r)); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("need more access, try as root or setup udev rules: %v", err)
+		}
+		return err
+	}
+	f, err := gpioLineOpen(uintptr(r.fd), p.chip.path+":"+strconv.Itoa(int(p.offset)))
+	if err != nil {
+		return err
+	}
+	p.f = f
+	return nil
+}
+
+// config fills c according to the cached state.
+//
+// lock must be held.
+func (p *LinePin) config(c *gpioV2LineConfig) {
+	c.flags = p.lineFlags(p.direction, p.edge)
+	switch p.direction {
+	case dIn:
+		if p.debounce != 0 {
+			a := &c.attrs[c.numAttrs]
+			a.attr.id = gpioV2LineAttrIDDebounce
+			a.attr.setDebounce(uint32(p.debounce / time.Microsecond))
+			a.mask = 1
+			c.numAttrs++
+		}
+	case dOut:
+		a := &c.attrs[c.numAttrs]
+		a.attr.id = gpioV2LineAttrIDOutputValues
+		if p.level {
+			a.attr.value = 1
+		}
+		a.mask = 1
+		c.numAttrs++
+	}
+}
+
+// lineFlags returns the line flags for the cached pull and drive, using
+// direction d and edge detection edge.
+//
+// lock must be held.
+func (p *LinePin) lineFlags(d direction, edge gpio.Edge) uint64 {
+	var f uint64
+	switch p.pull {
+	case gpio.Float:
+		f |= gpioV2LineFlagBiasDisabled
+	case gpio.PullDown:
+		f |= gpioV2LineFlagBiasPullDown
+	case gpio.PullUp:
+		f |= gpioV2LineFlagBiasPullUp
+	}
+	switch d {
+	case dIn:
+		f |= gpioV2LineFlagInput
+		if edge == gpio.RisingEdge || edge == gpio.BothEdges {
+			f |= gpioV2LineFlagEdgeRising
+		}
+		if edge == gpio.FallingEdge || edge == gpio.BothEdges {
+			f |= gpioV2LineFlagEdgeFalling
+		}
+	case dOut:
+		f |= gpioV2LineFlagOutput
+		switch p.drive {
+		case OpenDrain:
+			f |= gpioV2LineFlagOpenDrain
+		case OpenSource:
+			f |= gpioV2LineFlagOpenSource
+		}
+	}
+	return f
+}
+
+// flushEvents discards the accumulated edge events.
+//
+// lock must be held.
+func (p *LinePin) flushEvents() {
+	var e gpioV2LineEvent
+	for i := 0; i < gpioMaxFlushedEvents; i++ {
+		if err := p.f.SetReadDeadline(time.Now()); err != nil {
+			return
+		}
+		if _, err := p.f.Read(e.bytes()); err != nil {
+			return
+		}
+	}
+}
+
+func (p *LinePin) wrap(err error) error {
+	return fmt.Errorf("sysfs-gpiochip (%s): %v", p, err)
+}
+
+// lineGroup implements gpio.Group for lines of a single GPIOChip.
+type lineGroup struct {
+	chip  *GPIOChip
+	pins  []gpio.PinIO
+	lines []*LinePin
+
+	mu   sync.Mutex
+	f    gpioLineFile // handle to the line request; nil until requested
+	out  uint64       // lines configured as output
+	bits uint64       // last output values
+}
+
+func (g *lineGroup) String() string {
+	return gpio.GroupString(g.pins)
+}
+
+// Halt implements conn.Resource.
+//
+// It releases the line request so that the lines can be used individually
+// again.
+func (g *lineGroup) Halt() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.f == nil {
+		return nil
+	}
+	err := g.f.Close()
+	g.f = nil
+	if err != nil {
+		return g.wrap(err)
+	}
+	return nil
+}
+
+// Pins implements gpio.Group.
+func (g *lineGroup) Pins() []gpio.PinIO {
+	return g.pins
+}
+
+// Out implements gpio.Group.
+func (g *lineGroup) Out(bits, mask uint64) error {
+	mask &= g.all()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.f == nil {
+		g.load()
+	}
+	if g.f != nil && mask&^g.out == 0 {
+		v := gpioV2LineValues{bits: bits & mask, mask: mask}
+		if err := ioctlPtr(g.f, gpioV2LineSetValuesIOCTL, unsafe.Pointer(&v), unsafe.Sizeof(v)); err != nil {
+			return g.wrap(err)
+		}
+		g.bits = (g.bits &^ mask) | (bits & mask)
+		return nil
+	}
+	g.out |= mask
+	g.bits = (g.bits &^ mask) | (bits & mask)
+	if err := g.request(); err != nil {
+		return g.wrap(err)
+	}
+	return nil
+}
+
+// Read implements gpio.Group.
+func (g *lineGroup) Read(mask uint64) (uint64, error) {
+	mask &= g.all()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.f == nil {
+		g.load()
+		if err := g.request(); err != nil {
+			return 0, g.wrap(err)
+		}
+	}
+	v := gpioV2LineValues{mask: mask}
+	if err := ioctlPtr(g.f, gpioV2LineGetValuesIOCTL, unsafe.Pointer(&v), unsafe.Sizeof(v)); err != nil {
+		return 0, g.wrap(err)
+	}
+	return v.bits & mask, nil
+}
+
+// all returns the mask of all the lines in the group.
+func (g *lineGroup) all() uint64 {
+	return ^uint64(0) >> uint(64-len(g.lines))
+}
+
+// load initializes the group state from the lines' cached state, so lines
+// set as output stay at their current level.
+//
+// lock must be held.
+func (g *lineGroup) load() {
+	g.out = 0
+	g.bits = 0
+	for i, l := range g.lines {
+		bit := uint64(1) << uint(i)
+		l.mu.Lock()
+		if l.direction == dOut {
+			g.out |= bit
+			if l.level {
+				g.bits |= bit
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// request requests the lines as a single line request or updates its
+// configuration to match the cached state.
+//
+// lock must be held.
+func (g *lineGroup) request() error {
+	var c gpioV2LineConfig
+	if err := g.config(&c); err != nil {
+		return err
+	}
+	if g.f != nil {
+		return ioctlPtr(g.f, gpioV2LineSetConfigIOCTL, unsafe.Pointer(&c), unsafe.Sizeof(c))
+	}
+	// A line can only be requested once, so release the individual requests
+	// first.
+	offsets := make([]string, len(g.lines))
+	var r gpioV2LineRequest
+	for i, l := range g.lines {
+		l.mu.Lock()
+		if l.f != nil {
+			_ = l.f.Close()
+			l.f = nil
+		}
+		l.mu.Unlock()
+		r.offsets[i] = l.offset
+		offsets[i] = strconv.Itoa(int(l.offset))
+	}
+	r.numLines = uint32(len(g.lines))
+	copy(r.consumer[:], gpioConsumer)
+	r.config = c
+	if err := ioctlPtr(g.chip.f, gpioV2GetLineIOCTL, unsafe.Pointer(&r), unsafe.Sizeof(r)); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("need more access, try as root or setup udev rules: %v", err)
+		}
+		return err
+	}
+	f, err := gpioLineOpen(uintptr(r.fd), g.chip.path+":"+strings.Join(offsets, ","))
+	if err != nil {
+		return err
+	}
+	g.f = f
+	return nil
+}
+
+// config fills c according to the cached state.
+//
+// The flags of the first line are the default and the lines with different
+// flags are specified as attributes.
+//
+// lock must be held.
+func (g *lineGroup) config(c *gpioV2LineConfig) error {
+	for i, l := range g.lines {
+		bit := uint64(1) << uint(i)
+		d := dIn
+		if g.out&bit != 0 {
+			d = dOut
+		}
+		l.mu.Lock()
+		f := l.lineFlags(d, gpio.NoEdge)
+		l.mu.Unlock()
+		if i == 0 {
+			c.flags = f
+			continue
+		}
+		if f == c.flags {
+			continue
+		}
+		j := uint32(0)
+		for j < c.numAttrs && c.attrs[j].attr.value != f {
+			j++
+		}
+		if j == c.numAttrs {
+			// Keep one attribute for the output values.
+			if j == gpioV2LineNumAttrsMax-1 {
+				return errors.New("too many different line configurations")
+			}
+			c.attrs[j].attr.id = gpioV2LineAttrIDFlags
+			c.attrs[j].attr.value = f
+			c.numAttrs++
+		}
+		c.attrs[j].mask |= bit
+	}
+	if g.out != 0 {
+		a := &c.attrs[c.numAttrs]
+		a.attr.id = gpioV2LineAttrIDOutputValues
+		a.attr.value = g.bits & g.out
+		a.mask = g.out
+		c.numAttrs++
+	}
+	return nil
+}
+
+func (g *lineGroup) wrap(err error) error {
+	return fmt.Errorf("sysfs-gpiochip (%s): %v", g, err)
+}
+
+// lineInfo retrieves the kernel information about a line.
+func (c *GPIOChip) lineInfo(offset uint32, info *gpioV2LineInfo) error {
+	info.offset = offset
+	return ioctlPtr(c.f, gpioV2GetLineInfoIOCTL, unsafe.Pointer(info), unsafe.Sizeof(*info))
+}
+
+// ioctlPtr sends an ioctl whose argument is the size bytes pointed to by arg.
+//
+// The argument crosses the fs.Ioctler interface as an uintptr, which the
+// runtime doesn't track. It is copied to the heap for the duration of the
+// call since an argument on the stack would move if the stack grew.
+func ioctlPtr(f fs.Ioctler, op uint, arg unsafe.Pointer, size uintptr) error {
+	buf := make([]byte, size)
+	v := (*[1 << 16]byte)(arg)[:size:size]
+	copy(buf, v)
+	err := f.Ioctl(op, uintptr(unsafe.Pointer(&buf[0])))
+	copy(v, buf)
+	return err
+}
+
+// gpioLineFile is the handle returned by the kernel for a line request.
+type gpioLineFile interface {
+	fs.Ioctler
+	io.Closer
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+var gpioLineOpen = gpioLineOpenDefault
+
+// gpioConsumer is the consumer name reported to the kernel for requested
+// lines.
+const gpioConsumer = "periph"
+
+// gpioEventBufferSize is the number of edge events queued by the kernel for
+// each line.
+const gpioEventBufferSize = 64
+
+// gpioMaxFlushedEvents is the maximum number of events discarded by In().
+const gpioMaxFlushedEvents = gpioEventBufferSize
+
+// GPIO character device IOCTL control codes and structures.
+//
+// Constants and structure definition can be found at
+// /usr/include/linux/gpio.h. Only the v2 ABI, introduced in linux 5.10, is
+// supported.
+const (
+	gpioIOCMagic          uint = 0xB4
+	gpioMaxNameSize            = 32
+	gpioV2LinesMax             = 64
+	gpioV2LineNumAttrsMax      = 10
+)
+
+var (
+	gpioGetChipInfoIOCTL     = fs.IOR(gpioIOCMagic, 0x01, 68)   // GPIO_GET_CHIPINFO_IOCTL
+	gpioV2GetLineInfoIOCTL   = fs.IOWR(gpioIOCMagic, 0x05, 256) // GPIO_V2_GET_LINEINFO_IOCTL
+	gpioV2GetLineIOCTL       = fs.IOWR(gpioIOCMagic, 0x07, 592) // GPIO_V2_GET_LINE_IOCTL
+	gpioV2LineSetConfigIOCTL = fs.IOWR(gpioIOCMagic, 0x0D, 272) // GPIO_V2_LINE_SET_CONFIG_IOCTL
+	gpioV2LineGetValuesIOCTL = fs.IOWR(gpioIOCMagic, 0x0E, 16)  // GPIO_V2_LINE_GET_VALUES_IOCTL
+	gpioV2LineSetValuesIOCTL = fs.IOWR(gpioIOCMagic, 0x0F, 16)  // GPIO_V2_LINE_SET_VALUES_IOCTL
+)
+
+// enum gpio_v2_line_flag
+const (
+	gpioV2LineFlagUsed           uint64 = 1 << 0
+	gpioV2LineFlagActiveLow      uint64 = 1 << 1
+	gpioV2LineFlagInput          uint64 = 1 << 2
+	gpioV2LineFlagOutput         uint64 = 1 << 3
+	gpioV2LineFlagEdgeRising     uint64 = 1 << 4
+	gpioV2LineFlagEdgeFalling    uint64 = 1 << 5
+	gpioV2LineFlagOpenDrain      uint64 = 1 << 6
+	gpioV2LineFlagOpenSource     uint64 = 1 << 7
+	gpioV2LineFlagBiasPullUp     uint64 = 1 << 8
+	gpioV2LineFlagBiasPullDown   uint64 = 1 << 9
+	gpioV2LineFlagBiasDisabled   uint64 = 1 << 10
+	gpioV2LineFlagEventClockReal uint64 = 1 << 11
+)
+
+// enum gpio_v2_line_attr_id
+const (
+	gpioV2LineAttrIDFlags        uint32 = 1
+	gpioV2LineAttrIDOutputValues uint32 = 2
+	gpioV2LineAttrIDDebounce     uint32 = 3
+)
+
+// enum gpio_v2_line_event_id
+const (
+	gpioV2LineEventRisingEdge  uint32 = 1
+	gpioV2LineEventFallingEdge uint32 = 2
+)
+
+// gpioChipInfo is struct gpiochip_info in linux/gpio.h.
+type gpioChipInfo struct {
+	name  [gpioMaxNameSize]byte
+	label [gpioMaxNameSize]byte
+	lines uint32
+}
+
+// gpioV2LineAttribute is struct gpio_v2_line_attribute in linux/gpio.h.
+type gpioV2LineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64 // union of flags, values and debounce_period_us
+}
+
+// setDebounce sets debounce_period_us, which is a 32 bits member of the union.
+func (a *gpioV2LineAttribute) setDebounce(us uint32) {
+	a.value = 0
+	*(*uint32)(unsafe.Pointer(&a.value)) = us
+}
+
+// debounce returns debounce_period_us.
+func (a *gpioV2LineAttribute) debounce() uint32 {
+	return *(*uint32)(unsafe.Pointer(&a.value))
+}
+
+// gpioV2LineConfigAttribute is struct gpio_v2_line_config_attribute in
+// linux/gpio.h.
+type gpioV2LineConfigAttribute struct {
+	attr gpioV2LineAttribute
+	mask uint64
+}
+
+// gpioV2LineConfig is struct gpio_v2_line_config in linux/gpio.h.
+type gpioV2LineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [gpioV2LineNumAttrsMax]gpioV2LineConfigAttribute
+}
+
+// gpioV2LineRequest is struct gpio_v2_line_request in linux/gpio.h.
+type gpioV2LineRequest struct {
+	offsets         [gpioV2LinesMax]uint32
+	consumer        [gpioMaxNameSize]byte
+	config          gpioV2LineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+// gpioV2LineInfo is struct gpio_v2_line_info in linux/gpio.h.
+type gpioV2LineInfo struct {
+	name     [gpioMaxNameSize]byte
+	consumer [gpioMaxNameSize]byte
+	offset   uint32
+	numAttrs uint32
+	flags    uint64
+	attrs    [gpioV2LineNumAttrsMax]gpioV2LineAttribute
+	padding  [4]uint32
+}
+
+// gpioV2LineValues is struct gpio_v2_line_values in linux/gpio.h.
+type gpioV2LineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// gpioV2LineEvent is struct gpio_v2_line_event in linux/gpio.h.
+type gpioV2LineEvent struct {
+	timestampNs uint64
+	id          uint32
+	offset      uint32
+	seqno       uint32
+	lineSeqno   uint32
+	padding     [6]uint32
+}
+
+// bytes returns the event as a byte slice, to be used with Read().
+func (e *gpioV2LineEvent) bytes() []byte {
+	return (*[unsafe.Sizeof(*e)]byte)(unsafe.Pointer(e))[:]
+}
+
+// cString converts a NUL terminated C string.
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// newGPIOChip opens a GPIO character device and enumerates its lines.
+func newGPIOChip(path string) (*GPIOChip, error) {
+	f, err := ioctlOpen(path, os.O_RDWR)
+	if err != nil {
+		if os.IsPermission(err) {
+			return nil, fmt.Errorf("sysfs-gpiochip: need more access, try as root or setup udev rules: %v", err)
+		}
+		return nil, fmt.Errorf("sysfs-gpiochip: %v", err)
+	}
+	var info gpioChipInfo
+	if err := ioctlPtr(f, gpioGetChipInfoIOCTL, unsafe.Pointer(&info), unsafe.Sizeof(info)); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("sysfs-gpiochip: %s: %v", path, err)
+	}
+	c := &GPIOChip{
+		name:  cString(info.name[:]),
+		label: cString(info.label[:]),
+		path:  path,
+		f:     f,
+		lines: make([]*LinePin, info.lines),
+	}
+	for i := range c.lines {
+		var li gpioV2LineInfo
+		if err := c.lineInfo(uint32(i), &li); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("sysfs-gpiochip: %s: line %d: %v", path, i, err)
+		}
+		p := &LinePin{
+			chip:     c,
+			offset:   uint32(i),
+			number:   i,
+			lineName: cString(li.name[:]),
+			pull:     gpio.PullNoChange,
+		}
+		switch {
+		case li.flags&gpioV2LineFlagBiasDisabled != 0:
+			p.pull = gpio.Float
+		case li.flags&gpioV2LineFlagBiasPullDown != 0:
+			p.pull = gpio.PullDown
+		case li.flags&gpioV2LineFlagBiasPullUp != 0:
+			p.pull = gpio.PullUp
+		}
+		switch {
+		case li.flags&gpioV2LineFlagOpenDrain != 0:
+			p.drive = OpenDrain
+		case li.flags&gpioV2LineFlagOpenSource != 0:
+			p.drive = OpenSource
+		}
+		c.lines[i] = p
+	}
+	return c, nil
+}
+
+// driverGPIOChip implements periph.Driver.
+type driverGPIOChip struct {
+}
+
+func (d *driverGPIOChip) String() string {
+	return "sysfs-gpiochip"
+}
+
+func (d *driverGPIOChip) Prerequisites() []string {
+	return nil
+}
+
+func (d *driverGPIOChip) After() []string {
+	// Load after sysfs-gpio so the pins it registered can be superseded.
+	return []string{"sysfs-gpio"}
+}
+
+// Init initializes GPIO character device handling code.
+//
+// Uses the GPIO character device v2 ABI as described at
+// https://www.kernel.org/doc/html/latest/userspace-api/gpio/chardev.html
+//
+// It supersedes the GPIO sysfs driver which is deprecated.
+func (d *driverGPIOChip) Init() (bool, error) {
+	prefix := "/dev/gpiochip"
+	items, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return true, err
+	}
+	if len(items) == 0 {
+		return false, errors.New("no GPIO chip found")
+	}
+	// Sort numerically so gpiochip10 comes after gpiochip2.
+	sort.Slice(items, func(i, j int) bool {
+		a, _ := strconv.Atoi(items[i][len(prefix):])
+		b, _ := strconv.Atoi(items[j][len(prefix):])
+		return a < b
+	})
+	var chips []*GPIOChip
+	for _, item := range items {
+		if _, err := strconv.Atoi(item[len(prefix):]); err != nil {
+			continue
+		}
+		c, err := newGPIOChip(item)
+		if err != nil {
+			return true, err
+		}
+		chips = append(chips, c)
+	}
+	if err := d.register(chips); err != nil {
+		return true, err
+	}
+	GPIOChips = chips
+	return true, nil
+}
+
+// register registers the lines of all the chips into gpioreg.
+//
+// When sysfs-gpio exposes the chip, the global GPIO number is known and the
+// lines supersede the corresponding sysfs pins, using the same name. Otherwise
+// the lines are named after the chip label and the line offset.
+func (d *driverGPIOChip) register(chips []*GPIOChip) error {
+	labels := map[string]int{}
+	for _, c := range chips {
+		labels[c.label]++
+	}
+	for _, c := range chips {
+		base, ok := drvGPIO.chipBase(c.label)
+		prefix := c.label
+		if prefix == "" || labels[prefix] != 1 {
+			ok = false
+			prefix = c.name
+		}
+		for _, p := range c.lines {
+			if ok {
+				p.number = base + int(p.offset)
+				p.name = "GPIO" + strconv.Itoa(p.number)
+				// Do not error on it, since sysfs-gpio may have failed to load.
+				_ = gpioreg.Unregister(p.name)
+			} else {
+				p.name = prefix + "_" + strconv.Itoa(int(p.offset))
+			}
+			if err := gpioreg.Register(p); err != nil {
+				return err
+			}
+			if ok {
+				if err := gpioreg.RegisterAlias(strconv.Itoa(p.number), p.name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	if isLinux {
+		periph.MustRegister(&drvGPIOChip)
+	}
+}
+
+var drvGPIOChip driverGPIOChip
+
+var _ conn.Resource = &LinePin{}
+var _ gpio.PinIn = &LinePin{}
+var _ gpio.PinOut = &LinePin{}
+var _ gpio.PinIO = &LinePin{}
+var _ gpio.PinEdgeEvents = &LinePin{}
+var _ gpio.PinInContext = &LinePin{}
+var _ pin.PinFunc = &LinePin{}
+var _ gpio.Grouper = &LinePin{}
+var _ gpio.Group = &lineGroup{}
+var _ fmt.Stringer = &GPIOChip{}
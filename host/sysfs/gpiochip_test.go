@@ -0,0 +1,685 @@
+// Copyright 2020 The Periph Authors. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package sysfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpioreg"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/pin"
+)
+
+func TestGPIOChip_structs(t *testing.T) {
+	data := []struct {
+		name     string
+		got      uintptr
+		expected uintptr
+	}{
+		{"gpiochip_info", unsafe.Sizeof(gpioChipInfo{}), 68},
+		{"gpio_v2_line_attribute", unsafe.Sizeof(gpioV2LineAttribute{}), 16},
+		{"gpio_v2_line_config", unsafe.Sizeof(gpioV2LineConfig{}), 272},
+		{"gpio_v2_line_request", unsafe.Sizeof(gpioV2LineRequest{}), 592},
+		{"gpio_v2_line_info", unsafe.Sizeof(gpioV2LineInfo{}), 256},
+		{"gpio_v2_line_values", unsafe.Sizeof(gpioV2LineValues{}), 16},
+		{"gpio_v2_line_event", unsafe.Sizeof(gpioV2LineEvent{}), 48},
+	}
+	for _, line := range data {
+		if line.got != line.expected {
+			t.Fatalf("%s: %d != %d", line.name, line.got, line.expected)
+		}
+	}
+	if gpioV2GetLineIOCTL != 0xC250B407 {
+		t.Fatalf("0x%X", gpioV2GetLineIOCTL)
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	a := monotonic()
+	b := monotonic()
+	if a <= 0 || b < a {
+		t.Fatal(a, b)
+	}
+}
+
+func TestDrive_String(t *testing.T) {
+	if s := OpenDrain.String(); s != "OpenDrain" {
+		t.Fatal(s)
+	}
+	if s := Drive(10).String(); s != "Drive(10)" {
+		t.Fatal(s)
+	}
+}
+
+func TestNewGPIOChip(t *testing.T) {
+	defer reset()
+	c := newFakeChip()
+	ioctlOpen = func(path string, flag int) (ioctlCloser, error) {
+		if path != "/dev/gpiochip0" {
+			t.Fatal(path)
+		}
+		return c, nil
+	}
+	chip, err := newGPIOChip("/dev/gpiochip0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := chip.String(); s != "gpiochip0" {
+		t.Fatal(s)
+	}
+	if s := chip.Label(); s != "fake-chip" {
+		t.Fatal(s)
+	}
+	if l := len(chip.Lines()); l != 2 {
+		t.Fatal(l)
+	}
+	p := chip.Lines()[1]
+	if s := p.LineName(); s != "LINE1" {
+		t.Fatal(s)
+	}
+	if p.Chip() != chip || p.Offset() != 1 {
+		t.Fatal("unexpected line")
+	}
+	if pull := p.Pull(); pull != gpio.PullUp {
+		t.Fatal(pull)
+	}
+	if d := p.Drive(); d != OpenDrain {
+		t.Fatal(d)
+	}
+	if f := p.Func(); f != gpio.OUT {
+		t.Fatal(f)
+	}
+	if f := chip.Lines()[0].Func(); f != gpio.IN {
+		t.Fatal(f)
+	}
+}
+
+func TestNewGPIOChip_Err(t *testing.T) {
+	defer reset()
+	ioctlOpen = func(path string, flag int) (ioctlCloser, error) {
+		return nil, os.ErrPermission
+	}
+	if _, err := newGPIOChip("/dev/gpiochip0"); err == nil {
+		t.Fatal("open failed")
+	}
+	ioctlOpen = func(path string, flag int) (ioctlCloser, error) {
+		return &ioctlClose{ioctlErr: errors.New("injected")}, nil
+	}
+	if _, err := newGPIOChip("/dev/gpiochip0"); err == nil {
+		t.Fatal("ioctl failed")
+	}
+}
+
+func TestLinePin_In(t *testing.T) {
+	defer reset()
+	p, c := newFakeLinePin(t)
+	if p.Read() != gpio.Low {
+		t.Fatal("not requested")
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("not requested")
+	}
+	if p.In(gpio.Pull(10), gpio.NoEdge) == nil {
+		t.Fatal("invalid pull")
+	}
+	if p.In(gpio.PullNoChange, gpio.Edge(10)) == nil {
+		t.Fatal("invalid edge")
+	}
+	if err := p.In(gpio.PullDown, gpio.NoEdge); err != nil {
+		t.Fatal(err)
+	}
+	if c.line == nil {
+		t.Fatal("line was not requested")
+	}
+	if c.req.offsets[0] != 0 || c.req.numLines != 1 || c.req.eventBufferSize != 64 || cString(c.req.consumer[:]) != "periph" {
+		t.Fatalf("%#v", c.req)
+	}
+	if f := c.req.config.flags; f != gpioV2LineFlagInput|gpioV2LineFlagBiasPullDown {
+		t.Fatalf("0x%x", f)
+	}
+	if pull := p.Pull(); pull != gpio.PullDown {
+		t.Fatal(pull)
+	}
+	c.line.value = 1
+	if p.Read() != gpio.High {
+		t.Fatal("expected high")
+	}
+	if f := p.Func(); f != gpio.IN_HIGH {
+		t.Fatal(f)
+	}
+	c.line.value = 0
+	if f := p.Func(); f != gpio.IN_LOW {
+		t.Fatal(f)
+	}
+	if p.WaitForEdge(0) {
+		t.Fatal("edge detection not enabled")
+	}
+
+	// Reconfiguration is done via SET_CONFIG.
+	c.line.events = 3
+	if err := p.In(gpio.Float, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	if c.line.events != 0 {
+		t.Fatal("accumulated events were not flushed")
+	}
+	if f := c.line.config.flags; f != gpioV2LineFlagInput|gpioV2LineFlagBiasDisabled|gpioV2LineFlagEdgeRising|gpioV2LineFlagEdgeFalling {
+		t.Fatalf("0x%x", f)
+	}
+	if p.WaitForEdge(time.Millisecond) {
+		t.Fatal("no edge")
+	}
+	c.line.events = 1
+	if !p.WaitForEdge(-1) {
+		t.Fatal("expected edge")
+	}
+	c.line.events = 1
+	if e, ok := p.WaitForEdgeEvent(time.Second); !ok || e.Level != gpio.High || e.Time != 42*time.Microsecond {
+		t.Fatal(e, ok)
+	}
+	if _, ok := p.WaitForEdgeEvent(0); ok {
+		t.Fatal("no edge")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.RisingEdge); err != nil {
+		t.Fatal(err)
+	}
+	if pull := p.Pull(); pull != gpio.Float {
+		t.Fatal(pull)
+	}
+	if f := c.line.config.flags; f != gpioV2LineFlagInput|gpioV2LineFlagBiasDisabled|gpioV2LineFlagEdgeRising {
+		t.Fatalf("0x%x", f)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if f := c.line.config.flags; f != gpioV2LineFlagInput|gpioV2LineFlagBiasDisabled {
+		t.Fatalf("0x%x", f)
+	}
+	if err := p.Halt(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.SetDebounce(5 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if d := p.Debounce(); d != 5*time.Millisecond {
+		t.Fatal(d)
+	}
+	if n := c.line.config.numAttrs; n != 1 {
+		t.Fatal(n)
+	}
+	a := c.line.config.attrs[0]
+	if a.attr.id != gpioV2LineAttrIDDebounce || a.attr.debounce() != 5000 || a.mask != 1 {
+		t.Fatalf("%#v", a)
+	}
+	if p.SetDebounce(-1) == nil {
+		t.Fatal("invalid debounce")
+	}
+
+	c.line.err = errors.New("injected")
+	if p.In(gpio.PullNoChange, gpio.NoEdge) == nil {
+		t.Fatal("SET_CONFIG failed")
+	}
+}
+
+func TestLinePin_WaitForEdgeContext(t *testing.T) {
+	defer reset()
+	p, c := newFakeLinePin(t)
+	if p.WaitForEdgeContext(context.Background()) {
+		t.Fatal("not requested")
+	}
+	if err := p.In(gpio.PullNoChange, gpio.BothEdges); err != nil {
+		t.Fatal(err)
+	}
+	c.line.events = 1
+	if !p.WaitForEdgeContext(context.Background()) {
+		t.Fatal("expected edge")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.line.events = 1
+	if p.WaitForEdgeContext(ctx) {
+		t.Fatal("context is canceled")
+	}
+	if c.line.events != 1 {
+		t.Fatal("event was consumed")
+	}
+
+	// Canceling the context wakes up the pending read.
+	c.line.events = 0
+	c.line.block = true
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(time.Millisecond)
+		cancel2()
+	}()
+	if p.WaitForEdgeContext(ctx2) {
+		t.Fatal("context is canceled")
+	}
+	<-done
+	ctx3, cancel3 := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel3()
+	if p.WaitForEdgeContext(ctx3) {
+		t.Fatal("deadline exceeded")
+	}
+}
+
+func TestLinePin_Out(t *testing.T) {
+	defer reset()
+	p, c := newFakeLinePin(t)
+	if err := p.SetDrive(OpenSource); err != nil {
+		t.Fatal(err)
+	}
+	if c.line != nil {
+		t.Fatal("line shouldn't be requested yet")
+	}
+	if p.SetDrive(Drive(10)) == nil {
+		t.Fatal("invalid drive")
+	}
+	if err := p.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	if f := c.req.config.flags; f != gpioV2LineFlagOutput|gpioV2LineFlagOpenSource {
+		t.Fatalf("0x%x", f)
+	}
+	a := c.req.config.attrs[0]
+	if c.req.config.numAttrs != 1 || a.attr.id != gpioV2LineAttrIDOutputValues || a.attr.value != 1 || a.mask != 1 {
+		t.Fatalf("%#v", c.req.config)
+	}
+	if f := p.Func(); f != gpio.OUT_HIGH {
+		t.Fatal(f)
+	}
+	if err := p.Out(gpio.Low); err != nil {
+		t.Fatal(err)
+	}
+	if c.line.value != 0 {
+		t.Fatal("expected low")
+	}
+	if f := p.Func(); f != gpio.OUT_LOW {
+		t.Fatal(f)
+	}
+	if err := p.SetFunc(gpio.OUT_OC); err != nil {
+		t.Fatal(err)
+	}
+	if f := c.line.config.flags; f != gpioV2LineFlagOutput|gpioV2LineFlagOpenDrain {
+		t.Fatalf("0x%x", f)
+	}
+	if err := p.SetFunc(gpio.OUT_HIGH); err != nil {
+		t.Fatal(err)
+	}
+	if c.line.value != 1 {
+		t.Fatal("expected high")
+	}
+	if err := p.SetFunc(gpio.IN); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetFunc(gpio.OUT); err != nil {
+		t.Fatal(err)
+	}
+	if p.SetFunc(gpio.PWM) == nil {
+		t.Fatal("unsupported function")
+	}
+	if p.PWM(gpio.DutyHalf, physic.KiloHertz) == nil {
+		t.Fatal("PWM is not supported")
+	}
+	c.line.err = errors.New("injected")
+	if p.Out(gpio.High) == nil {
+		t.Fatal("SET_VALUES failed")
+	}
+}
+
+func TestLinePin_Group(t *testing.T) {
+	defer reset()
+	p0, c := newFakeLinePin(t)
+	p1 := p0.chip.Lines()[1]
+	p1.name = "fake-chip_1"
+	gpioLineOpen = func(fd uintptr, name string) (gpioLineFile, error) {
+		if fd != 42 || (name != "/dev/gpiochip0:0" && name != "/dev/gpiochip0:0,1") {
+			t.Fatal(fd, name)
+		}
+		return c.line, nil
+	}
+	if g := p0.Group([]gpio.PinIO{p0, p0}); g != nil {
+		t.Fatal("duplicate line")
+	}
+	if g := p0.Group([]gpio.PinIO{p0, gpio.INVALID}); g != nil {
+		t.Fatal("not a line")
+	}
+	if err := p0.Out(gpio.High); err != nil {
+		t.Fatal(err)
+	}
+	single := c.line
+	g, err := gpio.NewGroup(p0, p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*lineGroup); !ok {
+		t.Fatalf("expected native group, got %T", g)
+	}
+	if s := g.String(); s != "Group(fake-chip_0,fake-chip_1)" {
+		t.Fatal(s)
+	}
+	if len(g.Pins()) != 2 {
+		t.Fatal(g.Pins())
+	}
+
+	// The first access requests both lines at once; line 0 stays an output.
+	if v, err := g.Read(3); err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	if !single.closed {
+		t.Fatal("individual line request was not released")
+	}
+	if c.req.numLines != 2 || c.req.offsets[0] != 0 || c.req.offsets[1] != 1 {
+		t.Fatalf("%#v", c.req)
+	}
+	cfg := c.req.config
+	if cfg.flags != gpioV2LineFlagOutput || cfg.numAttrs != 2 {
+		t.Fatalf("%#v", cfg)
+	}
+	if a := cfg.attrs[0]; a.attr.id != gpioV2LineAttrIDFlags || a.attr.value != gpioV2LineFlagInput|gpioV2LineFlagBiasPullUp || a.mask != 2 {
+		t.Fatalf("%#v", a)
+	}
+	if a := cfg.attrs[1]; a.attr.id != gpioV2LineAttrIDOutputValues || a.attr.value != 1 || a.mask != 1 {
+		t.Fatalf("%#v", a)
+	}
+
+	// Lines already set as output are written in a single SET_VALUES.
+	if err := g.Out(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if c.line.value != 0 {
+		t.Fatal(c.line.value)
+	}
+	// Other lines are reconfigured as output first.
+	if err := g.Out(2, 3); err != nil {
+		t.Fatal(err)
+	}
+	cfg = c.line.config
+	if cfg.numAttrs != 2 || cfg.attrs[0].attr.value != gpioV2LineFlagOutput|gpioV2LineFlagOpenDrain|gpioV2LineFlagBiasPullUp {
+		t.Fatalf("%#v", cfg)
+	}
+	if a := cfg.attrs[1]; a.attr.value != 2 || a.mask != 3 {
+		t.Fatalf("%#v", a)
+	}
+	if err := g.Out(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := g.Read(1); err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+
+	c.line.err = errors.New("oops")
+	if err := g.Out(0, 1); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := g.Read(1); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := g.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.line.closed {
+		t.Fatal("group line request was not released")
+	}
+	if err := g.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	c.reqErr = errors.New("busy")
+	if _, err := g.Read(1); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLinePin_request_Err(t *testing.T) {
+	defer reset()
+	p, c := newFakeLinePin(t)
+	c.reqErr = os.ErrPermission
+	if p.Out(gpio.High) == nil {
+		t.Fatal("GET_LINE failed")
+	}
+	c.reqErr = nil
+	gpioLineOpen = func(fd uintptr, name string) (gpioLineFile, error) {
+		return nil, errors.New("injected")
+	}
+	if p.In(gpio.PullNoChange, gpio.NoEdge) == nil {
+		t.Fatal("open failed")
+	}
+}
+
+func TestLinePin_misc(t *testing.T) {
+	defer reset()
+	p, _ := newFakeLinePin(t)
+	if s := p.String(); s != "fake-chip_0" {
+		t.Fatal(s)
+	}
+	if s := p.Name(); s != "fake-chip_0" {
+		t.Fatal(s)
+	}
+	if n := p.Number(); n != 0 {
+		t.Fatal(n)
+	}
+	if s := p.Function(); s != string(gpio.IN) {
+		t.Fatal(s)
+	}
+	if l := len(p.SupportedFuncs()); l != 3 {
+		t.Fatal(l)
+	}
+	if pull := p.DefaultPull(); pull != gpio.PullNoChange {
+		t.Fatal(pull)
+	}
+	p.chip.f = &ioctlClose{ioctlErr: errors.New("injected")}
+	if f := p.Func(); f != pin.FuncNone {
+		t.Fatal(f)
+	}
+}
+
+func TestGPIOChipDriver(t *testing.T) {
+	defer reset()
+	d := driverGPIOChip{}
+	if len(d.Prerequisites()) != 0 {
+		t.Fatal("unexpected prerequisites")
+	}
+	if a := d.After(); len(a) != 1 || a[0] != "sysfs-gpio" {
+		t.Fatal(a)
+	}
+	ioctlOpen = func(path string, flag int) (ioctlCloser, error) {
+		return newFakeChip(), nil
+	}
+	c, err := newGPIOChip("/dev/gpiochip0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without sysfs-gpio, the lines are named after the chip label.
+	if err := d.register([]*GPIOChip{c}); err != nil {
+		t.Fatal(err)
+	}
+	if p := gpioreg.ByName("fake-chip_1"); p != c.Lines()[1] {
+		t.Fatal(p)
+	}
+	for _, p := range c.Lines() {
+		if err := gpioreg.Unregister(p.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// With sysfs-gpio, the lines supersede the sysfs pins.
+	drvGPIO.bases = map[string]int{"fake-chip": 100}
+	defer func() {
+		drvGPIO.bases = nil
+	}()
+	old := &Pin{number: 101, name: "GPIO101"}
+	if err := gpioreg.Register(old); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.register([]*GPIOChip{c}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range []string{"GPIO100", "GPIO101", "100", "101"} {
+			if err := gpioreg.Unregister(n); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}()
+	if p := gpioreg.ByName("GPIO101"); p != c.Lines()[1] {
+		t.Fatal(p)
+	}
+	if p := gpioreg.ByName("100"); p == nil || p.Number() != 100 {
+		t.Fatal(p)
+	}
+}
+
+//
+
+// fakeChip implements ioctlCloser for a fake 2 lines GPIO chip.
+type fakeChip struct {
+	ioctlClose
+	reqErr error
+	req    gpioV2LineRequest
+	line   *fakeLine
+}
+
+func newFakeChip() *fakeChip {
+	return &fakeChip{}
+}
+
+func (f *fakeChip) Ioctl(op uint, data uintptr) error {
+	if f.ioctlErr != nil {
+		return f.ioctlErr
+	}
+	switch op {
+	case gpioGetChipInfoIOCTL:
+		i := (*gpioChipInfo)(toPointer(data))
+		copy(i.name[:], "gpiochip0")
+		copy(i.label[:], "fake-chip")
+		i.lines = 2
+	case gpioV2GetLineInfoIOCTL:
+		i := (*gpioV2LineInfo)(toPointer(data))
+		switch i.offset {
+		case 0:
+			i.flags = gpioV2LineFlagInput
+		case 1:
+			copy(i.name[:], "LINE1")
+			i.flags = gpioV2LineFlagOutput | gpioV2LineFlagOpenDrain | gpioV2LineFlagBiasPullUp | gpioV2LineFlagUsed
+		default:
+			return errors.New("invalid offset")
+		}
+	case gpioV2GetLineIOCTL:
+		if f.reqErr != nil {
+			return f.reqErr
+		}
+		r := (*gpioV2LineRequest)(toPointer(data))
+		r.fd = 42
+		f.req = *r
+		f.line = &fakeLine{config: r.config}
+		for i := uint32(0); i < r.config.numAttrs; i++ {
+			if a := r.config.attrs[i]; a.attr.id == gpioV2LineAttrIDOutputValues {
+				f.line.value = a.attr.value & a.mask
+			}
+		}
+	default:
+		return errors.New("unexpected ioctl")
+	}
+	return nil
+}
+
+// fakeLine implements gpioLineFile.
+type fakeLine struct {
+	err    error
+	config gpioV2LineConfig
+	value  uint64
+	events int
+	closed bool
+	// block makes Read() wait for the read deadline when there is no event.
+	block    bool
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (f *fakeLine) Ioctl(op uint, data uintptr) error {
+	if f.err != nil {
+		return f.err
+	}
+	switch op {
+	case gpioV2LineSetConfigIOCTL:
+		f.config = *(*gpioV2LineConfig)(toPointer(data))
+	case gpioV2LineGetValuesIOCTL:
+		v := (*gpioV2LineValues)(toPointer(data))
+		v.bits = f.value & v.mask
+	case gpioV2LineSetValuesIOCTL:
+		v := (*gpioV2LineValues)(toPointer(data))
+		f.value = (f.value &^ v.mask) | (v.bits & v.mask)
+	default:
+		return errors.New("unexpected ioctl")
+	}
+	return nil
+}
+
+func (f *fakeLine) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeLine) Read(b []byte) (int, error) {
+	if f.events == 0 {
+		for f.block {
+			f.mu.Lock()
+			d := f.deadline
+			f.mu.Unlock()
+			if !d.IsZero() && time.Now().After(d) {
+				break
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+		return 0, errors.New("timeout")
+	}
+	f.events--
+	e := (*gpioV2LineEvent)(unsafe.Pointer(&b[0]))
+	e.id = gpioV2LineEventRisingEdge
+	e.timestampNs = 42000
+	return len(b), nil
+}
+
+func (f *fakeLine) SetReadDeadline(t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deadline = t
+	return nil
+}
+
+// toPointer converts the ioctl argument back into a pointer.
+func toPointer(data uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&data))
+}
+
+func newFakeLinePin(t *testing.T) (*LinePin, *fakeChip) {
+	c := newFakeChip()
+	ioctlOpen = func(path string, flag int) (ioctlCloser, error) {
+		return c, nil
+	}
+	gpioLineOpen = func(fd uintptr, name string) (gpioLineFile, error) {
+		if fd != 42 || name != "/dev/gpiochip0:0" {
+			t.Fatal(fd, name)
+		}
+		return c.line, nil
+	}
+	chip, err := newGPIOChip("/dev/gpiochip0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := chip.Lines()[0]
+	p.name = "fake-chip_0"
+	return p, c
+}
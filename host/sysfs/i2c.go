@@ -5,6 +5,7 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -16,10 +17,12 @@ import (
 	"unsafe"
 
 	"periph.io/x/periph"
+	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/smbus"
 	"periph.io/x/periph/conn/physic"
 )
 
@@ -57,7 +60,7 @@ func NewI2C(busNumber int) (*I2C, error) {
 	if isLinux {
 		return newI2C(busNumber)
 	}
-	return nil, errors.New("sysfs-i2c: is not supported on this platform")
+	return nil, fmt.Errorf("sysfs-i2c: %w on this platform", conn.ErrUnsupported)
 }
 
 // I2C is an open I²C bus via sysfs.
@@ -90,27 +93,42 @@ func (i *I2C) String() string {
 
 // Tx execute a transaction as a single operation unit.
 func (i *I2C) Tx(addr uint16, w, r []byte) error {
-	if addr >= 0x400 || (addr >= 0x80 && i.fn&func10BitAddr == 0) {
+	return i.TxContext(context.Background(), addr, w, r)
+}
+
+// TxContext implements i2c.BusContext.
+//
+// ctx is verified once exclusive access to the bus is acquired. The
+// transaction itself is executed by the kernel and cannot be interrupted.
+func (i *I2C) TxContext(ctx context.Context, addr uint16, w, r []byte) error {
+	if !i2c.ValidAddr(addr) || (i2c.Is10Bit(addr) && i.fn&func10BitAddr == 0) {
 		return errors.New("sysfs-i2c: invalid address")
 	}
 	if len(w) == 0 && len(r) == 0 {
 		return nil
 	}
+	var flags uint16
+	a := addr
+	if i2c.Is10Bit(addr) {
+		flags = flagTEN
+		a &^= i2c.TenBit
+	}
 
 	// Convert the messages to the internal format.
 	var buf [2]i2cMsg
 	msgs := buf[0:0]
 	if len(w) != 0 {
 		msgs = buf[:1]
-		buf[0].addr = addr
+		buf[0].addr = a
+		buf[0].flags = flags
 		buf[0].length = uint16(len(w))
 		buf[0].buf = uintptr(unsafe.Pointer(&w[0]))
 	}
 	if len(r) != 0 {
 		l := len(msgs)
 		msgs = msgs[:l+1] // extend the slice by one
-		buf[l].addr = addr
-		buf[l].flags = flagRD
+		buf[l].addr = a
+		buf[l].flags = flags | flagRD
 		buf[l].length = uint16(len(r))
 		buf[l].buf = uintptr(unsafe.Pointer(&r[0]))
 	}
@@ -121,8 +139,94 @@ func (i *I2C) Tx(addr uint16, w, r []byte) error {
 	pp := uintptr(unsafe.Pointer(&p))
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if err := i.f.Ioctl(ioctlRdwr, pp); err != nil {
-		return fmt.Errorf("sysfs-i2c: %v", err)
+		return i2cError(addr, err)
+	}
+	return nil
+}
+
+// SMBusSupported implements smbus.Native.
+//
+// It uses the functionality flags reported by the kernel driver.
+func (i *I2C) SMBusSupported(p smbus.Protocol, read, pec bool) bool {
+	if pec && i.fn&funcSMBusPEC == 0 {
+		return false
+	}
+	var f functionality
+	switch p {
+	case smbus.Quick:
+		f = funcSMBusQuick
+	case smbus.Byte:
+		f = funcSMBusWriteByte
+		if read {
+			f = funcSMBusReadByte
+		}
+	case smbus.ByteData:
+		f = funcSMBusWriteByteData
+		if read {
+			f = funcSMBusReadByteData
+		}
+	case smbus.WordData:
+		f = funcSMBusWriteWordData
+		if read {
+			f = funcSMBusReadWordData
+		}
+	case smbus.ProcCall:
+		f = funcSMBusProcCall
+	case smbus.BlockData:
+		f = funcSMBusWriteBlockData
+		if read {
+			f = funcSMBusReadBlockData
+		}
+	case smbus.BlockProcCall:
+		f = funcSMBusBlockProcCall
+	case smbus.I2CBlockData:
+		f = funcSMBusWriteI2CBlock
+		if read {
+			f = funcSMBusReadI2CBlock
+		}
+	default:
+		return false
+	}
+	return i.fn&f != 0
+}
+
+// SMBusTx implements smbus.Native.
+//
+// It uses the kernel SMBus ioctl, which is also supported by adapters that
+// only implement SMBus and not raw I²C transactions. Like Tx(), it accesses
+// the device even if a kernel driver is bound to it.
+func (i *I2C) SMBusTx(addr uint16, p smbus.Protocol, read bool, cmd byte, data []byte, pec bool) error {
+	if addr >= 0x80 {
+		return errors.New("sysfs-i2c: invalid address")
+	}
+	if int(p) >= len(smbusSizes) {
+		return fmt.Errorf("sysfs-i2c: invalid SMBus protocol %s", p)
+	}
+	if len(data) < smbus.DataSize {
+		return errors.New("sysfs-i2c: SMBus data buffer is too small")
+	}
+	d := smbusIoctlData{command: cmd, size: smbusSizes[p], data: uintptr(unsafe.Pointer(&data[0]))}
+	if read {
+		d.readWrite = 1
+	}
+	var v uintptr
+	if pec {
+		v = 1
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := i.f.Ioctl(ioctlSlaveForce, uintptr(addr)); err != nil {
+		return i2cError(addr, err)
+	}
+	if err := i.f.Ioctl(ioctlPEC, v); err != nil {
+		return i2cError(addr, err)
+	}
+	if err := i.f.Ioctl(ioctlSMBus, uintptr(unsafe.Pointer(&d))); err != nil {
+		return i2cError(addr, err)
 	}
 	return nil
 }
@@ -140,7 +244,7 @@ func (i *I2C) SetSpeed(f physic.Frequency) error {
 	if drvI2C.setSpeed != nil {
 		return drvI2C.setSpeed(f)
 	}
-	return errors.New("sysfs-i2c: not supported")
+	return fmt.Errorf("sysfs-i2c: SetSpeed: %w", conn.ErrUnsupported)
 }
 
 // SCL implements i2c.Pins.
@@ -157,6 +261,19 @@ func (i *I2C) SDA() gpio.PinIO {
 
 // Private details.
 
+// i2cError converts an error returned by the I²C kernel driver for a
+// transaction at addr, so it can be tested with errors.Is() and errors.As().
+func i2cError(addr uint16, err error) error {
+	switch f := i2cFault(err); f {
+	case nil:
+		return fmt.Errorf("sysfs-i2c: %w", err)
+	case i2c.ErrNACK:
+		return &i2c.NACKError{Addr: addr, Err: fmt.Errorf("sysfs-i2c: %w", err)}
+	default:
+		return fmt.Errorf("sysfs-i2c: %v: %w", err, f)
+	}
+}
+
 func newI2C(busNumber int) (*I2C, error) {
 	// Use the devfs path for now instead of sysfs path.
 	f, err := ioctlOpen(fmt.Sprintf("/dev/i2c-%d", busNumber), os.O_RDWR)
@@ -202,12 +319,15 @@ func (i *I2C) initPins() {
 // Constants and structure definition can be found at
 // /usr/include/linux/i2c-dev.h and /usr/include/linux/i2c.h.
 const (
-	ioctlRetries = 0x701 // TODO(maruel): Expose this
-	ioctlTimeout = 0x702 // TODO(maruel): Expose this; in units of 10ms
-	ioctlSlave   = 0x703
-	ioctlTenBits = 0x704 // TODO(maruel): Expose this but the header says it's broken (!?)
-	ioctlFuncs   = 0x705
-	ioctlRdwr    = 0x707
+	ioctlRetries    = 0x701 // TODO(maruel): Expose this
+	ioctlTimeout    = 0x702 // TODO(maruel): Expose this; in units of 10ms
+	ioctlSlave      = 0x703
+	ioctlTenBits    = 0x704 // TODO(maruel): Expose this but the header says it's broken (!?)
+	ioctlFuncs      = 0x705
+	ioctlSlaveForce = 0x706 // Like ioctlSlave, even if a kernel driver uses the address
+	ioctlRdwr       = 0x707
+	ioctlPEC        = 0x708
+	ioctlSMBus      = 0x720
 )
 
 // flags
@@ -310,6 +430,27 @@ type rdwrIoctlData struct {
 	nmsgs uint32
 }
 
+// smbusSizes maps smbus.Protocol to the kernel's I2C_SMBUS_* transaction
+// sizes.
+var smbusSizes = [...]uint32{
+	smbus.Quick:         0,
+	smbus.Byte:          1,
+	smbus.ByteData:      2,
+	smbus.WordData:      3,
+	smbus.ProcCall:      4,
+	smbus.BlockData:     5,
+	smbus.BlockProcCall: 7,
+	smbus.I2CBlockData:  8,
+}
+
+// smbusIoctlData is struct i2c_smbus_ioctl_data.
+type smbusIoctlData struct {
+	readWrite uint8 // 1 for read
+	command   uint8
+	size      uint32
+	data      uintptr // Pointer to union i2c_smbus_data
+}
+
 type i2cMsg struct {
 	addr   uint16 // Address to communicate with
 	flags  uint16 // 1 for read, see i2c.h for more details
@@ -386,3 +527,5 @@ var drvI2C driverI2C
 
 var _ i2c.Bus = &I2C{}
 var _ i2c.BusCloser = &I2C{}
+var _ i2c.BusContext = &I2C{}
+var _ smbus.Native = &I2C{}
@@ -5,9 +5,15 @@
 package sysfs
 
 import (
+	"context"
+	"errors"
+	"syscall"
 	"testing"
 
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
 	"periph.io/x/periph/conn/i2c/i2creg"
+	"periph.io/x/periph/conn/i2c/smbus"
 	"periph.io/x/periph/conn/physic"
 )
 
@@ -51,6 +57,161 @@ func TestI2C_faked(t *testing.T) {
 	}
 }
 
+func TestI2C_TxContext(t *testing.T) {
+	bus := I2C{f: &ioctlClose{}, busNumber: 24}
+	if err := bus.TxContext(context.Background(), 1, []byte{0}, []byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bus.TxContext(ctx, 1, []byte{0}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestI2C_TenBit(t *testing.T) {
+	f := &ioctlRecord{}
+	bus := I2C{f: f, busNumber: 24}
+	if bus.Tx(0x150, []byte{0}, nil) == nil {
+		t.Fatal("10-bit addressing is not supported")
+	}
+	if bus.Tx(0x50|i2c.TenBit, []byte{0}, nil) == nil {
+		t.Fatal("10-bit addressing is not supported")
+	}
+	bus.fn = func10BitAddr
+	if bus.Tx(0x400|i2c.TenBit, []byte{0}, nil) == nil {
+		t.Fatal("invalid address")
+	}
+	data := []struct {
+		addr     uint16
+		expected uint16
+		flags    uint16
+	}{
+		{0x50, 0x50, 0},
+		{0x150, 0x150, flagTEN},
+		{0x50 | i2c.TenBit, 0x50, flagTEN},
+	}
+	for i, line := range data {
+		if err := bus.Tx(line.addr, []byte{0}, []byte{0}); err != nil {
+			t.Fatal(err)
+		}
+		if len(f.msgs) != 2 {
+			t.Fatal(f.msgs)
+		}
+		if m := f.msgs[0]; m.addr != line.expected || m.flags != line.flags {
+			t.Errorf("#%d: %#v", i, m)
+		}
+		if m := f.msgs[1]; m.addr != line.expected || m.flags != line.flags|flagRD {
+			t.Errorf("#%d: %#v", i, m)
+		}
+	}
+}
+
+func TestI2C_SMBus(t *testing.T) {
+	f := &ioctlRecord{}
+	bus := I2C{f: f, busNumber: 24, fn: funcSMBusQuick | funcSMBusReadWordData | funcSMBusWriteI2CBlock}
+	data := []struct {
+		p        smbus.Protocol
+		read     bool
+		pec      bool
+		expected bool
+	}{
+		{smbus.Quick, false, false, true},
+		{smbus.Quick, false, true, false},
+		{smbus.WordData, true, false, true},
+		{smbus.WordData, false, false, false},
+		{smbus.Byte, true, false, false},
+		{smbus.I2CBlockData, false, false, true},
+		{smbus.I2CBlockData, true, false, false},
+		{smbus.Protocol(100), true, false, false},
+	}
+	for i, line := range data {
+		if v := bus.SMBusSupported(line.p, line.read, line.pec); v != line.expected {
+			t.Errorf("#%d: %t != %t", i, v, line.expected)
+		}
+	}
+	bus.fn |= funcSMBusPEC
+	if !bus.SMBusSupported(smbus.Quick, true, true) {
+		t.Fatal("PEC is supported")
+	}
+
+	d := smbus.Dev{Bus: &bus, Addr: 0x5A, PEC: true}
+	if _, err := d.ReadWordData(0x07); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint{ioctlSlaveForce, 0x5A, ioctlPEC, 1, ioctlSMBus}
+	if len(f.ops) != len(expected) {
+		t.Fatal(f.ops)
+	}
+	for i := range expected {
+		if f.ops[i] != expected[i] {
+			t.Fatal(f.ops)
+		}
+	}
+	if f.smbus != (smbusIoctlData{readWrite: 1, command: 0x07, size: 3}) {
+		t.Fatal(f.smbus)
+	}
+
+	var buf [smbus.DataSize]byte
+	if bus.SMBusTx(0x80, smbus.Quick, false, 0, buf[:], false) == nil {
+		t.Fatal("invalid address")
+	}
+	if bus.SMBusTx(1, smbus.Protocol(100), false, 0, buf[:], false) == nil {
+		t.Fatal("invalid protocol")
+	}
+	if bus.SMBusTx(1, smbus.Quick, false, 0, buf[:2], false) == nil {
+		t.Fatal("buffer too small")
+	}
+	bus.f = &ioctlClose{ioctlErr: errors.New("oops")}
+	if bus.SMBusTx(1, smbus.Quick, false, 0, buf[:], false) == nil {
+		t.Fatal("ioctl error")
+	}
+}
+
+func TestI2C_SMBus_bound(t *testing.T) {
+	// The device is accessed even though a kernel driver uses its address, the
+	// same as with Tx().
+	f := &ioctlRecord{bound: true}
+	bus := I2C{f: f, busNumber: 24, fn: funcSMBusReadByteData}
+	d := smbus.Dev{Bus: &bus, Addr: 0x50}
+	if _, err := d.ReadByteData(0x10); err != nil {
+		t.Fatal(err)
+	}
+	if f.ops[0] != ioctlSlaveForce || f.ops[1] != 0x50 {
+		t.Fatal(f.ops)
+	}
+	if f.smbus != (smbusIoctlData{readWrite: 1, command: 0x10, size: 2}) {
+		t.Fatal(f.smbus)
+	}
+}
+
+func TestI2C_errors(t *testing.T) {
+	if !isLinux {
+		t.Skip("errno mapping is only implemented on linux")
+	}
+	bus := I2C{f: &ioctlClose{ioctlErr: syscall.ENXIO}, busNumber: 24}
+	err := bus.Tx(0x76, []byte{1}, nil)
+	var nack *i2c.NACKError
+	if !errors.As(err, &nack) || nack.Addr != 0x76 || !errors.Is(err, syscall.ENXIO) {
+		t.Fatal(err)
+	}
+	bus.f = &ioctlClose{ioctlErr: syscall.EAGAIN}
+	if err := bus.Tx(0x76, []byte{1}, nil); !errors.Is(err, i2c.ErrArbitrationLost) || errors.Is(err, i2c.ErrNACK) {
+		t.Fatal(err)
+	}
+	bus.f = &ioctlClose{ioctlErr: syscall.ETIMEDOUT}
+	if err := bus.Tx(0x76, []byte{1}, nil); !errors.Is(err, conn.ErrTimeout) {
+		t.Fatal(err)
+	}
+	bus.f = &ioctlClose{ioctlErr: errors.New("oops")}
+	if err := bus.Tx(0x76, []byte{1}, nil); errors.Is(err, i2c.ErrNACK) || errors.Is(err, conn.ErrTimeout) {
+		t.Fatal(err)
+	}
+	if err := bus.SetSpeed(100 * physic.KiloHertz); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+}
+
 func TestI2C_functionality(t *testing.T) {
 	expected := "I2C|10BIT_ADDR|PROTOCOL_MANGLING|SMBUS_PEC|NOSTART|SMBUS_BLOCK_PROC_CALL|SMBUS_QUICK|SMBUS_READ_BYTE|SMBUS_WRITE_BYTE|SMBUS_READ_BYTE_DATA|SMBUS_WRITE_BYTE_DATA|SMBUS_READ_WORD_DATA|SMBUS_WRITE_WORD_DATA|SMBUS_PROC_CALL|SMBUS_READ_BLOCK_DATA|SMBUS_WRITE_BLOCK_DATA|SMBUS_READ_I2C_BLOCK|SMBUS_WRITE_I2C_BLOCK"
 	if s := functionality(0xFFFFFFFF).String(); s != expected {
@@ -100,6 +261,37 @@ func TestDriver_Init(t *testing.T) {
 	}
 }
 
+//
+
+// ioctlRecord records the ioctl operations and their integer argument.
+type ioctlRecord struct {
+	ioctlClose
+	ops   []uint
+	smbus smbusIoctlData
+	msgs  []i2cMsg
+	bound bool // A kernel driver uses the address, so ioctlSlave fails
+}
+
+func (i *ioctlRecord) Ioctl(op uint, data uintptr) error {
+	i.ops = append(i.ops, op)
+	switch op {
+	case ioctlSlave:
+		if i.bound {
+			return syscall.EBUSY
+		}
+		i.ops = append(i.ops, uint(data))
+	case ioctlSlaveForce, ioctlPEC:
+		i.ops = append(i.ops, uint(data))
+	case ioctlSMBus:
+		i.smbus = *(*smbusIoctlData)(toPointer(data))
+		i.smbus.data = 0
+	case ioctlRdwr:
+		d := (*rdwrIoctlData)(toPointer(data))
+		i.msgs = append([]i2cMsg{}, (*[2]i2cMsg)(toPointer(d.msgs))[:d.nmsgs]...)
+	}
+	return nil
+}
+
 func BenchmarkI2C(b *testing.B) {
 	b.ReportAllocs()
 	i := ioctlClose{}
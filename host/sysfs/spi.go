@@ -5,6 +5,7 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"periph.io/x/periph"
@@ -44,7 +46,7 @@ func NewSPI(busNumber, chipSelect int) (*SPI, error) {
 	if isLinux {
 		return newSPI(busNumber, chipSelect)
 	}
-	return nil, errors.New("sysfs-spi: not implemented on non-linux OSes")
+	return nil, fmt.Errorf("sysfs-spi: %w on non-linux OSes", conn.ErrUnsupported)
 }
 
 // SPI is an open SPI port.
@@ -94,11 +96,14 @@ func (s *SPI) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, er
 	if f < 100*physic.Hertz {
 		return nil, fmt.Errorf("sysfs-spi: invalid speed %s; minimum supported clock is 100Hz; did you forget to multiply by physic.MegaHertz?", f)
 	}
-	if mode&^(spi.Mode3|spi.HalfDuplex|spi.NoCS|spi.LSBFirst) != 0 {
-		return nil, fmt.Errorf("sysfs-spi: invalid mode %v", mode)
+	if mode&^(spi.Mode3|spi.HalfDuplex|spi.NoCS|spi.LSBFirst|spi.NoChunk|spi.TxDual|spi.TxQuad|spi.RxDual|spi.RxQuad) != 0 {
+		return nil, fmt.Errorf("sysfs-spi: invalid mode %v: %w", mode, conn.ErrUnsupported)
+	}
+	if mode&(spi.TxDual|spi.TxQuad) == spi.TxDual|spi.TxQuad || mode&(spi.RxDual|spi.RxQuad) == spi.RxDual|spi.RxQuad {
+		return nil, fmt.Errorf("sysfs-spi: invalid mode %v; specify only one of dual or quad per direction: %w", mode, conn.ErrUnsupported)
 	}
 	if bits < 1 || bits >= 256 {
-		return nil, fmt.Errorf("sysfs-spi: invalid bits %d", bits)
+		return nil, fmt.Errorf("sysfs-spi: invalid bits %d: %w", bits, conn.ErrUnsupported)
 	}
 	s.conn.mu.Lock()
 	defer s.conn.mu.Unlock()
@@ -108,6 +113,7 @@ func (s *SPI) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, er
 	s.conn.connected = true
 	s.conn.freqConn = f
 	s.conn.bitsPerWord = uint8(bits)
+	s.conn.noChunk = mode&spi.NoChunk != 0
 	// Only mode needs to be set via an IOCTL, others can be specified in the
 	// spiIOCTransfer packet, which saves a kernel call.
 	m := mode & spi.Mode3
@@ -130,15 +136,41 @@ func (s *SPI) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, er
 	if mode&spi.LSBFirst != 0 {
 		m |= lSBFirst
 	}
-	// Only the first 8 bits are used. This only works because the system is
-	// running in little endian.
-	if err := s.conn.setFlag(spiIOCMode, uint64(m)); err != nil {
-		return nil, fmt.Errorf("sysfs-spi: setting mode %v failed: %v", mode, err)
+	s.conn.txWidth, s.conn.rxWidth = 1, 1
+	if mode&spi.TxDual != 0 {
+		m |= txDual
+		s.conn.txWidth = 2
+	}
+	if mode&spi.TxQuad != 0 {
+		m |= txQuad
+		s.conn.txWidth = 4
+	}
+	if mode&spi.RxDual != 0 {
+		m |= rxDual
+		s.conn.rxWidth = 2
+	}
+	if mode&spi.RxQuad != 0 {
+		m |= rxQuad
+		s.conn.rxWidth = 4
+	}
+	// Only the first 8 bits are used with spiIOCMode, 32 bits with
+	// spiIOCMode32. This only works because the system is running in little
+	// endian.
+	op := spiIOCMode
+	if m > 0xFF {
+		op = spiIOCMode32
+	}
+	if err := s.conn.setFlag(op, uint64(m)); err != nil {
+		return nil, fmt.Errorf("sysfs-spi: setting mode %v failed: %w", mode, err)
 	}
 	return &s.conn, nil
 }
 
-// MaxTxSize implements conn.Limits
+// MaxTxSize implements conn.Limits.
+//
+// It is the maximum size of a single transfer to the kernel. Larger
+// transactions are split in multiple chunks unless spi.NoChunk was specified
+// to Connect().
 func (s *SPI) MaxTxSize() int {
 	return drvSPI.bufSize
 }
@@ -204,6 +236,9 @@ type spiConn struct {
 	connected   bool
 	halfDuplex  bool
 	noCS        bool
+	noChunk     bool
+	txWidth     uint8 // Maximum number of data lines to write
+	rxWidth     uint8 // Maximum number of data lines to read
 	// Heap optimization: reduce the amount of memory allocations during
 	// transactions.
 	io [4]spiIOCTransfer
@@ -227,15 +262,15 @@ func (s *spiConn) Read(b []byte) (int, error) {
 	if len(b) == 0 {
 		return 0, errors.New("sysfs-spi: Read() with empty buffer")
 	}
-	if drvSPI.bufSize != 0 && len(b) > drvSPI.bufSize {
-		return 0, fmt.Errorf("sysfs-spi: maximum Read length is %d, got %d bytes", drvSPI.bufSize, len(b))
+	if s.tooLarge(len(b)) {
+		return 0, &spi.TooLargeError{Size: len(b), Max: drvSPI.bufSize, Err: fmt.Errorf("sysfs-spi: maximum Read length is %d, got %d bytes", drvSPI.bufSize, len(b))}
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.p[0].W = nil
 	s.p[0].R = b
 	if err := s.txPackets(s.p[:1]); err != nil {
-		return 0, fmt.Errorf("sysfs-spi: Read() failed: %v", err)
+		return 0, fmt.Errorf("sysfs-spi: Read() failed: %w", err)
 	}
 	return len(b), nil
 }
@@ -245,15 +280,15 @@ func (s *spiConn) Write(b []byte) (int, error) {
 	if len(b) == 0 {
 		return 0, errors.New("sysfs-spi: Write() with empty buffer")
 	}
-	if drvSPI.bufSize != 0 && len(b) > drvSPI.bufSize {
-		return 0, fmt.Errorf("sysfs-spi: maximum Write length is %d, got %d bytes", drvSPI.bufSize, len(b))
+	if s.tooLarge(len(b)) {
+		return 0, &spi.TooLargeError{Size: len(b), Max: drvSPI.bufSize, Err: fmt.Errorf("sysfs-spi: maximum Write length is %d, got %d bytes", drvSPI.bufSize, len(b))}
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.p[0].W = b
 	s.p[0].R = nil
 	if err := s.txPackets(s.p[:1]); err != nil {
-		return 0, fmt.Errorf("sysfs-spi: Write() failed: %v", err)
+		return 0, fmt.Errorf("sysfs-spi: Write() failed: %w", err)
 	}
 	return len(b), nil
 }
@@ -263,9 +298,18 @@ func (s *spiConn) Write(b []byte) (int, error) {
 // It is OK if both w and r point to the same underlying byte slice.
 //
 // spidev enforces the maximum limit of transaction size. It can be as low as
-// 4096 bytes. See the platform documentation to learn how to increase the
-// limit.
+// 4096 bytes. Larger transactions are split in multiple chunks, keeping CS
+// asserted, unless spi.NoChunk was specified to Connect(). See the platform
+// documentation to learn how to increase the limit.
 func (s *spiConn) Tx(w, r []byte) error {
+	return s.TxContext(context.Background(), w, r)
+}
+
+// TxContext implements spi.ConnContext.
+//
+// ctx is verified once exclusive access to the port is acquired. The
+// transaction itself is executed by the kernel and cannot be interrupted.
+func (s *spiConn) TxContext(ctx context.Context, w, r []byte) error {
 	l := len(w)
 	if l == 0 {
 		if l = len(r); l == 0 {
@@ -277,11 +321,14 @@ func (s *spiConn) Tx(w, r []byte) error {
 			return fmt.Errorf("sysfs-spi: Tx(): when both w and r are used, they must be the same size; got %d and %d bytes", len(w), len(r))
 		}
 	}
-	if drvSPI.bufSize != 0 && l > drvSPI.bufSize {
-		return fmt.Errorf("sysfs-spi: maximum Tx length is %d, got %d bytes", drvSPI.bufSize, l)
+	if s.tooLarge(l) {
+		return &spi.TooLargeError{Size: l, Max: drvSPI.bufSize, Err: fmt.Errorf("sysfs-spi: maximum Tx length is %d, got %d bytes", drvSPI.bufSize, l)}
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.p[0].W = w
 	s.p[0].R = r
 	p := s.p[:1]
@@ -297,7 +344,7 @@ func (s *spiConn) Tx(w, r []byte) error {
 		s.p[0].KeepCS = false
 	}
 	if err := s.txPackets(p); err != nil {
-		return fmt.Errorf("sysfs-spi: Tx() failed: %v", err)
+		return fmt.Errorf("sysfs-spi: Tx() failed: %w", err)
 	}
 	return nil
 }
@@ -305,9 +352,21 @@ func (s *spiConn) Tx(w, r []byte) error {
 // TxPackets sends and receives packets as specified by the user.
 //
 // spidev enforces the maximum limit of transaction size. It can be as low as
-// 4096 bytes. See the platform documentation to learn how to increase the
-// limit.
+// 4096 bytes. Packets are split and grouped in chunks that fit the limit,
+// keeping CS asserted as requested by the packets, unless spi.NoChunk was
+// specified to Connect(). See the platform documentation to learn how to
+// increase the limit.
+//
+// WordDelay requires Linux 5.2 or later; a non-zero WordDelay returns an error
+// wrapping conn.ErrUnsupported on older kernels.
 func (s *spiConn) TxPackets(p []spi.Packet) error {
+	return s.TxPacketsContext(context.Background(), p)
+}
+
+// TxPacketsContext implements spi.ConnContext.
+//
+// It has the same cancellation semantics as TxContext().
+func (s *spiConn) TxPacketsContext(ctx context.Context, p []spi.Packet) error {
 	total := 0
 	for i := range p {
 		lW := len(p[i].W)
@@ -324,12 +383,15 @@ func (s *spiConn) TxPackets(p []spi.Packet) error {
 	if total == 0 {
 		return errors.New("sysfs-spi: empty packets")
 	}
-	if drvSPI.bufSize != 0 && total > drvSPI.bufSize {
-		return fmt.Errorf("sysfs-spi: maximum TxPackets length is %d, got %d bytes", drvSPI.bufSize, total)
+	if s.tooLarge(total) {
+		return &spi.TooLargeError{Size: total, Max: drvSPI.bufSize, Err: fmt.Errorf("sysfs-spi: maximum TxPackets length is %d, got %d bytes", drvSPI.bufSize, total)}
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if s.halfDuplex {
 		for i := range p {
 			if len(p[i].W) != 0 && len(p[i].R) != 0 {
@@ -337,8 +399,31 @@ func (s *spiConn) TxPackets(p []spi.Packet) error {
 			}
 		}
 	}
+	for i := range p {
+		if p[i].DelayAfter < 0 || p[i].DelayAfter > maxDelay {
+			return fmt.Errorf("sysfs-spi: invalid DelayAfter %s; maximum supported is %s: %w", p[i].DelayAfter, maxDelay, conn.ErrUnsupported)
+		}
+		if p[i].WordDelay < 0 || p[i].WordDelay > maxWordDelay {
+			return fmt.Errorf("sysfs-spi: invalid WordDelay %s; maximum supported is %s: %w", p[i].WordDelay, maxWordDelay, conn.ErrUnsupported)
+		}
+		if p[i].WordDelay != 0 && !drvSPI.wordDelay {
+			return fmt.Errorf("sysfs-spi: WordDelay requires Linux 5.2 or later: %w", conn.ErrUnsupported)
+		}
+		if p[i].Speed < 0 || p[i].Speed > physic.GigaHertz {
+			return fmt.Errorf("sysfs-spi: invalid Speed %s; maximum supported clock is 1GHz: %w", p[i].Speed, conn.ErrUnsupported)
+		}
+		if !validWidth(p[i].TxWidth, s.txWidth) {
+			return fmt.Errorf("sysfs-spi: invalid TxWidth %d; the mode specified to Connect() supports up to %d: %w", p[i].TxWidth, s.txWidth, conn.ErrUnsupported)
+		}
+		if !validWidth(p[i].RxWidth, s.rxWidth) {
+			return fmt.Errorf("sysfs-spi: invalid RxWidth %d; the mode specified to Connect() supports up to %d: %w", p[i].RxWidth, s.rxWidth, conn.ErrUnsupported)
+		}
+		if (p[i].TxWidth > 1 || p[i].RxWidth > 1) && len(p[i].W) != 0 && len(p[i].R) != 0 {
+			return errors.New("sysfs-spi: can only specify one of w or r in a dual or quad packet")
+		}
+	}
 	if err := s.txPackets(p); err != nil {
-		return fmt.Errorf("sysfs-spi: TxPackets() failed: %v", err)
+		return fmt.Errorf("sysfs-spi: TxPackets() failed: %w", err)
 	}
 	return nil
 }
@@ -388,27 +473,124 @@ func (s *spiConn) txPackets(p []spi.Packet) error {
 	if s.freqConn != 0 && (s.freqPort == 0 || s.freqConn < s.freqPort) {
 		f = s.freqConn
 	}
+	max := s.chunkSize()
+	n := 0
+	for i := range p {
+		n += chunks(packetLen(&p[i]), max)
+	}
 	var m []spiIOCTransfer
-	if len(p) > len(s.io) {
-		m = make([]spiIOCTransfer, len(p))
+	if n > len(s.io) {
+		m = make([]spiIOCTransfer, n)
 	} else {
-		m = s.io[:len(p)]
+		m = s.io[:n]
 	}
+	j := 0
 	for i := range p {
 		bits := p[i].BitsPerWord
 		if bits == 0 {
 			bits = s.bitsPerWord
 		}
-		csInvert := false
-		if !s.noCS {
-			// Invert CS behavior when a packet has KeepCS false, except for the last
-			// packet when KeepCS is true.
-			last := i == len(p)-1
-			csInvert = p[i].KeepCS == last
+		pf := f
+		if p[i].Speed != 0 {
+			if pf = p[i].Speed; s.freqPort != 0 && pf > s.freqPort {
+				pf = s.freqPort
+			}
+		}
+		l := packetLen(&p[i])
+		for off := 0; ; {
+			c := l - off
+			if max != 0 && c > max {
+				c = max
+			}
+			var w, r []byte
+			if len(p[i].W) != 0 {
+				w = p[i].W[off : off+c]
+			}
+			if len(p[i].R) != 0 {
+				r = p[i].R[off : off+c]
+			}
+			off += c
+			// For now, csChange tells if CS must stay asserted after the transfer.
+			m[j].reset(w, r, pf, bits, off < l || p[i].KeepCS)
+			m[j].wordDelayUsecs = uint8(toMicros(p[i].WordDelay))
+			m[j].txNBits = p[i].TxWidth
+			m[j].rxNBits = p[i].RxWidth
+			if off >= l {
+				// The delay is after the whole packet.
+				m[j].delayUsecs = uint16(toMicros(p[i].DelayAfter))
+			}
+			j++
+			if off >= l {
+				break
+			}
+		}
+	}
+	// Group the transfers in messages that fit max, one ioctl each.
+	for start := 0; start < n; {
+		end, total := start, 0
+		for end < n && (max == 0 || end == start || total+int(m[end].length) <= max) {
+			total += int(m[end].length)
+			end++
+		}
+		for k := start; k < end; k++ {
+			// Invert CS behavior when a transfer doesn't keep CS asserted, except
+			// for the last transfer of the message when it does.
+			last := k == end-1
+			if s.noCS || (m[k].csChange == 1) != last {
+				m[k].csChange = 0
+			} else {
+				m[k].csChange = 1
+			}
+		}
+		if err := s.f.Ioctl(spiIOCTx(end-start), uintptr(unsafe.Pointer(&m[start]))); err != nil {
+			return err
 		}
-		m[i].reset(p[i].W, p[i].R, f, bits, csInvert)
+		start = end
+	}
+	return nil
+}
+
+// validWidth returns true if w data lines are supported when up to max are.
+func validWidth(w, max uint8) bool {
+	return w <= 1 || (w == 2 || w == 4) && w <= max
+}
+
+// toMicros returns d in µs, rounded up.
+func toMicros(d time.Duration) int64 {
+	return int64((d + time.Microsecond - 1) / time.Microsecond)
+}
+
+// tooLarge returns true if a transaction of l bytes must be refused.
+func (s *spiConn) tooLarge(l int) bool {
+	return s.noChunk && drvSPI.bufSize != 0 && l > drvSPI.bufSize
+}
+
+// chunkSize returns the maximum number of bytes per ioctl, 0 if unlimited.
+//
+// It is rounded down to a multiple of 4 bytes so words are not split.
+func (s *spiConn) chunkSize() int {
+	if s.noChunk || drvSPI.bufSize == 0 {
+		return 0
+	}
+	if m := drvSPI.bufSize &^ 3; m != 0 {
+		return m
+	}
+	return drvSPI.bufSize
+}
+
+func packetLen(p *spi.Packet) int {
+	if l := len(p.W); l != 0 {
+		return l
+	}
+	return len(p.R)
+}
+
+// chunks returns the number of transfers needed for l bytes.
+func chunks(l, max int) int {
+	if max == 0 || l <= max {
+		return 1
 	}
-	return s.f.Ioctl(spiIOCTx(len(m)), uintptr(unsafe.Pointer(&m[0])))
+	return (l + max - 1) / max
 }
 
 func (s *spiConn) setFlag(op uint, arg uint64) error {
@@ -455,7 +637,10 @@ const (
 	loop      spi.Mode = 0x20 // loopback mode
 	noCS      spi.Mode = 0x40 // do not assert CS
 	ready     spi.Mode = 0x80 // slave pulls low to pause
-	// The driver optionally support dual and quad data lines.
+	txDual    spi.Mode = 0x100
+	txQuad    spi.Mode = 0x200
+	rxDual    spi.Mode = 0x400
+	rxQuad    spi.Mode = 0x800
 )
 
 // spidev driver IOCTL control codes.
@@ -492,9 +677,18 @@ type spiIOCTransfer struct {
 	csChange    uint8  // true to deassert CS before next transfer
 	txNBits     uint8
 	rxNBits     uint8
-	pad         uint16
+	// µs to wait between words; requires Linux 5.2 or later, ignored before.
+	// See driverSPI.wordDelay.
+	wordDelayUsecs uint8
+	pad            uint8
 }
 
+// Limits of the delays in spiIOCTransfer.
+const (
+	maxDelay     = 65535 * time.Microsecond
+	maxWordDelay = 255 * time.Microsecond
+)
+
 func (s *spiIOCTransfer) reset(w, r []byte, f physic.Frequency, bitsPerWord uint8, csInvert bool) {
 	s.tx = 0
 	s.rx = 0
@@ -518,6 +712,7 @@ func (s *spiIOCTransfer) reset(w, r []byte, f physic.Frequency, bitsPerWord uint
 	}
 	s.txNBits = 0
 	s.rxNBits = 0
+	s.wordDelayUsecs = 0
 	s.pad = 0
 }
 
@@ -527,6 +722,9 @@ func (s *spiIOCTransfer) reset(w, r []byte, f physic.Frequency, bitsPerWord uint
 type driverSPI struct {
 	// bufSize is the maximum number of bytes allowed per I/O on the SPI port.
 	bufSize int
+	// wordDelay is true if the kernel supports the word delay, which was added
+	// in Linux 5.2.
+	wordDelay bool
 }
 
 func (d *driverSPI) String() string {
@@ -579,6 +777,7 @@ func (d *driverSPI) Init() (bool, error) {
 			return true, err
 		}
 	}
+	drvSPI.wordDelay = kernelAtLeast(5, 2)
 	f, err := fs.Open("/sys/module/spidev/parameters/bufsiz", os.O_RDONLY)
 	if err != nil {
 		return true, err
@@ -593,6 +792,45 @@ func (d *driverSPI) Init() (bool, error) {
 	return true, err
 }
 
+// kernelAtLeast returns true if the running kernel is at least version
+// major.minor.
+func kernelAtLeast(major, minor int) bool {
+	f, err := fs.Open("/proc/sys/kernel/osrelease", os.O_RDONLY)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return false
+	}
+	ma, mi, ok := parseKernelRelease(string(b))
+	return ok && (ma > major || (ma == major && mi >= minor))
+}
+
+// parseKernelRelease parses the major and minor version out of a kernel
+// release like "5.4.0-42-generic".
+func parseKernelRelease(r string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimSpace(r), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	// The minor version may be followed by a suffix, e.g. "5.10-rc1".
+	i := 0
+	for i < len(parts[1]) && parts[1][i] >= '0' && parts[1][i] <= '9' {
+		i++
+	}
+	minor, err := strconv.Atoi(parts[1][:i])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
 type openerSPI struct {
 	bus int
 	cs  int
@@ -615,6 +853,7 @@ var _ conn.Limits = &spiConn{}
 var _ io.Reader = &spiConn{}
 var _ io.Writer = &spiConn{}
 var _ spi.Conn = &spiConn{}
+var _ spi.ConnContext = &spiConn{}
 var _ spi.Pins = &SPI{}
 var _ spi.Pins = &spiConn{}
 var _ spi.Port = &SPI{}
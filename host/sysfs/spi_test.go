@@ -5,9 +5,12 @@
 package sysfs
 
 import (
+	"context"
 	"errors"
 	"io"
+	"reflect"
 	"testing"
+	"time"
 
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
@@ -79,8 +82,9 @@ func TestSPI_Tx(t *testing.T) {
 	if err := c.Tx([]byte{0}, []byte{0, 1}); err == nil {
 		t.Fatal("different lengths")
 	}
-	if err := c.Tx(make([]byte, drvSPI.bufSize+1), nil); err.Error() != "sysfs-spi: maximum Tx length is 4096, got 4097 bytes" {
-		t.Fatal("buffer too long")
+	// Split in chunks.
+	if err := c.Tx(make([]byte, drvSPI.bufSize+1), nil); err != nil {
+		t.Fatal(err)
 	}
 	// Inject error.
 	f.ioctlErr = errors.New("foo")
@@ -99,11 +103,12 @@ func TestSPI_TxPackets(t *testing.T) {
 	if err := c.TxPackets(nil); err == nil {
 		t.Fatal("empty TxPackets")
 	}
+	// Split in chunks.
 	pkt := []spi.Packet{
 		{W: make([]byte, drvSPI.bufSize+1)},
 	}
-	if err := c.TxPackets(pkt); err == nil {
-		t.Fatal("buffer too long")
+	if err := c.TxPackets(pkt); err != nil {
+		t.Fatal(err)
 	}
 	pkt = []spi.Packet{
 		{W: []byte{0}, R: []byte{0, 1}},
@@ -124,6 +129,30 @@ func TestSPI_TxPackets(t *testing.T) {
 	}
 }
 
+func TestSPI_TxContext(t *testing.T) {
+	f := ioctlClose{}
+	p := SPI{spiConn{f: &f, busNumber: 24}}
+	c, err := p.Connect(100*physic.Hertz, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.TxContext(context.Background(), c, []byte{0}, []byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	pkt := []spi.Packet{{W: []byte{0}, R: []byte{0}}}
+	if err := spi.TxPacketsContext(context.Background(), c, pkt); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := conn.TxContext(ctx, c, []byte{0}, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+	if err := spi.TxPacketsContext(ctx, c, pkt); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
 func TestSPI_Read(t *testing.T) {
 	f := ioctlClose{}
 	p := SPI{spiConn{f: &f, busNumber: 24}}
@@ -137,7 +166,7 @@ func TestSPI_Read(t *testing.T) {
 	if n, err := c.(io.Reader).Read([]byte{0}); n != 1 || err != nil {
 		t.Fatal(n, err)
 	}
-	if n, err := c.(io.Reader).Read(make([]byte, drvSPI.bufSize+1)); n != 0 || err.Error() != "sysfs-spi: maximum Read length is 4096, got 4097 bytes" {
+	if n, err := c.(io.Reader).Read(make([]byte, drvSPI.bufSize+1)); n != drvSPI.bufSize+1 || err != nil {
 		t.Fatal(n, err)
 	}
 	// Inject error.
@@ -160,7 +189,7 @@ func TestSPI_Write(t *testing.T) {
 	if n, err := c.(io.Writer).Write([]byte{0}); n != 1 || err != nil {
 		t.Fatal(n, err)
 	}
-	if n, err := c.(io.Writer).Write(make([]byte, drvSPI.bufSize+1)); n != 0 || err.Error() != "sysfs-spi: maximum Write length is 4096, got 4097 bytes" {
+	if n, err := c.(io.Writer).Write(make([]byte, drvSPI.bufSize+1)); n != drvSPI.bufSize+1 || err != nil {
 		t.Fatal(n, err)
 	}
 	// Inject error.
@@ -170,6 +199,232 @@ func TestSPI_Write(t *testing.T) {
 	}
 }
 
+func TestSPI_NoChunk(t *testing.T) {
+	p := SPI{spiConn{f: &ioctlClose{}, busNumber: 24}}
+	c, err := p.Connect(100*physic.Hertz, spi.Mode3|spi.NoChunk, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Tx(make([]byte, drvSPI.bufSize+1), nil)
+	if err.Error() != "sysfs-spi: maximum Tx length is 4096, got 4097 bytes" {
+		t.Fatal("buffer too long")
+	}
+	var tl *spi.TooLargeError
+	if !errors.As(err, &tl) || tl.Size != drvSPI.bufSize+1 || tl.Max != drvSPI.bufSize || !errors.Is(err, conn.ErrTooLarge) {
+		t.Fatal(err)
+	}
+	pkt := []spi.Packet{{W: make([]byte, drvSPI.bufSize+1)}}
+	if err := c.TxPackets(pkt); err == nil {
+		t.Fatal("buffer too long")
+	}
+	if n, err := c.(io.Reader).Read(make([]byte, drvSPI.bufSize+1)); n != 0 || err.Error() != "sysfs-spi: maximum Read length is 4096, got 4097 bytes" {
+		t.Fatal(n, err)
+	}
+	if n, err := c.(io.Writer).Write(make([]byte, drvSPI.bufSize+1)); n != 0 || err.Error() != "sysfs-spi: maximum Write length is 4096, got 4097 bytes" {
+		t.Fatal(n, err)
+	}
+}
+
+func TestSPI_Chunks(t *testing.T) {
+	f := ioctlSPI{}
+	p := SPI{spiConn{f: &f, busNumber: 24}}
+	c, err := p.Connect(100*physic.Hertz, spi.Mode3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := make([]byte, 2*drvSPI.bufSize+10)
+	r := make([]byte, len(w))
+	if err := c.Tx(w, r); err != nil {
+		t.Fatal(err)
+	}
+	// CS stays asserted after the first two messages.
+	expected := [][]spiXfer{
+		{{4096, true}},
+		{{4096, true}},
+		{{10, false}},
+	}
+	f.check(t, expected)
+	if f.msgs[1][0].tx != f.msgs[0][0].tx+4096 || f.msgs[1][0].rx != f.msgs[0][0].rx+4096 {
+		t.Fatal("unexpected offsets")
+	}
+
+	f.msgs = nil
+	pkt := []spi.Packet{
+		{W: make([]byte, 10), KeepCS: true},
+		{R: make([]byte, 4100), KeepCS: true},
+		{W: make([]byte, 3000)},
+		{W: make([]byte, 10)},
+	}
+	if err := c.TxPackets(pkt); err != nil {
+		t.Fatal(err)
+	}
+	// In a message, a transfer with csChange set blips CS, except the last
+	// one which keeps CS asserted.
+	expected = [][]spiXfer{
+		{{10, true}},
+		{{4096, true}},
+		{{4, false}, {3000, true}, {10, false}},
+	}
+	f.check(t, expected)
+
+	// The word size is preserved.
+	f.msgs = nil
+	drvSPI.bufSize = 4095
+	defer func() { drvSPI.bufSize = 4096 }()
+	if err := c.Tx(make([]byte, 4096), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, [][]spiXfer{{{4092, true}}, {{4, false}}})
+
+	// Inject error.
+	f.ioctlErr = errors.New("foo")
+	if err := c.Tx(w, nil); err.Error() != "sysfs-spi: Tx() failed: foo" {
+		t.Fatal(err)
+	}
+}
+
+func TestSPI_Timing(t *testing.T) {
+	f := ioctlSPI{}
+	p := SPI{spiConn{f: &f, busNumber: 24}}
+	if err := p.LimitSpeed(10 * physic.MegaHertz); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt := []spi.Packet{
+		{W: []byte{1}, KeepCS: true, DelayAfter: 1500 * time.Nanosecond},
+		{R: make([]byte, drvSPI.bufSize+1), Speed: 20 * physic.MegaHertz, WordDelay: 3 * time.Microsecond, DelayAfter: 10 * time.Microsecond},
+		{W: []byte{2}, Speed: 100 * physic.KiloHertz},
+	}
+	if err := c.TxPackets(pkt); err != nil {
+		t.Fatal(err)
+	}
+	type timing struct {
+		speed, delay uint32
+		wordDelay    uint8
+	}
+	expected := []timing{
+		{1000000, 2, 0},
+		{10000000, 0, 3},
+		{10000000, 10, 3},
+		{100000, 0, 0},
+	}
+	var got []timing
+	for _, m := range f.msgs {
+		for _, x := range m {
+			got = append(got, timing{x.speedHz, uint32(x.delayUsecs), x.wordDelayUsecs})
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("%v != %v", got, expected)
+	}
+	for _, p := range []spi.Packet{
+		{W: []byte{1}, DelayAfter: 66 * time.Millisecond},
+		{W: []byte{1}, DelayAfter: -1},
+		{W: []byte{1}, WordDelay: 256 * time.Microsecond},
+		{W: []byte{1}, Speed: 2 * physic.GigaHertz},
+	} {
+		if err := c.TxPackets([]spi.Packet{p}); !errors.Is(err, conn.ErrUnsupported) {
+			t.Fatal("invalid timing", err)
+		}
+	}
+	// Kernels before 5.2 ignore the word delay.
+	drvSPI.wordDelay = false
+	defer func() { drvSPI.wordDelay = true }()
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}, WordDelay: time.Microsecond}}); !errors.Is(err, conn.ErrUnsupported) {
+		t.Fatal(err)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseKernelRelease(t *testing.T) {
+	data := []struct {
+		r            string
+		major, minor int
+		ok           bool
+	}{
+		{"5.4.0-42-generic\n", 5, 4, true},
+		{"4.19.118-v7+", 4, 19, true},
+		{"5.10-rc1", 5, 10, true},
+		{"6", 0, 0, false},
+		{"a.b", 0, 0, false},
+		{"5.b", 0, 0, false},
+	}
+	for i, line := range data {
+		major, minor, ok := parseKernelRelease(line.r)
+		if major != line.major || minor != line.minor || ok != line.ok {
+			t.Fatalf("#%d: %q: %d, %d, %t", i, line.r, major, minor, ok)
+		}
+	}
+}
+
+func TestSPI_Quad(t *testing.T) {
+	f := ioctlSPI{}
+	p := SPI{spiConn{f: &f, busNumber: 24}}
+	c, err := p.Connect(physic.MegaHertz, spi.Mode0|spi.TxQuad|spi.RxDual, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.modeOp != spiIOCMode32 || f.mode != uint64(txQuad|rxDual) {
+		t.Fatalf("0x%X 0x%X", f.modeOp, f.mode)
+	}
+	pkt := []spi.Packet{
+		{W: []byte{0x3B}, KeepCS: true},
+		{W: make([]byte, 3), TxWidth: 4, KeepCS: true},
+		{R: make([]byte, 8), RxWidth: 2},
+	}
+	if err := c.TxPackets(pkt); err != nil {
+		t.Fatal(err)
+	}
+	m := f.msgs[0]
+	if m[0].txNBits != 0 || m[1].txNBits != 4 || m[2].rxNBits != 2 {
+		t.Fatal(m)
+	}
+	for _, p := range []spi.Packet{
+		{R: make([]byte, 1), RxWidth: 4},
+		{W: make([]byte, 1), TxWidth: 3},
+		{W: make([]byte, 1), R: make([]byte, 1), TxWidth: 2},
+	} {
+		if err := c.TxPackets([]spi.Packet{p}); err == nil {
+			t.Fatal("invalid width")
+		}
+	}
+
+	// Single mode uses the 8 bits ioctl.
+	f = ioctlSPI{}
+	p = SPI{spiConn{f: &f, busNumber: 24}}
+	if c, err = p.Connect(physic.MegaHertz, spi.Mode3, 8); err != nil {
+		t.Fatal(err)
+	}
+	if f.modeOp != spiIOCMode || f.mode != 3 {
+		t.Fatalf("0x%X 0x%X", f.modeOp, f.mode)
+	}
+	if err := c.TxPackets([]spi.Packet{{W: make([]byte, 1), TxWidth: 2}}); err == nil {
+		t.Fatal("dual not enabled")
+	}
+	p = SPI{spiConn{f: &f, busNumber: 24}}
+	if _, err := p.Connect(physic.MegaHertz, spi.Mode0|spi.RxDual|spi.RxQuad, 8); err == nil {
+		t.Fatal("dual and quad")
+	}
+}
+
+func TestSPI_Chunks_NoCS(t *testing.T) {
+	f := ioctlSPI{}
+	p := SPI{spiConn{f: &f, busNumber: 24}}
+	c, err := p.Connect(100*physic.Hertz, spi.Mode0|spi.NoCS, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Tx(make([]byte, drvSPI.bufSize+1), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.check(t, [][]spiXfer{{{4096, false}}, {{1, false}}})
+}
+
 func TestSPI_Pins(t *testing.T) {
 	p := SPI{spiConn{f: &ioctlClose{}, busNumber: 24}}
 	if c := p.CLK(); c != gpio.INVALID {
@@ -367,6 +622,54 @@ func BenchmarkSPI_TxPackets5(b *testing.B) {
 
 //
 
+// spiXfer is the relevant part of a spiIOCTransfer.
+type spiXfer struct {
+	length   uint32
+	csChange bool
+}
+
+// ioctlSPI records the SPI messages.
+type ioctlSPI struct {
+	ioctlClose
+	modeOp uint
+	mode   uint64
+	msgs   [][]spiIOCTransfer
+}
+
+func (i *ioctlSPI) Ioctl(op uint, data uintptr) error {
+	if i.ioctlErr != nil {
+		return i.ioctlErr
+	}
+	if op == spiIOCMode || op == spiIOCMode32 {
+		i.modeOp = op
+		i.mode = *(*uint64)(toPointer(data))
+	}
+	if op&^(0x3FFF<<16) == spiIOCTx(0) {
+		n := int(op>>16&0x3FFF) / 32
+		m := (*[64]spiIOCTransfer)(toPointer(data))[:n:n]
+		i.msgs = append(i.msgs, append([]spiIOCTransfer{}, m...))
+	}
+	return nil
+}
+
+func (i *ioctlSPI) check(t *testing.T, expected [][]spiXfer) {
+	t.Helper()
+	if len(i.msgs) != len(expected) {
+		t.Fatalf("got %d messages, expected %d", len(i.msgs), len(expected))
+	}
+	for j, m := range i.msgs {
+		if len(m) != len(expected[j]) {
+			t.Fatalf("message %d: got %d transfers, expected %d", j, len(m), len(expected[j]))
+		}
+		for k := range m {
+			if x := (spiXfer{m[k].length, m[k].csChange != 0}); x != expected[j][k] {
+				t.Fatalf("message %d transfer %d: got %v, expected %v", j, k, x, expected[j][k])
+			}
+		}
+	}
+}
+
 func init() {
 	drvSPI.bufSize = 4096
+	drvSPI.wordDelay = true
 }
@@ -7,6 +7,12 @@ package sysfs
 import (
 	"os"
 	"syscall"
+	"time"
+	"unsafe"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/i2c"
+	"periph.io/x/periph/host/fs"
 )
 
 const isLinux = true
@@ -15,3 +21,46 @@ func isErrBusy(err error) bool {
 	e, ok := err.(*os.PathError)
 	return ok && e.Err == syscall.EBUSY
 }
+
+// i2cFault returns the error of packages i2c or conn matching the error
+// returned by the I²C kernel driver, or nil.
+//
+// See https://www.kernel.org/doc/html/latest/i2c/fault-codes.html
+func i2cFault(err error) error {
+	switch err {
+	case syscall.ENXIO, syscall.EREMOTEIO:
+		return i2c.ErrNACK
+	case syscall.EAGAIN:
+		return i2c.ErrArbitrationLost
+	case syscall.ETIMEDOUT:
+		return conn.ErrTimeout
+	case syscall.EBUSY:
+		return conn.ErrBusy
+	case syscall.EOPNOTSUPP:
+		return conn.ErrUnsupported
+	}
+	return nil
+}
+
+func gpioLineOpenDefault(fd uintptr, name string) (gpioLineFile, error) {
+	// Make the handle non-blocking so the Go runtime poller is used, which
+	// enables read deadlines.
+	if err := syscall.SetNonblock(int(fd), true); err != nil {
+		_ = syscall.Close(int(fd))
+		return nil, err
+	}
+	return &fs.File{File: os.NewFile(fd, name)}, nil
+}
+
+// clockMonotonic is CLOCK_MONOTONIC in linux/time.h.
+const clockMonotonic = 1
+
+// monotonic returns the current CLOCK_MONOTONIC time, which is the clock used
+// by the kernel to timestamp GPIO edge events.
+func monotonic() time.Duration {
+	var ts syscall.Timespec
+	if _, _, errno := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0); errno != 0 {
+		return 0
+	}
+	return time.Duration(ts.Nano())
+}
@@ -6,9 +6,30 @@
 
 package sysfs
 
+import (
+	"errors"
+	"time"
+)
+
 const isLinux = false
 
 func isErrBusy(err error) bool {
 	// This function is not used on non-linux.
 	return false
 }
+
+func i2cFault(err error) error {
+	// This function is not used on non-linux.
+	return nil
+}
+
+func gpioLineOpenDefault(fd uintptr, name string) (gpioLineFile, error) {
+	return nil, errors.New("sysfs-gpiochip: not supported on non-linux")
+}
+
+// monotonic returns the time elapsed since the process started.
+func monotonic() time.Duration {
+	return time.Since(epoch)
+}
+
+var epoch = time.Now()